@@ -0,0 +1,122 @@
+// Command crawl runs a single provider crawl in-process against an in-memory store and mock Smarty.
+// With -record it saves every fetched page into an archive; with -replay it crawls from such an archive
+// without touching the network, so a real crawl captured once can be replayed deterministically in CI.
+//
+//	go run ./cmd/crawl -provider atmb -links https://www.anytimemailbox.com/l/usa -record testdata/atmb
+//	go run ./cmd/crawl -provider atmb -links https://www.anytimemailbox.com/l/usa -replay testdata/atmb -out result.json
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/internal/business/crawler"
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/internal/platform/smarty"
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/internal/repository"
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/model"
+)
+
+func main() {
+	providerName := flag.String("provider", crawler.SourceATMB, "provider to crawl (atmb or postscanmail)")
+	links := flag.String("links", "", "comma-separated seed links (listing or detail pages)")
+	record := flag.String("record", "", "fetch from the network and save every response into this archive directory")
+	replay := flag.String("replay", "", "serve every fetch from this archive directory instead of the network")
+	workers := flag.Int("workers", 5, "concurrent fetch/parse workers")
+	out := flag.String("out", "", "write the resulting mailboxes as JSON to this file")
+	flag.Parse()
+
+	if *record != "" && *replay != "" {
+		log.Fatalf("-record and -replay are mutually exclusive")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var fetcher crawler.HTMLFetcher = crawler.NewHTTPFetcher()
+	switch {
+	case *record != "":
+		recorder, err := crawler.NewRecordingFetcher(fetcher, *record)
+		if err != nil {
+			log.Fatalf("record: %v", err)
+		}
+		fetcher = recorder
+		log.Printf("recording fetches into %s", *record)
+	case *replay != "":
+		replayer, err := crawler.NewReplayFetcher(*replay)
+		if err != nil {
+			log.Fatalf("replay: %v", err)
+		}
+		fetcher = replayer
+		log.Printf("replaying fetches from %s", *replay)
+	}
+
+	registry := crawler.NewRegistry(
+		crawler.NewATMBProvider(fetcher, nil),
+		crawler.NewPostScanProvider(fetcher),
+	)
+	provider, err := registry.Get(*providerName)
+	if err != nil {
+		log.Fatalf("%v (iPost1 drives a browser and cannot be recorded)", err)
+	}
+
+	var seeds []string
+	for _, l := range strings.Split(*links, ",") {
+		if l = strings.TrimSpace(l); l != "" {
+			seeds = append(seeds, l)
+		}
+	}
+	if checker, ok := provider.(crawler.SeedChecker); ok {
+		if err := checker.CheckSeeds(seeds); err != nil {
+			log.Fatalf("%v", err)
+		}
+	}
+
+	store := repository.NewMemoryMailboxRepository()
+	validator := smarty.New(nil, smarty.Config{Mock: true})
+	runID := "RUN_CLI"
+
+	stats, err := crawler.CrawlProvider(ctx, provider, store, nil, nil, validator, *workers, seeds, runID, nil, func(msg string) {
+		log.Printf("%s: %s", provider.Name(), msg)
+	})
+	if err != nil {
+		log.Fatalf("crawl: %v", err)
+	}
+
+	fmt.Printf("found=%d updated=%d skipped=%d validated=%d failed=%d\n",
+		stats.Found, stats.Updated, stats.Skipped, stats.Validated, stats.Failed)
+	v := stats.Validation
+	fmt.Printf("validation: verified=%d no_match=%d ambiguous=%d error=%d quota_exhausted=%d\n",
+		v.Verified, v.NoMatch, v.Ambiguous, v.Error, v.QuotaExhausted)
+	for _, e := range stats.Errors {
+		fmt.Printf("  %s error %s: %s\n", e.Stage, e.Link, e.Error)
+	}
+
+	if *out != "" {
+		all, err := store.FetchAllMap(ctx)
+		if err != nil {
+			log.Fatalf("read results: %v", err)
+		}
+		mailboxes := make([]model.Mailbox, 0, len(all))
+		for _, m := range all {
+			m.RawHTML = "" // Keep the output reviewable; the archive already holds the pages.
+			mailboxes = append(mailboxes, m)
+		}
+		sort.Slice(mailboxes, func(i, j int) bool { return mailboxes[i].Link < mailboxes[j].Link })
+		data, err := json.MarshalIndent(mailboxes, "", "  ")
+		if err != nil {
+			log.Fatalf("encode results: %v", err)
+		}
+		if err := os.WriteFile(*out, append(data, '\n'), 0o644); err != nil {
+			log.Fatalf("write %s: %v", *out, err)
+		}
+		log.Printf("wrote %d mailboxes to %s", len(mailboxes), *out)
+	}
+}
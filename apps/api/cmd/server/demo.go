@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/internal/business/crawler"
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/model"
+)
+
+// demoRunID ties the fixture mailboxes to a finished crawl run so MarkAndSweep treats them consistently.
+const demoRunID = "RUN_DEMO"
+
+// demoMailboxes is a small, realistic spread of sources, states and CMRA/RDI outcomes
+// so every dashboard filter has something to show.
+var demoMailboxes = []model.Mailbox{
+	{Source: "ATMB", Name: "Chicago - Monroe St", AddressRaw: model.AddressRaw{Street: "73 W Monroe St", City: "Chicago", State: "IL", Zip: "60603"}, Price: 19.99, Link: "https://www.anytimemailbox.com/s/chicago-73-w-monroe-st", CMRA: "Y", RDI: "Commercial"},
+	{Source: "ATMB", Name: "San Francisco - Market St", AddressRaw: model.AddressRaw{Street: "548 Market St", City: "San Francisco", State: "CA", Zip: "94104"}, Price: 14.99, Link: "https://www.anytimemailbox.com/s/san-francisco-548-market-st", CMRA: "Y", RDI: "Commercial"},
+	{Source: "ATMB", Name: "Austin - Congress Ave", AddressRaw: model.AddressRaw{Street: "815 Brazos St", City: "Austin", State: "TX", Zip: "78701"}, Price: 9.99, Link: "https://www.anytimemailbox.com/s/austin-815-brazos-st", CMRA: "N", RDI: "Commercial"},
+	{Source: "ATMB", Name: "Dover - Loockerman St", AddressRaw: model.AddressRaw{Street: "8 The Green", City: "Dover", State: "DE", Zip: "19901"}, Price: 12.99, Link: "https://www.anytimemailbox.com/s/dover-8-the-green", CMRA: "N", RDI: "Residential"},
+	{Source: "iPost1", Name: "iPost1 - Miami, FL", AddressRaw: model.AddressRaw{Street: "1000 Brickell Ave", City: "Miami", State: "FL", Zip: "33131"}, Price: 9.99, Link: "https://ipostal1.com/secure_checkout.php?id=1001", CMRA: "Y", RDI: "Commercial"},
+	{Source: "iPost1", Name: "iPost1 - Denver, CO", AddressRaw: model.AddressRaw{Street: "1600 Broadway", City: "Denver", State: "CO", Zip: "80202"}, Price: 11.99, Link: "https://ipostal1.com/secure_checkout.php?id=1002", CMRA: "N", RDI: "Commercial"},
+	{Source: "iPost1", Name: "iPost1 - Seattle, WA", AddressRaw: model.AddressRaw{Street: "1201 3rd Ave", City: "Seattle", State: "WA", Zip: "98101"}, Price: 15.99, Link: "https://ipostal1.com/secure_checkout.php?id=1003", CMRA: "N", RDI: "Residential"},
+	{Source: "iPost1", Name: "iPost1 - Newark, NJ", AddressRaw: model.AddressRaw{Street: "1 Gateway Ctr", City: "Newark", State: "NJ", Zip: "07102"}, Price: 10.99, Link: "https://ipostal1.com/secure_checkout.php?id=1004", CMRA: "Y", RDI: "Commercial"},
+}
+
+// seedDemoData fills the store with fixture mailboxes, a finished run, and precomputed stats.
+func seedDemoData(ctx context.Context, s stores) error {
+	now := time.Now().UTC()
+	mailboxes := make([]model.Mailbox, len(demoMailboxes))
+	for i, m := range demoMailboxes {
+		m.Active = true
+		m.Plans = []model.Plan{{Name: "Standard", MonthlyPrice: m.Price, BillingPeriod: model.BillingMonthly}}
+		m.CrawlRunID = demoRunID
+		m.ParserVersion = crawler.CurrentParserVersion
+		m.LastParsedAt = now
+		m.LastValidatedAt = now
+		m.StandardizedAddress = model.StandardizedAddress{
+			DeliveryLine1: m.AddressRaw.Street,
+			LastLine:      fmt.Sprintf("%s, %s %s", m.AddressRaw.City, m.AddressRaw.State, m.AddressRaw.Zip),
+		}
+		m.Analysis = model.AddressAnalysis{DPVMatchCode: "Y", DPVVacant: "N", DPVNoStat: "N"}
+		m.ValidationStatus = model.ValidationVerified
+		mailboxes[i] = m
+	}
+	if err := s.mailboxes.BatchUpsert(ctx, mailboxes); err != nil {
+		return fmt.Errorf("seed mailboxes: %w", err)
+	}
+
+	if err := s.runs.CreateRun(ctx, model.CrawlRun{
+		RunID:      demoRunID,
+		Source:     crawler.SourceATMB,
+		Status:     "success",
+		Stats:      model.CrawlRunStats{Found: len(mailboxes), Validated: len(mailboxes)},
+		StartedAt:  now.Add(-5 * time.Minute),
+		FinishedAt: now,
+	}); err != nil {
+		return fmt.Errorf("seed run: %w", err)
+	}
+
+	if err := s.stats.SaveSystemStats(ctx, crawler.AggregateSystemStats(mailboxes)); err != nil {
+		return fmt.Errorf("seed stats: %w", err)
+	}
+	return nil
+}
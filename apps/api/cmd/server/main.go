@@ -2,20 +2,30 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/internal/business/crawler"
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/internal/business/crawler/feed"
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/internal/business/crawler/ipost1"
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/internal/business/crawler/politeness"
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/internal/business/crawler/postscan"
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/internal/business/scheduler"
 	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/internal/platform/config"
 	firestoreclient "github.com/weiwei-tsao/virtualbox-verifier/apps/api/internal/platform/firestore"
 	apirouter "github.com/weiwei-tsao/virtualbox-verifier/apps/api/internal/platform/http"
 	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/internal/platform/smarty"
+	sqliteclient "github.com/weiwei-tsao/virtualbox-verifier/apps/api/internal/platform/sqlite"
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/internal/platform/usps"
 	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/internal/repository"
 )
 
@@ -32,37 +42,64 @@ func main() {
 
 	gin.SetMode(cfg.GinMode)
 
-	firestoreClient, credsSource, err := firestoreclient.New(ctx, cfg)
+	store, err := openStores(ctx, cfg)
 	if err != nil {
-		log.Fatalf("firestore init: %v", err)
+		log.Fatalf("storage init: %v", err)
 	}
-	defer firestoreClient.Close()
+	defer store.close()
 
-	if err := firestoreclient.Ping(ctx, firestoreClient); err != nil {
-		log.Fatalf("firestore ping: %v", err)
+	if cfg.DemoMode {
+		if err := seedDemoData(ctx, store); err != nil {
+			log.Fatalf("seed demo data: %v", err)
+		}
+		log.Printf("DEMO_MODE enabled: seeded in-memory store with fixture data")
 	}
-	log.Printf("connected to Firestore project %s using %s credentials", cfg.FirebaseProjectID, credsSource)
 
-	mailboxRepo := repository.NewMailboxRepository(firestoreClient)
-	runRepo := repository.NewRunRepository(firestoreClient)
-	statsRepo := repository.NewStatsRepository(firestoreClient)
+	validator := newValidator(cfg)
 
-	fetcher := crawler.NewHTTPFetcher()
-	validator := smarty.New(nil, smarty.Config{
-		AuthIDs:    cfg.SmartyAuthIDs,
-		AuthTokens: cfg.SmartyAuthTokens,
-		Mock:       cfg.SmartyMock,
-	})
-	if cfg.SmartyMock {
-		log.Printf("Smarty client initialized in MOCK mode")
-	} else {
-		log.Printf("Smarty client initialized with %d credential(s) for load balancing", len(cfg.SmartyAuthIDs))
+	// Each provider gets its own fetcher so rate limits and retries can be tuned per site.
+	fetcherFor := func(provider string) crawler.HTMLFetcher {
+		fetcher := crawler.NewHTTPFetcherWithPolicy(crawlPolicy(politeness.DefaultPolicy(), cfg.CrawlPolicyFor(provider)))
+		if cfg.CrawlCacheDir == "" {
+			return fetcher
+		}
+		cached, err := crawler.NewCachingFetcher(fetcher, filepath.Join(cfg.CrawlCacheDir, strings.ToLower(provider)), cfg.CrawlOffline)
+		if err != nil {
+			log.Fatalf("crawl cache: %v", err)
+		}
+		return cached
+	}
+	if cfg.CrawlCacheDir != "" {
+		log.Printf("crawl cache at %s (offline=%v)", cfg.CrawlCacheDir, cfg.CrawlOffline)
+	}
+	providers := crawler.NewRegistry(
+		crawler.NewATMBProvider(fetcherFor(crawler.SourceATMB), cfg.CrawlLinkSeeds),
+		crawler.NewIPost1Provider(crawlPolicy(ipost1.DefaultPolicy(), cfg.CrawlPolicyFor(ipost1.Source))),
+		crawler.NewPostScanProvider(fetcherFor(postscan.Source)),
+	)
+	if cfg.FeedConfigFile != "" {
+		feeds, err := feed.LoadConfigs(cfg.FeedConfigFile)
+		if err != nil {
+			log.Fatalf("feed config: %v", err)
+		}
+		for _, fc := range feeds {
+			providers.Register(crawler.NewFeedProvider(fc, fetcherFor(fc.Source)))
+			log.Printf("registered feed provider %s (%s)", fc.Source, fc.Location())
+		}
 	}
 
 	jobManager := crawler.NewJobManager()
-	crawlService := crawler.NewService(fetcher, validator, mailboxRepo, runRepo, statsRepo, 5, cfg.CrawlLinkSeeds, jobManager)
+	crawlService := crawler.NewService(providers, validator, store.mailboxes, store.runs, store.stats, store.history, store.jobs, cfg.CrawlerConcurrency, crawler.SweepPolicy{MinCoverage: cfg.SweepMinCoverage}, jobManager)
+
+	go crawlService.RunWorker(ctx)
+
+	sched := scheduler.New(store.schedules, store.runs, crawlService)
+	if cfg.SchedulerEnabled {
+		go sched.Run(ctx)
+		log.Printf("scheduler started")
+	}
 
-	router := apirouter.NewRouter(mailboxRepo, runRepo, statsRepo, crawlService, cfg.AllowedOrigins)
+	router := apirouter.NewRouter(store.mailboxes, store.runs, store.stats, store.history, crawlService, sched, cfg.AllowedOrigins)
 
 	server := &http.Server{
 		Addr:    ":" + cfg.Port,
@@ -87,3 +124,122 @@ func main() {
 	}
 	log.Println("server exited")
 }
+
+// crawlPolicy applies configured overrides on top of a provider's default politeness policy.
+func crawlPolicy(base politeness.Policy, o config.CrawlPolicy) politeness.Policy {
+	if o.RequestsPerSecond != nil {
+		base.RequestsPerSecond = *o.RequestsPerSecond
+	}
+	if o.Burst != nil {
+		base.Burst = *o.Burst
+	}
+	if o.MaxRetries != nil {
+		base.MaxRetries = *o.MaxRetries
+	}
+	if o.RespectRobots != nil {
+		base.RespectRobots = *o.RespectRobots
+	}
+	if o.UserAgent != nil {
+		base.UserAgent = *o.UserAgent
+	}
+	return base
+}
+
+// newValidator builds the address validator selected by VALIDATOR.
+func newValidator(cfg config.Config) crawler.ValidationClient {
+	if cfg.Validator == config.ValidatorConsensus {
+		backends := make([]crawler.ValidationClient, len(cfg.ConsensusValidators))
+		for i, name := range cfg.ConsensusValidators {
+			backend := cfg
+			backend.Validator = name
+			backends[i] = newValidator(backend)
+		}
+		log.Printf("Consensus validator initialized over %s", strings.Join(cfg.ConsensusValidators, ", "))
+		return crawler.NewConsensusValidator(backends...)
+	}
+	if cfg.Validator == config.ValidatorUSPS {
+		log.Printf("USPS Addresses API client initialized")
+		return usps.New(nil, usps.Config{
+			ClientID:     cfg.USPSClientID,
+			ClientSecret: cfg.USPSClientSecret,
+			BaseURL:      cfg.USPSBaseURL,
+		})
+	}
+	if cfg.SmartyMock {
+		log.Printf("Smarty client initialized in MOCK mode")
+	} else {
+		log.Printf("Smarty client initialized with %d credential(s) for load balancing", len(cfg.SmartyAuthIDs))
+	}
+	return smarty.New(nil, smarty.Config{
+		AuthIDs:    cfg.SmartyAuthIDs,
+		AuthTokens: cfg.SmartyAuthTokens,
+		Mock:       cfg.SmartyMock,
+		Candidates: cfg.SmartyCandidates,
+	})
+}
+
+// stores bundles the repositories of the selected storage backend.
+type stores struct {
+	mailboxes repository.MailboxStore
+	runs      repository.RunStore
+	stats     repository.StatsStore
+	history   repository.HistoryStore
+	schedules repository.ScheduleStore
+	jobs      repository.JobStore
+	close     func()
+}
+
+// openStores connects the storage backend selected by STORAGE_DRIVER and returns its repositories.
+func openStores(ctx context.Context, cfg config.Config) (stores, error) {
+	switch cfg.StorageDriver {
+	case config.StorageSQLite:
+		db, err := sqliteclient.New(ctx, cfg.SQLitePath)
+		if err != nil {
+			return stores{}, err
+		}
+		if err := repository.MigrateSQLite(ctx, db); err != nil {
+			db.Close()
+			return stores{}, err
+		}
+		log.Printf("using SQLite storage at %s", cfg.SQLitePath)
+		return stores{
+			mailboxes: repository.NewSQLiteMailboxRepository(db),
+			runs:      repository.NewSQLiteRunRepository(db),
+			stats:     repository.NewSQLiteStatsRepository(db),
+			history:   repository.NewSQLiteHistoryRepository(db),
+			schedules: repository.NewSQLiteScheduleRepository(db),
+			jobs:      repository.NewSQLiteJobRepository(db),
+			close:     func() { db.Close() },
+		}, nil
+	case config.StorageMemory:
+		log.Printf("using in-memory storage (data is lost on restart)")
+		return stores{
+			mailboxes: repository.NewMemoryMailboxRepository(),
+			runs:      repository.NewMemoryRunRepository(),
+			stats:     repository.NewMemoryStatsRepository(),
+			history:   repository.NewMemoryHistoryRepository(),
+			schedules: repository.NewMemoryScheduleRepository(),
+			jobs:      repository.NewMemoryJobRepository(),
+			close:     func() {},
+		}, nil
+	default:
+		firestoreClient, credsSource, err := firestoreclient.New(ctx, cfg)
+		if err != nil {
+			return stores{}, fmt.Errorf("firestore init: %w", err)
+		}
+		if err := firestoreclient.Ping(ctx, firestoreClient); err != nil {
+			firestoreClient.Close()
+			return stores{}, fmt.Errorf("firestore ping: %w", err)
+		}
+		log.Printf("connected to Firestore project %s using %s credentials", cfg.FirebaseProjectID, credsSource)
+		return stores{
+			mailboxes: repository.NewMailboxRepository(firestoreClient),
+			runs:      repository.NewRunRepository(firestoreClient),
+			stats:     repository.NewStatsRepository(firestoreClient),
+			history:   repository.NewHistoryRepository(firestoreClient),
+			schedules: repository.NewScheduleRepository(firestoreClient),
+			jobs:      repository.NewJobRepository(firestoreClient),
+			close:     func() { firestoreClient.Close() },
+		}, nil
+	}
+}
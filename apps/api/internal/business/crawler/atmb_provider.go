@@ -0,0 +1,94 @@
+package crawler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/model"
+)
+
+// SourceATMB is the source name of AnytimeMailbox mailboxes.
+const SourceATMB = "ATMB"
+
+// ATMBProvider crawls AnytimeMailbox detail pages.
+type ATMBProvider struct {
+	fetcher   HTMLFetcher
+	seedLinks []string
+}
+
+// NewATMBProvider creates the ATMB provider. seedLinks are used when a run is started without links.
+func NewATMBProvider(fetcher HTMLFetcher, seedLinks []string) *ATMBProvider {
+	return &ATMBProvider{fetcher: fetcher, seedLinks: seedLinks}
+}
+
+func (p *ATMBProvider) Name() string          { return SourceATMB }
+func (p *ATMBProvider) ParserVersion() string { return CurrentParserVersion }
+
+// CheckSeeds rejects runs that have neither request links nor configured seeds.
+func (p *ATMBProvider) CheckSeeds(seeds []string) error {
+	if len(seeds) == 0 && len(p.seedLinks) == 0 {
+		return fmt.Errorf("no links provided to crawl (set CRAWL_LINK_SEEDS or pass links in request)")
+	}
+	return nil
+}
+
+// Discover expands listing pages (/l/usa or /l/usa/xx) into detail links; detail links pass through as-is.
+// Seeds are only crawled as they are when discovery worked and found nothing to expand; pages that
+// failed to load are reported, and fail the discovery when nothing else was found.
+func (p *ATMBProvider) Discover(ctx context.Context, seeds []string) ([]model.Mailbox, error) {
+	if len(seeds) == 0 {
+		seeds = p.seedLinks
+	}
+	if err := p.CheckSeeds(seeds); err != nil {
+		return nil, err
+	}
+
+	links := seeds
+	needsDiscovery := false
+	for _, l := range seeds {
+		if strings.Contains(l, "/l/usa") {
+			needsDiscovery = true
+			break
+		}
+	}
+	var partial error // Failed state pages, passed on with the links that were found
+	if needsDiscovery {
+		discovered, err := DiscoverLinks(ctx, p.fetcher, seeds)
+		if len(discovered) == 0 && err != nil {
+			return nil, err
+		}
+		if len(discovered) > 0 {
+			links = discovered
+			partial = err
+		}
+	}
+
+	listings := make([]model.Mailbox, len(links))
+	for i, link := range links {
+		listings[i] = model.Mailbox{Link: link}
+	}
+	return listings, partial
+}
+
+// Parse fetches a detail page and keeps its HTML for reprocessing.
+func (p *ATMBProvider) Parse(ctx context.Context, listing model.Mailbox) (model.Mailbox, error) {
+	htmlBytes, err := fetchPage(ctx, p.fetcher, listing.Link)
+	if err != nil {
+		return model.Mailbox{}, err
+	}
+
+	parsed, err := p.ParseHTML(bytes.NewReader(htmlBytes), listing.Link)
+	if err != nil {
+		return model.Mailbox{}, fmt.Errorf("%w %s: %w", errParse, listing.Link, err)
+	}
+	parsed.RawHTML = string(htmlBytes)
+	return parsed, nil
+}
+
+// ParseHTML parses a stored detail page.
+func (p *ATMBProvider) ParseHTML(r io.Reader, link string) (model.Mailbox, error) {
+	return ParseMailboxHTML(r, link)
+}
@@ -0,0 +1,160 @@
+package crawler
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrNotCached is returned in offline mode for pages that were never cached.
+var ErrNotCached = errors.New("page not in crawl cache")
+
+// ErrUnchanged is returned by a provider's Parse when the page has not changed since the last crawl,
+// so the pipeline can keep the stored record without parsing.
+var ErrUnchanged = errors.New("page unchanged since last crawl")
+
+// ConditionalFetcher is implemented by fetchers that can tell whether a page changed since it was last fetched.
+type ConditionalFetcher interface {
+	HTMLFetcher
+	// FetchIfModified returns the current body and whether it differs from the previously fetched copy.
+	FetchIfModified(ctx context.Context, url string) (io.ReadCloser, bool, error)
+}
+
+// CachingFetcher keeps every fetched page on disk with its ETag/Last-Modified validators and revalidates
+// with If-None-Match/If-Modified-Since on later runs; a 304 is served from the cache.
+// In offline mode nothing is requested and only cached pages are served.
+type CachingFetcher struct {
+	upstream *HTTPFetcher
+	dir      string
+	offline  bool
+}
+
+// cacheEntry is the metadata stored next to each cached body.
+type cacheEntry struct {
+	URL          string    `json:"url"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"lastModified,omitempty"`
+	StoredAt     time.Time `json:"storedAt"`
+}
+
+// NewCachingFetcher caches upstream's pages under dir, creating it if needed.
+func NewCachingFetcher(upstream *HTTPFetcher, dir string, offline bool) (*CachingFetcher, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create crawl cache: %w", err)
+	}
+	return &CachingFetcher{upstream: upstream, dir: dir, offline: offline}, nil
+}
+
+func (f *CachingFetcher) Fetch(ctx context.Context, url string) (io.ReadCloser, error) {
+	body, _, err := f.FetchIfModified(ctx, url)
+	return body, err
+}
+
+// FetchIfModified revalidates the cached copy of url. Offline, a cached page is always reported as modified
+// because the cache is the only source for the run.
+func (f *CachingFetcher) FetchIfModified(ctx context.Context, url string) (io.ReadCloser, bool, error) {
+	entry, cached := f.load(url)
+	if f.offline {
+		if !cached {
+			return nil, false, fmt.Errorf("fetch url %s: %w", url, ErrNotCached)
+		}
+		body, err := os.Open(f.path(url, ".html"))
+		if err != nil {
+			return nil, false, fmt.Errorf("read cache for %s: %w", url, err)
+		}
+		return body, true, nil
+	}
+
+	var header http.Header
+	if cached && (entry.ETag != "" || entry.LastModified != "") {
+		header = http.Header{}
+		if entry.ETag != "" {
+			header.Set("If-None-Match", entry.ETag)
+		}
+		if entry.LastModified != "" {
+			header.Set("If-Modified-Since", entry.LastModified)
+		}
+	}
+
+	resp, err := f.upstream.get(ctx, url, header)
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		body, err := os.Open(f.path(url, ".html"))
+		if err != nil {
+			return nil, false, fmt.Errorf("read cache for %s: %w", url, err)
+		}
+		return body, false, nil
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, false, fmt.Errorf("read %s: %w", url, err)
+	}
+	f.store(url, data, cacheEntry{
+		URL:          url,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		StoredAt:     time.Now().UTC(),
+	})
+	return io.NopCloser(bytes.NewReader(data)), true, nil
+}
+
+func (f *CachingFetcher) path(url, ext string) string {
+	sum := sha256.Sum256([]byte(url))
+	return filepath.Join(f.dir, hex.EncodeToString(sum[:])+ext)
+}
+
+func (f *CachingFetcher) load(url string) (cacheEntry, bool) {
+	var entry cacheEntry
+	data, err := os.ReadFile(f.path(url, ".json"))
+	if err != nil || json.Unmarshal(data, &entry) != nil || entry.URL != url {
+		return cacheEntry{}, false
+	}
+	if _, err := os.Stat(f.path(url, ".html")); err != nil {
+		return cacheEntry{}, false
+	}
+	return entry, true
+}
+
+// store writes the body before its metadata so a crash never leaves validators pointing at a missing body.
+// Cache write failures only cost a future full download, so they are ignored.
+func (f *CachingFetcher) store(url string, body []byte, entry cacheEntry) {
+	meta, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	if writeFileAtomic(f.path(url, ".html"), body) != nil {
+		return
+	}
+	_ = writeFileAtomic(f.path(url, ".json"), meta)
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,167 @@
+package crawler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/model"
+)
+
+func readBody(t *testing.T, body io.ReadCloser) string {
+	t.Helper()
+	defer body.Close()
+	data, err := io.ReadAll(body)
+	if err != nil {
+		t.Fatalf("read body: %v", err)
+	}
+	return string(data)
+}
+
+func TestCachingFetcherRevalidates(t *testing.T) {
+	var full, notModified int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/etag":
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				atomic.AddInt32(&notModified, 1)
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", `"v1"`)
+		case "/last-modified":
+			if r.Header.Get("If-Modified-Since") == "Wed, 01 Jan 2025 00:00:00 GMT" {
+				atomic.AddInt32(&notModified, 1)
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("Last-Modified", "Wed, 01 Jan 2025 00:00:00 GMT")
+		}
+		atomic.AddInt32(&full, 1)
+		_, _ = w.Write([]byte("page " + r.URL.Path))
+	}))
+	defer srv.Close()
+
+	fetcher, err := NewCachingFetcher(NewHTTPFetcherWithPolicy(testPolicy()), t.TempDir(), false)
+	if err != nil {
+		t.Fatalf("NewCachingFetcher: %v", err)
+	}
+	ctx := context.Background()
+
+	for _, path := range []string{"/etag", "/last-modified", "/plain"} {
+		body, modified, err := fetcher.FetchIfModified(ctx, srv.URL+path)
+		if err != nil || !modified {
+			t.Fatalf("first fetch %s: modified=%v err=%v", path, modified, err)
+		}
+		readBody(t, body)
+
+		body, modified, err = fetcher.FetchIfModified(ctx, srv.URL+path)
+		if err != nil {
+			t.Fatalf("second fetch %s: %v", path, err)
+		}
+		if got := readBody(t, body); got != "page "+path {
+			t.Errorf("second fetch %s body = %q", path, got)
+		}
+		wantModified := path == "/plain" // no validators, so always downloaded again
+		if modified != wantModified {
+			t.Errorf("second fetch %s modified = %v, want %v", path, modified, wantModified)
+		}
+	}
+	if notModified != 2 || full != 4 {
+		t.Errorf("server saw %d full and %d 304 responses, want 4 and 2", full, notModified)
+	}
+}
+
+func TestCachingFetcherOffline(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		_, _ = w.Write([]byte("cached page"))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	online, _ := NewCachingFetcher(NewHTTPFetcherWithPolicy(testPolicy()), dir, false)
+	body, err := online.Fetch(context.Background(), srv.URL+"/a")
+	if err != nil {
+		t.Fatalf("online Fetch: %v", err)
+	}
+	readBody(t, body)
+
+	offline, _ := NewCachingFetcher(NewHTTPFetcherWithPolicy(testPolicy()), dir, true)
+	body, err = offline.Fetch(context.Background(), srv.URL+"/a")
+	if err != nil {
+		t.Fatalf("offline Fetch of cached page: %v", err)
+	}
+	if got := readBody(t, body); got != "cached page" {
+		t.Errorf("offline body = %q", got)
+	}
+	if _, err := offline.Fetch(context.Background(), srv.URL+"/b"); !errors.Is(err, ErrNotCached) {
+		t.Errorf("offline miss err = %v, want ErrNotCached", err)
+	}
+	if calls != 1 {
+		t.Errorf("server saw %d requests, want only the online one", calls)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 2 {
+		t.Errorf("cache dir has %d files, want body + metadata", len(entries))
+	}
+}
+
+// conditionalFetcher reports the links in unchanged as not modified.
+type conditionalFetcher struct {
+	html      []byte
+	unchanged map[string]bool
+}
+
+func (f conditionalFetcher) Fetch(ctx context.Context, url string) (io.ReadCloser, error) {
+	return io.NopCloser(bytes.NewReader(f.html)), nil
+}
+
+func (f conditionalFetcher) FetchIfModified(ctx context.Context, url string) (io.ReadCloser, bool, error) {
+	return io.NopCloser(bytes.NewReader(f.html)), !f.unchanged[url], nil
+}
+
+func TestScrapeAndUpsertSkipsUnchangedPages(t *testing.T) {
+	sample, err := os.ReadFile("testdata/sample_page.html")
+	if err != nil {
+		t.Fatalf("read sample html: %v", err)
+	}
+	validated := "https://anytimemailbox.com/locations/validated"
+	unvalidated := "https://anytimemailbox.com/locations/unvalidated"
+	store := &mockStore{existing: map[string]model.Mailbox{
+		// Stale data on purpose: an unchanged page must not be re-parsed.
+		validated:   {ID: "v", Link: validated, Name: "Stored Name", Price: 9.99, CMRA: "Y", Active: true},
+		unvalidated: {ID: "u", Link: unvalidated, Name: "Stored Name", Active: true},
+	}}
+	fetcher := conditionalFetcher{html: sample, unchanged: map[string]bool{validated: true, unvalidated: true}}
+	history := &mockHistory{}
+
+	stats, err := ScrapeAndUpsert(context.Background(), fetcher, store, history, nil, 2, []string{validated, unvalidated}, "RUN_1", nil, nil)
+	if err != nil {
+		t.Fatalf("ScrapeAndUpsert: %v", err)
+	}
+	if stats.Skipped != 1 || stats.Updated != 1 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+	// Records without validation are always parsed so they can be validated.
+	if len(store.saved) != 1 || store.saved[0].ID != "u" || store.saved[0].Name != "Chicago - Monroe St" {
+		t.Errorf("expected only the unvalidated record to be parsed and saved, got %+v", store.saved)
+	}
+	var observed bool
+	for _, p := range history.prices {
+		if p.MailboxID == "v" && p.Price == 9.99 && p.CrawlRunID == "RUN_1" {
+			observed = true
+		}
+	}
+	if !observed {
+		t.Errorf("unchanged page should still record its stored price, got %+v", history.prices)
+	}
+}
@@ -0,0 +1,61 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/model"
+)
+
+// Checkpointer persists the listings a run discovered and which of them are finished,
+// so an interrupted run can continue with only the unfinished ones.
+type Checkpointer interface {
+	SaveCheckpoint(ctx context.Context, cp model.CrawlCheckpoint) error
+	MarkCheckpointDone(ctx context.Context, runID string, links []string) error
+}
+
+// saveCheckpoint records freshly discovered listings. Failures are logged rather than returned:
+// a missing checkpoint only costs the ability to resume, never the crawl itself.
+func saveCheckpoint(ctx context.Context, checkpoints Checkpointer, runID, source string, listings []model.Mailbox, failures []string, logFn func(string)) {
+	if checkpoints == nil {
+		return
+	}
+	cp := model.CrawlCheckpoint{RunID: runID, Source: source, Failures: failures, Listings: make([]model.CheckpointEntry, len(listings))}
+	for i, l := range listings {
+		l.RawHTML = ""
+		cp.Listings[i] = model.CheckpointEntry{Listing: l}
+	}
+	if err := checkpoints.SaveCheckpoint(ctx, cp); err != nil && logFn != nil {
+		logFn(fmt.Sprintf("save checkpoint error: %v", err))
+	}
+}
+
+// markCheckpointDone flags links as finished, logging failures like saveCheckpoint.
+func markCheckpointDone(ctx context.Context, checkpoints Checkpointer, runID string, links []string, logFn func(string)) {
+	if checkpoints == nil || len(links) == 0 {
+		return
+	}
+	if err := checkpoints.MarkCheckpointDone(ctx, runID, links); err != nil && logFn != nil {
+		logFn(fmt.Sprintf("checkpoint error: %v", err))
+	}
+}
+
+// pendingListings returns the checkpointed listings the run has not finished, in discovery order.
+func pendingListings(cp model.CrawlCheckpoint) []model.Mailbox {
+	var pending []model.Mailbox
+	for _, e := range cp.Listings {
+		if !e.Done {
+			pending = append(pending, e.Listing)
+		}
+	}
+	return pending
+}
+
+// discoveredLinks returns the set of links a checkpointed run discovered, for MarkAndSweep.
+func discoveredLinks(cp model.CrawlCheckpoint) map[string]bool {
+	links := make(map[string]bool, len(cp.Listings))
+	for _, e := range cp.Listings {
+		links[e.Listing.Link] = true
+	}
+	return links
+}
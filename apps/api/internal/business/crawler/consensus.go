@@ -0,0 +1,162 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/model"
+)
+
+// ConsensusValidatorName is recorded as the validator of mailboxes validated by a ConsensusValidator.
+const ConsensusValidatorName = "consensus"
+
+// ConsensusValidator sends every address to several validators and settles on the CMRA/RDI most of
+// them agree on. Each backend's verdict is kept on the mailbox, so the classification of any one
+// backend can be audited against the others.
+type ConsensusValidator struct {
+	validators []ValidationClient
+}
+
+// NewConsensusValidator combines validators. Ties go to the earlier validator, and the standardized
+// address comes from the first one that backs the consensus.
+func NewConsensusValidator(validators ...ValidationClient) *ConsensusValidator {
+	return &ConsensusValidator{validators: validators}
+}
+
+// ValidateMailbox validates one mailbox with every backend.
+func (v *ConsensusValidator) ValidateMailbox(ctx context.Context, mailbox model.Mailbox) (model.Mailbox, error) {
+	results, err := v.ValidateMailboxBatch(ctx, []model.Mailbox{mailbox})
+	if len(results) != 1 {
+		return mailbox, err
+	}
+	return results[0], err
+}
+
+// ValidateMailboxBatch sends the batch to every backend in parallel and combines their answers per
+// mailbox. It only fails when every backend failed; a backend that failed alone counts as a verdict
+// with an error status, lowering the confidence.
+func (v *ConsensusValidator) ValidateMailboxBatch(ctx context.Context, mailboxes []model.Mailbox) ([]model.Mailbox, error) {
+	if len(v.validators) == 0 {
+		return nil, errors.New("consensus validator has no backends")
+	}
+	runs := make([][]model.Mailbox, len(v.validators))
+	errs := make([]error, len(v.validators))
+	var wg sync.WaitGroup
+	for b, backend := range v.validators {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Each backend gets its own copy: validators fill in the mailboxes they are given.
+			input := make([]model.Mailbox, len(mailboxes))
+			copy(input, mailboxes)
+			runs[b], errs[b] = backend.ValidateMailboxBatch(ctx, input)
+		}()
+	}
+	wg.Wait()
+
+	out := make([]model.Mailbox, len(mailboxes))
+	answers := make([]model.Mailbox, len(v.validators))
+	for i, mb := range mailboxes {
+		for b := range v.validators {
+			answers[b] = mb
+			err := errs[b]
+			if len(runs[b]) == len(mailboxes) {
+				answers[b] = runs[b][i]
+			} else if err == nil {
+				err = fmt.Errorf("validator returned %d results for %d mailboxes", len(runs[b]), len(mailboxes))
+			}
+			settleValidation(&answers[b], err)
+			if answers[b].Validator == "" {
+				answers[b].Validator = fmt.Sprintf("validator%d", b+1)
+			}
+		}
+		out[i] = combineVerdicts(answers)
+	}
+
+	for _, err := range errs {
+		if err == nil {
+			return out, nil
+		}
+	}
+	return out, fmt.Errorf("every consensus backend failed: %w", errors.Join(errs...))
+}
+
+// combineVerdicts merges one mailbox's answers from every backend. Only verified and ambiguous
+// answers vote; the consensus CMRA and RDI are each the most common vote, and the confidence is the
+// share of all backends that voted for both. Voters that differ leave the mailbox ambiguous.
+func combineVerdicts(answers []model.Mailbox) model.Mailbox {
+	verdicts := make([]model.ValidatorVerdict, len(answers))
+	var voters []int
+	for b, a := range answers {
+		verdicts[b] = model.ValidatorVerdict{
+			Validator: a.Validator,
+			CMRA:      a.CMRA,
+			RDI:       a.RDI,
+			Status:    a.ValidationStatus,
+			Reason:    a.ValidationReason,
+		}
+		if a.ValidationStatus == model.ValidationVerified || a.ValidationStatus == model.ValidationAmbiguous {
+			voters = append(voters, b)
+		}
+	}
+
+	if len(voters) == 0 {
+		// Nobody matched the address: report the first backend's outcome.
+		out := answers[0]
+		out.Verdicts = verdicts
+		out.Confidence = 0
+		out.ValidatorsDisagree = false
+		out.Validator = ConsensusValidatorName
+		return out
+	}
+
+	cmra := mostCommon(answers, voters, func(m model.Mailbox) string { return m.CMRA })
+	rdi := mostCommon(answers, voters, func(m model.Mailbox) string { return m.RDI })
+	base, agree := -1, 0
+	for _, b := range voters {
+		if answers[b].CMRA == cmra && answers[b].RDI == rdi {
+			agree++
+			if base < 0 {
+				base = b
+			}
+		}
+	}
+	if base < 0 {
+		base = voters[0]
+	}
+
+	out := answers[base]
+	out.CMRA = cmra
+	out.RDI = rdi
+	out.Verdicts = verdicts
+	out.Confidence = float64(agree) / float64(len(answers))
+	out.ValidatorsDisagree = agree < len(voters)
+	out.Validator = ConsensusValidatorName
+	if out.ValidatorsDisagree {
+		parts := make([]string, len(voters))
+		for i, b := range voters {
+			parts[i] = fmt.Sprintf("%s %s/%s", answers[b].Validator, answers[b].CMRA, answers[b].RDI)
+		}
+		out.ValidationStatus = model.ValidationAmbiguous
+		out.ValidationReason = "validators disagree on CMRA/RDI: " + strings.Join(parts, ", ")
+	}
+	return out
+}
+
+// mostCommon returns the value of field voted for most often; of tied values, the one that reached
+// that count first wins.
+func mostCommon(answers []model.Mailbox, voters []int, field func(model.Mailbox) string) string {
+	counts := make(map[string]int)
+	best, bestCount := "", 0
+	for _, b := range voters {
+		v := field(answers[b])
+		counts[v]++
+		if counts[v] > bestCount {
+			best, bestCount = v, counts[v]
+		}
+	}
+	return best
+}
@@ -0,0 +1,112 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/model"
+)
+
+// verdictValidator answers each mailbox with the CMRA keyed by its link; links it does not know get
+// no match. RDI is always Commercial. A non-nil err fails the whole batch.
+type verdictValidator struct {
+	name string
+	cmra map[string]string
+	err  error
+}
+
+func (v *verdictValidator) ValidateMailbox(ctx context.Context, m model.Mailbox) (model.Mailbox, error) {
+	m.Validator = v.name
+	if v.err != nil {
+		m.ValidationStatus, m.ValidationReason = model.ValidationError, v.err.Error()
+		return m, v.err
+	}
+	cmra, ok := v.cmra[m.Link]
+	if !ok {
+		m.ValidationStatus = model.ValidationNoMatch
+		return m, nil
+	}
+	m.CMRA, m.RDI = cmra, "Commercial"
+	m.StandardizedAddress.DeliveryLine1 = v.name + " line"
+	m.ValidationStatus = model.ValidationVerified
+	return m, nil
+}
+
+func (v *verdictValidator) ValidateMailboxBatch(ctx context.Context, mailboxes []model.Mailbox) ([]model.Mailbox, error) {
+	out := make([]model.Mailbox, len(mailboxes))
+	for i, m := range mailboxes {
+		out[i], _ = v.ValidateMailbox(ctx, m)
+	}
+	return out, v.err
+}
+
+func TestConsensusValidatorCombinesVerdicts(t *testing.T) {
+	smarty := &verdictValidator{name: "smarty", cmra: map[string]string{"agree": "Y", "split": "Y", "minority": "N", "alone": "Y"}}
+	usps := &verdictValidator{name: "usps", cmra: map[string]string{"agree": "Y", "split": "N", "minority": "Y"}}
+	third := &verdictValidator{name: "third", cmra: map[string]string{"agree": "Y", "split": "N", "minority": "Y"}}
+	v := NewConsensusValidator(smarty, usps, third)
+
+	got, err := v.ValidateMailboxBatch(context.Background(), []model.Mailbox{
+		{Link: "agree"}, {Link: "split"}, {Link: "minority"}, {Link: "alone"}, {Link: "nowhere"},
+	})
+	if err != nil {
+		t.Fatalf("ValidateMailboxBatch: %v", err)
+	}
+
+	tests := []struct {
+		cmra       string
+		confidence float64
+		disagree   bool
+		status     string
+		line       string
+	}{
+		{"Y", 1, false, model.ValidationVerified, "smarty line"},
+		{"N", 2.0 / 3, true, model.ValidationAmbiguous, "usps line"},
+		{"Y", 2.0 / 3, true, model.ValidationAmbiguous, "usps line"},
+		{"Y", 1.0 / 3, false, model.ValidationVerified, "smarty line"},
+		{"", 0, false, model.ValidationNoMatch, ""},
+	}
+	for i, tt := range tests {
+		mb := got[i]
+		if mb.CMRA != tt.cmra || mb.Confidence != tt.confidence || mb.ValidatorsDisagree != tt.disagree || mb.ValidationStatus != tt.status {
+			t.Errorf("%s: cmra %q confidence %.2f disagree %v status %q, want %q %.2f %v %q",
+				mb.Link, mb.CMRA, mb.Confidence, mb.ValidatorsDisagree, mb.ValidationStatus, tt.cmra, tt.confidence, tt.disagree, tt.status)
+		}
+		if mb.StandardizedAddress.DeliveryLine1 != tt.line {
+			t.Errorf("%s: address from %q, want %q", mb.Link, mb.StandardizedAddress.DeliveryLine1, tt.line)
+		}
+		if mb.Validator != ConsensusValidatorName || len(mb.Verdicts) != 3 {
+			t.Errorf("%s: validator %q with %d verdicts", mb.Link, mb.Validator, len(mb.Verdicts))
+		}
+	}
+	if v := got[1].Verdicts[0]; v.Validator != "smarty" || v.CMRA != "Y" || v.Status != model.ValidationVerified {
+		t.Errorf("split: smarty verdict = %+v", v)
+	}
+	if got[1].ValidationReason == "" {
+		t.Errorf("split: expected a reason naming the disagreeing validators")
+	}
+}
+
+func TestConsensusValidatorSurvivesOneBackendFailing(t *testing.T) {
+	down := errors.New("usps down")
+	smarty := &verdictValidator{name: "smarty", cmra: map[string]string{"a": "Y"}}
+	usps := &verdictValidator{name: "usps", err: down}
+	v := NewConsensusValidator(smarty, usps)
+
+	got, err := v.ValidateMailbox(context.Background(), model.Mailbox{Link: "a"})
+	if err != nil {
+		t.Fatalf("ValidateMailbox: %v", err)
+	}
+	if got.CMRA != "Y" || got.Confidence != 0.5 || got.ValidatorsDisagree {
+		t.Errorf("cmra %q confidence %.2f disagree %v, want Y 0.50 false", got.CMRA, got.Confidence, got.ValidatorsDisagree)
+	}
+	if got.Verdicts[1].Status != model.ValidationError {
+		t.Errorf("usps verdict status = %q, want error", got.Verdicts[1].Status)
+	}
+
+	smarty.err = errors.New("smarty down")
+	if _, err := v.ValidateMailbox(context.Background(), model.Mailbox{Link: "a"}); !errors.Is(err, down) {
+		t.Errorf("err = %v, want every backend's error", err)
+	}
+}
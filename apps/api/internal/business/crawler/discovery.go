@@ -12,18 +12,22 @@ const baseURL = "https://www.anytimemailbox.com"
 const detailBaseURL = "https://www.anytimemailbox.com"
 
 // DiscoverLinks parses listing pages to extract ATMB detail links.
+// Seeds and state pages that cannot be loaded are skipped and reported as a *PartialDiscoveryError.
 func DiscoverLinks(ctx context.Context, fetcher HTMLFetcher, seeds []string) ([]string, error) {
 	seen := make(map[string]struct{})
+	var failed []string
 	for _, seed := range seeds {
 		body, err := fetcher.Fetch(ctx, seed)
 		if err != nil {
 			log.Printf("discover: fetch seed %s error: %v", seed, err)
+			failed = append(failed, seed)
 			continue
 		}
 		doc, err := goquery.NewDocumentFromReader(body)
 		body.Close()
 		if err != nil {
 			log.Printf("discover: parse seed %s error: %v", seed, err)
+			failed = append(failed, seed)
 			continue
 		}
 		// Country page: find state links
@@ -33,12 +37,14 @@ func DiscoverLinks(ctx context.Context, fetcher HTMLFetcher, seeds []string) ([]
 				stateBody, err := fetcher.Fetch(ctx, stateLink)
 				if err != nil {
 					log.Printf("discover: fetch state %s error: %v", stateLink, err)
+					failed = append(failed, stateLink)
 					continue
 				}
 				stateDoc, err := goquery.NewDocumentFromReader(stateBody)
 				stateBody.Close()
 				if err != nil {
 					log.Printf("discover: parse state %s error: %v", stateLink, err)
+					failed = append(failed, stateLink)
 					continue
 				}
 				addDetailLinks(stateDoc, seen)
@@ -53,6 +59,9 @@ func DiscoverLinks(ctx context.Context, fetcher HTMLFetcher, seeds []string) ([]
 	for link := range seen {
 		links = append(links, link)
 	}
+	if len(failed) > 0 {
+		return links, &PartialDiscoveryError{Failed: failed}
+	}
 	return links, nil
 }
 
@@ -0,0 +1,62 @@
+package crawler
+
+import (
+	"sync"
+	"time"
+
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/model"
+)
+
+// eventBufferSize is how far a subscriber may fall behind before it starts missing events.
+const eventBufferSize = 256
+
+// EventBus fans live run events out to in-process subscribers such as SSE streams. Publishing
+// never blocks a run: a subscriber that falls behind misses events instead of slowing the crawl.
+type EventBus struct {
+	mu   sync.RWMutex
+	subs map[string]map[chan model.RunEvent]struct{}
+}
+
+func NewEventBus() *EventBus {
+	return &EventBus{subs: make(map[string]map[chan model.RunEvent]struct{})}
+}
+
+// Subscribe returns the events published for runID from now on, and a function that ends the
+// subscription and closes the channel.
+func (b *EventBus) Subscribe(runID string) (<-chan model.RunEvent, func()) {
+	ch := make(chan model.RunEvent, eventBufferSize)
+	b.mu.Lock()
+	if b.subs[runID] == nil {
+		b.subs[runID] = make(map[chan model.RunEvent]struct{})
+	}
+	b.subs[runID][ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subs[runID], ch)
+			if len(b.subs[runID]) == 0 {
+				delete(b.subs, runID)
+			}
+			b.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// Publish delivers ev to the subscribers of its run.
+func (b *EventBus) Publish(ev model.RunEvent) {
+	if ev.Time.IsZero() {
+		ev.Time = time.Now().UTC()
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for ch := range b.subs[ev.RunID] {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
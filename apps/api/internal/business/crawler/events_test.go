@@ -0,0 +1,39 @@
+package crawler
+
+import (
+	"testing"
+
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/model"
+)
+
+func TestEventBusDeliversToRunSubscribers(t *testing.T) {
+	bus := NewEventBus()
+	events, unsubscribe := bus.Subscribe("RUN_1")
+	other, unsubscribeOther := bus.Subscribe("RUN_2")
+	defer unsubscribeOther()
+
+	bus.Publish(model.RunEvent{Type: model.RunEventLog, RunID: "RUN_1", Message: "hello"})
+	ev := <-events
+	if ev.Message != "hello" || ev.Time.IsZero() {
+		t.Errorf("received %+v, want the published log line stamped with a time", ev)
+	}
+	select {
+	case ev := <-other:
+		t.Errorf("subscriber of another run received %+v", ev)
+	default:
+	}
+
+	// A subscriber that stops reading never blocks publishers; it just misses events.
+	for i := 0; i < eventBufferSize+10; i++ {
+		bus.Publish(model.RunEvent{Type: model.RunEventStats, RunID: "RUN_1"})
+	}
+	if len(events) != eventBufferSize {
+		t.Errorf("buffered %d events, want %d", len(events), eventBufferSize)
+	}
+
+	unsubscribe()
+	unsubscribe()
+	for range events {
+	}
+	bus.Publish(model.RunEvent{Type: model.RunEventLog, RunID: "RUN_1"})
+}
@@ -0,0 +1,105 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/model"
+)
+
+// errParse marks a listing whose page was fetched but could not be parsed.
+var errParse = errors.New("parse")
+
+// errNotValidated is recorded for a mailbox the validator returned no CMRA verdict for.
+var errNotValidated = errors.New("validator returned no CMRA result")
+
+// failureStage tells which stage a provider's Parse failed at: fetch unless the page failed to parse.
+func failureStage(err error) string {
+	if errors.Is(err, errParse) {
+		return model.StageParse
+	}
+	return model.StageFetch
+}
+
+func linkFailure(link, stage string, err error, elapsed time.Duration) model.LinkEvent {
+	return model.LinkEvent{
+		Link:       link,
+		Stage:      stage,
+		Error:      err.Error(),
+		DurationMs: elapsed.Milliseconds(),
+		At:         time.Now().UTC(),
+	}
+}
+
+// upsertBatch writes mailboxes, adding each of them to failures if the write fails.
+func upsertBatch(ctx context.Context, store MailboxStore, mailboxes []model.Mailbox, failures *[]model.LinkEvent) error {
+	start := time.Now()
+	err := store.BatchUpsert(ctx, mailboxes)
+	if err != nil {
+		for _, m := range mailboxes {
+			*failures = append(*failures, linkFailure(m.Link, model.StageSave, err, time.Since(start)))
+		}
+	}
+	return err
+}
+
+const (
+	// maxErrorSamples is how many failures a run keeps inline as its ErrorSample; all of them are
+	// stored as the run's events.
+	maxErrorSamples = 20
+	// failureFlushSize is how many failures are buffered before they are written to the run's events.
+	failureFlushSize = 50
+)
+
+// runFailures follows the failure list a pipeline grows for one run: each new failure is streamed to
+// subscribers, the first maxErrorSamples become the run's ErrorSample, and all are saved in batches.
+type runFailures struct {
+	s       *Service
+	runID   string
+	seen    int // Entries of the pipeline's list already handled
+	pending []model.LinkEvent
+	sample  []model.ErrorSample
+}
+
+// newRunFailures starts from the run's existing sample, so a resumed run keeps its first failures.
+func (s *Service) newRunFailures(run model.CrawlRun) *runFailures {
+	return &runFailures{s: s, runID: run.RunID, sample: append([]model.ErrorSample(nil), run.ErrorSample...)}
+}
+
+// observe handles the failures added to all since the last call.
+func (f *runFailures) observe(ctx context.Context, all []model.LinkEvent) {
+	for _, e := range all[min(f.seen, len(all)):] {
+		e.RunID = f.runID
+		f.s.events.Publish(model.RunEvent{Type: model.RunEventLinkError, RunID: f.runID, Link: e.Link, Stage: e.Stage, Message: e.Error})
+		if len(f.sample) < maxErrorSamples {
+			f.sample = append(f.sample, model.ErrorSample{Link: e.Link, Stage: e.Stage, Reason: e.Error})
+		}
+		f.pending = append(f.pending, e)
+	}
+	f.seen = len(all)
+	if len(f.pending) >= failureFlushSize {
+		f.flush(ctx)
+	}
+}
+
+// flush saves the buffered failures. A failed write is logged; the run carries on.
+func (f *runFailures) flush(ctx context.Context) {
+	if len(f.pending) == 0 {
+		return
+	}
+	if err := f.s.runs.AppendRunEvents(ctx, f.runID, f.pending); err != nil {
+		log.Printf("run %s: %v", f.runID, err)
+	}
+	f.pending = nil
+}
+
+// finish handles the pipeline's final failure list and records the sample on run. It saves with
+// a fresh context, since the run's own may already be cancelled.
+func (f *runFailures) finish(all []model.LinkEvent, run *model.CrawlRun) {
+	ctx := context.Background()
+	f.observe(ctx, all)
+	f.flush(ctx)
+	run.ErrorSample = f.sample
+}
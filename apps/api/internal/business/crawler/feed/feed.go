@@ -0,0 +1,101 @@
+package feed
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// ParserVersion tracks the feed mapping logic version for reprocessing support.
+const ParserVersion = "v1.0"
+
+// Supported feed formats.
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+// Fetcher abstracts how remote feeds are fetched (same contract as crawler.HTMLFetcher, redeclared to avoid an import cycle).
+type Fetcher interface {
+	Fetch(ctx context.Context, url string) (io.ReadCloser, error)
+}
+
+// Config describes one structured location feed and how its records map onto mailboxes.
+// A new provider that publishes a CSV/JSON list can be onboarded by adding a Config instead of a Go package.
+type Config struct {
+	Source  string   `json:"source"`  // Source stored on mailboxes and runs, e.g. "TravelingMailbox"
+	URL     string   `json:"url"`     // Remote feed; takes precedence over Path
+	Path    string   `json:"path"`    // Local feed file
+	Format  string   `json:"format"`  // "csv" or "json"; inferred from the URL/path extension when empty
+	Records string   `json:"records"` // JSON only: dotted path to the record array, e.g. "data.locations" (empty = top-level array)
+	Fields  FieldMap `json:"fields"`
+}
+
+// FieldMap names the CSV column (matched case-insensitively) or JSON key (dotted for nested objects)
+// that holds each mailbox field. Empty entries are left unset.
+type FieldMap struct {
+	Name   string `json:"name"`
+	Street string `json:"street"`
+	City   string `json:"city"`
+	State  string `json:"state"`
+	Zip    string `json:"zip"`
+	Price  string `json:"price"`
+	Link   string `json:"link"`
+}
+
+// Location returns where the feed is read from.
+func (c Config) Location() string {
+	if c.URL != "" {
+		return c.URL
+	}
+	return c.Path
+}
+
+// Validate checks that the feed can be read and mapped.
+func (c Config) Validate() error {
+	if strings.TrimSpace(c.Source) == "" {
+		return errors.New("feed source is required")
+	}
+	if c.Location() == "" {
+		return fmt.Errorf("feed %s: url or path is required", c.Source)
+	}
+	if c.Fields.Street == "" || c.Fields.City == "" {
+		return fmt.Errorf("feed %s: street and city fields must be mapped", c.Source)
+	}
+	if f := c.format(c.Location()); f != FormatCSV && f != FormatJSON {
+		return fmt.Errorf("feed %s: unsupported format %q (use %q or %q)", c.Source, f, FormatCSV, FormatJSON)
+	}
+	return nil
+}
+
+// format returns the configured format, or infers it from the location's extension.
+func (c Config) format(location string) string {
+	if c.Format != "" {
+		return strings.ToLower(c.Format)
+	}
+	location, _, _ = strings.Cut(location, "?")
+	return strings.TrimPrefix(strings.ToLower(path.Ext(location)), ".")
+}
+
+// LoadConfigs reads a JSON array of feed configs from a file and validates each one.
+func LoadConfigs(file string) ([]Config, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("read feed config: %w", err)
+	}
+	var configs []Config
+	if err := json.Unmarshal(data, &configs); err != nil {
+		return nil, fmt.Errorf("decode feed config %s: %w", file, err)
+	}
+	for _, c := range configs {
+		if err := c.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return configs, nil
+}
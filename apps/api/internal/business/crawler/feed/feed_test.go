@@ -0,0 +1,109 @@
+package feed
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/model"
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/util"
+)
+
+var csvConfig = Config{
+	Source: "TravelingMailbox",
+	Path:   "testdata/locations.csv",
+	Fields: FieldMap{Name: "Location", Street: "Address", City: "City", State: "State", Zip: "ZIP", Price: "Monthly Price", Link: "URL"},
+}
+
+var jsonConfig = Config{
+	Source:  "EarthClassMail",
+	URL:     "https://example.com/feed.json",
+	Records: "data.locations",
+	Fields:  FieldMap{Name: "title", Street: "address.line1", City: "address.city", State: "address.state", Zip: "address.postal", Price: "pricing.monthly", Link: "url"},
+}
+
+func TestLoadCSV(t *testing.T) {
+	got, err := Load(context.Background(), nil, csvConfig, "")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("got %d mailboxes, want 2 (row without street skipped)", len(got))
+	}
+
+	denver := got[0]
+	if denver.Name != "Denver - Larimer St" || denver.AddressRaw.State != "CO" || denver.Source != "TravelingMailbox" {
+		t.Errorf("unexpected first record: %+v", denver)
+	}
+	if denver.Price != 14.99 || len(denver.Plans) != 1 || denver.Plans[0].BillingPeriod != model.BillingMonthly {
+		t.Errorf("price = %v, plans = %+v, want 14.99 monthly", denver.Price, denver.Plans)
+	}
+
+	boise := got[1]
+	if boise.Name != "TravelingMailbox - Boise, ID" {
+		t.Errorf("default name = %q", boise.Name)
+	}
+	if boise.Price != 1019 {
+		t.Errorf("price = %v, want 1019", boise.Price)
+	}
+	wantLink := "testdata/locations.csv#" + util.HashString(util.FormatAddress(boise.AddressRaw))
+	if boise.Link != wantLink {
+		t.Errorf("fallback link = %q, want %q", boise.Link, wantLink)
+	}
+}
+
+// fileFetcher serves a local file for any URL.
+type fileFetcher string
+
+func (f fileFetcher) Fetch(ctx context.Context, url string) (io.ReadCloser, error) {
+	return os.Open(filepath.Join("testdata", string(f)))
+}
+
+func TestLoadJSON(t *testing.T) {
+	got, err := Load(context.Background(), fileFetcher("locations.json"), jsonConfig, "")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("got %d mailboxes, want 2", len(got))
+	}
+	miami := got[0]
+	want := model.AddressRaw{Street: "1001 Brickell Bay Dr", City: "Miami", State: "FL", Zip: "33131"}
+	if miami.AddressRaw != want || miami.Name != "Miami - Brickell" || miami.Price != 19.5 || miami.Link != "https://example.com/locations/miami" {
+		t.Errorf("unexpected record: %+v", miami)
+	}
+	if got[1].AddressRaw.Zip != "62701" || got[1].Price != 0 || got[1].Plans != nil {
+		t.Errorf("numeric zip / missing price not handled: %+v", got[1])
+	}
+
+	bad := jsonConfig
+	bad.Records = "data.missing"
+	if _, err := Load(context.Background(), fileFetcher("locations.json"), bad, ""); err == nil {
+		t.Errorf("expected an error for a records path that is not an array")
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{name: "csv path", cfg: csvConfig},
+		{name: "json url", cfg: jsonConfig},
+		{name: "missing source", cfg: Config{Path: "a.csv", Fields: csvConfig.Fields}, wantErr: true},
+		{name: "missing location", cfg: Config{Source: "X", Fields: csvConfig.Fields}, wantErr: true},
+		{name: "unmapped street", cfg: Config{Source: "X", Path: "a.csv", Fields: FieldMap{City: "city"}}, wantErr: true},
+		{name: "unknown format", cfg: Config{Source: "X", Path: "a.xml", Fields: csvConfig.Fields}, wantErr: true},
+		{name: "explicit format", cfg: Config{Source: "X", URL: "https://x/export?id=1", Format: "CSV", Fields: csvConfig.Fields}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package feed
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/model"
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/util"
+)
+
+// Load reads the feed at location (a URL fetched through fetcher, or a local file) and maps it into mailboxes.
+// An empty location uses the configured URL or Path.
+func Load(ctx context.Context, fetcher Fetcher, cfg Config, location string) ([]model.Mailbox, error) {
+	if location == "" {
+		location = cfg.Location()
+	}
+
+	var body io.ReadCloser
+	var err error
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		if fetcher == nil {
+			return nil, fmt.Errorf("fetch %s: no fetcher configured", location)
+		}
+		body, err = fetcher.Fetch(ctx, location)
+	} else {
+		body, err = os.Open(location)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open feed %s: %w", location, err)
+	}
+	defer body.Close()
+
+	return Parse(body, cfg, location)
+}
+
+// Parse maps the records of a CSV or JSON feed into mailboxes. location is used to infer the format
+// and to build links for records that do not map one.
+// Records without a street or city are skipped.
+func Parse(r io.Reader, cfg Config, location string) ([]model.Mailbox, error) {
+	var records []map[string]string
+	var err error
+	switch cfg.format(location) {
+	case FormatCSV:
+		records, err = readCSV(r)
+	case FormatJSON:
+		records, err = readJSON(r, cfg.Records)
+	default:
+		return nil, fmt.Errorf("unsupported feed format for %s", location)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read feed %s: %w", location, err)
+	}
+
+	var mailboxes []model.Mailbox
+	for _, rec := range records {
+		if mb, ok := mapRecord(rec, cfg, location); ok {
+			mailboxes = append(mailboxes, mb)
+		}
+	}
+	return mailboxes, nil
+}
+
+func mapRecord(rec map[string]string, cfg Config, location string) (model.Mailbox, bool) {
+	field := func(key string) string {
+		if key == "" {
+			return ""
+		}
+		return strings.Join(strings.Fields(rec[strings.ToLower(key)]), " ")
+	}
+
+	addr := model.AddressRaw{
+		Street: field(cfg.Fields.Street),
+		City:   field(cfg.Fields.City),
+		State:  strings.ToUpper(field(cfg.Fields.State)),
+		Zip:    field(cfg.Fields.Zip),
+	}
+	if addr.Street == "" || addr.City == "" {
+		return model.Mailbox{}, false
+	}
+
+	name := field(cfg.Fields.Name)
+	if name == "" {
+		name = fmt.Sprintf("%s - %s, %s", cfg.Source, addr.City, addr.State)
+	}
+
+	// The link is the dedup key; feeds without one get a stable per-address anchor on the feed location.
+	link := field(cfg.Fields.Link)
+	if link == "" {
+		link = location + "#" + util.HashString(util.FormatAddress(addr))
+	}
+
+	mb := model.Mailbox{
+		Name:       name,
+		AddressRaw: addr,
+		Link:       link,
+		Source:     cfg.Source,
+		Active:     true,
+	}
+	if price := parsePrice(field(cfg.Fields.Price)); price > 0 {
+		mb.Plans = []model.Plan{{Name: "Standard", MonthlyPrice: price, BillingPeriod: model.BillingMonthly}}
+		mb.Price = price
+	}
+	return mb, true
+}
+
+// readCSV returns one map per row, keyed by lower-cased header.
+func readCSV(r io.Reader) ([]map[string]string, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	header := make([]string, len(rows[0]))
+	for i, h := range rows[0] {
+		header[i] = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))
+	}
+
+	records := make([]map[string]string, 0, len(rows)-1)
+	for _, row := range rows[1:] {
+		rec := make(map[string]string, len(header))
+		for i, val := range row {
+			if i < len(header) {
+				rec[header[i]] = val
+			}
+		}
+		records = append(records, rec)
+	}
+	return records, nil
+}
+
+// readJSON returns one flattened map per element of the record array found at recordsPath.
+// Nested objects are flattened into dotted, lower-cased keys (e.g. "address.city").
+func readJSON(r io.Reader, recordsPath string) ([]map[string]string, error) {
+	var doc any
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	if recordsPath != "" {
+		for _, key := range strings.Split(recordsPath, ".") {
+			obj, ok := doc.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("records path %q not found", recordsPath)
+			}
+			doc = obj[key]
+		}
+	}
+	items, ok := doc.([]any)
+	if !ok {
+		return nil, fmt.Errorf("records path %q is not an array", recordsPath)
+	}
+
+	records := make([]map[string]string, 0, len(items))
+	for _, item := range items {
+		obj, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		rec := make(map[string]string)
+		flatten("", obj, rec)
+		records = append(records, rec)
+	}
+	return records, nil
+}
+
+func flatten(prefix string, obj map[string]any, out map[string]string) {
+	for k, v := range obj {
+		key := strings.ToLower(k)
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		switch val := v.(type) {
+		case map[string]any:
+			flatten(key, val, out)
+		case string:
+			out[key] = val
+		case json.Number:
+			out[key] = val.String()
+		case bool:
+			out[key] = strconv.FormatBool(val)
+		}
+	}
+}
+
+// parsePrice extracts the numeric amount from values like "19.99", "$19.99" or "US$ 1,019.99".
+func parsePrice(input string) float64 {
+	var clean strings.Builder
+	for _, r := range input {
+		if (r >= '0' && r <= '9') || r == '.' {
+			clean.WriteRune(r)
+		}
+	}
+	price, err := strconv.ParseFloat(strings.Trim(clean.String(), "."), 64)
+	if err != nil {
+		return 0
+	}
+	return price
+}
@@ -0,0 +1,47 @@
+package crawler
+
+import (
+	"context"
+
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/internal/business/crawler/feed"
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/model"
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/util"
+)
+
+// FeedProvider imports mailboxes from a structured CSV/JSON location list described by a feed.Config.
+type FeedProvider struct {
+	cfg     feed.Config
+	fetcher HTMLFetcher
+}
+
+// NewFeedProvider creates a provider for one configured feed. fetcher is used for URL feeds.
+func NewFeedProvider(cfg feed.Config, fetcher HTMLFetcher) *FeedProvider {
+	return &FeedProvider{cfg: cfg, fetcher: fetcher}
+}
+
+func (p *FeedProvider) Name() string          { return p.cfg.Source }
+func (p *FeedProvider) ParserVersion() string { return feed.ParserVersion }
+
+// Discover loads the configured feed, or each seed (a URL or file path) in its place.
+// Feed records are already complete, so every listing is a full mailbox.
+func (p *FeedProvider) Discover(ctx context.Context, seeds []string) ([]model.Mailbox, error) {
+	if len(seeds) == 0 {
+		seeds = []string{p.cfg.Location()}
+	}
+	var listings []model.Mailbox
+	for _, location := range seeds {
+		records, err := feed.Load(ctx, p.fetcher, p.cfg, location)
+		if err != nil {
+			return nil, err
+		}
+		listings = append(listings, records...)
+	}
+	return listings, nil
+}
+
+// Parse cleans the mapped record; hashing, validation and storage happen in the shared pipeline.
+func (p *FeedProvider) Parse(ctx context.Context, listing model.Mailbox) (model.Mailbox, error) {
+	listing.AddressRaw = util.CleanAddress(listing.AddressRaw)
+	listing.Link = util.CleanLink(listing.Link)
+	return listing, nil
+}
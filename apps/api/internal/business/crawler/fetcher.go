@@ -2,48 +2,130 @@ package crawler
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	neturl "net/url"
 	"time"
+
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/internal/business/crawler/politeness"
 )
 
-// HTTPFetcher fetches HTML pages over HTTP.
+// ErrDisallowedByRobots is returned for URLs the host's robots.txt asks crawlers to skip.
+var ErrDisallowedByRobots = errors.New("disallowed by robots.txt")
+
+// HTTPFetcher fetches HTML pages over HTTP, following a politeness policy: per-host rate limiting,
+// jittered exponential backoff, Retry-After on 429/503, and an optional robots.txt check.
 type HTTPFetcher struct {
-	client *http.Client
+	client  *http.Client
+	policy  politeness.Policy
+	limiter *politeness.HostLimiter
+	robots  *politeness.Robots
 }
 
-// NewHTTPFetcher creates a fetcher with a sane timeout.
+// NewHTTPFetcher creates a fetcher with a sane timeout and the default politeness policy.
 func NewHTTPFetcher() *HTTPFetcher {
-	return &HTTPFetcher{
-		client: &http.Client{Timeout: 20 * time.Second},
+	return NewHTTPFetcherWithPolicy(politeness.DefaultPolicy())
+}
+
+// NewHTTPFetcherWithPolicy creates a fetcher that follows policy.
+func NewHTTPFetcherWithPolicy(policy politeness.Policy) *HTTPFetcher {
+	f := &HTTPFetcher{
+		client:  &http.Client{Timeout: 20 * time.Second},
+		policy:  policy,
+		limiter: politeness.NewHostLimiter(policy.RequestsPerSecond, policy.Burst),
 	}
+	if policy.RespectRobots {
+		f.robots = politeness.NewRobots(policy.UserAgent)
+	}
+	return f
+}
+
+// defaultUserAgent is sent when the policy names no User-Agent of its own.
+const defaultUserAgent = "Mozilla/5.0 (X11; Linux x86_64; rv:128.0) Gecko/20100101 Firefox/128.0"
+
+// userAgent is the identity sent on the wire, the same one robots.txt rules are matched against.
+func (f *HTTPFetcher) userAgent() string {
+	if f.policy.UserAgent != "" {
+		return f.policy.UserAgent
+	}
+	return defaultUserAgent
 }
 
 func (f *HTTPFetcher) Fetch(ctx context.Context, url string) (io.ReadCloser, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	resp, err := f.get(ctx, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// get performs a polite GET with the extra request headers and returns the response when the status is
+// 200, or 304 for conditional requests. Any other outcome is an error once retries are exhausted.
+func (f *HTTPFetcher) get(ctx context.Context, url string, header http.Header) (*http.Response, error) {
+	target, err := neturl.Parse(url)
 	if err != nil {
-		return nil, fmt.Errorf("build request: %w", err)
+		return nil, fmt.Errorf("parse url %s: %w", url, err)
+	}
+	if f.robots != nil && !f.robots.Allowed(ctx, f.client, target) {
+		return nil, fmt.Errorf("fetch url %s: %w", url, ErrDisallowedByRobots)
 	}
-	req.Header.Set("User-Agent", "Mozilla/5.0 (X11; Linux x86_64; rv:128.0) Gecko/20100101 Firefox/128.0")
-	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
-	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
-	req.Header.Set("Referer", "https://www.anytimemailbox.com/")
 
 	var lastErr error
-	for attempt := 0; attempt < 3; attempt++ {
+	var delay time.Duration
+	for attempt := 0; attempt <= f.policy.MaxRetries; attempt++ {
+		if attempt > 0 {
+			if err := politeness.Sleep(ctx, delay); err != nil {
+				return nil, err
+			}
+		}
+		if err := f.limiter.Wait(ctx, target.Host); err != nil {
+			return nil, err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, fmt.Errorf("build request: %w", err)
+		}
+		req.Header.Set("User-Agent", f.userAgent())
+		req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+		req.Header.Set("Accept-Language", "en-US,en;q=0.9")
+		req.Header.Set("Referer", "https://www.anytimemailbox.com/")
+		for key, vals := range header {
+			req.Header[key] = vals
+		}
+
 		resp, err := f.client.Do(req)
 		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
 			lastErr = err
-			time.Sleep(time.Duration(attempt+1) * 500 * time.Millisecond)
+			delay = f.policy.Backoff(attempt + 1)
 			continue
 		}
-		if resp.StatusCode == http.StatusOK {
-			return resp.Body, nil
+		if resp.StatusCode == http.StatusOK || (resp.StatusCode == http.StatusNotModified && header != nil) {
+			return resp, nil
 		}
-		lastErr = fmt.Errorf("status %d for %s", resp.StatusCode, url)
 		resp.Body.Close()
-		time.Sleep(time.Duration(attempt+1) * 500 * time.Millisecond)
+		lastErr = fmt.Errorf("status %d for %s", resp.StatusCode, url)
+		if !politeness.Retryable(resp.StatusCode) {
+			break
+		}
+
+		delay = f.policy.Backoff(attempt + 1)
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+			if after, ok := politeness.RetryAfter(resp.Header, time.Now()); ok {
+				// A host asking for a longer pause than MaxBackoff would stall the worker for it; give
+				// up on the link instead of retrying early against the host's wishes.
+				if f.policy.MaxBackoff > 0 && after > f.policy.MaxBackoff {
+					lastErr = fmt.Errorf("%w: Retry-After %s exceeds max backoff %s", lastErr, after, f.policy.MaxBackoff)
+					break
+				}
+				delay = after
+			}
+		}
 	}
 	return nil, fmt.Errorf("fetch url %s: %w", url, lastErr)
 }
@@ -0,0 +1,151 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/internal/business/crawler/politeness"
+)
+
+func testPolicy() politeness.Policy {
+	return politeness.Policy{MaxRetries: 2, BaseBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond}
+}
+
+func TestHTTPFetcherRetriesWithRetryAfter(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("<html>ok</html>"))
+	}))
+	defer srv.Close()
+
+	body, err := NewHTTPFetcherWithPolicy(testPolicy()).Fetch(context.Background(), srv.URL+"/page")
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	defer body.Close()
+	data, _ := io.ReadAll(body)
+	if string(data) != "<html>ok</html>" || calls != 2 {
+		t.Errorf("body = %q after %d calls, want ok after 2", data, calls)
+	}
+}
+
+func TestHTTPFetcherGivesUpOnLongRetryAfter(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("Retry-After", "86400")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	start := time.Now()
+	if _, err := NewHTTPFetcherWithPolicy(testPolicy()).Fetch(context.Background(), srv.URL+"/page"); err == nil {
+		t.Fatalf("expected an error for a Retry-After beyond MaxBackoff")
+	}
+	if calls != 1 || time.Since(start) > time.Second {
+		t.Errorf("fetched %d times in %s, want one call and no wait", calls, time.Since(start))
+	}
+}
+
+func TestHTTPFetcherDoesNotRetryClientErrors(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	if _, err := NewHTTPFetcherWithPolicy(testPolicy()).Fetch(context.Background(), srv.URL+"/gone"); err == nil {
+		t.Fatalf("expected an error for 404")
+	}
+	if calls != 1 {
+		t.Errorf("404 fetched %d times, want 1", calls)
+	}
+}
+
+func TestHTTPFetcherGivesUpAfterMaxRetries(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	if _, err := NewHTTPFetcherWithPolicy(testPolicy()).Fetch(context.Background(), srv.URL); err == nil {
+		t.Fatalf("expected an error after retries")
+	}
+	if calls != 3 {
+		t.Errorf("fetched %d times, want 1 attempt + 2 retries", calls)
+	}
+}
+
+func TestHTTPFetcherRespectsRobots(t *testing.T) {
+	var pageCalls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			_, _ = w.Write([]byte("User-agent: *\nDisallow: /private\n"))
+			return
+		}
+		atomic.AddInt32(&pageCalls, 1)
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	policy := testPolicy()
+	policy.RespectRobots = true
+	fetcher := NewHTTPFetcherWithPolicy(policy)
+
+	if _, err := fetcher.Fetch(context.Background(), srv.URL+"/private/page"); !errors.Is(err, ErrDisallowedByRobots) {
+		t.Errorf("err = %v, want ErrDisallowedByRobots", err)
+	}
+	body, err := fetcher.Fetch(context.Background(), srv.URL+"/public")
+	if err != nil {
+		t.Fatalf("Fetch allowed page: %v", err)
+	}
+	body.Close()
+	if pageCalls != 1 {
+		t.Errorf("page requests = %d, want only the allowed one", pageCalls)
+	}
+}
+
+func TestHTTPFetcherSendsPolicyUserAgent(t *testing.T) {
+	var sent atomic.Value
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			_, _ = w.Write([]byte("User-agent: virtualboxbot\nDisallow: /bots\n"))
+			return
+		}
+		sent.Store(r.UserAgent())
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	policy := testPolicy()
+	policy.RespectRobots = true
+	policy.UserAgent = "VirtualBoxBot/1.0"
+	fetcher := NewHTTPFetcherWithPolicy(policy)
+
+	// The robots.txt group addressed to the bot applies, because that is who the requests say they are.
+	if _, err := fetcher.Fetch(context.Background(), srv.URL+"/bots/page"); !errors.Is(err, ErrDisallowedByRobots) {
+		t.Errorf("err = %v, want ErrDisallowedByRobots", err)
+	}
+	body, err := fetcher.Fetch(context.Background(), srv.URL+"/public")
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	body.Close()
+	if got := sent.Load(); got != "VirtualBoxBot/1.0" {
+		t.Errorf("User-Agent = %v, want the policy's", got)
+	}
+}
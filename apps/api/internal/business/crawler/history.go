@@ -0,0 +1,53 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/model"
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/util"
+)
+
+// HistoryRecorder persists field-level change records and observed prices for mailboxes.
+type HistoryRecorder interface {
+	AppendHistory(ctx context.Context, entries []model.MailboxHistory) error
+	AppendPrices(ctx context.Context, points []model.PricePoint) error
+}
+
+// recordHistory diffs saved mailboxes against their previous state (keyed by link) and appends change records.
+// New mailboxes have no previous state and produce no record.
+// Failures are logged rather than returned so the audit trail never aborts a crawl.
+func recordHistory(ctx context.Context, history HistoryRecorder, existing map[string]model.Mailbox, saved []model.Mailbox, runID string, logFn func(string)) {
+	if history == nil || len(saved) == 0 {
+		return
+	}
+	now := time.Now().UTC()
+	var entries []model.MailboxHistory
+	for _, next := range saved {
+		key := next.Link
+		if key == "" {
+			key = next.ID
+		}
+		prev, ok := existing[key]
+		if !ok {
+			continue
+		}
+		if entry, changed := util.HistoryEntry(prev, next, runID, now); changed {
+			entries = append(entries, entry)
+		}
+	}
+	if err := history.AppendHistory(ctx, entries); err != nil && logFn != nil {
+		logFn(fmt.Sprintf("record history for %d changes error: %v", len(entries), err))
+	}
+}
+
+// recordPrices appends the given price observations. Like recordHistory, failures are only logged.
+func recordPrices(ctx context.Context, history HistoryRecorder, points []model.PricePoint, logFn func(string)) {
+	if history == nil || len(points) == 0 {
+		return
+	}
+	if err := history.AppendPrices(ctx, points); err != nil && logFn != nil {
+		logFn(fmt.Sprintf("record %d prices error: %v", len(points), err))
+	}
+}
@@ -3,15 +3,42 @@ package ipost1
 import (
 	"context"
 	"fmt"
-	"time"
+	"strings"
 
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/internal/business/crawler/politeness"
 	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/model"
-	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/util"
 )
 
+// Source is the source name of iPost1 mailboxes.
+const Source = "iPost1"
+
+// ParserVersion tracks the locations parser logic version.
+const ParserVersion = "v1.1"
+
+// DefaultPolicy keeps the historical pace of one state request every 2 seconds.
+// Only the rate applies: requests go through the browser session, not an HTTP client.
+func DefaultPolicy() politeness.Policy {
+	return politeness.Policy{RequestsPerSecond: 0.5, Burst: 1}
+}
+
+// PartialError reports the states whose locations could not be fetched or parsed.
+// DiscoverAll returns it together with the locations of every other state.
+type PartialError struct {
+	States []string
+}
+
+func (e *PartialError) Error() string {
+	return fmt.Sprintf("no locations for %d states: %s", len(e.States), strings.Join(e.States, ", "))
+}
+
+// FailedSegments lists the failed states, so the crawler knows its inventory is incomplete.
+func (e *PartialError) FailedSegments() []string { return e.States }
+
 // DiscoverAll fetches all mailbox locations across all US states/territories.
-// Returns a slice of mailboxes ready for validation and storage.
-func DiscoverAll(ctx context.Context, logFn func(string)) ([]model.Mailbox, error) {
+// limiter paces the per-state requests (nil means no pacing).
+// Returns a slice of mailboxes ready for validation and storage; if some states failed,
+// the locations of the others come with a *PartialError.
+func DiscoverAll(ctx context.Context, limiter *politeness.Limiter, logFn func(string)) ([]model.Mailbox, error) {
 	client, err := NewClient()
 	if err != nil {
 		return nil, fmt.Errorf("failed to create client: %w", err)
@@ -33,6 +60,7 @@ func DiscoverAll(ctx context.Context, logFn func(string)) ([]model.Mailbox, erro
 	}
 
 	var allMailboxes []model.Mailbox
+	var failed []string
 
 	// Step 2: Iterate through each state and get locations
 	for i, state := range states {
@@ -46,12 +74,18 @@ func DiscoverAll(ctx context.Context, logFn func(string)) ([]model.Mailbox, erro
 			logFn(fmt.Sprintf("[%d/%d] processing %s (ID: %s)", i+1, len(states), state.Name, state.ID))
 		}
 
+		// Rate limiting: pace requests between states to avoid overwhelming the server
+		if err := limiter.Wait(ctx); err != nil {
+			return allMailboxes, err
+		}
+
 		// Fetch locations for this state
 		response, err := client.GetLocationsByState(state.ID)
 		if err != nil {
 			if logFn != nil {
 				logFn(fmt.Sprintf("error fetching locations for %s: %v", state.Name, err))
 			}
+			failed = append(failed, state.Name)
 			continue
 		}
 
@@ -61,6 +95,7 @@ func DiscoverAll(ctx context.Context, logFn func(string)) ([]model.Mailbox, erro
 			if logFn != nil {
 				logFn(fmt.Sprintf("error parsing locations for %s: %v", state.Name, err))
 			}
+			failed = append(failed, state.Name)
 			continue
 		}
 
@@ -69,195 +104,21 @@ func DiscoverAll(ctx context.Context, logFn func(string)) ([]model.Mailbox, erro
 		}
 
 		allMailboxes = append(allMailboxes, mailboxes...)
-
-		// Rate limiting: wait between states to avoid overwhelming the server
-		if i < len(states)-1 {
-			time.Sleep(2 * time.Second)
-		}
 	}
 
 	if logFn != nil {
 		logFn(fmt.Sprintf("discovery complete: %d total locations found", len(allMailboxes)))
 	}
 
-	return allMailboxes, nil
-}
-
-// ProcessAndValidate discovers all iPost1 locations and validates them with Smarty.
-// This is similar to ATMB's ScrapeAndUpsert but adapted for iPost1's data structure.
-// Uses batch validation to reduce API calls by up to 99%.
-func ProcessAndValidate(
-	ctx context.Context,
-	validator ValidationClient,
-	store MailboxStore,
-	runID string,
-	logFn func(string),
-) (Stats, error) {
-	stats := Stats{}
-
-	// Discover all locations
-	discovered, err := DiscoverAll(ctx, logFn)
-	if err != nil {
-		return stats, fmt.Errorf("discovery failed: %w", err)
-	}
-
-	stats.Found = len(discovered)
-
-	if stats.Found == 0 {
-		return stats, fmt.Errorf("no locations discovered")
-	}
-
-	// Fetch existing mailboxes for deduplication
-	existing, err := store.FetchAllMetadata(ctx)
-	if err != nil {
-		return stats, fmt.Errorf("failed to fetch existing mailboxes: %w", err)
-	}
-
-	var toSave []model.Mailbox
-	var toValidateIndices []int // Track indices that need validation
-	const batchSize = 20        // Write every 20 items
-
-	for i, mb := range discovered {
-		select {
-		case <-ctx.Done():
-			return stats, ctx.Err()
-		default:
-		}
-
-		// Clean address data (remove HTML remnants from scraper)
-		mb.AddressRaw = util.CleanAddress(mb.AddressRaw)
-		mb.Link = util.CleanLink(mb.Link)
-
-		// Set metadata fields
-		mb.CrawlRunID = runID
-		mb.Active = true
-		mb.Source = "iPost1"
-
-		// Generate unique hash for deduplication
-		mb.DataHash = hashMailbox(mb)
-
-		// Check if already exists with same data
-		if prev, ok := existing[mb.Link]; ok {
-			if prev.DataHash == mb.DataHash && prev.CMRA != "" {
-				stats.Skipped++
-				continue
-			}
-			// Preserve ID for updates
-			mb.ID = prev.ID
-		}
-
-		// Track if validation needed
-		needsValidation := mb.CMRA == "" || mb.RDI == ""
-
-		toSave = append(toSave, mb)
-		if needsValidation && validator != nil {
-			toValidateIndices = append(toValidateIndices, len(toSave)-1)
-		}
-		stats.Updated++
-
-		// Incremental write with batch validation
-		if len(toSave) >= batchSize {
-			// Batch validate before writing
-			if len(toValidateIndices) > 0 && validator != nil {
-				toSave, stats = batchValidateSubset(ctx, validator, toSave, toValidateIndices, stats, logFn)
-				toValidateIndices = toValidateIndices[:0]
-			}
-
-			if err := store.BatchUpsert(ctx, toSave); err != nil {
-				return stats, fmt.Errorf("batch upsert failed: %w", err)
-			}
-			if logFn != nil {
-				logFn(fmt.Sprintf("wrote %d items to DB (%d/%d processed)", len(toSave), i+1, stats.Found))
-			}
-			toSave = toSave[:0]
-		}
-	}
-
-	// Final write with batch validation
-	if len(toSave) > 0 {
-		// Batch validate remaining items
-		if len(toValidateIndices) > 0 && validator != nil {
-			toSave, stats = batchValidateSubset(ctx, validator, toSave, toValidateIndices, stats, logFn)
-		}
-
-		if err := store.BatchUpsert(ctx, toSave); err != nil {
-			return stats, fmt.Errorf("final batch upsert failed: %w", err)
-		}
-		if logFn != nil {
-			logFn(fmt.Sprintf("wrote final %d items to DB", len(toSave)))
-		}
-	}
-
-	return stats, nil
-}
-
-// batchValidateSubset validates a subset of mailboxes by their indices using batch API.
-func batchValidateSubset(
-	ctx context.Context,
-	validator ValidationClient,
-	mailboxes []model.Mailbox,
-	indices []int,
-	stats Stats,
-	logFn func(string),
-) ([]model.Mailbox, Stats) {
-	if len(indices) == 0 {
-		return mailboxes, stats
-	}
-
-	// Extract subset to validate
-	subset := make([]model.Mailbox, len(indices))
-	for i, idx := range indices {
-		subset[i] = mailboxes[idx]
-	}
-
-	// Batch validate
-	validated, err := validator.ValidateMailboxBatch(ctx, subset)
-	if err != nil {
-		// On error, count all as failed
-		stats.Failed += len(indices)
-		if logFn != nil {
-			logFn(fmt.Sprintf("batch validation failed for %d items: %v", len(indices), err))
-		}
-		return mailboxes, stats
+	if len(failed) > 0 {
+		return allMailboxes, &PartialError{States: failed}
 	}
-
-	// Merge results back
-	for i, idx := range indices {
-		mailboxes[idx] = validated[i]
-		if validated[i].CMRA != "" {
-			stats.Validated++
-		} else {
-			stats.Failed++
-		}
-	}
-
-	return mailboxes, stats
-}
-
-// Stats tracks the progress of iPost1 crawl.
-type Stats struct {
-	Found     int
-	Updated   int
-	Skipped   int
-	Validated int
-	Failed    int
-}
-
-// ValidationClient interface for Smarty API validation.
-type ValidationClient interface {
-	ValidateMailbox(ctx context.Context, mb model.Mailbox) (model.Mailbox, error)
-	ValidateMailboxBatch(ctx context.Context, mailboxes []model.Mailbox) ([]model.Mailbox, error)
-}
-
-// MailboxStore interface for database operations.
-type MailboxStore interface {
-	FetchAllMap(ctx context.Context) (map[string]model.Mailbox, error)
-	FetchAllMetadata(ctx context.Context) (map[string]model.Mailbox, error)
-	BatchUpsert(ctx context.Context, mailboxes []model.Mailbox) error
+	return allMailboxes, nil
 }
 
-// hashMailbox creates a unique hash for deduplication.
-func hashMailbox(mb model.Mailbox) string {
+// HashMailbox creates a unique hash for deduplication.
+// It predates util.HashMailboxKey; changing it would make every stored iPost1 record look modified.
+func HashMailbox(mb model.Mailbox) string {
 	// Simple hash based on name and address
 	key := fmt.Sprintf("%s|%s|%s|%s|%s",
 		mb.Name,
@@ -267,6 +128,5 @@ func hashMailbox(mb model.Mailbox) string {
 		mb.AddressRaw.Zip,
 	)
 
-	// Use a simple hash for now (you can import util.HashMailboxKey if available)
 	return fmt.Sprintf("%x", []byte(key))
 }
@@ -7,6 +7,7 @@ import (
 
 	"github.com/PuerkitoBio/goquery"
 	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/model"
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/util"
 )
 
 // ParseLocationsHTML extracts mailbox data from iPost1 HTML fragments.
@@ -39,9 +40,9 @@ func ParseLocationsHTML(htmlContent string) ([]model.Mailbox, error) {
 		cityStateZip := extractTextAfterLabel(cityStateZipHTML)
 		city, state, zip := parseCityStateZip(cityStateZip)
 
-		// Extract price from the desktop view
-		priceText := strings.TrimSpace(s.Find(".store-plan-desktop b").Text())
-		price := parsePrice(priceText)
+		// Extract plans from the desktop view (the mobile view repeats them)
+		plans := parsePlans(s)
+		price := util.CheapestMonthlyPrice(plans)
 
 		// Extract link to checkout page
 		link, _ := s.Find("a[href*='secure_checkout']").Attr("href")
@@ -63,8 +64,9 @@ func ParseLocationsHTML(htmlContent string) ([]model.Mailbox, error) {
 				Zip:    zip,
 			}
 			mb.Price = price
+			mb.Plans = plans
 			mb.Link = link
-			mb.Source = "iPost1"
+			mb.Source = Source
 
 			mailboxes = append(mailboxes, mb)
 		}
@@ -73,6 +75,40 @@ func ParseLocationsHTML(htmlContent string) ([]model.Mailbox, error) {
 	return mailboxes, nil
 }
 
+// parsePlans reads one plan per `.store-plan-desktop` block. The price is the bold text
+// (e.g., "$15.95/month"); the name comes from `.plan-name` and defaults to "Standard".
+func parsePlans(card *goquery.Selection) []model.Plan {
+	var plans []model.Plan
+	card.Find(".store-plan-desktop").Each(func(_ int, s *goquery.Selection) {
+		priceText := strings.TrimSpace(s.Find("b").First().Text())
+		listed := parsePrice(priceText)
+		if listed <= 0 {
+			return
+		}
+
+		name := strings.TrimSpace(s.Find(".plan-name").First().Text())
+		if name == "" {
+			name = "Standard"
+		}
+
+		var features []string
+		s.Find("li").Each(func(_ int, li *goquery.Selection) {
+			if txt := strings.Join(strings.Fields(li.Text()), " "); txt != "" {
+				features = append(features, txt)
+			}
+		})
+
+		period := util.BillingPeriod(priceText)
+		plans = append(plans, model.Plan{
+			Name:          name,
+			MonthlyPrice:  util.MonthlyPrice(listed, period),
+			BillingPeriod: period,
+			Features:      features,
+		})
+	})
+	return plans
+}
+
 // extractTextAfterLabel removes HTML labels and placeholders, extracting only the actual text.
 // Example: "<span>Street Address:</span> 123 Main St" -> "123 Main St"
 func extractTextAfterLabel(htmlContent string) string {
@@ -2,6 +2,8 @@ package ipost1
 
 import (
 	"testing"
+
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/model"
 )
 
 func TestParseLocationsHTML(t *testing.T) {
@@ -123,6 +125,40 @@ func TestParseLocationsHTML(t *testing.T) {
 	}
 }
 
+func TestParseLocationsHTMLPlans(t *testing.T) {
+	html := `
+		<article class="mail-center-card">
+			<div class="store-street-address"><span>Street Address:</span> 123 Main St</div>
+			<div class="store-city-state-zip"><span>City, State Zip:</span> San Francisco, CA 94102</div>
+			<div class="store-plan-desktop"><span class="plan-name">Premium</span> <b>$24.95/month</b><ul><li>Unlimited scans</li></ul></div>
+			<div class="store-plan-desktop"><b>$15.95/month</b></div>
+			<div class="store-plan-mobile"><b>$15.95/month</b></div>
+		</article>
+	`
+
+	mailboxes, err := ParseLocationsHTML(html)
+	if err != nil {
+		t.Fatalf("ParseLocationsHTML: %v", err)
+	}
+	if len(mailboxes) != 1 {
+		t.Fatalf("got %d mailboxes, want 1", len(mailboxes))
+	}
+
+	mb := mailboxes[0]
+	if len(mb.Plans) != 2 {
+		t.Fatalf("plans = %+v, want 2 desktop plans", mb.Plans)
+	}
+	if p := mb.Plans[0]; p.Name != "Premium" || p.MonthlyPrice != 24.95 || p.BillingPeriod != model.BillingMonthly || len(p.Features) != 1 {
+		t.Errorf("plans[0] = %+v", p)
+	}
+	if p := mb.Plans[1]; p.Name != "Standard" || p.MonthlyPrice != 15.95 {
+		t.Errorf("plans[1] = %+v, want Standard 15.95", p)
+	}
+	if mb.Price != 15.95 {
+		t.Errorf("price = %v, want cheapest plan 15.95", mb.Price)
+	}
+}
+
 func TestParseCityStateZip(t *testing.T) {
 	tests := []struct {
 		input     string
@@ -0,0 +1,40 @@
+package crawler
+
+import (
+	"context"
+	"log"
+
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/internal/business/crawler/ipost1"
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/internal/business/crawler/politeness"
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/model"
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/util"
+)
+
+// IPost1Provider crawls iPost1 through its state-by-state locations API.
+type IPost1Provider struct {
+	policy politeness.Policy
+}
+
+// NewIPost1Provider creates the iPost1 provider; policy paces the per-state requests (see ipost1.DefaultPolicy).
+func NewIPost1Provider(policy politeness.Policy) *IPost1Provider {
+	return &IPost1Provider{policy: policy}
+}
+
+func (p *IPost1Provider) Name() string          { return ipost1.Source }
+func (p *IPost1Provider) ParserVersion() string { return ipost1.ParserVersion }
+
+// Discover loads every location; the API response already carries full records, so seeds are ignored.
+func (p *IPost1Provider) Discover(ctx context.Context, seeds []string) ([]model.Mailbox, error) {
+	limiter := politeness.NewLimiter(p.policy.RequestsPerSecond, p.policy.Burst)
+	return ipost1.DiscoverAll(ctx, limiter, func(msg string) {
+		log.Printf("ipost1: %s", msg)
+	})
+}
+
+// Parse cleans HTML remnants left by the locations API and applies iPost1's own dedup hash.
+func (p *IPost1Provider) Parse(ctx context.Context, listing model.Mailbox) (model.Mailbox, error) {
+	listing.AddressRaw = util.CleanAddress(listing.AddressRaw)
+	listing.Link = util.CleanLink(listing.Link)
+	listing.DataHash = ipost1.HashMailbox(listing)
+	return listing, nil
+}
@@ -3,19 +3,29 @@ package crawler
 import (
 	"context"
 	"sync"
+	"time"
 )
 
-// JobManager manages cancel functions for running crawler jobs.
-// It allows external cancellation of jobs by their run ID.
+// JobManager manages cancel functions and pause state for running crawler jobs.
+// It allows external cancellation, pausing and resuming of jobs by their run ID.
 type JobManager struct {
 	mu      sync.RWMutex
 	cancels map[string]context.CancelFunc
+	pauses  map[string]*pauseState
+}
+
+// pauseState tracks whether a job is paused and how long it has spent paused.
+type pauseState struct {
+	resumed chan struct{} // Closed on resume; nil while the job runs
+	since   time.Time     // When the current pause began
+	total   time.Duration // Time spent in earlier pauses
 }
 
 // NewJobManager creates a new JobManager instance.
 func NewJobManager() *JobManager {
 	return &JobManager{
 		cancels: make(map[string]context.CancelFunc),
+		pauses:  make(map[string]*pauseState),
 	}
 }
 
@@ -25,6 +35,7 @@ func (jm *JobManager) Register(runID string, cancel context.CancelFunc) {
 	jm.mu.Lock()
 	defer jm.mu.Unlock()
 	jm.cancels[runID] = cancel
+	jm.pauses[runID] = &pauseState{}
 }
 
 // Cancel invokes the cancel function for a job if it exists.
@@ -35,6 +46,7 @@ func (jm *JobManager) Cancel(runID string) bool {
 	if cancel, ok := jm.cancels[runID]; ok {
 		cancel()
 		delete(jm.cancels, runID)
+		jm.release(runID)
 		return true
 	}
 	return false
@@ -46,6 +58,15 @@ func (jm *JobManager) Unregister(runID string) {
 	jm.mu.Lock()
 	defer jm.mu.Unlock()
 	delete(jm.cancels, runID)
+	jm.release(runID)
+}
+
+// release drops a job's pause state, waking its crawl loops if it was paused. The caller holds mu.
+func (jm *JobManager) release(runID string) {
+	if p, ok := jm.pauses[runID]; ok && p.resumed != nil {
+		close(p.resumed)
+	}
+	delete(jm.pauses, runID)
 }
 
 // IsRunning checks if a job is currently registered (running).
@@ -55,3 +76,95 @@ func (jm *JobManager) IsRunning(runID string) bool {
 	_, ok := jm.cancels[runID]
 	return ok
 }
+
+// Pause holds a running job: its crawl loops stop taking new work at their next WaitIfPaused.
+// Work already in flight finishes. Returns true if the job was found and was not already paused.
+func (jm *JobManager) Pause(runID string) bool {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+	p, ok := jm.pauses[runID]
+	if !ok || p.resumed != nil {
+		return false
+	}
+	p.resumed = make(chan struct{})
+	p.since = time.Now()
+	return true
+}
+
+// Resume lets a paused job continue. Returns true if the job was found and paused.
+func (jm *JobManager) Resume(runID string) bool {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+	p, ok := jm.pauses[runID]
+	if !ok || p.resumed == nil {
+		return false
+	}
+	close(p.resumed)
+	p.resumed = nil
+	p.total += time.Since(p.since)
+	return true
+}
+
+// IsPaused checks if a job is registered and currently paused.
+func (jm *JobManager) IsPaused(runID string) bool {
+	jm.mu.RLock()
+	defer jm.mu.RUnlock()
+	p, ok := jm.pauses[runID]
+	return ok && p.resumed != nil
+}
+
+// PausedFor returns how long a job has spent paused so far, including a pause still in effect.
+func (jm *JobManager) PausedFor(runID string) time.Duration {
+	jm.mu.RLock()
+	defer jm.mu.RUnlock()
+	p, ok := jm.pauses[runID]
+	if !ok {
+		return 0
+	}
+	if p.resumed != nil {
+		return p.total + time.Since(p.since)
+	}
+	return p.total
+}
+
+// WaitIfPaused blocks while a job is paused. It returns ctx's error, so a loop can both
+// wait out a pause and stop once its job is cancelled.
+func (jm *JobManager) WaitIfPaused(ctx context.Context, runID string) error {
+	for {
+		jm.mu.RLock()
+		var resumed chan struct{}
+		if p, ok := jm.pauses[runID]; ok {
+			resumed = p.resumed
+		}
+		jm.mu.RUnlock()
+		if resumed == nil {
+			return ctx.Err()
+		}
+		select {
+		case <-resumed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// pauseKey marks a context whose crawl loops hold while its job is paused.
+type pauseKey struct{}
+
+type pauseRef struct {
+	jm    *JobManager
+	runID string
+}
+
+func withPause(ctx context.Context, jm *JobManager, runID string) context.Context {
+	return context.WithValue(ctx, pauseKey{}, pauseRef{jm: jm, runID: runID})
+}
+
+// waitIfPaused blocks while the job ctx belongs to is paused, then returns ctx's error.
+// Without a context from withPause it only reports ctx's error.
+func waitIfPaused(ctx context.Context) error {
+	if ref, ok := ctx.Value(pauseKey{}).(pauseRef); ok {
+		return ref.jm.WaitIfPaused(ctx, ref.runID)
+	}
+	return ctx.Err()
+}
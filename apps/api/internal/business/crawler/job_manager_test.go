@@ -0,0 +1,47 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestJobManagerPauseResume(t *testing.T) {
+	jm := NewJobManager()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if jm.Pause("RUN_1") {
+		t.Fatalf("paused a job that is not registered")
+	}
+	jm.Register("RUN_1", cancel)
+	if !jm.Pause("RUN_1") || jm.Pause("RUN_1") || !jm.IsPaused("RUN_1") {
+		t.Fatalf("Pause should succeed once and leave the job paused")
+	}
+
+	waited := make(chan error, 1)
+	go func() { waited <- jm.WaitIfPaused(ctx, "RUN_1") }()
+	select {
+	case err := <-waited:
+		t.Fatalf("WaitIfPaused returned %v while paused", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	if !jm.Resume("RUN_1") || jm.Resume("RUN_1") || jm.IsPaused("RUN_1") {
+		t.Fatalf("Resume should succeed once and leave the job running")
+	}
+	if err := <-waited; err != nil {
+		t.Fatalf("WaitIfPaused after resume = %v", err)
+	}
+
+	// Cancelling a paused job releases its waiters with the context's error.
+	jm.Pause("RUN_1")
+	go func() { waited <- jm.WaitIfPaused(ctx, "RUN_1") }()
+	jm.Cancel("RUN_1")
+	if err := <-waited; !errors.Is(err, context.Canceled) {
+		t.Errorf("WaitIfPaused after cancel = %v, want context.Canceled", err)
+	}
+	if jm.IsPaused("RUN_1") || jm.IsRunning("RUN_1") {
+		t.Errorf("cancelled job still registered")
+	}
+}
@@ -2,14 +2,24 @@ package crawler
 
 import (
 	"context"
+	"log"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/model"
 )
 
-// WorkerFn processes a mailbox URL and returns the parsed mailbox.
-type WorkerFn func(ctx context.Context, url string) (model.Mailbox, error)
+// WorkerFn fetches and parses one listing into a mailbox.
+type WorkerFn func(ctx context.Context, listing model.Mailbox) (model.Mailbox, error)
+
+// Result is the outcome of one listing; exactly one of Mailbox or Err is meaningful.
+type Result struct {
+	Listing model.Mailbox
+	Mailbox model.Mailbox
+	Err     error
+	Elapsed time.Duration // Time spent fetching and parsing the listing
+}
 
 // Orchestrator coordinates concurrent scraping with cancellation support.
 type Orchestrator struct {
@@ -23,37 +33,29 @@ func NewOrchestrator(workerCount int) *Orchestrator {
 	return &Orchestrator{workerCount: workerCount}
 }
 
-// Run processes the provided URLs with bounded concurrency. It stops when context is canceled
-// or when all URLs are processed. Results and errors are pushed into the provided channels.
-func (o *Orchestrator) Run(ctx context.Context, urls []string, fn WorkerFn) (<-chan model.Mailbox, <-chan error) {
-	out := make(chan model.Mailbox)
-	errCh := make(chan error, 1)
+// Run processes the listings with bounded concurrency and emits one Result per listing, in completion order.
+// Failures are reported per listing rather than dropped. The channel is closed once every listing is done,
+// or early when ctx is canceled (listings not yet started are then not reported). While the run behind
+// ctx is paused, no further listings are started.
+func (o *Orchestrator) Run(ctx context.Context, listings []model.Mailbox, fn WorkerFn) <-chan Result {
+	out := make(chan Result)
 
 	go func() {
 		defer close(out)
-		defer close(errCh)
 
-		jobs := make(chan string)
+		jobs := make(chan model.Mailbox)
 		var wg sync.WaitGroup
 
 		worker := func() {
 			defer wg.Done()
-			for url := range jobs {
-				select {
-				case <-ctx.Done():
+			for listing := range jobs {
+				if ctx.Err() != nil {
 					return
-				default:
-				}
-				m, err := fn(ctx, url)
-				if err != nil {
-					select {
-					case errCh <- err:
-					default:
-					}
-					continue
 				}
+				start := time.Now()
+				m, err := fn(ctx, listing)
 				select {
-				case out <- m:
+				case out <- Result{Listing: listing, Mailbox: m, Err: err, Elapsed: time.Since(start)}:
 				case <-ctx.Done():
 					return
 				}
@@ -65,41 +67,70 @@ func (o *Orchestrator) Run(ctx context.Context, urls []string, fn WorkerFn) (<-c
 			go worker()
 		}
 
-		for _, url := range urls {
+	feed:
+		for _, listing := range listings {
+			// Hold new work while the run is paused; listings in flight still finish.
+			if waitIfPaused(ctx) != nil {
+				break feed
+			}
 			select {
-			case jobs <- url:
+			case jobs <- listing:
 			case <-ctx.Done():
-				close(jobs)
-				wg.Wait()
-				return
+				break feed
 			}
 		}
 		close(jobs)
 		wg.Wait()
 	}()
 
-	return out, errCh
+	return out
 }
 
-// MarkAndSweep sets active=false for mailboxes whose crawlRunId != currentRunId.
+// MarkAndSweep sets active=false for mailboxes whose crawlRunId != currentRunId and returns their links.
 // Only affects mailboxes from the same source to prevent interference between different crawlers.
-func MarkAndSweep(ctx context.Context, repo MailboxStore, currentRunID string, source string) error {
+// Links in discovered stay active even if this run never wrote them: unchanged, failed, or
+// not yet reached listings of an interrupted or resumed run are still offered by the provider.
+// Each deactivation is recorded in history against currentRunID.
+func MarkAndSweep(ctx context.Context, repo MailboxStore, history HistoryRecorder, currentRunID string, source string, discovered map[string]bool) ([]string, error) {
 	all, err := repo.FetchAllMap(ctx)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	toUpdate := sweepCandidates(all, currentRunID, source, discovered)
+	if len(toUpdate) == 0 {
+		return nil, nil
+	}
+	for i := range toUpdate {
+		toUpdate[i].Active = false
 	}
-	var toUpdate []model.Mailbox
+	if err := repo.BatchUpsert(ctx, toUpdate); err != nil {
+		return nil, err
+	}
+	recordHistory(ctx, history, all, toUpdate, currentRunID, func(msg string) {
+		log.Printf("sweep %s: %s", currentRunID, msg)
+	})
+	return sweptLinks(toUpdate), nil
+}
+
+// sweepCandidates returns the active mailboxes of source that MarkAndSweep would deactivate, sorted by link.
+func sweepCandidates(all map[string]model.Mailbox, currentRunID string, source string, discovered map[string]bool) []model.Mailbox {
+	var candidates []model.Mailbox
 	for _, m := range all {
 		// Only process mailboxes from the same source
-		if m.Source == source && m.CrawlRunID != currentRunID && m.Active {
-			m.Active = false
-			toUpdate = append(toUpdate, m)
+		if m.Source == source && m.CrawlRunID != currentRunID && m.Active && !discovered[m.Link] {
+			candidates = append(candidates, m)
 		}
 	}
-	if len(toUpdate) == 0 {
-		return nil
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].Link < candidates[j].Link })
+	return candidates
+}
+
+func sweptLinks(mailboxes []model.Mailbox) []string {
+	links := make([]string, len(mailboxes))
+	for i, m := range mailboxes {
+		links[i] = m.Link
 	}
-	return repo.BatchUpsert(ctx, toUpdate)
+	return links
 }
 
 // RunLifecycleRepo persists crawl run metadata.
@@ -108,24 +139,16 @@ type RunLifecycleRepo interface {
 	UpdateRun(ctx context.Context, run model.CrawlRun) error
 }
 
-// StartRun initializes a CrawlRun record.
-func StartRun(ctx context.Context, repo RunLifecycleRepo, runID string, source string, startedAt time.Time) error {
-	return repo.CreateRun(ctx, model.CrawlRun{
-		RunID:     runID,
-		Source:    source,
-		Status:    "running",
-		StartedAt: startedAt,
-	})
+// StartRun initializes a CrawlRun record with status "running".
+func StartRun(ctx context.Context, repo RunLifecycleRepo, run model.CrawlRun) error {
+	run.Status = "running"
+	return repo.CreateRun(ctx, run)
 }
 
-// FinishRun finalizes a CrawlRun record with stats and status.
-func FinishRun(ctx context.Context, repo RunLifecycleRepo, runID string, source string, stats model.CrawlRunStats, status string, startedAt time.Time) error {
-	return repo.UpdateRun(ctx, model.CrawlRun{
-		RunID:      runID,
-		Source:     source,
-		Status:     status,
-		Stats:      stats,
-		StartedAt:  startedAt,
-		FinishedAt: time.Now().UTC(),
-	})
+// FinishRun finalizes a CrawlRun record with stats and status, keeping the rest of run as recorded.
+func FinishRun(ctx context.Context, repo RunLifecycleRepo, run model.CrawlRun, stats model.CrawlRunStats, status string) error {
+	run.Stats = stats
+	run.Status = status
+	run.FinishedAt = time.Now().UTC()
+	return repo.UpdateRun(ctx, run)
 }
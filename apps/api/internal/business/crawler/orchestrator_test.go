@@ -0,0 +1,103 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/model"
+)
+
+func TestOrchestratorRun(t *testing.T) {
+	var listings []model.Mailbox
+	for _, link := range []string{"a", "b", "c", "d", "e", "f"} {
+		listings = append(listings, model.Mailbox{Link: link})
+	}
+
+	var running, peak int32
+	fn := func(ctx context.Context, listing model.Mailbox) (model.Mailbox, error) {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		if listing.Link == "b" || listing.Link == "e" {
+			return model.Mailbox{}, errors.New("boom")
+		}
+		return model.Mailbox{Link: listing.Link, Name: "parsed"}, nil
+	}
+
+	var ok, failed int
+	for res := range NewOrchestrator(2).Run(context.Background(), listings, fn) {
+		if res.Err != nil {
+			failed++
+			if res.Listing.Link != "b" && res.Listing.Link != "e" {
+				t.Errorf("unexpected failure for %s", res.Listing.Link)
+			}
+			continue
+		}
+		ok++
+	}
+	if ok != 4 || failed != 2 {
+		t.Errorf("ok = %d, failed = %d, want 4 and 2 (every error reported)", ok, failed)
+	}
+	if peak > 2 {
+		t.Errorf("peak concurrency = %d, want <= 2", peak)
+	}
+}
+
+func TestOrchestratorRunCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	listings := make([]model.Mailbox, 100)
+
+	var calls int32
+	results := NewOrchestrator(3).Run(ctx, listings, func(ctx context.Context, listing model.Mailbox) (model.Mailbox, error) {
+		atomic.AddInt32(&calls, 1)
+		return listing, nil
+	})
+	<-results
+	cancel()
+	for range results {
+		// Drain until the orchestrator closes the channel.
+	}
+	if n := atomic.LoadInt32(&calls); n >= 100 {
+		t.Errorf("processed %d listings after cancel, want fewer than 100", n)
+	}
+}
+
+func TestOrchestratorRunPaused(t *testing.T) {
+	jm := NewJobManager()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	jm.Register("RUN_1", cancel)
+	jm.Pause("RUN_1")
+
+	var calls int32
+	listings := make([]model.Mailbox, 10)
+	results := NewOrchestrator(2).Run(withPause(ctx, jm, "RUN_1"), listings, func(ctx context.Context, listing model.Mailbox) (model.Mailbox, error) {
+		atomic.AddInt32(&calls, 1)
+		return listing, nil
+	})
+	time.Sleep(20 * time.Millisecond)
+	if n := atomic.LoadInt32(&calls); n != 0 {
+		t.Fatalf("processed %d listings while paused, want 0", n)
+	}
+
+	jm.Resume("RUN_1")
+	var got int
+	for range results {
+		got++
+	}
+	if got != len(listings) {
+		t.Errorf("results after resume = %d, want %d", got, len(listings))
+	}
+	if jm.PausedFor("RUN_1") < 20*time.Millisecond {
+		t.Errorf("PausedFor = %v, want the time spent paused", jm.PausedFor("RUN_1"))
+	}
+}
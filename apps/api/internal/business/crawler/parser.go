@@ -8,6 +8,7 @@ import (
 
 	"github.com/PuerkitoBio/goquery"
 	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/model"
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/util"
 )
 
 // ParseMailboxHTML extracts mailbox details from a single ATMB detail page HTML.
@@ -64,14 +65,9 @@ func ParseMailboxHTML(r io.Reader, sourceLink string) (model.Mailbox, error) {
 		}
 	}
 
-	// Extract price from first plan (e.g., "US$ 19.99 / month")
-	priceRaw := strings.TrimSpace(doc.Find(".t-plan .t-price").First().Text())
-
-	price, err := parsePrice(priceRaw)
-	if err != nil {
-		// Some pages omit price; treat as zero instead of failing the record.
-		price = 0
-	}
+	// Extract every plan tier; Price is the cheapest monthly plan.
+	plans := parsePlans(doc)
+	price := util.CheapestMonthlyPrice(plans)
 
 	link := sourceLink
 	if href, ok := doc.Find("a.store-link").First().Attr("href"); ok && strings.TrimSpace(href) != "" {
@@ -87,11 +83,45 @@ func ParseMailboxHTML(r io.Reader, sourceLink string) (model.Mailbox, error) {
 			Zip:    zip,
 		},
 		Price:  price,
+		Plans:  plans,
 		Link:   link,
 		Active: true,
 	}, nil
 }
 
+// parsePlans reads the `.t-plan` tiers (e.g., Bronze/Silver/Gold). A tier listing both a monthly and an
+// annual `.t-price` yields one plan per billing period. Tiers without a readable price are skipped.
+func parsePlans(doc *goquery.Document) []model.Plan {
+	var plans []model.Plan
+	doc.Find(".t-plan").Each(func(_ int, s *goquery.Selection) {
+		name := strings.TrimSpace(s.Find(".t-title").First().Text())
+
+		var features []string
+		s.Find("li").Each(func(_ int, li *goquery.Selection) {
+			if txt := strings.Join(strings.Fields(li.Text()), " "); txt != "" {
+				features = append(features, txt)
+			}
+		})
+
+		s.Find(".t-price").Each(func(_ int, p *goquery.Selection) {
+			priceRaw := strings.TrimSpace(p.Text())
+			listed, err := parsePrice(priceRaw)
+			if err != nil || listed <= 0 {
+				// Some pages omit price; skip the tier instead of failing the record.
+				return
+			}
+			period := util.BillingPeriod(priceRaw)
+			plans = append(plans, model.Plan{
+				Name:          name,
+				MonthlyPrice:  util.MonthlyPrice(listed, period),
+				BillingPeriod: period,
+				Features:      features,
+			})
+		})
+	})
+	return plans
+}
+
 func parsePrice(raw string) (float64, error) {
 	clean := make([]rune, 0, len(raw))
 	for _, r := range raw {
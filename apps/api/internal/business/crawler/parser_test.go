@@ -2,7 +2,11 @@ package crawler
 
 import (
 	"os"
+	"strings"
 	"testing"
+
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/model"
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/util"
 )
 
 func TestParseMailboxHTML(t *testing.T) {
@@ -38,4 +42,51 @@ func TestParseMailboxHTML(t *testing.T) {
 	if mailbox.Link != "https://anytimemailbox.com/locations/default" {
 		t.Errorf("link = %q", mailbox.Link)
 	}
+	if len(mailbox.Plans) != 3 {
+		t.Fatalf("plans = %d, want 3", len(mailbox.Plans))
+	}
+	if p := mailbox.Plans[2]; p.Name != "Gold" || p.MonthlyPrice != 49.99 || p.BillingPeriod != model.BillingMonthly {
+		t.Errorf("plans[2] = %+v, want Gold 49.99 monthly", p)
+	}
+}
+
+func TestParseMailboxHTMLPlans(t *testing.T) {
+	html := `<html><body>
+		<h1>Austin - Congress Ave</h1>
+		<div class="theme-loc-detail-plans">
+			<div class="t-plan">
+				<div class="t-title">Premium</div>
+				<div class="t-price">US$ 39.99 / month</div>
+				<ul><li>120 mail items / month</li><li>30 scans</li></ul>
+			</div>
+			<div class="t-plan">
+				<div class="t-title">Basic</div>
+				<div class="t-price">US$ 14.99 / month</div>
+				<div class="t-price">US$ 143.88 / year</div>
+				<ul><li>30 mail items / month</li></ul>
+			</div>
+			<div class="t-plan">
+				<div class="t-title">Call for pricing</div>
+				<div class="t-price">Contact us</div>
+			</div>
+		</div>
+	</body></html>`
+
+	mailbox, err := ParseMailboxHTML(strings.NewReader(html), "https://anytimemailbox.com/s/austin")
+	if err != nil {
+		t.Fatalf("ParseMailboxHTML: %v", err)
+	}
+
+	want := []model.Plan{
+		{Name: "Premium", MonthlyPrice: 39.99, BillingPeriod: model.BillingMonthly, Features: []string{"120 mail items / month", "30 scans"}},
+		{Name: "Basic", MonthlyPrice: 14.99, BillingPeriod: model.BillingMonthly, Features: []string{"30 mail items / month"}},
+		{Name: "Basic", MonthlyPrice: 11.99, BillingPeriod: model.BillingAnnual, Features: []string{"30 mail items / month"}},
+	}
+	if !util.SamePlans(mailbox.Plans, want) {
+		t.Errorf("plans = %+v, want %+v", mailbox.Plans, want)
+	}
+	// Price is the cheapest monthly-billed plan, not the first one or the annual equivalent.
+	if mailbox.Price != 14.99 {
+		t.Errorf("price = %v, want 14.99", mailbox.Price)
+	}
 }
@@ -0,0 +1,79 @@
+package politeness
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limiter is a token bucket: it refills at rate tokens per second up to burst, and every Wait takes one token.
+type Limiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewLimiter creates a full bucket. A rate <= 0 never waits.
+func NewLimiter(rate float64, burst int) *Limiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &Limiter{rate: rate, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+// Wait blocks until a token is available or ctx is done. Callers are served in arrival order:
+// each one reserves its token up front, so the bucket may run negative while they sleep.
+func (l *Limiter) Wait(ctx context.Context) error {
+	if l == nil || l.rate <= 0 {
+		return ctx.Err()
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+	l.tokens--
+	var wait time.Duration
+	if l.tokens < 0 {
+		wait = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+
+	if err := Sleep(ctx, wait); err != nil {
+		// Give the reservation back so a canceled caller does not slow down the others.
+		l.mu.Lock()
+		l.tokens++
+		l.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// HostLimiter keeps one Limiter per host so a slow site does not throttle the others.
+type HostLimiter struct {
+	mu       sync.Mutex
+	rate     float64
+	burst    int
+	limiters map[string]*Limiter
+}
+
+func NewHostLimiter(rate float64, burst int) *HostLimiter {
+	return &HostLimiter{rate: rate, burst: burst, limiters: make(map[string]*Limiter)}
+}
+
+// Wait blocks until host may receive another request.
+func (h *HostLimiter) Wait(ctx context.Context, host string) error {
+	h.mu.Lock()
+	l, ok := h.limiters[host]
+	if !ok {
+		l = NewLimiter(h.rate, h.burst)
+		h.limiters[host] = l
+	}
+	h.mu.Unlock()
+	return l.Wait(ctx)
+}
@@ -0,0 +1,94 @@
+package politeness
+
+import (
+	"context"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Policy controls how hard a crawler may hit a site.
+type Policy struct {
+	RequestsPerSecond float64       // Per-host request rate; <= 0 disables rate limiting
+	Burst             int           // Requests allowed back to back before the rate applies (min 1)
+	MaxRetries        int           // Retries after the first attempt
+	BaseBackoff       time.Duration // First retry delay; doubles per attempt, with jitter
+	MaxBackoff        time.Duration // Upper bound for a computed backoff delay
+	RespectRobots     bool          // Skip URLs disallowed by the host's robots.txt
+	UserAgent         string        // Sent with requests and matched to robots.txt groups ("*" always applies); empty sends a browser UA
+}
+
+// DefaultPolicy matches the fetcher's historical behaviour (3 attempts, ~0.5s apart) plus a modest rate limit.
+func DefaultPolicy() Policy {
+	return Policy{
+		RequestsPerSecond: 4,
+		Burst:             5,
+		MaxRetries:        2,
+		BaseBackoff:       500 * time.Millisecond,
+		MaxBackoff:        30 * time.Second,
+	}
+}
+
+// Backoff returns the delay before retry number attempt (1-based): BaseBackoff doubled per attempt,
+// capped at MaxBackoff, then jittered into [d/2, d] so concurrent workers do not retry in lockstep.
+func (p Policy) Backoff(attempt int) time.Duration {
+	if p.BaseBackoff <= 0 || attempt <= 0 {
+		return 0
+	}
+	d := p.BaseBackoff
+	for i := 1; i < attempt && (p.MaxBackoff <= 0 || d < p.MaxBackoff); i++ {
+		d *= 2
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	half := d / 2
+	return half + time.Duration(rand.Int64N(int64(half)+1))
+}
+
+// Retryable reports whether a response status is worth retrying. Client errors other than
+// timeouts and rate limiting will not change on retry.
+func Retryable(status int) bool {
+	if status == http.StatusRequestTimeout || status == http.StatusTooManyRequests {
+		return true
+	}
+	return status >= 500
+}
+
+// RetryAfter parses a Retry-After header given either as seconds or as an HTTP date.
+func RetryAfter(h http.Header, now time.Time) (time.Duration, bool) {
+	val := strings.TrimSpace(h.Get("Retry-After"))
+	if val == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(val); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	if at, err := http.ParseTime(val); err == nil {
+		if d := at.Sub(now); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// Sleep waits for d or until ctx is done.
+func Sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
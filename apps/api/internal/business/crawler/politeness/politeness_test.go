@@ -0,0 +1,137 @@
+package politeness
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLimiterWait(t *testing.T) {
+	l := NewLimiter(50, 2) // one token every 20ms after a burst of 2
+	ctx := context.Background()
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		if err := l.Wait(ctx); err != nil {
+			t.Fatalf("Wait: %v", err)
+		}
+	}
+	// Two requests ride the burst, the next two wait ~20ms each.
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("4 waits took %v, want at least ~40ms of pacing", elapsed)
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := NewLimiter(0.001, 1).Wait(canceled); err == nil {
+		t.Errorf("Wait on a canceled context should fail")
+	}
+	if err := NewLimiter(0, 1).Wait(ctx); err != nil {
+		t.Errorf("a zero rate should never wait: %v", err)
+	}
+}
+
+func TestHostLimiterIsolatesHosts(t *testing.T) {
+	h := NewHostLimiter(1, 1)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if err := h.Wait(ctx, "a.example"); err != nil {
+		t.Fatalf("first request to a: %v", err)
+	}
+	if err := h.Wait(ctx, "b.example"); err != nil {
+		t.Errorf("b should have its own bucket: %v", err)
+	}
+	if err := h.Wait(ctx, "a.example"); err == nil {
+		t.Errorf("second request to a should wait past the deadline")
+	}
+}
+
+func TestPolicyBackoff(t *testing.T) {
+	p := Policy{BaseBackoff: 100 * time.Millisecond, MaxBackoff: 300 * time.Millisecond}
+	tests := []struct {
+		attempt int
+		max     time.Duration
+	}{
+		{attempt: 1, max: 100 * time.Millisecond},
+		{attempt: 2, max: 200 * time.Millisecond},
+		{attempt: 3, max: 300 * time.Millisecond}, // capped
+		{attempt: 10, max: 300 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			d := p.Backoff(tt.attempt)
+			if d < tt.max/2 || d > tt.max {
+				t.Fatalf("Backoff(%d) = %v, want within [%v, %v]", tt.attempt, d, tt.max/2, tt.max)
+			}
+		}
+	}
+	if d := (Policy{}).Backoff(1); d != 0 {
+		t.Errorf("zero policy backoff = %v, want 0", d)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		header string
+		want   time.Duration
+		ok     bool
+	}{
+		{name: "missing", header: "", ok: false},
+		{name: "seconds", header: "120", want: 2 * time.Minute, ok: true},
+		{name: "http date", header: now.Add(30 * time.Second).Format(http.TimeFormat), want: 30 * time.Second, ok: true},
+		{name: "past date", header: now.Add(-time.Minute).Format(http.TimeFormat), want: 0, ok: true},
+		{name: "garbage", header: "soon", ok: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := http.Header{}
+			if tt.header != "" {
+				h.Set("Retry-After", tt.header)
+			}
+			got, ok := RetryAfter(h, now)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("RetryAfter(%q) = %v, %v; want %v, %v", tt.header, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestParseRobots(t *testing.T) {
+	robots := `
+# comment
+User-agent: *
+Disallow: /private
+Allow: /private/open
+
+User-agent: otherbot
+Disallow: /
+
+User-agent: verifierbot
+Disallow: /locations/draft
+`
+	rules := parseRobots(strings.NewReader(robots), "verifierbot/1.0")
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{path: "/", want: true},
+		{path: "/locations/ca", want: true},
+		{path: "/private/page", want: false},
+		{path: "/private/open/page", want: true},
+		{path: "/locations/draft/1", want: false},
+	}
+	for _, tt := range tests {
+		if got := rules.allowed(tt.path); got != tt.want {
+			t.Errorf("allowed(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+
+	if !parseRobots(strings.NewReader("User-agent: *\nDisallow:\n"), "").allowed("/anything") {
+		t.Errorf("an empty Disallow should allow everything")
+	}
+}
@@ -0,0 +1,127 @@
+package politeness
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Robots caches robots.txt rules per host.
+type Robots struct {
+	mu        sync.Mutex
+	userAgent string
+	rules     map[string]*robotsRules
+}
+
+// NewRobots creates a cache that applies the "*" group plus any group matching userAgent.
+func NewRobots(userAgent string) *Robots {
+	return &Robots{userAgent: strings.ToLower(strings.TrimSpace(userAgent)), rules: make(map[string]*robotsRules)}
+}
+
+// Allowed reports whether target may be fetched. robots.txt is fetched once per host with client;
+// a missing or unreadable robots.txt allows everything.
+func (r *Robots) Allowed(ctx context.Context, client *http.Client, target *url.URL) bool {
+	r.mu.Lock()
+	rules, ok := r.rules[target.Host]
+	r.mu.Unlock()
+	if !ok {
+		rules = r.load(ctx, client, target)
+		r.mu.Lock()
+		r.rules[target.Host] = rules
+		r.mu.Unlock()
+	}
+	return rules.allowed(target.EscapedPath())
+}
+
+func (r *Robots) load(ctx context.Context, client *http.Client, target *url.URL) *robotsRules {
+	robotsURL := fmt.Sprintf("%s://%s/robots.txt", target.Scheme, target.Host)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, robotsURL, nil)
+	if err != nil {
+		return &robotsRules{}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return &robotsRules{}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return &robotsRules{}
+	}
+	return parseRobots(io.LimitReader(resp.Body, 512*1024), r.userAgent)
+}
+
+type robotsRule struct {
+	prefix string
+	allow  bool
+}
+
+type robotsRules struct {
+	rules []robotsRule
+}
+
+// allowed applies the longest matching rule; Allow wins ties. Wildcards are not supported.
+func (rr *robotsRules) allowed(path string) bool {
+	if path == "" {
+		path = "/"
+	}
+	best := -1
+	allow := true
+	for _, rule := range rr.rules {
+		if !strings.HasPrefix(path, rule.prefix) {
+			continue
+		}
+		if len(rule.prefix) > best || (len(rule.prefix) == best && rule.allow) {
+			best = len(rule.prefix)
+			allow = rule.allow
+		}
+	}
+	return allow
+}
+
+// parseRobots keeps the rules of every group addressed to "*" or to a token contained in userAgent.
+func parseRobots(r io.Reader, userAgent string) *robotsRules {
+	rr := &robotsRules{}
+	scanner := bufio.NewScanner(r)
+	var groupAgents []string
+	inRules := false
+	applies := func() bool {
+		for _, a := range groupAgents {
+			if a == "*" || (userAgent != "" && strings.Contains(userAgent, a)) {
+				return true
+			}
+		}
+		return false
+	}
+
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		key, val, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		val = strings.TrimSpace(val)
+
+		switch key {
+		case "user-agent":
+			if inRules {
+				// A user-agent line after rules starts a new group.
+				groupAgents = nil
+				inRules = false
+			}
+			groupAgents = append(groupAgents, strings.ToLower(val))
+		case "allow", "disallow":
+			inRules = true
+			if val == "" || !applies() {
+				continue // An empty Disallow allows everything.
+			}
+			rr.rules = append(rr.rules, robotsRule{prefix: val, allow: key == "allow"})
+		}
+	}
+	return rr
+}
@@ -0,0 +1,107 @@
+package postscan
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// PartialError reports the state pages that could not be loaded while walking the locations index.
+// DiscoverLinks returns it together with the links of every other state.
+type PartialError struct {
+	Pages []string
+}
+
+func (e *PartialError) Error() string {
+	return fmt.Sprintf("%d state pages unreachable: %s", len(e.Pages), strings.Join(e.Pages, ", "))
+}
+
+// FailedSegments lists the unreachable state pages, so the crawler knows its inventory is incomplete.
+func (e *PartialError) FailedSegments() []string { return e.Pages }
+
+// DiscoverLinks expands seeds into location detail links. A seed may be the locations index
+// (links to state pages), a state page (links to locations), or a location page itself.
+// Unreachable pages are reported through logFn and skipped; unreachable state pages of an
+// index also come back as a *PartialError alongside the links that were found.
+func DiscoverLinks(ctx context.Context, fetcher Fetcher, seeds []string, logFn func(string)) ([]string, error) {
+	if len(seeds) == 0 {
+		seeds = []string{DefaultSeed}
+	}
+
+	seen := make(map[string]struct{})
+	var links []string
+	var failed []string
+	add := func(link string) {
+		if _, ok := seen[link]; ok {
+			return
+		}
+		seen[link] = struct{}{}
+		links = append(links, link)
+	}
+
+	for _, seed := range seeds {
+		if err := ctx.Err(); err != nil {
+			return links, err
+		}
+		doc, err := fetchDocument(ctx, fetcher, seed)
+		if err != nil {
+			logf(logFn, "discover: %v", err)
+			continue
+		}
+
+		if states := ParseStateLinks(doc); len(states) > 0 {
+			for _, state := range states {
+				if err := ctx.Err(); err != nil {
+					return links, err
+				}
+				stateDoc, err := fetchDocument(ctx, fetcher, state)
+				if err != nil {
+					logf(logFn, "discover: %v", err)
+					failed = append(failed, state)
+					continue
+				}
+				for _, link := range ParseLocationLinks(stateDoc) {
+					add(link)
+				}
+			}
+			continue
+		}
+
+		if locations := ParseLocationLinks(doc); len(locations) > 0 {
+			for _, link := range locations {
+				add(link)
+			}
+			continue
+		}
+
+		// Neither index nor state page: treat the seed as a location page.
+		if doc.Find("[itemprop='address']").Length() > 0 {
+			add(seed)
+		}
+	}
+	if len(failed) > 0 {
+		return links, &PartialError{Pages: failed}
+	}
+	return links, nil
+}
+
+func fetchDocument(ctx context.Context, fetcher Fetcher, url string) (*goquery.Document, error) {
+	body, err := fetcher.Fetch(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("fetch %s: %w", url, err)
+	}
+	defer body.Close()
+	doc, err := goquery.NewDocumentFromReader(body)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", url, err)
+	}
+	return doc, nil
+}
+
+func logf(logFn func(string), format string, args ...any) {
+	if logFn != nil {
+		logFn(fmt.Sprintf(format, args...))
+	}
+}
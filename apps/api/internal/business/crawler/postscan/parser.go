@@ -0,0 +1,147 @@
+package postscan
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/model"
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/util"
+)
+
+// ParseLocationHTML extracts mailbox details from a single PostScan Mail location page.
+// The address is read from the schema.org PostalAddress microdata; plans from `.plan-card` blocks.
+func ParseLocationHTML(r io.Reader, sourceLink string) (model.Mailbox, error) {
+	doc, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return model.Mailbox{}, fmt.Errorf("parse html: %w", err)
+	}
+
+	address := doc.Find("[itemprop='address']").First()
+	if address.Length() == 0 {
+		return model.Mailbox{}, fmt.Errorf("no address found on %s", sourceLink)
+	}
+	addr := model.AddressRaw{
+		Street: itemprop(address, "streetAddress"),
+		City:   itemprop(address, "addressLocality"),
+		State:  itemprop(address, "addressRegion"),
+		Zip:    itemprop(address, "postalCode"),
+	}
+	if addr.Street == "" || addr.City == "" {
+		return model.Mailbox{}, fmt.Errorf("incomplete address on %s", sourceLink)
+	}
+
+	name := strings.TrimSpace(doc.Find("h1.location-title").First().Text())
+	if name == "" {
+		name = fmt.Sprintf("PostScan Mail - %s, %s", addr.City, addr.State)
+	}
+
+	plans := parsePlans(doc)
+
+	link := sourceLink
+	if href, ok := doc.Find("link[rel='canonical']").Attr("href"); ok && strings.TrimSpace(href) != "" {
+		link = resolve(strings.TrimSpace(href))
+	}
+
+	return model.Mailbox{
+		Name:       name,
+		AddressRaw: addr,
+		Price:      util.CheapestMonthlyPrice(plans),
+		Plans:      plans,
+		Link:       link,
+		Source:     Source,
+		Active:     true,
+	}, nil
+}
+
+// parsePlans reads every `.plan-card`; cards without a readable price (e.g., "Contact sales") are skipped.
+func parsePlans(doc *goquery.Document) []model.Plan {
+	var plans []model.Plan
+	doc.Find(".plan-card").Each(func(_ int, s *goquery.Selection) {
+		priceText := strings.TrimSpace(s.Find(".plan-price").First().Text())
+		listed := parsePrice(priceText)
+		if listed <= 0 {
+			return
+		}
+
+		var features []string
+		s.Find(".plan-features li").Each(func(_ int, li *goquery.Selection) {
+			if txt := strings.Join(strings.Fields(li.Text()), " "); txt != "" {
+				features = append(features, txt)
+			}
+		})
+
+		period := util.BillingPeriod(priceText)
+		plans = append(plans, model.Plan{
+			Name:          strings.TrimSpace(s.Find(".plan-name").First().Text()),
+			MonthlyPrice:  util.MonthlyPrice(listed, period),
+			BillingPeriod: period,
+			Features:      features,
+		})
+	})
+	return plans
+}
+
+// ParseStateLinks returns the state page links from the locations index.
+func ParseStateLinks(doc *goquery.Document) []string {
+	return hrefs(doc.Find(".state-list a"))
+}
+
+// ParseLocationLinks returns the location detail links from a state page.
+func ParseLocationLinks(doc *goquery.Document) []string {
+	return hrefs(doc.Find("a.location-link"))
+}
+
+func hrefs(sel *goquery.Selection) []string {
+	var links []string
+	sel.Each(func(_ int, a *goquery.Selection) {
+		if href, ok := a.Attr("href"); ok && strings.TrimSpace(href) != "" {
+			links = append(links, resolve(strings.TrimSpace(href)))
+		}
+	})
+	return links
+}
+
+func itemprop(sel *goquery.Selection, name string) string {
+	return strings.Join(strings.Fields(sel.Find("[itemprop='"+name+"']").First().Text()), " ")
+}
+
+// resolve makes site-relative links absolute.
+func resolve(href string) string {
+	if strings.HasPrefix(href, "http") {
+		return href
+	}
+	if !strings.HasPrefix(href, "/") {
+		href = "/" + href
+	}
+	return BaseURL + href
+}
+
+// parsePrice extracts the numeric amount from text like "$15/month" or "$150.00 / year".
+func parsePrice(input string) float64 {
+	var clean []rune
+	started := false
+	for _, r := range input {
+		if (r >= '0' && r <= '9') || (r == '.' && started) {
+			clean = append(clean, r)
+			started = true
+			continue
+		}
+		if r == ',' && started {
+			continue
+		}
+		if started {
+			break
+		}
+	}
+	if len(clean) == 0 {
+		return 0
+	}
+	price, err := strconv.ParseFloat(strings.TrimSuffix(string(clean), "."), 64)
+	if err != nil {
+		return 0
+	}
+	return price
+}
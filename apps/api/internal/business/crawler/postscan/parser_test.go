@@ -0,0 +1,118 @@
+package postscan
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/model"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files in testdata")
+
+func TestParseLocationHTMLGolden(t *testing.T) {
+	tests := []struct {
+		fixture string
+		link    string
+	}{
+		{fixture: "location_san_francisco", link: BaseURL + "/locations/california/san-francisco-market-st"},
+		{fixture: "location_austin", link: BaseURL + "/locations/texas/austin-congress-ave"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			f, err := os.Open(filepath.Join("testdata", tt.fixture+".html"))
+			if err != nil {
+				t.Fatalf("open fixture: %v", err)
+			}
+			defer f.Close()
+
+			got, err := ParseLocationHTML(f, tt.link)
+			if err != nil {
+				t.Fatalf("ParseLocationHTML: %v", err)
+			}
+
+			golden := filepath.Join("testdata", tt.fixture+".golden.json")
+			if *update {
+				data, _ := json.MarshalIndent(got, "", "  ")
+				if err := os.WriteFile(golden, append(data, '\n'), 0o644); err != nil {
+					t.Fatalf("write golden: %v", err)
+				}
+			}
+
+			data, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("read golden (run with -update to create): %v", err)
+			}
+			var want model.Mailbox
+			if err := json.Unmarshal(data, &want); err != nil {
+				t.Fatalf("decode golden: %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				gotJSON, _ := json.MarshalIndent(got, "", "  ")
+				t.Errorf("parsed mailbox does not match %s\ngot:\n%s", golden, gotJSON)
+			}
+		})
+	}
+}
+
+func TestParseLocationHTMLMissingAddress(t *testing.T) {
+	_, err := ParseLocationHTML(strings.NewReader(`<html><body><h1 class="location-title">Nowhere</h1></body></html>`), BaseURL+"/locations/x")
+	if err == nil {
+		t.Errorf("expected an error for a page without an address")
+	}
+}
+
+// fixtureFetcher serves testdata files by URL.
+type fixtureFetcher map[string]string
+
+func (f fixtureFetcher) Fetch(ctx context.Context, url string) (io.ReadCloser, error) {
+	name, ok := f[url]
+	if !ok {
+		return nil, fmt.Errorf("status 404 for %s", url)
+	}
+	return os.Open(filepath.Join("testdata", name))
+}
+
+func TestDiscoverLinks(t *testing.T) {
+	fetcher := fixtureFetcher{
+		DefaultSeed:                                      "locations.html",
+		BaseURL + "/locations/california":                "state_california.html",
+		BaseURL + "/locations/texas":                     "state_texas.html",
+		BaseURL + "/locations/texas/austin-congress-ave": "location_austin.html",
+	}
+	sf := BaseURL + "/locations/california/san-francisco-market-st"
+	la := BaseURL + "/locations/california/los-angeles-wilshire-blvd"
+	austin := BaseURL + "/locations/texas/austin-congress-ave"
+
+	tests := []struct {
+		name  string
+		seeds []string
+		want  []string
+	}{
+		{name: "default index", seeds: nil, want: []string{sf, la, austin}},
+		{name: "state page", seeds: []string{BaseURL + "/locations/texas"}, want: []string{austin}},
+		{name: "location page", seeds: []string{austin}, want: []string{austin}},
+		{name: "unreachable seed skipped", seeds: []string{BaseURL + "/missing", BaseURL + "/locations/texas"}, want: []string{austin}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs []string
+			got, err := DiscoverLinks(context.Background(), fetcher, tt.seeds, func(msg string) { logs = append(logs, msg) })
+			if err != nil {
+				t.Fatalf("DiscoverLinks: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("links = %v, want %v (logs: %v)", got, tt.want, logs)
+			}
+		})
+	}
+}
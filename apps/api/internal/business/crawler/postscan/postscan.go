@@ -0,0 +1,24 @@
+package postscan
+
+import (
+	"context"
+	"io"
+)
+
+// Source is the source name of PostScan Mail mailboxes.
+// The site is crawled as: locations index -> state pages -> one detail page per location.
+const Source = "PostScanMail"
+
+// ParserVersion tracks the location parser logic version for reprocessing support.
+const ParserVersion = "v1.0"
+
+// BaseURL is the PostScan Mail site root; relative links are resolved against it.
+const BaseURL = "https://www.postscanmail.com"
+
+// DefaultSeed is the locations index used when a run is started without links.
+const DefaultSeed = BaseURL + "/locations"
+
+// Fetcher abstracts how pages are fetched (same contract as crawler.HTMLFetcher, redeclared to avoid an import cycle).
+type Fetcher interface {
+	Fetch(ctx context.Context, url string) (io.ReadCloser, error)
+}
@@ -0,0 +1,62 @@
+package crawler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/internal/business/crawler/postscan"
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/model"
+)
+
+// PostScanProvider crawls PostScan Mail location pages.
+type PostScanProvider struct {
+	fetcher HTMLFetcher
+}
+
+func NewPostScanProvider(fetcher HTMLFetcher) *PostScanProvider {
+	return &PostScanProvider{fetcher: fetcher}
+}
+
+func (p *PostScanProvider) Name() string          { return postscan.Source }
+func (p *PostScanProvider) ParserVersion() string { return postscan.ParserVersion }
+
+// Discover walks the locations index (or the given state/location pages) down to detail links.
+// Unreachable state pages are passed on as a *postscan.PartialError with the links that were found.
+func (p *PostScanProvider) Discover(ctx context.Context, seeds []string) ([]model.Mailbox, error) {
+	links, err := postscan.DiscoverLinks(ctx, p.fetcher, seeds, func(msg string) {
+		log.Printf("postscan: %s", msg)
+	})
+	var partial *postscan.PartialError
+	if err != nil && !errors.As(err, &partial) {
+		return nil, err
+	}
+	listings := make([]model.Mailbox, len(links))
+	for i, link := range links {
+		listings[i] = model.Mailbox{Link: link}
+	}
+	return listings, err
+}
+
+// Parse fetches a location page and keeps its HTML for reprocessing.
+func (p *PostScanProvider) Parse(ctx context.Context, listing model.Mailbox) (model.Mailbox, error) {
+	htmlBytes, err := fetchPage(ctx, p.fetcher, listing.Link)
+	if err != nil {
+		return model.Mailbox{}, err
+	}
+
+	parsed, err := p.ParseHTML(bytes.NewReader(htmlBytes), listing.Link)
+	if err != nil {
+		return model.Mailbox{}, fmt.Errorf("%w %s: %w", errParse, listing.Link, err)
+	}
+	parsed.RawHTML = string(htmlBytes)
+	return parsed, nil
+}
+
+// ParseHTML parses a stored location page.
+func (p *PostScanProvider) ParseHTML(r io.Reader, link string) (model.Mailbox, error) {
+	return postscan.ParseLocationHTML(r, link)
+}
@@ -0,0 +1,163 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/model"
+)
+
+// Provider is a mailbox source the crawler can run (e.g., ATMB, iPost1).
+// The shared pipeline handles dedup, validation, storage, history and sweeping; a provider only
+// knows how to find its locations and turn each one into a mailbox.
+type Provider interface {
+	// Name is the source stored on mailboxes and runs, e.g. "ATMB".
+	Name() string
+	// ParserVersion is recorded on every mailbox the provider parses.
+	ParserVersion() string
+	// Discover returns one listing per location. A listing carries at least Link; providers whose
+	// discovery already yields full records (e.g., iPost1) may return them complete.
+	// seeds are optional caller-supplied starting points.
+	Discover(ctx context.Context, seeds []string) ([]model.Mailbox, error)
+	// Parse fetches and parses a single listing into a mailbox.
+	Parse(ctx context.Context, listing model.Mailbox) (model.Mailbox, error)
+}
+
+// SeedChecker is implemented by providers that cannot run without seeds,
+// so a bad request is rejected before a run is created.
+type SeedChecker interface {
+	CheckSeeds(seeds []string) error
+}
+
+// HTMLParser is implemented by providers that keep RawHTML, so stored records can be re-parsed
+// without re-fetching when the parser changes.
+type HTMLParser interface {
+	ParserVersion() string
+	ParseHTML(r io.Reader, link string) (model.Mailbox, error)
+}
+
+// ErrUnknownProvider is returned when a provider name is not registered.
+var ErrUnknownProvider = errors.New("unknown provider")
+
+// PartialDiscoveryError reports discovery segments (e.g., ATMB state pages) that could not be listed.
+// Discover returns it together with the listings of every other segment.
+type PartialDiscoveryError struct {
+	Failed []string
+}
+
+func (e *PartialDiscoveryError) Error() string {
+	return fmt.Sprintf("discovery incomplete, %d segments failed: %s", len(e.Failed), strings.Join(e.Failed, ", "))
+}
+
+// FailedSegments lists the segments discovery could not list.
+func (e *PartialDiscoveryError) FailedSegments() []string { return e.Failed }
+
+// partialDiscovery is implemented by discovery errors that still come with usable listings
+// (PartialDiscoveryError, ipost1.PartialError, postscan.PartialError). The crawl goes on, but the
+// provider's inventory is incomplete, so the sweep must not trust it.
+type partialDiscovery interface {
+	error
+	FailedSegments() []string
+}
+
+// ProviderInfo describes a registered provider for the API.
+type ProviderInfo struct {
+	Name          string `json:"name"`
+	ParserVersion string `json:"parserVersion"`
+}
+
+// Registry holds the providers available to the crawler, keyed case-insensitively by name.
+type Registry struct {
+	mu        sync.RWMutex
+	providers map[string]Provider
+}
+
+func NewRegistry(providers ...Provider) *Registry {
+	r := &Registry{providers: make(map[string]Provider)}
+	for _, p := range providers {
+		r.Register(p)
+	}
+	return r
+}
+
+// Register adds or replaces a provider.
+func (r *Registry) Register(p Provider) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.providers[strings.ToLower(p.Name())] = p
+}
+
+// Get looks up a provider by name, ignoring case.
+func (r *Registry) Get(name string) (Provider, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	p, ok := r.providers[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownProvider, name)
+	}
+	return p, nil
+}
+
+// List returns the registered providers sorted by name.
+func (r *Registry) List() []ProviderInfo {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	infos := make([]ProviderInfo, 0, len(r.providers))
+	for _, p := range r.providers {
+		infos = append(infos, ProviderInfo{Name: p.Name(), ParserVersion: p.ParserVersion()})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+// HTMLParsers returns the providers that can re-parse stored HTML, keyed by source name.
+func (r *Registry) HTMLParsers() map[string]HTMLParser {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	parsers := make(map[string]HTMLParser)
+	for _, p := range r.providers {
+		if hp, ok := p.(HTMLParser); ok {
+			parsers[p.Name()] = hp
+		}
+	}
+	return parsers
+}
+
+// conditionalFetchKey marks a context whose listing already has a validated record,
+// so fetchPage may answer ErrUnchanged instead of a body.
+type conditionalFetchKey struct{}
+
+func withConditionalFetch(ctx context.Context) context.Context {
+	return context.WithValue(ctx, conditionalFetchKey{}, true)
+}
+
+// fetchPage fetches a page and reads it fully so providers can both parse it and keep it as RawHTML.
+// With a ConditionalFetcher and a context from withConditionalFetch, an unmodified page yields ErrUnchanged.
+func fetchPage(ctx context.Context, fetcher HTMLFetcher, link string) ([]byte, error) {
+	var body io.ReadCloser
+	var err error
+	if cf, ok := fetcher.(ConditionalFetcher); ok && ctx.Value(conditionalFetchKey{}) != nil {
+		var modified bool
+		body, modified, err = cf.FetchIfModified(ctx, link)
+		if err == nil && !modified {
+			body.Close()
+			return nil, ErrUnchanged
+		}
+	} else {
+		body, err = fetcher.Fetch(ctx, link)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("fetch %s: %w", link, err)
+	}
+	defer body.Close()
+	htmlBytes, err := io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", link, err)
+	}
+	return htmlBytes, nil
+}
@@ -0,0 +1,348 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/internal/business/crawler/feed"
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/internal/business/crawler/ipost1"
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/internal/business/crawler/postscan"
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/internal/repository"
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/model"
+)
+
+type fakeProvider struct {
+	name     string
+	listings []model.Mailbox
+	err      error
+}
+
+func (p fakeProvider) Name() string          { return p.name }
+func (p fakeProvider) ParserVersion() string { return "fake-v1" }
+
+func (p fakeProvider) Discover(ctx context.Context, seeds []string) ([]model.Mailbox, error) {
+	return p.listings, p.err
+}
+
+func (p fakeProvider) Parse(ctx context.Context, listing model.Mailbox) (model.Mailbox, error) {
+	if listing.Name == "" {
+		return model.Mailbox{}, fmt.Errorf("no name for %s", listing.Link)
+	}
+	return listing, nil
+}
+
+func TestRegistry(t *testing.T) {
+	reg := NewRegistry(fakeProvider{name: "Zeta"}, NewATMBProvider(nil, nil))
+
+	if p, err := reg.Get("atmb"); err != nil || p.Name() != SourceATMB {
+		t.Errorf("Get(atmb) = %v, %v", p, err)
+	}
+	if _, err := reg.Get("missing"); !errors.Is(err, ErrUnknownProvider) {
+		t.Errorf("Get(missing) error = %v, want ErrUnknownProvider", err)
+	}
+
+	infos := reg.List()
+	if len(infos) != 2 || infos[0].Name != SourceATMB || infos[1].Name != "Zeta" || infos[0].ParserVersion != CurrentParserVersion {
+		t.Errorf("unexpected List: %+v", infos)
+	}
+}
+
+func TestCrawlProvider(t *testing.T) {
+	provider := fakeProvider{
+		name: "Fake",
+		listings: []model.Mailbox{
+			{Link: "https://fake/1", Name: "One", AddressRaw: model.AddressRaw{Street: "1 Main St", City: "Austin", State: "TX"}, Price: 10},
+			{Link: "https://fake/2"}, // fails to parse
+		},
+	}
+	store := &mockStore{existing: map[string]model.Mailbox{}}
+
+	stats, err := CrawlProvider(context.Background(), provider, store, nil, nil, nil, 4, nil, "RUN_1", nil, nil)
+	if err != nil {
+		t.Fatalf("CrawlProvider: %v", err)
+	}
+	if stats.Found != 2 || stats.Updated != 1 || stats.Failed != 1 || len(stats.Errors) != 1 || stats.Errors[0].Link != "https://fake/2" {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+
+	saved := store.saved[0]
+	if saved.Source != "Fake" || saved.ParserVersion != "fake-v1" || saved.CrawlRunID != "RUN_1" || !saved.Active || saved.DataHash == "" {
+		t.Errorf("pipeline metadata not applied: %+v", saved)
+	}
+
+	_, err = CrawlProvider(context.Background(), fakeProvider{name: "Empty"}, store, nil, nil, nil, 4, nil, "RUN_2", nil, nil)
+	if err == nil {
+		t.Errorf("expected an error when discovery finds nothing")
+	}
+}
+
+func TestCrawlProviderCheckpointAndSweep(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryMailboxRepository()
+	runs := repository.NewMemoryRunRepository()
+	seed := []model.Mailbox{
+		{Link: "https://fake/2", Source: "Fake", CrawlRunID: "RUN_0", Active: true}, // discovered but fails to parse
+		{Link: "https://fake/gone", Source: "Fake", CrawlRunID: "RUN_0", Active: true},
+	}
+	if err := repo.BatchUpsert(ctx, seed); err != nil {
+		t.Fatalf("BatchUpsert: %v", err)
+	}
+	provider := fakeProvider{
+		name: "Fake",
+		listings: []model.Mailbox{
+			{Link: "https://fake/1", Name: "One", AddressRaw: model.AddressRaw{Street: "1 Main St", City: "Austin", State: "TX"}},
+			{Link: "https://fake/2"},
+		},
+	}
+
+	if _, err := CrawlProvider(ctx, provider, repo, nil, runs, nil, 2, nil, "RUN_1", nil, nil); err != nil {
+		t.Fatalf("CrawlProvider: %v", err)
+	}
+	cp, err := runs.GetCheckpoint(ctx, "RUN_1")
+	if err != nil {
+		t.Fatalf("GetCheckpoint: %v", err)
+	}
+	if cp.Source != "Fake" || len(cp.Listings) != 2 || len(pendingListings(cp)) != 0 {
+		t.Fatalf("every listing should be checkpointed and done: %+v", cp)
+	}
+
+	swept, err := MarkAndSweep(ctx, repo, nil, "RUN_1", "Fake", discoveredLinks(cp))
+	if err != nil {
+		t.Fatalf("MarkAndSweep: %v", err)
+	}
+	if len(swept) != 1 || swept[0] != "https://fake/gone" {
+		t.Errorf("swept = %v, want only https://fake/gone", swept)
+	}
+	all, _ := repo.FetchAllMap(ctx)
+	if !all["https://fake/2"].Active {
+		t.Errorf("a discovered listing that failed this run must stay active")
+	}
+	if all["https://fake/gone"].Active {
+		t.Errorf("a listing the provider no longer offers should be swept")
+	}
+}
+
+func TestCrawlProviderPartialDiscovery(t *testing.T) {
+	ctx := context.Background()
+	runs := repository.NewMemoryRunRepository()
+	provider := fakeProvider{
+		name:     "Fake",
+		listings: []model.Mailbox{{Link: "https://fake/1", Name: "One", AddressRaw: model.AddressRaw{Street: "1 Main St", City: "Austin", State: "TX"}}},
+		err:      &PartialDiscoveryError{Failed: []string{"Wyoming"}},
+	}
+
+	stats, err := CrawlProvider(ctx, provider, &mockStore{existing: map[string]model.Mailbox{}}, nil, runs, nil, 2, nil, "RUN_1", nil, nil)
+	if err != nil {
+		t.Fatalf("a partial discovery should still crawl what was found: %v", err)
+	}
+	if stats.Updated != 1 || len(stats.DiscoveryFailures) != 1 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+	if cp, _ := runs.GetCheckpoint(ctx, "RUN_1"); len(cp.Failures) != 1 || cp.Failures[0] != "Wyoming" {
+		t.Errorf("checkpoint failures = %v, want [Wyoming]", cp.Failures)
+	}
+
+	provider.listings = nil
+	if _, err := CrawlProvider(ctx, provider, &mockStore{}, nil, nil, nil, 2, nil, "RUN_2", nil, nil); err == nil {
+		t.Errorf("a discovery that failed everywhere should fail the crawl")
+	}
+}
+
+// statePagesDown serves the ATMB country page and answers every state page with a 503.
+type statePagesDown struct{}
+
+func (statePagesDown) Fetch(ctx context.Context, url string) (io.ReadCloser, error) {
+	if url != "https://www.anytimemailbox.com/l/usa" {
+		return nil, fmt.Errorf("GET %s: status 503", url)
+	}
+	page := `<a class="theme-simple-link" href="/l/usa/texas">Texas</a><a class="theme-simple-link" href="/l/usa/wyoming">Wyoming</a>`
+	return io.NopCloser(strings.NewReader(page)), nil
+}
+
+func TestATMBDiscoveryFailingEverywhereHoldsSweep(t *testing.T) {
+	ctx := context.Background()
+	mailboxes := repository.NewMemoryMailboxRepository()
+	runs := repository.NewMemoryRunRepository()
+	existing := "https://www.anytimemailbox.com/s/austin"
+	if err := mailboxes.BatchUpsert(ctx, []model.Mailbox{{Link: existing, Name: "Austin", Source: SourceATMB, CrawlRunID: "RUN_0", Active: true}}); err != nil {
+		t.Fatalf("BatchUpsert: %v", err)
+	}
+	provider := NewATMBProvider(statePagesDown{}, nil)
+	svc := NewService(NewRegistry(provider), nil, mailboxes, runs, repository.NewMemoryStatsRepository(),
+		repository.NewMemoryHistoryRepository(), repository.NewMemoryJobRepository(), 1, DefaultSweepPolicy(), NewJobManager())
+
+	if _, err := provider.Discover(ctx, []string{"https://www.anytimemailbox.com/l/usa"}); err == nil {
+		t.Fatalf("Discover should fail rather than fall back to crawling the country page as a detail link")
+	}
+
+	run := model.CrawlRun{RunID: "RUN_1", Source: SourceATMB, Kind: model.RunKindCrawl}
+	_, status := svc.executeProvider(ctx, provider, &run, []string{"https://www.anytimemailbox.com/l/usa"})
+	if status != "failed" {
+		t.Errorf("status = %q, want failed", status)
+	}
+	if run.Sweep == nil || !run.Sweep.Held || len(run.Sweep.Quarantined) != 1 || run.Sweep.Quarantined[0] != existing {
+		t.Fatalf("sweep should be held with the existing mailbox quarantined: %+v", run.Sweep)
+	}
+	if all, _ := mailboxes.FetchAllMap(ctx); !all[existing].Active {
+		t.Errorf("a held sweep must not deactivate anything")
+	}
+}
+
+func TestIPost1ProviderParseKeepsLegacyHash(t *testing.T) {
+	listing := model.Mailbox{
+		Name:       "iPost1 - Austin, TX",
+		AddressRaw: model.AddressRaw{Street: "100 Congress Ave", City: "Austin", State: "TX", Zip: "78701"},
+		Link:       "https://ipostal1.com/secure_checkout.php?id=1",
+	}
+	parsed, err := NewIPost1Provider(ipost1.DefaultPolicy()).Parse(context.Background(), listing)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if parsed.DataHash != ipost1.HashMailbox(parsed) {
+		t.Errorf("DataHash = %q, want the iPost1 hash", parsed.DataHash)
+	}
+}
+
+func TestATMBProviderCheckSeeds(t *testing.T) {
+	if err := NewATMBProvider(nil, nil).CheckSeeds(nil); err == nil {
+		t.Errorf("expected an error without links or seeds")
+	}
+	if err := NewATMBProvider(nil, []string{"https://www.anytimemailbox.com/l/usa"}).CheckSeeds(nil); err != nil {
+		t.Errorf("configured seeds should satisfy CheckSeeds: %v", err)
+	}
+}
+
+// postscanFetcher serves the PostScan Mail fixtures: the Texas state page and its single location.
+func postscanFetcher(t *testing.T) mockFetcher {
+	t.Helper()
+	read := func(name string) []byte {
+		data, err := os.ReadFile(filepath.Join("postscan", "testdata", name))
+		if err != nil {
+			t.Fatalf("read fixture: %v", err)
+		}
+		return data
+	}
+	return mockFetcher{
+		perURL: map[string][]byte{
+			postscan.BaseURL + "/locations/texas":                     read("state_texas.html"),
+			postscan.BaseURL + "/locations/texas/austin-congress-ave": read("location_austin.html"),
+		},
+	}
+}
+
+func TestPostScanProviderSourceIsolation(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryMailboxRepository()
+	if err := repo.BatchUpsert(ctx, []model.Mailbox{
+		{ID: "atmb", Link: "https://anytimemailbox.com/locations/a", Source: SourceATMB, CrawlRunID: "RUN_0", Active: true, RDI: "Commercial"},
+		{ID: "stale", Link: postscan.BaseURL + "/locations/texas/closed", Source: postscan.Source, CrawlRunID: "RUN_0", Active: true},
+	}); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+
+	provider := NewPostScanProvider(postscanFetcher(t))
+	stats, err := CrawlProvider(ctx, provider, repo, nil, nil, nil, 4, []string{postscan.BaseURL + "/locations/texas"}, "RUN_1", nil, nil)
+	if err != nil {
+		t.Fatalf("CrawlProvider: %v", err)
+	}
+	if stats.Found != 1 || stats.Updated != 1 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+	if _, err := MarkAndSweep(ctx, repo, nil, "RUN_1", provider.Name(), nil); err != nil {
+		t.Fatalf("MarkAndSweep: %v", err)
+	}
+
+	all, _ := repo.FetchAllMap(ctx)
+	if !all["https://anytimemailbox.com/locations/a"].Active {
+		t.Errorf("PostScan sweep must not deactivate ATMB mailboxes")
+	}
+	if all[postscan.BaseURL+"/locations/texas/closed"].Active {
+		t.Errorf("stale PostScan mailbox should be swept")
+	}
+	austin := all[postscan.BaseURL+"/locations/texas/austin-congress-ave"]
+	if austin.Source != postscan.Source || austin.ParserVersion != postscan.ParserVersion || austin.RawHTML == "" || !austin.Active {
+		t.Errorf("unexpected PostScan mailbox: %+v", austin)
+	}
+
+	var mailboxes []model.Mailbox
+	for _, m := range all {
+		mailboxes = append(mailboxes, m)
+	}
+	sys := AggregateSystemStats(mailboxes)
+	if sys.BySource[postscan.Source] != 1 || sys.BySource[SourceATMB] != 1 {
+		t.Errorf("BySource = %v, want one active mailbox per source", sys.BySource)
+	}
+}
+
+func TestReprocessFromDBDispatchesBySource(t *testing.T) {
+	html, err := os.ReadFile(filepath.Join("postscan", "testdata", "location_austin.html"))
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	link := postscan.BaseURL + "/locations/texas/austin-congress-ave"
+	store := &mockStore{existing: map[string]model.Mailbox{
+		link:              {ID: "ps", Link: link, Source: postscan.Source, Name: "OLD NAME", RawHTML: string(html), ParserVersion: "v0.1", Active: true},
+		"https://other/1": {ID: "other", Link: "https://other/1", Source: "Other", RawHTML: "<html></html>", Active: true},
+	}}
+
+	opts := ReprocessOptions{Parsers: NewRegistry(NewPostScanProvider(nil)).HTMLParsers()}
+	stats, err := ReprocessFromDB(context.Background(), store, nil, nil, opts, nil, nil)
+	if err != nil {
+		t.Fatalf("ReprocessFromDB: %v", err)
+	}
+	if stats.Processed != 1 || stats.Skipped != 1 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+
+	saved := store.saved[0]
+	if saved.Name != "PostScan Mail - Austin, TX" || saved.Source != postscan.Source || saved.ParserVersion != postscan.ParserVersion {
+		t.Errorf("PostScan record not re-parsed with its own parser: %+v", saved)
+	}
+}
+
+// markValidator marks every mailbox it validates as a commercial non-CMRA address.
+type markValidator struct{ calls int }
+
+func (v *markValidator) ValidateMailbox(ctx context.Context, m model.Mailbox) (model.Mailbox, error) {
+	m.CMRA, m.RDI = "N", "Commercial"
+	return m, nil
+}
+
+func (v *markValidator) ValidateMailboxBatch(ctx context.Context, mailboxes []model.Mailbox) ([]model.Mailbox, error) {
+	v.calls++
+	out := make([]model.Mailbox, len(mailboxes))
+	for i, m := range mailboxes {
+		out[i], _ = v.ValidateMailbox(ctx, m)
+	}
+	return out, nil
+}
+
+func TestFeedProviderPipeline(t *testing.T) {
+	cfg := feed.Config{
+		Source: "TravelingMailbox",
+		Path:   filepath.Join("feed", "testdata", "locations.csv"),
+		Fields: feed.FieldMap{Name: "Location", Street: "Address", City: "City", State: "State", Zip: "ZIP", Price: "Monthly Price", Link: "URL"},
+	}
+	store := &mockStore{existing: map[string]model.Mailbox{}}
+	validator := &markValidator{}
+
+	provider := NewFeedProvider(cfg, nil)
+	stats, err := CrawlProvider(context.Background(), provider, store, nil, nil, validator, 4, nil, "RUN_1", nil, nil)
+	if err != nil {
+		t.Fatalf("CrawlProvider: %v", err)
+	}
+	if stats.Found != 2 || stats.Validated != 2 || validator.calls != 1 {
+		t.Fatalf("unexpected stats: %+v (batch calls %d)", stats, validator.calls)
+	}
+	for _, saved := range store.saved {
+		if saved.Source != "TravelingMailbox" || saved.ParserVersion != feed.ParserVersion || saved.DataHash == "" || saved.CMRA != "N" {
+			t.Errorf("feed record did not go through the shared pipeline: %+v", saved)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package crawler
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// ErrNotRecorded is returned when replaying a URL that is not in the archive.
+var ErrNotRecorded = errors.New("url not in crawl archive")
+
+// Crawl archives are plain directories so they can be checked into testdata and reviewed in diffs:
+//
+//	index.json         sorted list of {url, file, error}
+//	pages/<sha256>.html one body per successfully fetched URL
+const archiveIndex = "index.json"
+
+// archiveEntry records one fetch. Failed fetches keep their error so a replay fails the same way.
+type archiveEntry struct {
+	URL   string `json:"url"`
+	File  string `json:"file,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// RecordingFetcher passes fetches through to upstream and saves every URL→response pair into an archive.
+type RecordingFetcher struct {
+	upstream HTMLFetcher
+	dir      string
+
+	mu      sync.Mutex
+	entries map[string]archiveEntry
+}
+
+// NewRecordingFetcher records into dir, keeping entries already archived there.
+func NewRecordingFetcher(upstream HTMLFetcher, dir string) (*RecordingFetcher, error) {
+	if err := os.MkdirAll(filepath.Join(dir, "pages"), 0o755); err != nil {
+		return nil, fmt.Errorf("create crawl archive: %w", err)
+	}
+	entries, err := readArchiveIndex(dir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if entries == nil {
+		entries = make(map[string]archiveEntry)
+	}
+	return &RecordingFetcher{upstream: upstream, dir: dir, entries: entries}, nil
+}
+
+func (f *RecordingFetcher) Fetch(ctx context.Context, url string) (io.ReadCloser, error) {
+	body, err := f.upstream.Fetch(ctx, url)
+	if err != nil {
+		if ctx.Err() == nil {
+			// Cancellation says nothing about the page, so only real failures are archived.
+			f.record(archiveEntry{URL: url, Error: err.Error()}, nil)
+		}
+		return nil, err
+	}
+	defer body.Close()
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", url, err)
+	}
+
+	sum := sha256.Sum256([]byte(url))
+	file := filepath.ToSlash(filepath.Join("pages", hex.EncodeToString(sum[:])+".html"))
+	if err := f.record(archiveEntry{URL: url, File: file}, data); err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+// record writes the body and rewrites the index, so an interrupted crawl still leaves a usable archive.
+func (f *RecordingFetcher) record(entry archiveEntry, body []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if entry.File != "" {
+		if err := writeFileAtomic(filepath.Join(f.dir, filepath.FromSlash(entry.File)), body); err != nil {
+			return fmt.Errorf("archive %s: %w", entry.URL, err)
+		}
+	}
+	f.entries[entry.URL] = entry
+
+	list := make([]archiveEntry, 0, len(f.entries))
+	for _, e := range f.entries {
+		list = append(list, e)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].URL < list[j].URL })
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode archive index: %w", err)
+	}
+	if err := writeFileAtomic(filepath.Join(f.dir, archiveIndex), append(data, '\n')); err != nil {
+		return fmt.Errorf("write archive index: %w", err)
+	}
+	return nil
+}
+
+// ReplayFetcher serves fetches from an archive written by RecordingFetcher, never touching the network.
+type ReplayFetcher struct {
+	dir     string
+	entries map[string]archiveEntry
+}
+
+func NewReplayFetcher(dir string) (*ReplayFetcher, error) {
+	entries, err := readArchiveIndex(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &ReplayFetcher{dir: dir, entries: entries}, nil
+}
+
+func (f *ReplayFetcher) Fetch(ctx context.Context, url string) (io.ReadCloser, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	entry, ok := f.entries[url]
+	if !ok {
+		return nil, fmt.Errorf("fetch url %s: %w", url, ErrNotRecorded)
+	}
+	if entry.Error != "" {
+		return nil, fmt.Errorf("%s (replayed)", entry.Error)
+	}
+	body, err := os.Open(filepath.Join(f.dir, filepath.FromSlash(entry.File)))
+	if err != nil {
+		return nil, fmt.Errorf("replay %s: %w", url, err)
+	}
+	return body, nil
+}
+
+func readArchiveIndex(dir string) (map[string]archiveEntry, error) {
+	data, err := os.ReadFile(filepath.Join(dir, archiveIndex))
+	if err != nil {
+		return nil, fmt.Errorf("read crawl archive: %w", err)
+	}
+	var list []archiveEntry
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("decode crawl archive %s: %w", dir, err)
+	}
+	entries := make(map[string]archiveEntry, len(list))
+	for _, e := range list {
+		entries[e.URL] = e
+	}
+	return entries, nil
+}
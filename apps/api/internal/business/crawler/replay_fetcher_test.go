@@ -0,0 +1,89 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/internal/repository"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	dir := t.TempDir()
+	upstream := mockFetcher{perURL: map[string][]byte{"https://example.com/a": []byte("page a")}}
+	recorder, err := NewRecordingFetcher(upstream, dir)
+	if err != nil {
+		t.Fatalf("NewRecordingFetcher: %v", err)
+	}
+	ctx := context.Background()
+	readBody(t, mustFetch(t, recorder, "https://example.com/a"))
+
+	failing, _ := NewRecordingFetcher(mockFetcher{err: errors.New("status 503")}, dir)
+	if _, err := failing.Fetch(ctx, "https://example.com/down"); err == nil {
+		t.Fatalf("expected the upstream error")
+	}
+
+	replay, err := NewReplayFetcher(dir)
+	if err != nil {
+		t.Fatalf("NewReplayFetcher: %v", err)
+	}
+	if got := readBody(t, mustFetch(t, replay, "https://example.com/a")); got != "page a" {
+		t.Errorf("replayed body = %q", got)
+	}
+	if _, err := replay.Fetch(ctx, "https://example.com/down"); err == nil {
+		t.Errorf("a recorded failure should replay as a failure")
+	}
+	if _, err := replay.Fetch(ctx, "https://example.com/never"); !errors.Is(err, ErrNotRecorded) {
+		t.Errorf("err = %v, want ErrNotRecorded", err)
+	}
+}
+
+// TestReplayATMBCrawl runs discovery and the full upsert pipeline against a recorded ATMB crawl.
+func TestReplayATMBCrawl(t *testing.T) {
+	replay, err := NewReplayFetcher("testdata/replay_atmb")
+	if err != nil {
+		t.Fatalf("NewReplayFetcher: %v", err)
+	}
+	repo := repository.NewMemoryMailboxRepository()
+	provider := NewATMBProvider(replay, []string{"https://www.anytimemailbox.com/l/usa"})
+
+	stats, err := CrawlProvider(context.Background(), provider, repo, nil, nil, nil, 4, nil, "RUN_REPLAY", nil, nil)
+	if err != nil {
+		t.Fatalf("CrawlProvider: %v", err)
+	}
+	// Wyoming's state page failed during recording; the closed Austin detail page returned 404.
+	if stats.Found != 3 || stats.Updated != 2 || stats.Failed != 1 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+	if len(stats.Errors) != 1 || stats.Errors[0].Link != "https://www.anytimemailbox.com/s/austin-closed" {
+		t.Errorf("unexpected errors: %+v", stats.Errors)
+	}
+	if len(stats.DiscoveryFailures) != 1 || !strings.HasSuffix(stats.DiscoveryFailures[0], "/wyoming") {
+		t.Errorf("discovery failures = %v, want the Wyoming state page", stats.DiscoveryFailures)
+	}
+
+	all, _ := repo.FetchAllMap(context.Background())
+	var names []string
+	for _, m := range all {
+		names = append(names, m.Name+" "+m.AddressRaw.State)
+		if m.RawHTML == "" || m.CrawlRunID != "RUN_REPLAY" {
+			t.Errorf("unexpected stored mailbox: %+v", m)
+		}
+	}
+	sort.Strings(names)
+	if len(names) != 2 || names[0] != "Austin - Congress Ave TX" || names[1] != "Chicago - Monroe St IL" {
+		t.Errorf("stored mailboxes = %v", names)
+	}
+}
+
+func mustFetch(t *testing.T, f HTMLFetcher, url string) io.ReadCloser {
+	t.Helper()
+	body, err := f.Fetch(context.Background(), url)
+	if err != nil {
+		t.Fatalf("Fetch %s: %v", url, err)
+	}
+	return body
+}
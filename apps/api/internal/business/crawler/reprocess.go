@@ -12,21 +12,26 @@ import (
 
 // ReprocessOptions configures how reprocessing is performed.
 type ReprocessOptions struct {
-	TargetVersion   string    // Target parser version (defaults to CurrentParserVersion)
-	OnlyOutdated    bool      // Only reprocess records with different parser version
-	ForceRevalidate bool      // Force Smarty re-validation even if DataHash unchanged (useful when switching from mock to real API)
-	SinceTime       time.Time // Only reprocess records updated after this time
-	BatchSize       int       // Number of records to process per batch (defaults to 100)
+	TargetVersion   string                // Target parser version (defaults to each source parser's current version)
+	OnlyOutdated    bool                  // Only reprocess records with different parser version
+	ForceRevalidate bool                  // Force Smarty re-validation even if DataHash unchanged (useful when switching from mock to real API)
+	SinceTime       time.Time             // Only reprocess records updated after this time
+	BatchSize       int                   // Number of records to process per batch (defaults to 100)
+	RunID           string                // Run recorded as the cause of any resulting mailbox history
+	Parsers         map[string]HTMLParser // Parsers by source; ATMB (and records without a source) always use the ATMB parser
 }
 
 // ReprocessStats tracks progress of reprocessing operation.
 type ReprocessStats struct {
-	Total      int // Total records found
-	Processed  int // Records successfully reprocessed
-	Skipped    int // Records skipped (no HTML or version match)
-	Failed     int // Records that failed parsing
-	NoHTML     int // Records without RawHTML field
-	UpToDate   int // Records already at target version
+	Total     int               // Total records found
+	Processed int               // Records successfully reprocessed
+	Skipped   int               // Records skipped (no HTML, version match or no parser for the source)
+	Failed    int               // Records that failed parsing
+	NoHTML    int               // Records without RawHTML field
+	UpToDate  int               // Records already at target version
+	Errors    []model.LinkEvent // One entry per record that failed to parse, validate or save
+	// Validation counts the validator's results by status for the records that were re-validated.
+	Validation model.ValidationOutcomes
 }
 
 // ReprocessFromDB re-parses mailboxes from stored RawHTML without re-fetching.
@@ -35,14 +40,16 @@ type ReprocessStats struct {
 func ReprocessFromDB(
 	ctx context.Context,
 	store MailboxStore,
+	history HistoryRecorder,
 	smarty ValidationClient,
 	opts ReprocessOptions,
 	logFn func(string),
 	onProgress func(ReprocessStats),
 ) (ReprocessStats, error) {
 	// Set defaults
-	if opts.TargetVersion == "" {
-		opts.TargetVersion = CurrentParserVersion
+	parsers := map[string]HTMLParser{SourceATMB: NewATMBProvider(nil, nil)}
+	for source, parser := range opts.Parsers {
+		parsers[source] = parser
 	}
 	if opts.BatchSize <= 0 {
 		opts.BatchSize = 100
@@ -58,18 +65,21 @@ func ReprocessFromDB(
 
 	stats.Total = len(existing)
 	if logFn != nil {
-		logFn(fmt.Sprintf("reprocessing %d mailboxes to version %s", stats.Total, opts.TargetVersion))
+		target := opts.TargetVersion
+		if target == "" {
+			target = "current"
+		}
+		logFn(fmt.Sprintf("reprocessing %d mailboxes to version %s", stats.Total, target))
 	}
 
 	var toUpdate []model.Mailbox
-	var toValidateIndices []int // Track indices that need validation
+	var toValidateIndices []int          // Track indices that need validation
 	const incrementalWriteThreshold = 20 // Write to DB every 20 items (reduced due to RawHTML size)
 
 	for link, mb := range existing {
-		select {
-		case <-ctx.Done():
-			return stats, ctx.Err()
-		default:
+		// Holds while the run is paused; stops once it is cancelled.
+		if err := waitIfPaused(ctx); err != nil {
+			return stats, err
 		}
 
 		// Skip if no raw HTML available
@@ -82,8 +92,25 @@ func ReprocessFromDB(
 			continue
 		}
 
+		source := mb.Source
+		if source == "" {
+			source = SourceATMB
+		}
+		parser, ok := parsers[source]
+		if !ok {
+			stats.Skipped++
+			if logFn != nil {
+				logFn(fmt.Sprintf("skipping %s (no HTML parser for source %s)", link, source))
+			}
+			continue
+		}
+		targetVersion := opts.TargetVersion
+		if targetVersion == "" {
+			targetVersion = parser.ParserVersion()
+		}
+
 		// Skip if already at target version and OnlyOutdated is true
-		if opts.OnlyOutdated && mb.ParserVersion == opts.TargetVersion {
+		if opts.OnlyOutdated && mb.ParserVersion == targetVersion {
 			stats.UpToDate++
 			stats.Skipped++
 			continue
@@ -96,9 +123,11 @@ func ReprocessFromDB(
 		}
 
 		// Re-parse from stored HTML
-		reparsed, err := ParseMailboxHTML(strings.NewReader(mb.RawHTML), link)
+		start := time.Now()
+		reparsed, err := parser.ParseHTML(strings.NewReader(mb.RawHTML), link)
 		if err != nil {
 			stats.Failed++
+			stats.Errors = append(stats.Errors, linkFailure(link, model.StageParse, err, time.Since(start)))
 			if logFn != nil {
 				logFn(fmt.Sprintf("parse error for %s: %v", link, err))
 			}
@@ -107,13 +136,13 @@ func ReprocessFromDB(
 
 		// Preserve original metadata and update parsed fields
 		reparsed.ID = mb.ID
-		reparsed.Source = mb.Source // Preserve original source (ATMB or iPost1)
+		reparsed.Source = mb.Source // Preserve original source
 		reparsed.Link = link
 		reparsed.RawHTML = mb.RawHTML // Keep original HTML
 		reparsed.CrawlRunID = mb.CrawlRunID
 		reparsed.Active = mb.Active
 		reparsed.DataHash = util.HashMailboxKey(reparsed.Name, reparsed.AddressRaw)
-		reparsed.ParserVersion = opts.TargetVersion
+		reparsed.ParserVersion = targetVersion
 		reparsed.LastParsedAt = time.Now()
 
 		// Re-validate with Smarty if:
@@ -123,10 +152,9 @@ func ReprocessFromDB(
 
 		if !needsRevalidation {
 			// Keep existing validation if data unchanged and not forcing revalidation
-			reparsed.CMRA = mb.CMRA
-			reparsed.RDI = mb.RDI
-			reparsed.StandardizedAddress = mb.StandardizedAddress
-			reparsed.LastValidatedAt = mb.LastValidatedAt
+			carryValidation(&reparsed, mb)
+		} else {
+			reparsed.ValidationStatus = model.ValidationPending
 		}
 
 		toUpdate = append(toUpdate, reparsed)
@@ -139,16 +167,17 @@ func ReprocessFromDB(
 		if len(toUpdate) >= incrementalWriteThreshold {
 			// Batch validate before writing
 			if len(toValidateIndices) > 0 && smarty != nil {
-				toUpdate = reprocessBatchValidate(ctx, smarty, toUpdate, toValidateIndices, logFn)
+				toUpdate = reprocessBatchValidate(ctx, smarty, toUpdate, toValidateIndices, &stats, logFn)
 				toValidateIndices = toValidateIndices[:0]
 			}
 
-			if err := store.BatchUpsert(ctx, toUpdate); err != nil {
+			if err := upsertBatch(ctx, store, toUpdate, &stats.Errors); err != nil {
 				if logFn != nil {
 					logFn(fmt.Sprintf("batch upsert error: %v", err))
 				}
 				return stats, fmt.Errorf("batch upsert: %w", err)
 			}
+			recordHistory(ctx, history, existing, toUpdate, opts.RunID, logFn)
 			if logFn != nil {
 				logFn(fmt.Sprintf("wrote %d items to DB (incremental)", len(toUpdate)))
 			}
@@ -164,34 +193,54 @@ func ReprocessFromDB(
 	if len(toUpdate) > 0 {
 		// Batch validate remaining items
 		if len(toValidateIndices) > 0 && smarty != nil {
-			toUpdate = reprocessBatchValidate(ctx, smarty, toUpdate, toValidateIndices, logFn)
+			toUpdate = reprocessBatchValidate(ctx, smarty, toUpdate, toValidateIndices, &stats, logFn)
 		}
 
-		if err := store.BatchUpsert(ctx, toUpdate); err != nil {
+		if err := upsertBatch(ctx, store, toUpdate, &stats.Errors); err != nil {
 			if logFn != nil {
 				logFn(fmt.Sprintf("final batch upsert error: %v", err))
 			}
 			return stats, fmt.Errorf("batch upsert: %w", err)
 		}
+		recordHistory(ctx, history, existing, toUpdate, opts.RunID, logFn)
 		if logFn != nil {
 			logFn(fmt.Sprintf("wrote final %d items to DB", len(toUpdate)))
 		}
 	}
 
 	if logFn != nil {
-		logFn(fmt.Sprintf("reprocessing complete: processed=%d, skipped=%d (noHTML=%d, upToDate=%d), failed=%d",
-			stats.Processed, stats.Skipped, stats.NoHTML, stats.UpToDate, stats.Failed))
+		v := stats.Validation
+		logFn(fmt.Sprintf("reprocessing complete: processed=%d, skipped=%d (noHTML=%d, upToDate=%d), failed=%d, validation: verified=%d, noMatch=%d, ambiguous=%d, error=%d, quotaExhausted=%d",
+			stats.Processed, stats.Skipped, stats.NoHTML, stats.UpToDate, stats.Failed, v.Verified, v.NoMatch, v.Ambiguous, v.Error, v.QuotaExhausted))
 	}
 
 	return stats, nil
 }
 
-// reprocessBatchValidate validates a subset of mailboxes by their indices using batch API.
+// carryValidation copies Smarty results from the stored record onto a freshly parsed one.
+func carryValidation(dst *model.Mailbox, prev model.Mailbox) {
+	dst.CMRA = prev.CMRA
+	dst.RDI = prev.RDI
+	dst.StandardizedAddress = prev.StandardizedAddress
+	dst.Analysis = prev.Analysis
+	dst.Candidates = prev.Candidates
+	dst.ValidationStatus = prev.ValidationStatus
+	dst.ValidationReason = prev.ValidationReason
+	dst.Validator = prev.Validator
+	dst.Verdicts = prev.Verdicts
+	dst.Confidence = prev.Confidence
+	dst.ValidatorsDisagree = prev.ValidatorsDisagree
+	dst.LastValidatedAt = prev.LastValidatedAt
+}
+
+// reprocessBatchValidate validates a subset of mailboxes by their indices using batch API,
+// counting each outcome in stats and adding every mailbox left without a verdict to its errors.
 func reprocessBatchValidate(
 	ctx context.Context,
 	validator ValidationClient,
 	mailboxes []model.Mailbox,
 	indices []int,
+	stats *ReprocessStats,
 	logFn func(string),
 ) []model.Mailbox {
 	if len(indices) == 0 {
@@ -205,20 +254,19 @@ func reprocessBatchValidate(
 	}
 
 	// Batch validate
+	start := time.Now()
 	validated, err := validator.ValidateMailboxBatch(ctx, subset)
-	if err != nil {
-		if logFn != nil {
-			logFn(fmt.Sprintf("batch validation failed for %d items: %v", len(indices), err))
-		}
-		return mailboxes
+	if err != nil && logFn != nil {
+		logFn(fmt.Sprintf("batch validation failed for %d items: %v", len(indices), err))
 	}
 
 	// Merge results back
-	now := time.Now()
-	for i, idx := range indices {
-		mailboxes[idx] = validated[i]
-		mailboxes[idx].LastValidatedAt = now
+	mergeValidated(mailboxes, indices, validated, err, time.Since(start), &stats.Validation, &stats.Errors)
+	if err == nil {
+		now := time.Now()
+		for _, idx := range indices {
+			mailboxes[idx].LastValidatedAt = now
+		}
 	}
-
 	return mailboxes
 }
@@ -27,8 +27,8 @@ func TestReprocessFromDB(t *testing.T) {
 			State:  "XX",
 			Zip:    "00000",
 		},
-		RawHTML:       string(sample),                 // Has raw HTML
-		ParserVersion: "v0.9",                         // Old version
+		RawHTML:       string(sample),                  // Has raw HTML
+		ParserVersion: "v0.9",                          // Old version
 		LastParsedAt:  time.Now().Add(-24 * time.Hour), // Parsed 1 day ago
 		Active:        true,
 	}
@@ -75,7 +75,7 @@ func TestReprocessFromDB(t *testing.T) {
 		BatchSize:     100,
 	}
 
-	stats, err := ReprocessFromDB(context.Background(), store, nil, opts, nil, nil)
+	stats, err := ReprocessFromDB(context.Background(), store, nil, nil, opts, nil, nil)
 	if err != nil {
 		t.Fatalf("ReprocessFromDB: %v", err)
 	}
@@ -154,7 +154,7 @@ func TestReprocessFromDB_AllRecords(t *testing.T) {
 		BatchSize:     100,
 	}
 
-	stats, err := ReprocessFromDB(context.Background(), store, nil, opts, nil, nil)
+	stats, err := ReprocessFromDB(context.Background(), store, nil, nil, opts, nil, nil)
 	if err != nil {
 		t.Fatalf("ReprocessFromDB: %v", err)
 	}
@@ -0,0 +1,57 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/model"
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/util"
+)
+
+var (
+	// ErrMailboxNotFound is returned when the mailbox to review could not be loaded.
+	ErrMailboxNotFound = errors.New("mailbox not found")
+	// ErrNoSuchCandidate is returned when picking a candidate the mailbox does not have.
+	ErrNoSuchCandidate = errors.New("no such candidate")
+)
+
+// PickCandidate settles a mailbox on one of its stored candidates, as chosen by an operator from
+// the review queue, and records the resulting CMRA/RDI change in the mailbox history. An operator's
+// pick also settles a validator disagreement: the verdicts stay for auditing, but the mailbox leaves
+// the disagreement list with full confidence.
+func (s *Service) PickCandidate(ctx context.Context, id string, index int) (model.Mailbox, error) {
+	prev, err := s.mailboxes.Get(ctx, id)
+	if err != nil {
+		return model.Mailbox{}, fmt.Errorf("%w: %v", ErrMailboxNotFound, err)
+	}
+	if index < 0 || index >= len(prev.Candidates) {
+		return model.Mailbox{}, fmt.Errorf("%w: mailbox %s has %d candidates, got %d", ErrNoSuchCandidate, id, len(prev.Candidates), index)
+	}
+
+	next := prev
+	c := prev.Candidates[index]
+	next.StandardizedAddress = c.StandardizedAddress
+	next.CMRA = c.CMRA
+	next.RDI = c.RDI
+	next.Analysis = c.Analysis
+	next.ValidationStatus = model.ValidationVerified
+	next.ValidationReason = fmt.Sprintf("candidate %d picked in review", index)
+	if prev.ValidatorsDisagree {
+		next.ValidationReason += " over a validator disagreement"
+	}
+	next.ValidatorsDisagree = false
+	next.Confidence = 1
+	if err := s.mailboxes.BatchUpsert(ctx, []model.Mailbox{next}); err != nil {
+		return model.Mailbox{}, err
+	}
+
+	if entry, changed := util.HistoryEntry(prev, next, "", time.Now().UTC()); changed && s.history != nil {
+		if err := s.history.AppendHistory(ctx, []model.MailboxHistory{entry}); err != nil {
+			log.Printf("record history for mailbox %s: %v", id, err)
+		}
+	}
+	return next, nil
+}
@@ -0,0 +1,89 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/model"
+)
+
+// runLockTTL is how long a run's lease on its source lasts without a heartbeat. A run renews it every
+// third of that, so the lock of an instance that died mid-run frees up within runLockTTL.
+const runLockTTL = 2 * time.Minute
+
+// ErrRunInProgress is returned (wrapped in a *RunConflictError) when another run already holds the source.
+var ErrRunInProgress = errors.New("another run is in progress")
+
+// RunConflictError reports the run that holds the lease a new run needed.
+type RunConflictError struct {
+	Key   string
+	RunID string
+}
+
+func (e *RunConflictError) Error() string {
+	return fmt.Sprintf("%v: %s holds %s", ErrRunInProgress, e.RunID, e.Key)
+}
+
+func (e *RunConflictError) Unwrap() error { return ErrRunInProgress }
+
+// runLockKey is the lease a run needs: crawls and resumes of a source share one, so a sweep never
+// deactivates records a concurrent run of the same source just wrote; reprocess runs share another.
+func runLockKey(run model.CrawlRun) string {
+	if run.Kind == model.RunKindReprocess {
+		return model.RunKindReprocess
+	}
+	return model.RunKindCrawl + ":" + strings.ToLower(run.Source)
+}
+
+// acquireRunLock takes the run's lease, failing with *RunConflictError if another live run holds it.
+func (s *Service) acquireRunLock(ctx context.Context, run model.CrawlRun) error {
+	now := time.Now().UTC()
+	lock := model.RunLock{Key: runLockKey(run), RunID: run.RunID, AcquiredAt: now, ExpiresAt: now.Add(runLockTTL)}
+	holder, ok, err := s.runs.AcquireRunLock(ctx, lock)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return &RunConflictError{Key: lock.Key, RunID: holder.RunID}
+	}
+	return nil
+}
+
+// holdRunLock renews the run's lease until ctx is done, then releases it. If the lease is lost
+// (another run took it over after a missed heartbeat), lost is called so the run can stop. When ctx
+// ends with errLeaseLost the lock is left as is: it is keyed by run ID, so the worker that now owns
+// the job takes it over, and deleting it could drop that worker's hold.
+func (s *Service) holdRunLock(ctx context.Context, run model.CrawlRun, lost func()) {
+	key := runLockKey(run)
+	ticker := time.NewTicker(runLockTTL / 3)
+	defer ticker.Stop()
+	defer func() {
+		if errors.Is(context.Cause(ctx), errLeaseLost) {
+			return
+		}
+		if err := s.runs.ReleaseRunLock(context.Background(), key, run.RunID); err != nil {
+			log.Printf("run %s: %v", run.RunID, err)
+		}
+	}()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			held, err := s.runs.RenewRunLock(ctx, key, run.RunID, time.Now().UTC().Add(runLockTTL))
+			if err != nil {
+				log.Printf("run %s: %v", run.RunID, err)
+				continue
+			}
+			if !held {
+				log.Printf("run %s: lost lock %s, stopping", run.RunID, key)
+				lost()
+				return
+			}
+		}
+	}
+}
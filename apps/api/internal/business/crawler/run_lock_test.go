@@ -0,0 +1,25 @@
+package crawler
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestGenerateRunIDUniqueAndSorted(t *testing.T) {
+	ids := make([]string, 1000)
+	seen := make(map[string]bool, len(ids))
+	for i := range ids {
+		ids[i] = generateRunID()
+		if seen[ids[i]] {
+			t.Fatalf("duplicate run ID %s", ids[i])
+		}
+		seen[ids[i]] = true
+	}
+	if !sort.StringsAreSorted(ids) {
+		t.Errorf("run IDs do not sort in creation order")
+	}
+	// IDs keep sorting after the legacy RUN_<unix> form.
+	if legacy := "RUN_1700000000"; ids[0] <= legacy {
+		t.Errorf("%s sorts before legacy %s", ids[0], legacy)
+	}
+}
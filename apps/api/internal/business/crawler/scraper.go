@@ -1,8 +1,8 @@
 package crawler
 
 import (
-	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"time"
@@ -12,7 +12,7 @@ import (
 )
 
 // CurrentParserVersion tracks the parser logic version for reprocessing support.
-const CurrentParserVersion = "v1.1"
+const CurrentParserVersion = "v1.2"
 
 // HTMLFetcher abstracts how pages are fetched so we can test the scraper without network calls.
 type HTMLFetcher interface {
@@ -33,21 +33,97 @@ type ScrapeStats struct {
 	Updated   int
 	Validated int
 	Failed    int
+	Errors    []model.LinkEvent // One entry per listing that failed to fetch, parse, validate or save
+	// Validation counts the validator's results by status; Validated and Failed include them.
+	Validation model.ValidationOutcomes
+	// DiscoveryFailures lists the segments (e.g., states) discovery could not list; the listings
+	// found elsewhere were still crawled.
+	DiscoveryFailures []string
 }
 
-// ScrapeAndUpsert runs the scrape pipeline: fetch pages, parse, hash, compare, and batch upsert.
-// Uses batch validation to reduce API calls by up to 99%.
+// ScrapeAndUpsert runs the ATMB pipeline over explicit detail links: fetch pages, parse, hash, compare, and batch upsert.
+// Pages are fetched and parsed by a pool of workers; uses batch validation to reduce API calls by up to 99%.
 func ScrapeAndUpsert(
 	ctx context.Context,
 	fetcher HTMLFetcher,
 	store MailboxStore,
+	history HistoryRecorder,
 	validator ValidationClient,
+	workers int,
 	links []string,
 	runID string,
 	onProgress func(ScrapeStats),
 	logFn func(string),
 ) (ScrapeStats, error) {
-	stats := ScrapeStats{Found: len(links)}
+	listings := make([]model.Mailbox, len(links))
+	for i, link := range links {
+		listings[i] = model.Mailbox{Link: link}
+	}
+	return upsertListings(ctx, NewATMBProvider(fetcher, nil), store, history, nil, validator, workers, listings, runID, onProgress, logFn)
+}
+
+// CrawlProvider discovers a provider's locations and runs them through the shared upsert pipeline.
+// With a Checkpointer, the discovered listings and their completion are recorded under runID.
+func CrawlProvider(
+	ctx context.Context,
+	provider Provider,
+	store MailboxStore,
+	history HistoryRecorder,
+	checkpoints Checkpointer,
+	validator ValidationClient,
+	workers int,
+	seeds []string,
+	runID string,
+	onProgress func(ScrapeStats),
+	logFn func(string),
+) (ScrapeStats, error) {
+	listings, err := provider.Discover(ctx, seeds)
+	var partial partialDiscovery
+	var failures []string
+	if errors.As(err, &partial) && len(listings) > 0 {
+		// Crawl what was found; the failures keep the sweep from trusting this inventory.
+		failures = partial.FailedSegments()
+		if logFn != nil {
+			logFn(fmt.Sprintf("partial %s discovery: %v", provider.Name(), err))
+		}
+	} else if errors.As(err, &partial) {
+		// Every segment failed: checkpoint the failures alone so the run's sweep is held on them.
+		failures = partial.FailedSegments()
+		saveCheckpoint(ctx, checkpoints, runID, provider.Name(), nil, failures, logFn)
+		return ScrapeStats{DiscoveryFailures: failures}, fmt.Errorf("discover %s: %w", provider.Name(), err)
+	} else if err != nil {
+		return ScrapeStats{}, fmt.Errorf("discover %s: %w", provider.Name(), err)
+	}
+	if len(listings) == 0 {
+		return ScrapeStats{}, fmt.Errorf("no %s locations discovered", provider.Name())
+	}
+	if logFn != nil {
+		logFn(fmt.Sprintf("discovered %d %s locations", len(listings), provider.Name()))
+	}
+	saveCheckpoint(ctx, checkpoints, runID, provider.Name(), listings, failures, logFn)
+	stats, err := upsertListings(ctx, provider, store, history, checkpoints, validator, workers, listings, runID, onProgress, logFn)
+	stats.DiscoveryFailures = failures
+	return stats, err
+}
+
+// upsertListings parses the listings with the provider on a pool of workers, skips unchanged mailboxes,
+// validates the rest in batches, and writes them with their change history and price observations.
+// Results are consumed on a single goroutine, so stats, batching and progress need no locking.
+// A listing is marked done in the checkpoint once its outcome is persisted.
+func upsertListings(
+	ctx context.Context,
+	provider Provider,
+	store MailboxStore,
+	history HistoryRecorder,
+	checkpoints Checkpointer,
+	validator ValidationClient,
+	workers int,
+	listings []model.Mailbox,
+	runID string,
+	onProgress func(ScrapeStats),
+	logFn func(string),
+) (ScrapeStats, error) {
+	stats := ScrapeStats{Found: len(listings)}
 
 	// Use FetchAllMetadata for deduplication (90% faster, excludes RawHTML)
 	existing, err := store.FetchAllMetadata(ctx)
@@ -56,81 +132,93 @@ func ScrapeAndUpsert(
 	}
 
 	var toSave []model.Mailbox
-	var toValidateIndices []int // Track indices that need validation
+	var prices []model.PricePoint        // Every observed price, including skipped mailboxes
+	var toValidateIndices []int          // Track indices that need validation
+	var done []string                    // Finished listing links not yet marked in the checkpoint
+	var saving []string                  // Listing links of toSave; done once written
 	const incrementalWriteThreshold = 20 // Write to DB every 20 items (reduced due to RawHTML size)
+	const checkpointThreshold = 100      // Mark skipped/failed links done every 100 items when nothing is written
 
-	for _, link := range links {
-		select {
-		case <-ctx.Done():
-			return stats, ctx.Err()
-		default:
-		}
-		body, err := fetcher.Fetch(ctx, link)
-		if err != nil {
-			stats.Failed++
-			if logFn != nil {
-				logFn(fmt.Sprintf("fetch %s error: %v", link, err))
-			}
-			if onProgress != nil {
-				onProgress(stats)
-			}
-			continue
+	// Stop the workers if we return before draining their results.
+	workCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	parse := func(ctx context.Context, listing model.Mailbox) (model.Mailbox, error) {
+		// Only validated records may be skipped without parsing, matching the skip rule below.
+		if prev, ok := existing[listing.Link]; ok && prev.CMRA != "" {
+			ctx = withConditionalFetch(ctx)
 		}
+		return provider.Parse(ctx, listing)
+	}
 
-		// Read HTML into memory for both parsing and storage
-		htmlBytes, err := io.ReadAll(body)
-		body.Close()
-		if err != nil {
-			stats.Failed++
-			if logFn != nil {
-				logFn(fmt.Sprintf("read %s error: %v", link, err))
+	for res := range NewOrchestrator(workers).Run(workCtx, listings, parse) {
+		if errors.Is(res.Err, ErrUnchanged) {
+			// The page is byte-for-byte what we stored last time: keep the record, but still observe its price.
+			stats.Skipped++
+			done = append(done, res.Listing.Link)
+			if point, ok := util.ObservePrice(existing[res.Listing.Link], runID, time.Now()); ok {
+				prices = append(prices, point)
 			}
 			if onProgress != nil {
 				onProgress(stats)
 			}
 			continue
 		}
-
-		// Parse HTML from bytes
-		parsed, err := ParseMailboxHTML(bytes.NewReader(htmlBytes), link)
-		if err != nil {
+		if res.Err != nil {
 			stats.Failed++
+			stats.Errors = append(stats.Errors, linkFailure(res.Listing.Link, failureStage(res.Err), res.Err, res.Elapsed))
+			done = append(done, res.Listing.Link)
 			if logFn != nil {
-				logFn(fmt.Sprintf("parse %s error: %v", link, err))
+				logFn(fmt.Sprintf("%s error: %v", provider.Name(), res.Err))
 			}
 			if onProgress != nil {
 				onProgress(stats)
 			}
 			continue
 		}
+		parsed := res.Mailbox
+		listing := res.Listing
 
 		// Set metadata fields
-		parsed.Source = "ATMB" // Mark as ATMB source
-		parsed.DataHash = util.HashMailboxKey(parsed.Name, parsed.AddressRaw)
+		parsed.Source = provider.Name()
 		if parsed.Link == "" {
-			parsed.Link = link
+			parsed.Link = listing.Link
+		}
+		if parsed.DataHash == "" {
+			parsed.DataHash = util.HashMailboxKey(parsed.Name, parsed.AddressRaw)
 		}
 		parsed.CrawlRunID = runID
 		parsed.Active = true
-
-		// Save raw HTML for reprocessing support
-		parsed.RawHTML = string(htmlBytes)
-		parsed.ParserVersion = CurrentParserVersion
+		parsed.ParserVersion = provider.ParserVersion()
 		parsed.LastParsedAt = time.Now()
 
-		if prev, ok := existing[parsed.Link]; ok {
-			if prev.DataHash == parsed.DataHash && prev.CMRA != "" {
+		prev, seen := existing[parsed.Link]
+		if seen {
+			// Preserve IDs so updates target existing docs.
+			parsed.ID = prev.ID
+		}
+		if point, ok := util.ObservePrice(parsed, runID, parsed.LastParsedAt); ok {
+			prices = append(prices, point)
+		}
+		if seen && prev.DataHash == parsed.DataHash && prev.CMRA != "" {
+			if prev.Price == parsed.Price && util.SamePlans(prev.Plans, parsed.Plans) {
 				stats.Skipped++
+				done = append(done, listing.Link)
 				continue
 			}
-			// Preserve IDs so updates target existing docs.
-			parsed.ID = prev.ID
+			// Only pricing moved: keep the existing validation instead of re-querying Smarty.
+			carryValidation(&parsed, prev)
 		}
 
 		// Track if validation needed (CMRA/RDI are always empty after HTML parsing)
 		needsValidation := parsed.CMRA == "" || parsed.RDI == ""
+		if needsValidation {
+			parsed.ValidationStatus = model.ValidationPending
+			parsed.ValidationReason = ""
+		}
 
 		toSave = append(toSave, parsed)
+		saving = append(saving, listing.Link)
 		if needsValidation && validator != nil {
 			toValidateIndices = append(toValidateIndices, len(toSave)-1)
 		}
@@ -138,22 +226,36 @@ func ScrapeAndUpsert(
 
 		// Incremental write with batch validation: flush to DB every N items
 		if len(toSave) >= incrementalWriteThreshold {
+			// A paused run spends no validation quota until it is resumed.
+			if err := waitIfPaused(ctx); err != nil {
+				return stats, err
+			}
 			// Batch validate before writing
 			if len(toValidateIndices) > 0 && validator != nil {
 				toSave, stats = batchValidateSubset(ctx, validator, toSave, toValidateIndices, stats, logFn)
 				toValidateIndices = toValidateIndices[:0]
 			}
 
-			if err := store.BatchUpsert(ctx, toSave); err != nil {
+			if err := upsertBatch(ctx, store, toSave, &stats.Errors); err != nil {
 				if logFn != nil {
 					logFn(fmt.Sprintf("incremental batch upsert error: %v", err))
 				}
 				return stats, fmt.Errorf("batch upsert: %w", err)
 			}
+			recordHistory(ctx, history, existing, toSave, runID, logFn)
+			recordPrices(ctx, history, prices, logFn)
 			if logFn != nil {
 				logFn(fmt.Sprintf("wrote %d items to DB (incremental)", len(toSave)))
 			}
+			done = append(done, saving...)
+			markCheckpointDone(ctx, checkpoints, runID, done, logFn)
 			toSave = toSave[:0] // Clear slice but keep capacity
+			prices = prices[:0]
+			saving = saving[:0]
+			done = done[:0]
+		} else if len(done) >= checkpointThreshold {
+			markCheckpointDone(ctx, checkpoints, runID, done, logFn)
+			done = done[:0]
 		}
 
 		if onProgress != nil {
@@ -161,6 +263,10 @@ func ScrapeAndUpsert(
 		}
 	}
 
+	if err := ctx.Err(); err != nil {
+		return stats, err
+	}
+
 	// Final write with batch validation: flush any remaining items
 	if len(toSave) > 0 {
 		// Batch validate remaining items
@@ -168,16 +274,20 @@ func ScrapeAndUpsert(
 			toSave, stats = batchValidateSubset(ctx, validator, toSave, toValidateIndices, stats, logFn)
 		}
 
-		if err := store.BatchUpsert(ctx, toSave); err != nil {
+		if err := upsertBatch(ctx, store, toSave, &stats.Errors); err != nil {
 			if logFn != nil {
 				logFn(fmt.Sprintf("final batch upsert error: %v", err))
 			}
 			return stats, fmt.Errorf("batch upsert: %w", err)
 		}
+		recordHistory(ctx, history, existing, toSave, runID, logFn)
 		if logFn != nil {
 			logFn(fmt.Sprintf("wrote final %d items to DB", len(toSave)))
 		}
+		done = append(done, saving...)
 	}
+	recordPrices(ctx, history, prices, logFn)
+	markCheckpointDone(ctx, checkpoints, runID, done, logFn)
 	return stats, nil
 }
 
@@ -201,25 +311,15 @@ func batchValidateSubset(
 	}
 
 	// Batch validate
+	start := time.Now()
 	validated, err := validator.ValidateMailboxBatch(ctx, subset)
-	if err != nil {
-		// On error, count all as failed
-		stats.Failed += len(indices)
-		if logFn != nil {
-			logFn(fmt.Sprintf("batch validation failed for %d items: %v", len(indices), err))
-		}
-		return mailboxes, stats
+	if err != nil && logFn != nil {
+		logFn(fmt.Sprintf("batch validation failed for %d items: %v", len(indices), err))
 	}
 
 	// Merge results back
-	for i, idx := range indices {
-		mailboxes[idx] = validated[i]
-		if validated[i].CMRA != "" {
-			stats.Validated++
-		} else {
-			stats.Failed++
-		}
-	}
-
+	ok, failed := mergeValidated(mailboxes, indices, validated, err, time.Since(start), &stats.Validation, &stats.Errors)
+	stats.Validated += ok
+	stats.Failed += failed
 	return mailboxes, stats
 }
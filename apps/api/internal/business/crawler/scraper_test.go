@@ -3,8 +3,11 @@ package crawler
 import (
 	"bytes"
 	"context"
+	"errors"
+	"fmt"
 	"io"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/model"
@@ -46,13 +49,20 @@ func (m *mockStore) BatchUpsert(ctx context.Context, mailboxes []model.Mailbox)
 	return nil
 }
 
+// samplePlans are the plan tiers parsed from testdata/sample_page.html.
+var samplePlans = []model.Plan{
+	{Name: "Bronze", MonthlyPrice: 19.99, BillingPeriod: model.BillingMonthly},
+	{Name: "Silver", MonthlyPrice: 34.99, BillingPeriod: model.BillingMonthly},
+	{Name: "Gold", MonthlyPrice: 49.99, BillingPeriod: model.BillingMonthly},
+}
+
 func TestScrapeAndUpsert(t *testing.T) {
 	sample, err := os.ReadFile("testdata/sample_page.html")
 	if err != nil {
 		t.Fatalf("read sample html: %v", err)
 	}
 
-	// Existing mailbox with matching hash and CMRA should be skipped.
+	// Existing mailbox with matching hash, pricing and CMRA should be skipped.
 	existingMailbox := model.Mailbox{
 		ID:   "existing-id",
 		Link: "https://anytimemailbox.com/locations/chicago-monroe-st",
@@ -63,6 +73,8 @@ func TestScrapeAndUpsert(t *testing.T) {
 			State:  "IL",
 			Zip:    "60603",
 		},
+		Price:    19.99,
+		Plans:    samplePlans,
 		CMRA:     "Y",
 		DataHash: util.HashMailboxKey("Chicago - Monroe St", model.AddressRaw{Street: "73 W Monroe St", City: "Chicago", State: "IL", Zip: "60603"}),
 	}
@@ -86,7 +98,7 @@ func TestScrapeAndUpsert(t *testing.T) {
 		links[1]: sample,
 	}
 
-	stats, err := ScrapeAndUpsert(context.Background(), fetcher, store, nil, links, "RUN_1", nil, nil)
+	stats, err := ScrapeAndUpsert(context.Background(), fetcher, store, nil, nil, 4, links, "RUN_1", nil, nil)
 	if err != nil {
 		t.Fatalf("ScrapeAndUpsert: %v", err)
 	}
@@ -110,3 +122,235 @@ func TestScrapeAndUpsert(t *testing.T) {
 		t.Errorf("saved link = %q, want %q", saved.Link, links[1])
 	}
 }
+
+type mockHistory struct {
+	entries []model.MailboxHistory
+	prices  []model.PricePoint
+}
+
+func (m *mockHistory) AppendHistory(ctx context.Context, entries []model.MailboxHistory) error {
+	m.entries = append(m.entries, entries...)
+	return nil
+}
+
+func (m *mockHistory) AppendPrices(ctx context.Context, points []model.PricePoint) error {
+	m.prices = append(m.prices, points...)
+	return nil
+}
+
+func TestScrapeAndUpsertRecordsHistory(t *testing.T) {
+	sample, err := os.ReadFile("testdata/sample_page.html")
+	if err != nil {
+		t.Fatalf("read sample html: %v", err)
+	}
+
+	link := "https://anytimemailbox.com/locations/chicago-monroe-st"
+	store := &mockStore{
+		existing: map[string]model.Mailbox{
+			link: {ID: "existing-id", Link: link, Name: "Chicago - Old Name", Price: 9.99, Active: true},
+		},
+	}
+	history := &mockHistory{}
+
+	_, err = ScrapeAndUpsert(context.Background(), mockFetcher{html: sample}, store, history, nil, 4, []string{link}, "RUN_2", nil, nil)
+	if err != nil {
+		t.Fatalf("ScrapeAndUpsert: %v", err)
+	}
+
+	if len(history.entries) != 1 {
+		t.Fatalf("expected 1 history entry, got %d", len(history.entries))
+	}
+	entry := history.entries[0]
+	if entry.MailboxID != "existing-id" || entry.CrawlRunID != "RUN_2" {
+		t.Errorf("unexpected entry: %+v", entry)
+	}
+	fields := map[string]bool{}
+	for _, c := range entry.Changes {
+		fields[c.Field] = true
+	}
+	if !fields["name"] || !fields["price"] || !fields["address"] {
+		t.Errorf("expected name, price and address changes, got %+v", entry.Changes)
+	}
+}
+
+func TestScrapeAndUpsertPriceOnlyChange(t *testing.T) {
+	sample, err := os.ReadFile("testdata/sample_page.html")
+	if err != nil {
+		t.Fatalf("read sample html: %v", err)
+	}
+
+	addr := model.AddressRaw{Street: "73 W Monroe St", City: "Chicago", State: "IL", Zip: "60603"}
+	unchanged := "https://anytimemailbox.com/locations/unchanged"
+	repriced := "https://anytimemailbox.com/locations/repriced"
+	base := model.Mailbox{
+		Name:       "Chicago - Monroe St",
+		AddressRaw: addr,
+		CMRA:       "Y",
+		RDI:        "Commercial",
+		DataHash:   util.HashMailboxKey("Chicago - Monroe St", addr),
+		Active:     true,
+	}
+	prevUnchanged := base
+	prevUnchanged.ID, prevUnchanged.Link, prevUnchanged.Price, prevUnchanged.Plans = "unchanged-id", unchanged, 19.99, samplePlans
+	prevRepriced := base
+	prevRepriced.ID, prevRepriced.Link, prevRepriced.Price, prevRepriced.Plans = "repriced-id", repriced, 14.99, samplePlans
+
+	store := &mockStore{existing: map[string]model.Mailbox{unchanged: prevUnchanged, repriced: prevRepriced}}
+	history := &mockHistory{}
+
+	stats, err := ScrapeAndUpsert(context.Background(), mockFetcher{html: sample}, store, history, nil, 4, []string{unchanged, repriced}, "RUN_3", nil, nil)
+	if err != nil {
+		t.Fatalf("ScrapeAndUpsert: %v", err)
+	}
+	if stats.Skipped != 1 || stats.Updated != 1 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+
+	if len(store.saved) != 1 || store.saved[0].ID != "repriced-id" {
+		t.Fatalf("expected only the repriced mailbox to be saved, got %+v", store.saved)
+	}
+	if saved := store.saved[0]; saved.Price != 19.99 || saved.CMRA != "Y" || saved.RDI != "Commercial" {
+		t.Errorf("price-only update should keep validation: %+v", saved)
+	}
+
+	// Skipped mailboxes still contribute a price observation.
+	if len(history.prices) != 2 {
+		t.Fatalf("expected 2 price points, got %d", len(history.prices))
+	}
+	for _, p := range history.prices {
+		if p.CrawlRunID != "RUN_3" || p.Price != 19.99 {
+			t.Errorf("unexpected price point: %+v", p)
+		}
+	}
+	if len(history.entries) != 1 || history.entries[0].Changes[0].Field != "price" {
+		t.Errorf("expected a single price change record, got %+v", history.entries)
+	}
+}
+
+// brokenFetcher fails every URL containing "/broken/" and serves html otherwise.
+type brokenFetcher struct{ html []byte }
+
+func (f brokenFetcher) Fetch(ctx context.Context, url string) (io.ReadCloser, error) {
+	if strings.Contains(url, "/broken/") {
+		return nil, fmt.Errorf("status 500 for %s", url)
+	}
+	return io.NopCloser(bytes.NewReader(f.html)), nil
+}
+
+func TestScrapeAndUpsertConcurrentCollectsErrors(t *testing.T) {
+	sample, err := os.ReadFile("testdata/sample_page.html")
+	if err != nil {
+		t.Fatalf("read sample html: %v", err)
+	}
+
+	var links []string
+	for i := 0; i < 45; i++ {
+		links = append(links, fmt.Sprintf("https://anytimemailbox.com/locations/store-%d", i))
+	}
+	for i := 0; i < 5; i++ {
+		links = append(links, fmt.Sprintf("https://anytimemailbox.com/broken/store-%d", i))
+	}
+
+	store := &mockStore{existing: map[string]model.Mailbox{}}
+	var progressCalls int
+	stats, err := ScrapeAndUpsert(context.Background(), brokenFetcher{html: sample}, store, nil, nil, 8, links, "RUN_4",
+		func(ScrapeStats) { progressCalls++ }, nil)
+	if err != nil {
+		t.Fatalf("ScrapeAndUpsert: %v", err)
+	}
+
+	if stats.Found != 50 || stats.Updated != 45 || stats.Failed != 5 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+	if progressCalls != 50 {
+		t.Errorf("progress called %d times, want once per listing", progressCalls)
+	}
+	if len(store.saved) != 45 {
+		t.Errorf("saved %d mailboxes, want 45", len(store.saved))
+	}
+
+	// Every failure is reported with its link, not just the first one.
+	if len(stats.Errors) != 5 {
+		t.Fatalf("collected %d errors, want 5", len(stats.Errors))
+	}
+	for _, e := range stats.Errors {
+		if !strings.Contains(e.Link, "/broken/") || !strings.Contains(e.Error, "status 500") || e.Stage != model.StageFetch {
+			t.Errorf("unexpected error sample: %+v", e)
+		}
+	}
+}
+
+func TestScrapeAndUpsertCanceled(t *testing.T) {
+	sample, err := os.ReadFile("testdata/sample_page.html")
+	if err != nil {
+		t.Fatalf("read sample html: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	store := &mockStore{existing: map[string]model.Mailbox{}}
+	_, err = ScrapeAndUpsert(ctx, mockFetcher{html: sample}, store, nil, nil, 4, []string{"https://anytimemailbox.com/locations/a"}, "RUN_5", nil, nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+}
+
+// statusValidator answers each mailbox with the status keyed by its link and fails the batch
+// with err, the way the Smarty client reports a chunk that ran out of quota.
+type statusValidator struct {
+	statuses map[string]string
+	err      error
+}
+
+func (v statusValidator) ValidateMailbox(ctx context.Context, m model.Mailbox) (model.Mailbox, error) {
+	m.ValidationStatus = v.statuses[m.Link]
+	if m.ValidationStatus == model.ValidationVerified || m.ValidationStatus == model.ValidationAmbiguous {
+		m.CMRA, m.RDI = "Y", "Commercial"
+	}
+	return m, nil
+}
+
+func (v statusValidator) ValidateMailboxBatch(ctx context.Context, mailboxes []model.Mailbox) ([]model.Mailbox, error) {
+	out := make([]model.Mailbox, len(mailboxes))
+	for i, m := range mailboxes {
+		out[i], _ = v.ValidateMailbox(ctx, m)
+	}
+	return out, v.err
+}
+
+func TestBatchValidateSubsetCountsOutcomes(t *testing.T) {
+	mailboxes := []model.Mailbox{
+		{Link: "verified"}, {Link: "no-match"}, {Link: "ambiguous"}, {Link: "quota"}, {Link: "unmarked"},
+		{Link: "not-validated", ValidationStatus: model.ValidationPending},
+	}
+	validator := statusValidator{statuses: map[string]string{
+		"verified":  model.ValidationVerified,
+		"no-match":  model.ValidationNoMatch,
+		"ambiguous": model.ValidationAmbiguous,
+		"quota":     model.ValidationQuotaExhausted,
+	}}
+
+	got, stats := batchValidateSubset(context.Background(), validator, mailboxes, []int{0, 1, 2, 3, 4}, ScrapeStats{}, nil)
+	want := model.ValidationOutcomes{Verified: 1, NoMatch: 2, Ambiguous: 1, QuotaExhausted: 1}
+	if stats.Validation != want {
+		t.Errorf("outcomes = %+v, want %+v", stats.Validation, want)
+	}
+	if stats.Validated != 2 || stats.Failed != 3 || len(stats.Errors) != 3 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+	// A mailbox the validator left unmarked and without a verdict did not match.
+	if got[4].ValidationStatus != model.ValidationNoMatch || got[4].ValidationReason == "" {
+		t.Errorf("unmarked mailbox = %+v, want no_match with a reason", got[4])
+	}
+	if got[5].ValidationStatus != model.ValidationPending {
+		t.Errorf("mailbox outside the subset changed: %+v", got[5])
+	}
+
+	// A failed batch records its error on the mailboxes the validator did not mark.
+	validator.err = errors.New("smarty status 500")
+	_, stats = batchValidateSubset(context.Background(), validator, mailboxes, []int{0, 5}, ScrapeStats{}, nil)
+	want = model.ValidationOutcomes{Verified: 1, Error: 1}
+	if stats.Validation != want || stats.Failed != 1 || !strings.Contains(stats.Errors[0].Error, "status 500") {
+		t.Errorf("failed batch: outcomes %+v, stats %+v", stats.Validation, stats)
+	}
+}
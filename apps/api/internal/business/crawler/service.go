@@ -2,420 +2,511 @@ package crawler
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"log"
 	"sort"
-	"strings"
+	"sync"
 	"time"
 
-	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/internal/business/crawler/ipost1"
 	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/internal/repository"
 	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/model"
 )
 
 // Service orchestrates end-to-end crawl.
 type Service struct {
-	fetcher    HTMLFetcher
-	validator  ValidationClient
-	mailboxes  *repository.MailboxRepository
-	runs       *repository.RunRepository
-	statsRepo  *repository.StatsRepository
-	workerCnt  int
-	seedLinks  []string
-	jobManager *JobManager
+	providers   *Registry
+	validator   ValidationClient
+	mailboxes   repository.MailboxStore
+	runs        repository.RunStore
+	statsRepo   repository.StatsStore
+	history     repository.HistoryStore
+	jobs        repository.JobStore
+	workerCnt   int
+	sweepPolicy SweepPolicy
+	jobManager  *JobManager
+	events      *EventBus
+	wake        chan struct{}
 }
 
-func NewService(fetcher HTMLFetcher, validator ValidationClient, mailboxes *repository.MailboxRepository, runs *repository.RunRepository, statsRepo *repository.StatsRepository, workerCnt int, seedLinks []string, jobManager *JobManager) *Service {
+func NewService(providers *Registry, validator ValidationClient, mailboxes repository.MailboxStore, runs repository.RunStore, statsRepo repository.StatsStore, history repository.HistoryStore, jobs repository.JobStore, workerCnt int, sweepPolicy SweepPolicy, jobManager *JobManager) *Service {
 	if workerCnt <= 0 {
 		workerCnt = 5
 	}
 	return &Service{
-		fetcher:    fetcher,
-		validator:  validator,
-		mailboxes:  mailboxes,
-		runs:       runs,
-		statsRepo:  statsRepo,
-		workerCnt:  workerCnt,
-		seedLinks:  seedLinks,
-		jobManager: jobManager,
+		providers:   providers,
+		validator:   validator,
+		mailboxes:   mailboxes,
+		runs:        runs,
+		statsRepo:   statsRepo,
+		history:     history,
+		jobs:        jobs,
+		workerCnt:   workerCnt,
+		sweepPolicy: sweepPolicy,
+		jobManager:  jobManager,
+		events:      NewEventBus(),
+		wake:        make(chan struct{}, 1),
 	}
 }
 
-// Start kicks off a crawl run asynchronously.
-func (s *Service) Start(ctx context.Context, links []string) (string, error) {
-	if len(links) == 0 {
-		links = s.seedLinks
-	}
-	if len(links) == 0 {
-		return "", fmt.Errorf("no links provided to crawl (set CRAWL_LINK_SEEDS or pass links in request)")
-	}
-	startTime := time.Now().UTC()
-	runID := generateRunID()
-	if err := StartRun(ctx, s.runs, runID, "ATMB", startTime); err != nil {
-		return "", err
-	}
-	// Guard long-running crawls to avoid stuck runs.
-	runCtx, timeoutCancel := context.WithTimeout(context.Background(), 30*time.Minute)
-	runCtx, cancel := context.WithCancel(runCtx)
-
-	// Register for external cancellation
-	s.jobManager.Register(runID, cancel)
-
-	go func() {
-		defer s.jobManager.Unregister(runID)
-		defer cancel()
-		defer timeoutCancel()
-		s.execute(runCtx, runID, links, startTime)
-	}()
-	return runID, nil
+// SubscribeRun streams the live events of a run executing on this instance until the returned
+// function is called.
+func (s *Service) SubscribeRun(runID string) (<-chan model.RunEvent, func()) {
+	return s.events.Subscribe(runID)
 }
 
-func (s *Service) execute(ctx context.Context, runID string, links []string, startedAt time.Time) {
-	status := "running"
-	stats := model.CrawlRunStats{}
+// publishStatus announces a run's status (and counters) to its subscribers.
+func (s *Service) publishStatus(runID, status string, stats model.CrawlRunStats) {
+	s.events.Publish(model.RunEvent{Type: model.RunEventStatus, RunID: runID, Status: status, Stats: &stats})
+}
 
-	// Always finalize the run document, even on panic.
-	defer func() {
-		if rec := recover(); rec != nil {
-			status = "failed"
-			log.Printf("crawl panic run %s: %v", runID, rec)
-		}
-		// Check if cancelled externally
-		if ctx.Err() == context.Canceled && status == "running" {
-			status = "cancelled"
-			log.Printf("crawl cancelled run %s", runID)
-		}
-		// Use background context for final update since runCtx may be cancelled
-		if err := FinishRun(context.Background(), s.runs, runID, "ATMB", stats, status, startedAt); err != nil {
-			log.Printf("finish run %s: %v", runID, err)
-		}
-	}()
+// Providers lists the registered crawl providers.
+func (s *Service) Providers() []ProviderInfo {
+	return s.providers.List()
+}
 
-	// If links look like listing pages (/l/usa or /l/usa/xx), attempt discovery even if seeds are empty.
-	needsDiscovery := false
-	for _, l := range links {
-		if strings.Contains(l, "/l/usa") {
-			needsDiscovery = true
-			break
-		}
+// StartProvider queues a crawl run for the named provider; a worker executes it asynchronously.
+// seeds are optional starting points passed to the provider's Discover.
+func (s *Service) StartProvider(ctx context.Context, name string, seeds []string) (string, error) {
+	provider, err := s.providers.Get(name)
+	if err != nil {
+		return "", err
 	}
-	if needsDiscovery {
-		if discovered, err := DiscoverLinks(ctx, s.fetcher, links); err == nil && len(discovered) > 0 {
-			links = discovered
-			log.Printf("run %s: discovered %d detail links", runID, len(links))
+	if checker, ok := provider.(SeedChecker); ok {
+		if err := checker.CheckSeeds(seeds); err != nil {
+			return "", err
 		}
 	}
+	run := model.CrawlRun{RunID: generateRunID(), Source: provider.Name(), Kind: model.RunKindCrawl}
+	return s.enqueue(ctx, model.Job{Kind: model.JobCrawl, Provider: provider.Name(), Seeds: seeds}, run)
+}
 
-	stats = model.CrawlRunStats{Found: len(links)}
-	status = "success"
+// ErrRunNotResumable is returned when a run is still in progress, already complete, or has no checkpoint.
+var ErrRunNotResumable = errors.New("run cannot be resumed")
 
-	progress := func(curr ScrapeStats) {
-		// Update run in Firestore periodically.
-		if (curr.Updated+curr.Skipped)%25 == 0 || curr.Updated+curr.Skipped == curr.Found {
-			_ = s.runs.UpdateRun(ctx, model.CrawlRun{
-				RunID:  runID,
-				Status: "running",
-				Stats: model.CrawlRunStats{
-					Found:     curr.Found,
-					Validated: curr.Validated,
-					Skipped:   curr.Skipped,
-					Failed:    curr.Failed,
-				},
-			})
-		}
+// ResumeRun continues a paused run where it stands. Any other interrupted crawl run is queued to
+// continue under the same run ID, processing only the listings its checkpoint has not marked done.
+func (s *Service) ResumeRun(ctx context.Context, runID string) error {
+	if job, err := s.jobs.GetJob(ctx, runID); err == nil && job.Status == model.JobRunning && job.PauseRequested {
+		return s.setPaused(ctx, runID, false)
 	}
-
-	scrapeStats, err := ScrapeAndUpsert(ctx, s.fetcher, s.mailboxes, s.validator, links, runID, progress, func(msg string) {
-		log.Printf("run %s: %s", runID, msg)
-	})
+	if s.jobManager.IsRunning(runID) {
+		return fmt.Errorf("%w: %s is still running", ErrRunNotResumable, runID)
+	}
+	run, err := s.runs.GetRun(ctx, runID)
 	if err != nil {
-		status = "failed"
-		log.Printf("scrape error run %s: %v", runID, err)
+		return err
 	}
-	stats.Found = scrapeStats.Found
-	stats.Skipped = scrapeStats.Skipped
-	stats.Validated = scrapeStats.Validated
-	stats.Failed = scrapeStats.Failed
-
-	if err := MarkAndSweep(ctx, s.mailboxes, runID, "ATMB"); err != nil {
-		status = "partial_halt"
-		log.Printf("mark and sweep error run %s: %v", runID, err)
+	switch run.Status {
+	case "success":
+		return fmt.Errorf("%w: %s already finished", ErrRunNotResumable, runID)
+	case "queued":
+		return fmt.Errorf("%w: %s is already queued", ErrRunNotResumable, runID)
+	}
+	cp, err := s.runs.GetCheckpoint(ctx, runID)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrRunNotResumable, err)
+	}
+	provider, err := s.providers.Get(cp.Source)
+	if err != nil {
+		return err
 	}
+	if len(pendingListings(cp)) == 0 {
+		return fmt.Errorf("%w: %s has no unfinished listings", ErrRunNotResumable, runID)
+	}
+	run.Source = cp.Source
+	run.FinishedAt = time.Time{}
+	_, err = s.enqueue(ctx, model.Job{Kind: model.JobCrawl, Provider: provider.Name(), Resume: true}, run)
+	return err
+}
 
-	// If nothing was processed successfully, mark as failed.
-	if stats.Validated == 0 && stats.Skipped == 0 && stats.Found > 0 && stats.Failed >= stats.Found {
-		status = "failed"
+// resumeJob continues run from its checkpoint. Counters carry over from the interrupted attempt;
+// Found covers the listings it already finished.
+func (s *Service) resumeJob(run *model.CrawlRun, provider Provider, cp model.CrawlCheckpoint) runJob {
+	pending := pendingListings(cp)
+	base := run.Stats
+	base.Found = len(cp.Listings) - len(pending)
+	run.Source = cp.Source
+	run.ResumedAt = time.Now().UTC()
+	run.FinishedAt = time.Time{}
+	run.Sweep = nil
+	return func(ctx context.Context, run *model.CrawlRun) (model.CrawlRunStats, string) {
+		log.Printf("run %s: resuming %d of %d %s listings", run.RunID, len(pending), len(cp.Listings), provider.Name())
+		failures := s.newRunFailures(*run)
+		scrapeStats, err := upsertListings(ctx, provider, s.mailboxes, s.history, s.runs, s.validator, s.workerCnt, pending, run.RunID, s.progress(ctx, *run, base, failures), s.logger(run.RunID))
+		failures.finish(scrapeStats.Errors, run)
+		return s.settleProvider(ctx, provider, run, mergeStats(base, scrapeStats), err)
 	}
+}
 
-	all, err := s.mailboxes.FetchAllMap(ctx)
-	if err == nil {
-		var list []model.Mailbox
-		for _, m := range all {
-			list = append(list, m)
-		}
-		sort.Slice(list, func(i, j int) bool { return list[i].Link < list[j].Link })
-		sysStats := AggregateSystemStats(list)
-		if err := s.statsRepo.SaveSystemStats(ctx, sysStats); err != nil {
-			log.Printf("save system stats error run %s: %v", runID, err)
+// ErrRunNotPausable is returned when pausing a run that is not running or already paused.
+var ErrRunNotPausable = errors.New("run cannot be paused")
+
+// PauseRun holds a running run without losing its progress: it stops taking new listings (and
+// spends no validation quota) until ResumeRun. A run on this instance pauses at once; one leased by
+// another instance pauses at its worker's next heartbeat.
+func (s *Service) PauseRun(ctx context.Context, runID string) error {
+	return s.setPaused(ctx, runID, true)
+}
+
+// setPaused records the pause request on the run's job, where whichever worker holds it finds it,
+// and applies it right away when that worker is this instance.
+func (s *Service) setPaused(ctx context.Context, runID string, paused bool) error {
+	job, changed, err := s.jobs.RequestJobPause(ctx, runID, paused)
+	if err != nil {
+		return err
+	}
+	if !changed {
+		switch {
+		case job.Status != model.JobRunning:
+			return fmt.Errorf("%w: %s is not running", ErrRunNotPausable, runID)
+		case paused:
+			return fmt.Errorf("%w: %s is already paused", ErrRunNotPausable, runID)
+		default:
+			return fmt.Errorf("%w: %s is not paused", ErrRunNotResumable, runID)
 		}
-	} else {
-		log.Printf("fetch all mailboxes error run %s: %v", runID, err)
 	}
+	s.applyPause(ctx, runID, paused)
+	return nil
 }
 
-func generateRunID() string {
-	return fmt.Sprintf("RUN_%d", time.Now().Unix())
+// applyPause pauses or resumes a run executing on this instance and saves its status.
+func (s *Service) applyPause(ctx context.Context, runID string, paused bool) {
+	var changed bool
+	if paused {
+		changed = s.jobManager.Pause(runID)
+	} else {
+		changed = s.jobManager.Resume(runID)
+	}
+	if !changed {
+		return
+	}
+	run, err := s.runs.GetRun(ctx, runID)
+	if err != nil {
+		log.Printf("run %s: %v", runID, err)
+		return
+	}
+	run.Status = s.liveStatus(runID)
+	if !paused {
+		// The stale check counts from here, so the time spent paused is not held against the run.
+		run.ResumedAt = time.Now().UTC()
+	}
+	log.Printf("run %s: %s", runID, run.Status)
+	s.publishStatus(runID, run.Status, run.Stats)
+	if err := s.runs.UpdateRun(ctx, run); err != nil {
+		log.Printf("run %s: %v", runID, err)
+	}
 }
 
-// Reprocess re-parses mailboxes from stored RawHTML without re-fetching.
-// Returns immediately with a runID; actual reprocessing happens asynchronously.
-func (s *Service) Reprocess(ctx context.Context, opts ReprocessOptions) (string, error) {
-	runID := generateRunID()
-	startTime := time.Now().UTC()
-
-	if err := StartRun(ctx, s.runs, runID, "ATMB", startTime); err != nil {
-		return "", err
+// liveStatus is the status of a run in progress: paused while its job is held, running otherwise.
+func (s *Service) liveStatus(runID string) string {
+	if s.jobManager.IsPaused(runID) {
+		return "paused"
 	}
+	return "running"
+}
 
-	// Run reprocessing asynchronously
-	runCtx, timeoutCancel := context.WithTimeout(context.Background(), 30*time.Minute)
-	runCtx, cancel := context.WithCancel(runCtx)
+// runTimeout guards long-running crawls against getting stuck. Time spent paused does not count.
+const runTimeout = 30 * time.Minute
 
-	// Register for external cancellation
-	s.jobManager.Register(runID, cancel)
+// enforceTimeout stops the run with context.DeadlineExceeded once it has worked for runTimeout.
+func (s *Service) enforceTimeout(ctx context.Context, runID string, started time.Time, stop context.CancelCauseFunc) {
+	for {
+		left := runTimeout - (time.Since(started) - s.jobManager.PausedFor(runID))
+		if left <= 0 {
+			stop(context.DeadlineExceeded)
+			return
+		}
+		timer := time.NewTimer(left)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
 
+// runJob performs the work of one run and returns its final stats and status.
+// It may annotate run (e.g., with a sweep report); the annotations are saved with the final status.
+type runJob func(ctx context.Context, run *model.CrawlRun) (model.CrawlRunStats, string)
+
+// execute is the single run lifecycle shared by every crawl, resume and reprocess: it executes job
+// under a timeout, pausing and cancellation (of ctx or through the JobManager), always finalizes the run
+// document, refreshes system stats afterwards, and returns the final status. A paused run starts held,
+// as when its pause was requested before this worker took the job over.
+func (s *Service) execute(ctx context.Context, run model.CrawlRun, paused bool, job runJob) (status string) {
+	runID := run.RunID
+	runCtx, stop := context.WithCancelCause(ctx)
+	cancel := func() { stop(context.Canceled) }
+
+	// Keep the source's lease alive while the run works; it is released after the run is finalized,
+	// or handed over as soon as the job's lease is lost.
+	lockCtx, stopLock := context.WithCancelCause(context.Background())
+	lockDone := make(chan struct{})
+	go func(run model.CrawlRun) {
+		defer close(lockDone)
+		s.holdRunLock(lockCtx, run, cancel)
+	}(run)
+	leaseCtx := runCtx // runCtx is rewrapped below; the watcher keeps its own copy.
 	go func() {
-		defer s.jobManager.Unregister(runID)
-		defer cancel()
-		defer timeoutCancel()
-		s.executeReprocess(runCtx, runID, opts, startTime)
+		<-leaseCtx.Done()
+		if errors.Is(context.Cause(leaseCtx), errLeaseLost) {
+			stopLock(errLeaseLost)
+		}
+	}()
+	defer func() {
+		stopLock(nil)
+		<-lockDone
 	}()
 
-	return runID, nil
-}
+	// Register for external cancellation and pausing
+	s.jobManager.Register(runID, cancel)
+	defer s.jobManager.Unregister(runID)
+	if paused {
+		s.applyPause(ctx, runID, true)
+	}
+	defer cancel()
+	runCtx = withPause(runCtx, s.jobManager, runID)
+	go s.enforceTimeout(runCtx, runID, time.Now(), stop)
 
-func (s *Service) executeReprocess(ctx context.Context, runID string, opts ReprocessOptions, startedAt time.Time) {
-	status := "running"
-	stats := model.CrawlRunStats{}
+	status = "running"
+	stats := run.Stats
 
-	// Always finalize the run document
+	// Always finalize the run document, even on panic.
 	defer func() {
 		if rec := recover(); rec != nil {
 			status = "failed"
-			log.Printf("reprocess panic run %s: %v", runID, rec)
+			log.Printf("%s run %s panic: %v", run.Source, runID, rec)
+		}
+		// Another worker owns the job now and carries on with the same run document.
+		if errors.Is(context.Cause(runCtx), errLeaseLost) {
+			log.Printf("%s run %s: job lease lost, leaving the run to its new worker", run.Source, runID)
+			return
 		}
 		// Check if cancelled externally
-		if ctx.Err() == context.Canceled && status == "running" {
+		if context.Cause(runCtx) == context.Canceled {
 			status = "cancelled"
-			log.Printf("reprocess cancelled run %s", runID)
+			log.Printf("%s run %s cancelled", run.Source, runID)
 		}
 		// Use background context for final update since runCtx may be cancelled
-		if err := FinishRun(context.Background(), s.runs, runID, "ATMB", stats, status, startedAt); err != nil {
+		if err := FinishRun(context.Background(), s.runs, run, stats, status); err != nil {
 			log.Printf("finish run %s: %v", runID, err)
 		}
+		s.publishStatus(runID, status, stats)
 	}()
 
-	progress := func(curr ReprocessStats) {
-		// Update run status periodically
-		if curr.Processed%25 == 0 || curr.Processed+curr.Skipped >= curr.Total {
-			_ = s.runs.UpdateRun(ctx, model.CrawlRun{
-				RunID:  runID,
-				Status: "running",
-				Stats: model.CrawlRunStats{
-					Found:     curr.Total,
-					Validated: curr.Processed,
-					Skipped:   curr.Skipped,
-					Failed:    curr.Failed,
-				},
-			})
-		}
-	}
-
-	reprocessStats, err := ReprocessFromDB(ctx, s.mailboxes, s.validator, opts, func(msg string) {
-		log.Printf("run %s: %s", runID, msg)
-	}, progress)
-
-	if err != nil {
-		status = "failed"
-		log.Printf("reprocess error run %s: %v", runID, err)
-	} else {
-		status = "success"
+	stats, status = job(runCtx, &run)
+	if !errors.Is(context.Cause(runCtx), errLeaseLost) {
+		s.refreshSystemStats(runCtx, runID)
 	}
+	return status
+}
 
-	stats.Found = reprocessStats.Total
-	stats.Validated = reprocessStats.Processed
-	stats.Skipped = reprocessStats.Skipped
-	stats.Failed = reprocessStats.Failed
+// progress returns a callback that streams the run's counters (added onto base) and new failures
+// to subscribers, and periodically saves the counters, error sample and failures.
+func (s *Service) progress(ctx context.Context, run model.CrawlRun, base model.CrawlRunStats, failures *runFailures) func(ScrapeStats) {
+	return func(curr ScrapeStats) {
+		stats := mergeStats(base, curr)
+		status := s.liveStatus(run.RunID)
+		failures.observe(ctx, curr.Errors)
+		s.events.Publish(model.RunEvent{Type: model.RunEventStats, RunID: run.RunID, Status: status, Stats: &stats})
 
-	// Update system stats after reprocessing
-	all, err := s.mailboxes.FetchAllMap(ctx)
-	if err == nil {
-		var list []model.Mailbox
-		for _, m := range all {
-			list = append(list, m)
-		}
-		sort.Slice(list, func(i, j int) bool { return list[i].Link < list[j].Link })
-		sysStats := AggregateSystemStats(list)
-		if err := s.statsRepo.SaveSystemStats(ctx, sysStats); err != nil {
-			log.Printf("save system stats error run %s: %v", runID, err)
+		// Update run in Firestore periodically.
+		if (curr.Updated+curr.Skipped)%25 == 0 || curr.Updated+curr.Skipped == curr.Found {
+			failures.flush(ctx)
+			run.Status = status
+			run.Stats = stats
+			run.ErrorSample = failures.sample
+			_ = s.runs.UpdateRun(ctx, run)
 		}
 	}
 }
 
-// StartIPost1Crawl kicks off an iPost1 crawl run asynchronously.
-func (s *Service) StartIPost1Crawl(ctx context.Context) (string, error) {
-	startTime := time.Now().UTC()
-	runID := generateRunID()
-	if err := StartRun(ctx, s.runs, runID, "iPost1", startTime); err != nil {
-		return "", err
+// logger returns the pipelines' logFn for a run: each line is logged and streamed to subscribers.
+func (s *Service) logger(runID string) func(string) {
+	return func(msg string) {
+		log.Printf("run %s: %s", runID, msg)
+		s.events.Publish(model.RunEvent{Type: model.RunEventLog, RunID: runID, Message: msg})
 	}
-
-	// Guard long-running crawls
-	runCtx, timeoutCancel := context.WithTimeout(context.Background(), 30*time.Minute)
-	runCtx, cancel := context.WithCancel(runCtx)
-
-	// Register for external cancellation
-	s.jobManager.Register(runID, cancel)
-
-	go func() {
-		defer s.jobManager.Unregister(runID)
-		defer cancel()
-		defer timeoutCancel()
-		s.executeIPost1(runCtx, runID, startTime)
-	}()
-	return runID, nil
 }
 
-func (s *Service) executeIPost1(ctx context.Context, runID string, startedAt time.Time) {
-	status := "running"
-	stats := model.CrawlRunStats{}
-
-	// Always finalize the run document
-	defer func() {
-		if rec := recover(); rec != nil {
-			status = "failed"
-			log.Printf("ipost1 crawl panic run %s: %v", runID, rec)
-		}
-		// Check if cancelled externally
-		if ctx.Err() == context.Canceled && status == "running" {
-			status = "cancelled"
-			log.Printf("ipost1 crawl cancelled run %s", runID)
-		}
-		// Use background context for final update since runCtx may be cancelled
-		if err := FinishRun(context.Background(), s.runs, runID, "iPost1", stats, status, startedAt); err != nil {
-			log.Printf("finish run %s: %v", runID, err)
-		}
-	}()
+func (s *Service) executeProvider(ctx context.Context, provider Provider, run *model.CrawlRun, seeds []string) (model.CrawlRunStats, string) {
+	failures := s.newRunFailures(*run)
+	scrapeStats, err := CrawlProvider(ctx, provider, s.mailboxes, s.history, s.runs, s.validator, s.workerCnt, seeds, run.RunID, s.progress(ctx, *run, model.CrawlRunStats{}, failures), s.logger(run.RunID))
+	failures.finish(scrapeStats.Errors, run)
+	return s.settleProvider(ctx, provider, run, mergeStats(model.CrawlRunStats{}, scrapeStats), err)
+}
 
-	// Import iPost1 package - note: this creates a dependency
-	// Using a separate adapter to avoid direct import
-	ipostStats, err := s.executeIPost1Discovery(ctx, runID)
+// settleProvider turns a finished crawl attempt into the run's final status and sweeps the
+// provider's mailboxes that its discovery no longer lists, as far as the sweep policy allows.
+func (s *Service) settleProvider(ctx context.Context, provider Provider, run *model.CrawlRun, stats model.CrawlRunStats, err error) (model.CrawlRunStats, string) {
+	runID := run.RunID
+	status := "success"
 	if err != nil {
 		status = "failed"
-		log.Printf("ipost1 discovery error run %s: %v", runID, err)
-	} else {
-		status = "success"
+		log.Printf("%s crawl error run %s: %v", provider.Name(), runID, err)
 	}
 
-	// Map iPost1 stats to CrawlRunStats
-	stats.Found = ipostStats.Found
-	stats.Validated = ipostStats.Validated
-	stats.Skipped = ipostStats.Skipped
-	stats.Failed = ipostStats.Failed
-
-	// Mark and sweep for iPost1 source only
-	if err := MarkAndSweep(ctx, s.mailboxes, runID, "iPost1"); err != nil {
-		status = "partial_halt"
-		log.Printf("mark and sweep error run %s: %v", runID, err)
+	// Sweep only this provider's source so crawlers do not interfere with each other.
+	// Without discovered listings there is nothing to compare against, so nothing is swept; a
+	// discovery that failed everywhere still records a held sweep naming the failed segments.
+	var partial partialDiscovery
+	if stats.Found > 0 || errors.As(err, &partial) {
+		report, err := s.sweep(ctx, provider.Name(), runID)
+		run.Sweep = report
+		if err != nil {
+			status = "partial_halt"
+			log.Printf("mark and sweep error run %s: %v", runID, err)
+		} else if report.Held {
+			log.Printf("sweep held for run %s: %s (%d mailboxes quarantined)", runID, report.Reason, len(report.Quarantined))
+		}
 	}
 
-	// If nothing was processed successfully, mark as failed
+	// If nothing was processed successfully, mark as failed.
 	if stats.Validated == 0 && stats.Skipped == 0 && stats.Found > 0 && stats.Failed >= stats.Found {
 		status = "failed"
 	}
+	return stats, status
+}
+
+// mergeStats adds a crawl attempt's counters onto base.
+func mergeStats(base model.CrawlRunStats, curr ScrapeStats) model.CrawlRunStats {
+	return model.CrawlRunStats{
+		Found:      base.Found + curr.Found,
+		Validated:  base.Validated + curr.Validated,
+		Skipped:    base.Skipped + curr.Skipped,
+		Failed:     base.Failed + curr.Failed,
+		Validation: addOutcomes(base.Validation, curr.Validation),
+	}
+}
 
-	// Update system stats
+// RefreshStats recomputes the system stats from every stored mailbox and saves them.
+func (s *Service) RefreshStats(ctx context.Context) (model.SystemStats, error) {
 	all, err := s.mailboxes.FetchAllMap(ctx)
-	if err == nil {
-		var list []model.Mailbox
-		for _, m := range all {
-			list = append(list, m)
-		}
-		sort.Slice(list, func(i, j int) bool { return list[i].Link < list[j].Link })
-		sysStats := AggregateSystemStats(list)
-		if err := s.statsRepo.SaveSystemStats(ctx, sysStats); err != nil {
-			log.Printf("save system stats error run %s: %v", runID, err)
-		}
+	if err != nil {
+		return model.SystemStats{}, fmt.Errorf("fetch mailboxes: %w", err)
 	}
+	list := make([]model.Mailbox, 0, len(all))
+	for _, m := range all {
+		list = append(list, m)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Link < list[j].Link })
+	sysStats := AggregateSystemStats(list)
+	if err := s.statsRepo.SaveSystemStats(ctx, sysStats); err != nil {
+		return model.SystemStats{}, fmt.Errorf("save stats: %w", err)
+	}
+	return sysStats, nil
 }
 
-// IPost1Stats represents statistics from iPost1 crawl (to avoid circular import).
-type IPost1Stats struct {
-	Found     int
-	Validated int
-	Skipped   int
-	Failed    int
+func (s *Service) refreshSystemStats(ctx context.Context, runID string) {
+	if _, err := s.RefreshStats(ctx); err != nil {
+		log.Printf("refresh system stats run %s: %v", runID, err)
+	}
 }
 
-func (s *Service) executeIPost1Discovery(ctx context.Context, runID string) (IPost1Stats, error) {
-	// Import ipost1 discovery dynamically to avoid tight coupling
-	// Note: You could also inject this as a dependency in NewService
-	stats, err := s.runIPost1ProcessAndValidate(ctx, runID)
-	if err != nil {
-		return IPost1Stats{}, err
+var (
+	runIDMu   sync.Mutex
+	lastRunID time.Time
+)
+
+// generateRunID returns a unique run ID that sorts by start time, since ListRuns orders by runId and
+// the sweep baseline relies on that. Within a process the microsecond timestamp strictly increases;
+// the random suffix keeps runs started at the same instant on different instances apart. IDs still
+// sort after the older "RUN_<unix>" form of the same second.
+func generateRunID() string {
+	runIDMu.Lock()
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	if !now.After(lastRunID) {
+		now = lastRunID.Add(time.Microsecond)
+	}
+	lastRunID = now
+	runIDMu.Unlock()
+
+	var suffix [4]byte
+	_, _ = rand.Read(suffix[:])
+	return fmt.Sprintf("RUN_%d_%06d_%s", now.Unix(), now.Nanosecond()/1000, hex.EncodeToString(suffix[:]))
+}
+
+// Reprocess queues a run that re-parses mailboxes from stored RawHTML without re-fetching
+// (and re-validates every address when opts.ForceRevalidate is set).
+// Returns immediately with a runID; a worker executes it asynchronously.
+func (s *Service) Reprocess(ctx context.Context, opts ReprocessOptions) (string, error) {
+	job := model.Job{Kind: model.JobReprocess, TargetVersion: opts.TargetVersion, OnlyOutdated: opts.OnlyOutdated}
+	if opts.ForceRevalidate {
+		job.Kind = model.JobRevalidate
 	}
+	run := model.CrawlRun{RunID: generateRunID(), Source: SourceATMB, Kind: model.RunKindReprocess}
+	return s.enqueue(ctx, job, run)
+}
 
-	return IPost1Stats{
-		Found:     stats.Found,
-		Validated: stats.Validated,
-		Skipped:   stats.Skipped,
-		Failed:    stats.Failed,
-	}, nil
+// Revalidate queues a reprocess run that re-validates every address with Smarty.
+func (s *Service) Revalidate(ctx context.Context) (string, error) {
+	return s.Reprocess(ctx, ReprocessOptions{ForceRevalidate: true})
 }
 
-// runIPost1ProcessAndValidate executes the iPost1 discovery and validation process.
-// This method acts as an adapter between the Service and the ipost1 package.
-func (s *Service) runIPost1ProcessAndValidate(ctx context.Context, runID string) (struct {
-	Found     int
-	Validated int
-	Skipped   int
-	Failed    int
-}, error) {
-	// Call iPost1 ProcessAndValidate with proper adapters
-	stats, err := ipost1.ProcessAndValidate(
-		ctx,
-		s.validator, // Already implements the ValidationClient interface
-		s.mailboxes, // Already implements the MailboxStore interface
-		runID,
-		func(msg string) {
-			log.Printf("run %s: %s", runID, msg)
-		},
-	)
+func (s *Service) executeReprocess(ctx context.Context, run *model.CrawlRun, opts ReprocessOptions) (model.CrawlRunStats, string) {
+	runID := run.RunID
+	failures := s.newRunFailures(*run)
+	progress := func(curr ReprocessStats) {
+		stats := model.CrawlRunStats{
+			Found:      curr.Total,
+			Validated:  curr.Processed,
+			Skipped:    curr.Skipped,
+			Failed:     curr.Failed,
+			Validation: curr.Validation,
+		}
+		status := s.liveStatus(runID)
+		failures.observe(ctx, curr.Errors)
+		s.events.Publish(model.RunEvent{Type: model.RunEventStats, RunID: runID, Status: status, Stats: &stats})
 
+		// Update run status periodically
+		if curr.Processed%25 == 0 || curr.Processed+curr.Skipped >= curr.Total {
+			failures.flush(ctx)
+			update := *run
+			update.Status = status
+			update.Stats = stats
+			update.ErrorSample = failures.sample
+			_ = s.runs.UpdateRun(ctx, update)
+		}
+	}
+
+	opts.RunID = runID
+	opts.Parsers = s.providers.HTMLParsers()
+	reprocessStats, err := ReprocessFromDB(ctx, s.mailboxes, s.history, s.validator, opts, s.logger(runID), progress)
+	failures.finish(reprocessStats.Errors, run)
+
+	status := "success"
 	if err != nil {
-		return struct {
-			Found     int
-			Validated int
-			Skipped   int
-			Failed    int
-		}{}, err
-	}
-
-	return struct {
-		Found     int
-		Validated int
-		Skipped   int
-		Failed    int
-	}{
-		Found:     stats.Found,
-		Validated: stats.Validated,
-		Skipped:   stats.Skipped,
-		Failed:    stats.Failed,
-	}, nil
+		status = "failed"
+		log.Printf("reprocess error run %s: %v", runID, err)
+	}
+
+	return model.CrawlRunStats{
+		Found:      reprocessStats.Total,
+		Validated:  reprocessStats.Processed,
+		Skipped:    reprocessStats.Skipped,
+		Failed:     reprocessStats.Failed,
+		Validation: reprocessStats.Validation,
+	}, status
 }
 
-// CancelJob cancels a running job by its ID.
+// CancelJob cancels a job running on this instance by its ID.
 // Returns true if the job was found and cancelled, false if not running.
 func (s *Service) CancelJob(runID string) bool {
 	return s.jobManager.Cancel(runID)
 }
+
+// CancelRun cancels a queued or running run wherever it executes: a run on this instance stops at
+// once, a queued job is dropped, and a job leased by another instance is flagged in the queue for
+// its worker, which stops it at its next heartbeat. It reports whether the run was running here.
+func (s *Service) CancelRun(ctx context.Context, runID string) (bool, error) {
+	wasRunning := s.CancelJob(runID)
+	if _, err := s.jobs.RequestJobCancel(ctx, runID); err != nil {
+		// Runs recorded before the queue existed have no job.
+		log.Printf("run %s: %v", runID, err)
+	}
+	return wasRunning, s.runs.CancelRun(ctx, runID)
+}
@@ -0,0 +1,84 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/model"
+)
+
+// SweepPolicy decides when a crawl may deactivate the mailboxes it did not see. A held sweep
+// deactivates nothing and records the would-be deactivations as quarantined on the run instead.
+// Interrupted runs and runs whose discovery failed for some segments are always held.
+type SweepPolicy struct {
+	// MinCoverage is the smallest fraction of the previous successful crawl's listings a run must
+	// discover before it may sweep; 0 disables the check.
+	MinCoverage float64
+}
+
+// DefaultSweepPolicy holds the sweep when a crawl finds less than 80% of the previous one.
+func DefaultSweepPolicy() SweepPolicy {
+	return SweepPolicy{MinCoverage: 0.8}
+}
+
+// baselineRuns is how many recent runs are searched for the previous successful crawl of a source.
+const baselineRuns = 100
+
+// sweep applies the policy to a finished provider run and deactivates what it allows.
+func (s *Service) sweep(ctx context.Context, source, runID string) (*model.SweepReport, error) {
+	cp, err := s.runs.GetCheckpoint(ctx, runID)
+	if err != nil {
+		return nil, fmt.Errorf("load checkpoint: %w", err)
+	}
+	discovered := discoveredLinks(cp)
+	report := &model.SweepReport{}
+	if baseline, ok := s.baselineRun(ctx, source, runID); ok {
+		report.BaselineRun = baseline.RunID
+		report.Coverage = float64(len(cp.Listings)) / float64(baseline.Stats.Found)
+	}
+
+	switch {
+	case ctx.Err() != nil:
+		report.Reason = fmt.Sprintf("run interrupted: %v", ctx.Err())
+	case len(cp.Failures) > 0:
+		report.Reason = fmt.Sprintf("discovery failed for %s", strings.Join(cp.Failures, ", "))
+	case s.sweepPolicy.MinCoverage > 0 && report.BaselineRun != "" && report.Coverage < s.sweepPolicy.MinCoverage:
+		report.Reason = fmt.Sprintf("discovered %d listings, %.0f%% of run %s (minimum %.0f%%)",
+			len(cp.Listings), report.Coverage*100, report.BaselineRun, s.sweepPolicy.MinCoverage*100)
+	}
+	if report.Reason == "" {
+		report.Swept, err = MarkAndSweep(ctx, s.mailboxes, s.history, runID, source, discovered)
+		return report, err
+	}
+
+	// Record what the sweep would have done; the run's own context may already be cancelled.
+	report.Held = true
+	all, err := s.mailboxes.FetchAllMetadata(context.WithoutCancel(ctx))
+	if err != nil {
+		return report, fmt.Errorf("list quarantined mailboxes: %w", err)
+	}
+	report.Quarantined = sweptLinks(sweepCandidates(all, runID, source, discovered))
+	return report, nil
+}
+
+// baselineRun finds the most recent successful crawl of source other than runID. A run whose sweep
+// was held still counts: when a provider really shrinks, the next crawl that finds the same smaller
+// inventory is covered by it and may sweep, instead of every later run being held against the old size.
+// Runs recorded before kinds existed were crawls, so they count as well.
+func (s *Service) baselineRun(ctx context.Context, source, runID string) (model.CrawlRun, bool) {
+	runs, err := s.runs.ListRuns(context.WithoutCancel(ctx), baselineRuns)
+	if err != nil {
+		return model.CrawlRun{}, false
+	}
+	for _, run := range runs {
+		if run.RunID == runID || run.Source != source || (run.Kind != "" && run.Kind != model.RunKindCrawl) || run.Status != "success" {
+			continue
+		}
+		if run.Stats.Found == 0 {
+			continue
+		}
+		return run, true
+	}
+	return model.CrawlRun{}, false
+}
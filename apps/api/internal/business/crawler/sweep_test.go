@@ -0,0 +1,26 @@
+package crawler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/model"
+)
+
+func TestBaselineRunCountsLegacyCrawls(t *testing.T) {
+	svc, runs := newWorkerTestService()
+	ctx := context.Background()
+	for _, run := range []model.CrawlRun{
+		{RunID: "RUN_1", Source: SourceATMB, Status: "success", Stats: model.CrawlRunStats{Found: 10}}, // Recorded before kinds existed
+		{RunID: "RUN_2", Source: SourceATMB, Kind: model.RunKindReprocess, Status: "success", Stats: model.CrawlRunStats{Found: 3}},
+	} {
+		if err := runs.CreateRun(ctx, run); err != nil {
+			t.Fatalf("CreateRun: %v", err)
+		}
+	}
+
+	baseline, ok := svc.baselineRun(ctx, SourceATMB, "RUN_3")
+	if !ok || baseline.RunID != "RUN_1" {
+		t.Errorf("baseline = %q (%v), want the legacy crawl RUN_1", baseline.RunID, ok)
+	}
+}
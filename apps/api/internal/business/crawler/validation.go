@@ -2,14 +2,100 @@ package crawler
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/model"
 )
 
-// ValidationClient abstracts Smarty validation for testability.
+// ValidationClient abstracts address validation (Smarty, USPS or a consensus of several) for testability.
 type ValidationClient interface {
 	ValidateMailbox(ctx context.Context, mailbox model.Mailbox) (model.Mailbox, error)
 	// ValidateMailboxBatch validates multiple mailboxes in a single batch request.
 	// This is significantly more efficient than individual calls (up to 100 addresses per request).
 	ValidateMailboxBatch(ctx context.Context, mailboxes []model.Mailbox) ([]model.Mailbox, error)
 }
+
+// mergeValidated writes a batch's results back into mailboxes at indices, counting each outcome and
+// adding a validate failure for every mailbox that got no usable verdict. err is the batch's error;
+// mailboxes the validator did not mark are recorded as failed with it. It returns how many mailboxes
+// got a verdict and how many did not.
+func mergeValidated(
+	mailboxes []model.Mailbox,
+	indices []int,
+	validated []model.Mailbox,
+	err error,
+	elapsed time.Duration,
+	outcomes *model.ValidationOutcomes,
+	failures *[]model.LinkEvent,
+) (ok, failed int) {
+	if err == nil && len(validated) != len(indices) {
+		err = fmt.Errorf("validator returned %d results for %d mailboxes", len(validated), len(indices))
+	}
+	for i, idx := range indices {
+		mb := mailboxes[idx]
+		if len(validated) == len(indices) {
+			mb = validated[i]
+		}
+		settleValidation(&mb, err)
+		mailboxes[idx] = mb
+		countOutcome(outcomes, mb.ValidationStatus)
+
+		switch mb.ValidationStatus {
+		case model.ValidationVerified, model.ValidationAmbiguous:
+			ok++
+		default:
+			failed++
+			cause := err
+			if cause == nil || mb.ValidationStatus == model.ValidationNoMatch {
+				cause = fmt.Errorf("%w (%s: %s)", errNotValidated, mb.ValidationStatus, mb.ValidationReason)
+			}
+			*failures = append(*failures, linkFailure(mb.Link, model.StageValidate, cause, elapsed))
+		}
+	}
+	return ok, failed
+}
+
+// settleValidation fills in the status of a mailbox the validator left unmarked: failed when the
+// batch failed, otherwise verified if it carries a CMRA verdict.
+func settleValidation(mb *model.Mailbox, err error) {
+	if mb.ValidationStatus != "" && mb.ValidationStatus != model.ValidationPending {
+		return
+	}
+	switch {
+	case err != nil:
+		mb.ValidationStatus = model.ValidationError
+		mb.ValidationReason = err.Error()
+	case mb.CMRA != "":
+		mb.ValidationStatus = model.ValidationVerified
+		mb.ValidationReason = ""
+	default:
+		mb.ValidationStatus = model.ValidationNoMatch
+		mb.ValidationReason = errNotValidated.Error()
+	}
+}
+
+func countOutcome(o *model.ValidationOutcomes, status string) {
+	switch status {
+	case model.ValidationVerified:
+		o.Verified++
+	case model.ValidationNoMatch:
+		o.NoMatch++
+	case model.ValidationAmbiguous:
+		o.Ambiguous++
+	case model.ValidationError:
+		o.Error++
+	case model.ValidationQuotaExhausted:
+		o.QuotaExhausted++
+	}
+}
+
+func addOutcomes(a, b model.ValidationOutcomes) model.ValidationOutcomes {
+	return model.ValidationOutcomes{
+		Verified:       a.Verified + b.Verified,
+		NoMatch:        a.NoMatch + b.NoMatch,
+		Ambiguous:      a.Ambiguous + b.Ambiguous,
+		Error:          a.Error + b.Error,
+		QuotaExhausted: a.QuotaExhausted + b.QuotaExhausted,
+	}
+}
@@ -0,0 +1,300 @@
+package crawler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/model"
+)
+
+const (
+	// jobLeaseTTL is how long a claimed job stays leased without a heartbeat. Workers renew it every
+	// third of that; a job whose worker died is queued again once its lease has lapsed.
+	jobLeaseTTL = 2 * time.Minute
+	// jobPollInterval is how often an idle worker looks for jobs queued by other instances.
+	jobPollInterval = 5 * time.Second
+	// maxJobAttempts is how many leases a job may lose before it is failed instead of requeued.
+	maxJobAttempts = 3
+	// jobSlots is how many jobs one worker executes at a time. Jobs of the same source or kind
+	// never run together: the queue admits only one active job per lock key.
+	jobSlots = 3
+)
+
+// errLeaseLost is the cancellation cause of a job whose lease lapsed. The job may already be running
+// on another worker, so the run it was executing must be left for that worker to finish.
+var errLeaseLost = errors.New("job lease lost")
+
+// enqueue records run as queued and queues job to execute it. The run is written first, so a worker
+// that claims the job at once always finds it. It fails with *RunConflictError while another queued
+// or running job needs the same lease, leaving the run as it was.
+func (s *Service) enqueue(ctx context.Context, job model.Job, run model.CrawlRun) (string, error) {
+	job.ID = run.RunID
+	job.LockKey = runLockKey(run)
+	job.EnqueuedAt = time.Now().UTC()
+
+	previous := run
+	queued := run
+	queued.Status = "queued"
+	var err error
+	if job.Resume {
+		previous, err = s.runs.GetRun(ctx, run.RunID)
+		if err == nil {
+			err = s.runs.UpdateRun(ctx, queued)
+		}
+	} else {
+		err = s.runs.CreateRun(ctx, queued)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	holder, ok, err := s.jobs.EnqueueJob(ctx, job)
+	if err == nil && !ok {
+		err = &RunConflictError{Key: job.LockKey, RunID: holder.ID}
+	}
+	if err != nil {
+		var undoErr error
+		if job.Resume {
+			undoErr = s.runs.UpdateRun(ctx, previous)
+		} else {
+			undoErr = s.runs.DeleteRun(ctx, run.RunID)
+		}
+		if undoErr != nil {
+			log.Printf("run %s: %v", run.RunID, undoErr)
+		}
+		return "", err
+	}
+	s.wakeWorker()
+	return run.RunID, nil
+}
+
+func (s *Service) wakeWorker() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// RunWorker claims queued jobs and executes them until ctx is done. Every server instance runs
+// one; they share the queue through the store. Jobs still running when ctx ends are left to
+// finish; if the process exits first, their leases lapse and another worker picks them up.
+func (s *Service) RunWorker(ctx context.Context) {
+	owner := workerID()
+	slots := make(chan struct{}, jobSlots)
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.wake:
+		case <-timer.C:
+		}
+		s.requeueExpiredJobs(ctx)
+		s.claimJobs(ctx, owner, slots)
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(jobPollInterval)
+	}
+}
+
+// claimJobs claims queued jobs while slots are free, executing each in the background.
+func (s *Service) claimJobs(ctx context.Context, owner string, slots chan struct{}) {
+	for {
+		select {
+		case slots <- struct{}{}:
+		default:
+			return
+		}
+		job, ok, err := s.jobs.ClaimJob(ctx, owner, time.Now().UTC().Add(jobLeaseTTL))
+		if err != nil || !ok {
+			<-slots
+			if err != nil {
+				log.Printf("worker %s: %v", owner, err)
+			}
+			return
+		}
+		go func() {
+			defer func() {
+				<-slots
+				s.wakeWorker()
+			}()
+			s.perform(job, owner)
+		}()
+	}
+}
+
+// requeueExpiredJobs returns jobs whose worker stopped heartbeating to the queue. Runs of jobs
+// that are given up on are finalized here, since no worker will finish them.
+func (s *Service) requeueExpiredJobs(ctx context.Context) {
+	jobs, err := s.jobs.RequeueExpiredJobs(ctx, time.Now().UTC(), maxJobAttempts)
+	if err != nil {
+		log.Printf("requeue jobs: %v", err)
+	}
+	for _, job := range jobs {
+		if job.Status == model.JobQueued {
+			log.Printf("run %s: %s, queued again", job.ID, job.Error)
+			continue
+		}
+		status := "failed"
+		if job.Status == model.JobCancelled {
+			status = "cancelled"
+		}
+		log.Printf("run %s: job %s: %s", job.ID, job.Status, job.Error)
+		run, err := s.runs.GetRun(ctx, job.ID)
+		if err != nil {
+			log.Printf("run %s: %v", job.ID, err)
+			continue
+		}
+		if err := FinishRun(ctx, s.runs, run, run.Stats, status); err != nil {
+			log.Printf("finish run %s: %v", job.ID, err)
+		}
+	}
+}
+
+// perform executes a claimed job under its lease and records the outcome in the queue.
+func (s *Service) perform(job model.Job, owner string) {
+	// The job outlives the worker's context: shutting the worker down must not cancel the run.
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+
+	run, work, err := s.prepare(ctx, job)
+	if err == nil {
+		err = s.acquireRunLock(ctx, run)
+	}
+	if err != nil {
+		log.Printf("run %s: %v", job.ID, err)
+		if finishErr := FinishRun(ctx, s.runs, run, run.Stats, "failed"); finishErr != nil {
+			log.Printf("finish run %s: %v", job.ID, finishErr)
+		}
+		s.publishStatus(run.RunID, "failed", run.Stats)
+		if err := s.jobs.CompleteJob(ctx, job.ID, owner, model.JobFailed, err.Error()); err != nil {
+			log.Printf("run %s: %v", job.ID, err)
+		}
+		return
+	}
+	run.Status = "running"
+	if err := StartRun(ctx, s.runs, run); err != nil {
+		log.Printf("run %s: %v", job.ID, err)
+	}
+	s.publishStatus(run.RunID, run.Status, run.Stats)
+
+	go s.heartbeatJob(ctx, job.ID, owner, cancel)
+	jobStatus := model.JobDone
+	if s.execute(ctx, run, job.PauseRequested, work) == "cancelled" {
+		jobStatus = model.JobCancelled
+	}
+	if errors.Is(context.Cause(ctx), errLeaseLost) {
+		return // The job is no longer ours to complete.
+	}
+	if err := s.jobs.CompleteJob(context.Background(), job.ID, owner, jobStatus, ""); err != nil {
+		log.Printf("run %s: %v", job.ID, err)
+	}
+}
+
+// prepare builds the run a job executes and the work to do. A crawl job resumes from the
+// checkpoint when asked to, or when an earlier attempt that lost its lease left one behind.
+func (s *Service) prepare(ctx context.Context, job model.Job) (model.CrawlRun, runJob, error) {
+	run, err := s.runs.GetRun(ctx, job.ID)
+	if err != nil {
+		run = model.CrawlRun{RunID: job.ID} // The record was lost; the job still knows what to do.
+	}
+	fresh := func() {
+		run.StartedAt = time.Now().UTC()
+		run.Stats = model.CrawlRunStats{}
+	}
+
+	switch job.Kind {
+	case model.JobCrawl:
+		provider, err := s.providers.Get(job.Provider)
+		if err != nil {
+			return run, nil, err
+		}
+		run.Source = provider.Name()
+		run.Kind = model.RunKindCrawl
+		if job.Resume || job.Attempts > 1 {
+			cp, err := s.runs.GetCheckpoint(ctx, job.ID)
+			if err == nil {
+				return run, s.resumeJob(&run, provider, cp), nil
+			}
+			if job.Resume {
+				return run, nil, fmt.Errorf("%w: %v", ErrRunNotResumable, err)
+			}
+		}
+		fresh()
+		seeds := job.Seeds
+		return run, func(ctx context.Context, run *model.CrawlRun) (model.CrawlRunStats, string) {
+			return s.executeProvider(ctx, provider, run, seeds)
+		}, nil
+	case model.JobReprocess, model.JobRevalidate:
+		if run.Source == "" {
+			run.Source = SourceATMB
+		}
+		run.Kind = model.RunKindReprocess
+		fresh()
+		opts := ReprocessOptions{
+			TargetVersion:   job.TargetVersion,
+			OnlyOutdated:    job.OnlyOutdated,
+			ForceRevalidate: job.Kind == model.JobRevalidate,
+		}
+		return run, func(ctx context.Context, run *model.CrawlRun) (model.CrawlRunStats, string) {
+			return s.executeReprocess(ctx, run, opts)
+		}, nil
+	default:
+		return run, nil, fmt.Errorf("unknown job kind %q", job.Kind)
+	}
+}
+
+// heartbeatJob renews the job's lease until ctx is done. It cancels the job when a cancellation
+// was requested through the queue (possibly from another instance) or the lease was lost, and
+// pauses or resumes it as requested through the queue.
+func (s *Service) heartbeatJob(ctx context.Context, id, owner string, cancel context.CancelCauseFunc) {
+	ticker := time.NewTicker(jobLeaseTTL / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			job, held, err := s.jobs.HeartbeatJob(ctx, id, owner, time.Now().UTC().Add(jobLeaseTTL))
+			if err != nil {
+				log.Printf("run %s: %v", id, err)
+				continue
+			}
+			if !held {
+				log.Printf("run %s: lost job lease, stopping", id)
+				cancel(errLeaseLost)
+				return
+			}
+			if job.CancelRequested {
+				log.Printf("run %s: cancel requested", id)
+				cancel(context.Canceled)
+				return
+			}
+			if job.PauseRequested != s.jobManager.IsPaused(id) {
+				s.applyPause(ctx, id, job.PauseRequested)
+			}
+		}
+	}
+}
+
+// workerID identifies this process as a lease owner.
+func workerID() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "worker"
+	}
+	var suffix [4]byte
+	_, _ = rand.Read(suffix[:])
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), hex.EncodeToString(suffix[:]))
+}
@@ -0,0 +1,154 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/internal/repository"
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/model"
+)
+
+func newWorkerTestService() (*Service, *repository.MemoryRunRepository) {
+	runs := repository.NewMemoryRunRepository()
+	svc := NewService(NewRegistry(), nil, repository.NewMemoryMailboxRepository(), runs, repository.NewMemoryStatsRepository(),
+		repository.NewMemoryHistoryRepository(), repository.NewMemoryJobRepository(), 1, DefaultSweepPolicy(), NewJobManager())
+	return svc, runs
+}
+
+func TestExecuteLeavesRunToNewOwnerAfterLeaseLoss(t *testing.T) {
+	svc, runs := newWorkerTestService()
+	ctx := context.Background()
+	run := model.CrawlRun{RunID: "RUN_LEASED", Source: "ATMB", Kind: model.RunKindCrawl, Status: "running", StartedAt: time.Now().UTC()}
+	if err := runs.CreateRun(ctx, run); err != nil {
+		t.Fatalf("CreateRun: %v", err)
+	}
+	if err := svc.acquireRunLock(ctx, run); err != nil {
+		t.Fatalf("acquireRunLock: %v", err)
+	}
+
+	jobCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	svc.execute(jobCtx, run, false, func(ctx context.Context, run *model.CrawlRun) (model.CrawlRunStats, string) {
+		cancel(errLeaseLost) // As heartbeatJob does when the queue gave the job to another worker.
+		<-ctx.Done()
+		return model.CrawlRunStats{}, "success"
+	})
+
+	got, err := runs.GetRun(ctx, run.RunID)
+	if err != nil {
+		t.Fatalf("GetRun: %v", err)
+	}
+	if got.Status != "running" || !got.FinishedAt.IsZero() {
+		t.Errorf("run finalized as %q at %v, want it left running for the new owner", got.Status, got.FinishedAt)
+	}
+	// The lock stays with the run ID: the new owner can take it, another run cannot.
+	if err := svc.acquireRunLock(ctx, run); err != nil {
+		t.Errorf("new owner of the same run could not take the lock: %v", err)
+	}
+	other := model.CrawlRun{RunID: "RUN_OTHER", Source: "ATMB", Kind: model.RunKindCrawl}
+	if err := svc.acquireRunLock(ctx, other); err == nil {
+		t.Errorf("the lock was released to an unrelated run")
+	}
+}
+
+// runCheckingJobs records the status of each job's run at the moment the job is queued.
+type runCheckingJobs struct {
+	*repository.MemoryJobRepository
+	runs   *repository.MemoryRunRepository
+	status map[string]string
+}
+
+func (j *runCheckingJobs) EnqueueJob(ctx context.Context, job model.Job) (model.Job, bool, error) {
+	run, err := j.runs.GetRun(ctx, job.ID)
+	if err != nil {
+		run.Status = "missing"
+	}
+	j.status[job.ID] = run.Status
+	return j.MemoryJobRepository.EnqueueJob(ctx, job)
+}
+
+func TestEnqueueRecordsRunBeforeQueueingJob(t *testing.T) {
+	runs := repository.NewMemoryRunRepository()
+	jobs := &runCheckingJobs{MemoryJobRepository: repository.NewMemoryJobRepository(), runs: runs, status: make(map[string]string)}
+	svc := NewService(NewRegistry(), nil, repository.NewMemoryMailboxRepository(), runs, repository.NewMemoryStatsRepository(),
+		repository.NewMemoryHistoryRepository(), jobs, 1, DefaultSweepPolicy(), NewJobManager())
+	ctx := context.Background()
+
+	first := model.CrawlRun{RunID: "RUN_1", Source: "ATMB", Kind: model.RunKindCrawl}
+	if _, err := svc.enqueue(ctx, model.Job{Kind: model.JobCrawl, Provider: "ATMB"}, first); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	if jobs.status["RUN_1"] != "queued" {
+		t.Errorf("run was %q when its job was queued, want queued", jobs.status["RUN_1"])
+	}
+
+	// A second crawl of the source is refused and leaves no run behind.
+	second := model.CrawlRun{RunID: "RUN_2", Source: "ATMB", Kind: model.RunKindCrawl}
+	var conflict *RunConflictError
+	if _, err := svc.enqueue(ctx, model.Job{Kind: model.JobCrawl, Provider: "ATMB"}, second); !errors.As(err, &conflict) {
+		t.Fatalf("enqueue = %v, want a run conflict", err)
+	}
+	if _, err := runs.GetRun(ctx, "RUN_2"); err == nil {
+		t.Errorf("refused run was kept")
+	}
+
+	// A refused resume puts the interrupted run back as it was.
+	interrupted := model.CrawlRun{RunID: "RUN_0", Source: "ATMB", Kind: model.RunKindCrawl, Status: "failed", FinishedAt: time.Now().UTC()}
+	if err := runs.CreateRun(ctx, interrupted); err != nil {
+		t.Fatalf("CreateRun: %v", err)
+	}
+	resumed := interrupted
+	resumed.FinishedAt = time.Time{}
+	if _, err := svc.enqueue(ctx, model.Job{Kind: model.JobCrawl, Provider: "ATMB", Resume: true}, resumed); !errors.As(err, &conflict) {
+		t.Fatalf("enqueue resume = %v, want a run conflict", err)
+	}
+	if got, _ := runs.GetRun(ctx, "RUN_0"); got.Status != "failed" || got.FinishedAt.IsZero() {
+		t.Errorf("refused resume left the run as %q finished %v", got.Status, got.FinishedAt)
+	}
+}
+
+func TestResumeAfterLongPauseIsNotStale(t *testing.T) {
+	svc, runs := newWorkerTestService()
+	ctx := context.Background()
+	// The run started long ago and has been paused ever since.
+	run := model.CrawlRun{RunID: "RUN_PAUSED", Source: "ATMB", Kind: model.RunKindCrawl, Status: "paused", StartedAt: time.Now().UTC().Add(-2 * repository.StaleRunTimeout)}
+	if err := runs.CreateRun(ctx, run); err != nil {
+		t.Fatalf("CreateRun: %v", err)
+	}
+	svc.jobManager.Register(run.RunID, func() {})
+	defer svc.jobManager.Unregister(run.RunID)
+	svc.jobManager.Pause(run.RunID)
+
+	svc.applyPause(ctx, run.RunID, false)
+
+	listed, err := runs.ListRuns(ctx, 10)
+	if err != nil || len(listed) != 1 {
+		t.Fatalf("ListRuns = %d runs, %v", len(listed), err)
+	}
+	if got := listed[0]; got.Status != "running" || got.ResumedAt.IsZero() {
+		t.Errorf("resumed run = %q resumed at %v, want running and not timed out", got.Status, got.ResumedAt)
+	}
+}
+
+func TestExecuteStartsHeldWhenPauseWasRequested(t *testing.T) {
+	svc, runs := newWorkerTestService()
+	ctx := context.Background()
+	run := model.CrawlRun{RunID: "RUN_RECLAIMED", Source: "ATMB", Kind: model.RunKindCrawl, Status: "running", StartedAt: time.Now().UTC()}
+	if err := runs.CreateRun(ctx, run); err != nil {
+		t.Fatalf("CreateRun: %v", err)
+	}
+
+	var held bool
+	var recorded string
+	svc.execute(ctx, run, true, func(ctx context.Context, run *model.CrawlRun) (model.CrawlRunStats, string) {
+		held = svc.jobManager.IsPaused(run.RunID)
+		got, _ := runs.GetRun(ctx, run.RunID)
+		recorded = got.Status
+		return model.CrawlRunStats{}, "success"
+	})
+	if !held || recorded != "paused" {
+		t.Errorf("run started with paused=%v recorded as %q, want it held and recorded paused", held, recorded)
+	}
+}
@@ -0,0 +1,229 @@
+// Package scheduler fires recurring crawls, reprocess runs and stats refreshes from cron-style
+// schedules kept in the configured store.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/internal/business/crawler"
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/internal/repository"
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/model"
+)
+
+// TickInterval is how often Run looks for due schedules; cron expressions have minute granularity.
+const TickInterval = time.Minute
+
+// overlapLookback is how many recent runs are checked for one still in progress before firing.
+const overlapLookback = 50
+
+var (
+	// ErrInvalidSchedule is returned when a schedule has an unknown action, provider or cron expression.
+	ErrInvalidSchedule = errors.New("invalid schedule")
+	// ErrScheduleNotFound is returned when deleting a schedule that does not exist.
+	ErrScheduleNotFound = errors.New("schedule not found")
+)
+
+// Runner starts the jobs a schedule can fire. *crawler.Service implements it.
+type Runner interface {
+	Providers() []crawler.ProviderInfo
+	StartProvider(ctx context.Context, name string, seeds []string) (string, error)
+	Reprocess(ctx context.Context, opts crawler.ReprocessOptions) (string, error)
+	RefreshStats(ctx context.Context) (model.SystemStats, error)
+}
+
+// Scheduler fires due schedules. Several server instances may share a store: each due firing is
+// claimed in the store first, so only one instance starts it.
+type Scheduler struct {
+	schedules repository.ScheduleStore
+	runs      repository.RunStore
+	runner    Runner
+}
+
+func New(schedules repository.ScheduleStore, runs repository.RunStore, runner Runner) *Scheduler {
+	return &Scheduler{schedules: schedules, runs: runs, runner: runner}
+}
+
+// List returns every schedule.
+func (s *Scheduler) List(ctx context.Context) ([]model.Schedule, error) {
+	return s.schedules.ListSchedules(ctx)
+}
+
+// Create validates and stores a new schedule, due at the next time its cron expression matches.
+func (s *Scheduler) Create(ctx context.Context, sched model.Schedule) (model.Schedule, error) {
+	cron, err := ParseCron(sched.Cron)
+	if err != nil {
+		return model.Schedule{}, fmt.Errorf("%w: %v", ErrInvalidSchedule, err)
+	}
+	switch sched.Action {
+	case model.ScheduleCrawl:
+		name, ok := s.providerName(sched.Provider)
+		if !ok {
+			return model.Schedule{}, fmt.Errorf("%w: unknown provider %q", ErrInvalidSchedule, sched.Provider)
+		}
+		sched.Provider = name
+	case model.ScheduleReprocess, model.ScheduleRefreshStats:
+		sched.Provider = ""
+	default:
+		return model.Schedule{}, fmt.Errorf("%w: unknown action %q (use %q, %q or %q)", ErrInvalidSchedule, sched.Action,
+			model.ScheduleCrawl, model.ScheduleReprocess, model.ScheduleRefreshStats)
+	}
+	if sched.Action != model.ScheduleReprocess {
+		sched.OnlyOutdated = false
+	}
+
+	now := time.Now().UTC()
+	next := cron.Next(now)
+	if next.IsZero() {
+		return model.Schedule{}, fmt.Errorf("%w: cron %q never matches", ErrInvalidSchedule, sched.Cron)
+	}
+	sched.ID = fmt.Sprintf("SCHED_%d", now.UnixNano())
+	sched.CreatedAt = now
+	sched.NextRunAt = next
+	sched.LastRunAt = time.Time{}
+	sched.LastRunID = ""
+	sched.LastError = ""
+	if err := s.schedules.SaveSchedule(ctx, sched); err != nil {
+		return model.Schedule{}, err
+	}
+	return sched, nil
+}
+
+// Delete removes a schedule. A run it already started is not affected.
+func (s *Scheduler) Delete(ctx context.Context, id string) error {
+	if _, err := s.schedules.GetSchedule(ctx, id); err != nil {
+		return fmt.Errorf("%w: %s", ErrScheduleNotFound, id)
+	}
+	return s.schedules.DeleteSchedule(ctx, id)
+}
+
+func (s *Scheduler) providerName(name string) (string, bool) {
+	for _, p := range s.runner.Providers() {
+		if strings.EqualFold(p.Name, name) {
+			return p.Name, true
+		}
+	}
+	return "", false
+}
+
+// Run fires due schedules every TickInterval until ctx is done.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(TickInterval)
+	defer ticker.Stop()
+	for {
+		s.Tick(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Tick fires every schedule due at now.
+func (s *Scheduler) Tick(ctx context.Context, now time.Time) {
+	schedules, err := s.schedules.ListSchedules(ctx)
+	if err != nil {
+		log.Printf("scheduler: list schedules: %v", err)
+		return
+	}
+	now = now.UTC()
+	for _, sched := range schedules {
+		if sched.NextRunAt.IsZero() || sched.NextRunAt.After(now) {
+			continue
+		}
+		s.fire(ctx, sched, now)
+	}
+}
+
+// fire claims a due schedule and starts its job.
+func (s *Scheduler) fire(ctx context.Context, sched model.Schedule, now time.Time) {
+	cron, err := ParseCron(sched.Cron)
+	if err != nil {
+		log.Printf("scheduler: schedule %s: %v", sched.ID, err)
+		return
+	}
+
+	// However many firings were missed while the server was down, fire once and
+	// continue from now, rather than replaying every one of them.
+	due := sched.NextRunAt
+	claimed := sched
+	claimed.NextRunAt = cron.Next(now)
+	claimed.LastRunAt = now
+	claimed.LastRunID = ""
+	claimed.LastError = ""
+	ok, err := s.schedules.ClaimSchedule(ctx, claimed, due)
+	if err != nil {
+		log.Printf("scheduler: %v", err)
+		return
+	}
+	if !ok {
+		return // Another instance fired it.
+	}
+
+	runID, err := s.start(ctx, sched)
+	claimed.LastRunID = runID
+	if err != nil {
+		claimed.LastError = err.Error()
+		log.Printf("scheduler: schedule %s (%s %s): %v", sched.ID, sched.Action, sched.Provider, err)
+	} else {
+		log.Printf("scheduler: schedule %s fired %s %s run %s", sched.ID, sched.Action, sched.Provider, runID)
+	}
+	// Claiming against the new NextRunAt records the outcome without resurrecting a schedule deleted meanwhile.
+	if _, err := s.schedules.ClaimSchedule(ctx, claimed, claimed.NextRunAt); err != nil {
+		log.Printf("scheduler: record schedule %s: %v", sched.ID, err)
+	}
+}
+
+// start launches the schedule's job, refusing to overlap a run of the same kind still in progress.
+func (s *Scheduler) start(ctx context.Context, sched model.Schedule) (string, error) {
+	switch sched.Action {
+	case model.ScheduleCrawl:
+		if runID, err := s.runningRun(ctx, model.RunKindCrawl, sched.Provider); err != nil || runID != "" {
+			return "", overlapError(runID, err)
+		}
+		return s.runner.StartProvider(ctx, sched.Provider, nil)
+	case model.ScheduleReprocess:
+		if runID, err := s.runningRun(ctx, model.RunKindReprocess, ""); err != nil || runID != "" {
+			return "", overlapError(runID, err)
+		}
+		return s.runner.Reprocess(ctx, crawler.ReprocessOptions{OnlyOutdated: sched.OnlyOutdated})
+	case model.ScheduleRefreshStats:
+		_, err := s.runner.RefreshStats(ctx)
+		return "", err
+	default:
+		return "", fmt.Errorf("%w: unknown action %q", ErrInvalidSchedule, sched.Action)
+	}
+}
+
+// runningRun returns the ID of a recent run of kind (and source, if given) that is still queued, running or paused.
+func (s *Scheduler) runningRun(ctx context.Context, kind, source string) (string, error) {
+	runs, err := s.runs.ListRuns(ctx, overlapLookback)
+	if err != nil {
+		return "", err
+	}
+	for _, run := range runs {
+		runKind := run.Kind
+		if runKind == "" {
+			runKind = model.RunKindCrawl // Runs recorded before kinds existed were crawls.
+		}
+		if (run.Status != "running" && run.Status != "queued" && run.Status != "paused") || runKind != kind {
+			continue
+		}
+		if source == "" || strings.EqualFold(run.Source, source) {
+			return run.RunID, nil
+		}
+	}
+	return "", nil
+}
+
+func overlapError(runID string, err error) error {
+	if err != nil {
+		return fmt.Errorf("check running runs: %w", err)
+	}
+	return fmt.Errorf("skipped: run %s is still running", runID)
+}
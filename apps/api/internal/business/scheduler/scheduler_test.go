@@ -0,0 +1,157 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/internal/business/crawler"
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/internal/repository"
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/model"
+)
+
+type fakeRunner struct {
+	started   []string
+	reprocess []crawler.ReprocessOptions
+	refreshes int
+}
+
+func (r *fakeRunner) Providers() []crawler.ProviderInfo {
+	return []crawler.ProviderInfo{{Name: "ATMB"}, {Name: "iPost1"}}
+}
+
+func (r *fakeRunner) StartProvider(ctx context.Context, name string, seeds []string) (string, error) {
+	r.started = append(r.started, name)
+	return fmt.Sprintf("RUN_%d", len(r.started)), nil
+}
+
+func (r *fakeRunner) Reprocess(ctx context.Context, opts crawler.ReprocessOptions) (string, error) {
+	r.reprocess = append(r.reprocess, opts)
+	return "RUN_REPROCESS", nil
+}
+
+func (r *fakeRunner) RefreshStats(ctx context.Context) (model.SystemStats, error) {
+	r.refreshes++
+	return model.SystemStats{}, nil
+}
+
+func TestSchedulerCreateValidates(t *testing.T) {
+	ctx := context.Background()
+	s := New(repository.NewMemoryScheduleRepository(), repository.NewMemoryRunRepository(), &fakeRunner{})
+
+	for _, bad := range []model.Schedule{
+		{Action: model.ScheduleCrawl, Provider: "nope", Cron: "@daily"},
+		{Action: "vacuum", Cron: "@daily"},
+		{Action: model.ScheduleRefreshStats, Cron: "61 * * * *"},
+		{Action: model.ScheduleRefreshStats, Cron: "0 0 30 2 *"},
+	} {
+		if _, err := s.Create(ctx, bad); !errors.Is(err, ErrInvalidSchedule) {
+			t.Errorf("Create(%+v) err = %v, want ErrInvalidSchedule", bad, err)
+		}
+	}
+
+	sched, err := s.Create(ctx, model.Schedule{Action: model.ScheduleCrawl, Provider: "ipost1", Cron: "0 3 * * *"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if sched.ID == "" || sched.Provider != "iPost1" {
+		t.Errorf("schedule = %+v, want an ID and the canonical provider name", sched)
+	}
+	if sched.NextRunAt.Hour() != 3 || sched.NextRunAt.Minute() != 0 || !sched.NextRunAt.After(time.Now()) {
+		t.Errorf("NextRunAt = %v, want the next 03:00 UTC", sched.NextRunAt)
+	}
+
+	if err := s.Delete(ctx, "SCHED_missing"); !errors.Is(err, ErrScheduleNotFound) {
+		t.Errorf("Delete missing err = %v, want ErrScheduleNotFound", err)
+	}
+	if err := s.Delete(ctx, sched.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if list, _ := s.List(ctx); len(list) != 0 {
+		t.Errorf("List after delete = %+v, want empty", list)
+	}
+}
+
+func TestSchedulerTickCoalescesMissedRuns(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewMemoryScheduleRepository()
+	runner := &fakeRunner{}
+	s := New(store, repository.NewMemoryRunRepository(), runner)
+
+	// Hourly schedule last due three days ago: the server was down for 72 firings.
+	due := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+	now := due.Add(72*time.Hour + 20*time.Minute)
+	store.SaveSchedule(ctx, model.Schedule{ID: "SCHED_1", Action: model.ScheduleCrawl, Provider: "ATMB", Cron: "@hourly", NextRunAt: due})
+	store.SaveSchedule(ctx, model.Schedule{ID: "SCHED_2", Action: model.ScheduleReprocess, OnlyOutdated: true, Cron: "0 4 * * *", NextRunAt: now.Add(time.Hour)})
+
+	s.Tick(ctx, now)
+	s.Tick(ctx, now.Add(time.Minute))
+
+	if len(runner.started) != 1 || runner.started[0] != "ATMB" {
+		t.Fatalf("started = %v, want one ATMB crawl", runner.started)
+	}
+	if len(runner.reprocess) != 0 {
+		t.Errorf("reprocess fired before it was due")
+	}
+	got, _ := store.GetSchedule(ctx, "SCHED_1")
+	if want := time.Date(2025, 3, 4, 9, 0, 0, 0, time.UTC); !got.NextRunAt.Equal(want) {
+		t.Errorf("NextRunAt = %v, want %v (continue from now, not replay)", got.NextRunAt, want)
+	}
+	if got.LastRunID != "RUN_1" || !got.LastRunAt.Equal(now) || got.LastError != "" {
+		t.Errorf("schedule = %+v, want the fired run recorded", got)
+	}
+
+	s.Tick(ctx, now.Add(2*time.Hour))
+	if len(runner.reprocess) != 1 || !runner.reprocess[0].OnlyOutdated {
+		t.Errorf("reprocess = %+v, want one onlyOutdated run", runner.reprocess)
+	}
+}
+
+func TestSchedulerSkipsOverlappingRun(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewMemoryScheduleRepository()
+	runs := repository.NewMemoryRunRepository()
+	runner := &fakeRunner{}
+	s := New(store, runs, runner)
+
+	now := time.Now().UTC().Truncate(time.Minute)
+	runs.CreateRun(ctx, model.CrawlRun{RunID: "RUN_BUSY", Source: "ATMB", Kind: model.RunKindCrawl, Status: "running", StartedAt: now})
+	store.SaveSchedule(ctx, model.Schedule{ID: "SCHED_ATMB", Action: model.ScheduleCrawl, Provider: "ATMB", Cron: "*/5 * * * *", NextRunAt: now})
+	store.SaveSchedule(ctx, model.Schedule{ID: "SCHED_IPOST1", Action: model.ScheduleCrawl, Provider: "iPost1", Cron: "*/5 * * * *", NextRunAt: now})
+	store.SaveSchedule(ctx, model.Schedule{ID: "SCHED_STATS", Action: model.ScheduleRefreshStats, Cron: "*/5 * * * *", NextRunAt: now})
+
+	s.Tick(ctx, now)
+
+	if len(runner.started) != 1 || runner.started[0] != "iPost1" {
+		t.Errorf("started = %v, want only iPost1 (ATMB is still running)", runner.started)
+	}
+	if runner.refreshes != 1 {
+		t.Errorf("refreshes = %d, want 1", runner.refreshes)
+	}
+	got, _ := store.GetSchedule(ctx, "SCHED_ATMB")
+	if !strings.Contains(got.LastError, "RUN_BUSY") || !got.NextRunAt.After(now) {
+		t.Errorf("skipped schedule = %+v, want LastError naming RUN_BUSY and NextRunAt advanced", got)
+	}
+}
+
+func TestSchedulerFiresOnceAcrossInstances(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewMemoryScheduleRepository()
+	runs := repository.NewMemoryRunRepository()
+	a, b := &fakeRunner{}, &fakeRunner{}
+
+	now := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+	store.SaveSchedule(ctx, model.Schedule{ID: "SCHED_1", Action: model.ScheduleCrawl, Provider: "ATMB", Cron: "@hourly", NextRunAt: now})
+	stale, _ := store.ListSchedules(ctx)
+
+	New(store, runs, a).Tick(ctx, now)
+	// The second instance read the schedule before the first claimed it.
+	New(store, runs, b).fire(ctx, stale[0], now)
+
+	if len(a.started)+len(b.started) != 1 {
+		t.Errorf("started %v and %v, want exactly one crawl", a.started, b.started)
+	}
+}
@@ -9,18 +9,59 @@ import (
 	"strings"
 )
 
+// Supported values for STORAGE_DRIVER.
+const (
+	StorageFirestore = "firestore"
+	StorageSQLite    = "sqlite"
+	StorageMemory    = "memory"
+)
+
+// Supported values for VALIDATOR.
+const (
+	ValidatorSmarty    = "smarty"
+	ValidatorUSPS      = "usps"
+	ValidatorConsensus = "consensus" // Every backend in CONSENSUS_VALIDATORS, combined by majority
+)
+
 // Config holds runtime configuration loaded from environment variables.
 type Config struct {
 	Port                string
 	GinMode             string
+	StorageDriver       string // "firestore" (default), "sqlite" or "memory"
+	SQLitePath          string // Database file used when StorageDriver is "sqlite"
 	FirebaseProjectID   string
 	FirebaseCredsBase64 string
 	FirebaseCredsFile   string
+	Validator           string   // Address validator backend: "smarty" (default), "usps" or "consensus"
+	ConsensusValidators []string // Backends combined when Validator is "consensus"
 	SmartyAuthIDs       []string // Multiple auth IDs for load balancing
 	SmartyAuthTokens    []string // Multiple auth tokens (must match IDs length)
 	SmartyMock          bool
+	SmartyCandidates    int // Matches requested per address; several mean the address is ambiguous
+	USPSClientID        string
+	USPSClientSecret    string
+	USPSBaseURL         string // Addresses API host; empty uses https://apis.usps.com
 	AllowedOrigins      string
 	CrawlLinkSeeds      []string
+	CrawlerConcurrency  int                    // Workers fetching and parsing pages in parallel during a crawl
+	FeedConfigFile      string                 // JSON list of structured CSV/JSON feeds registered as extra providers
+	CrawlCacheDir       string                 // When set, fetched pages are cached on disk and revalidated with ETag/Last-Modified
+	CrawlOffline        bool                   // Serve crawls only from CrawlCacheDir, never touching the network
+	CrawlPolicies       map[string]CrawlPolicy // Politeness overrides keyed by lower-case provider name; "" holds the CRAWL_* defaults
+	SweepMinCoverage    float64                // Hold the sweep when a crawl discovers less than this fraction of the previous successful one; 0 disables
+	SchedulerEnabled    bool                   // Fire stored schedules from this instance; several instances may share a store
+	DemoMode            bool                   // Boot with an in-memory store seeded with fixture data and mock Smarty
+}
+
+// CrawlPolicy overrides a provider's crawl politeness policy. Nil fields keep the provider default.
+// Set globally with CRAWL_RPS, CRAWL_BURST, CRAWL_MAX_RETRIES, CRAWL_RESPECT_ROBOTS and CRAWL_USER_AGENT,
+// or per provider by suffixing the provider name, e.g. CRAWL_RPS_ATMB=1 or CRAWL_RESPECT_ROBOTS_POSTSCANMAIL=true.
+type CrawlPolicy struct {
+	RequestsPerSecond *float64
+	Burst             *int
+	MaxRetries        *int
+	RespectRobots     *bool
+	UserAgent         *string
 }
 
 // Load reads environment variables into a Config with sensible defaults.
@@ -28,14 +69,53 @@ func Load() (Config, error) {
 	cfg := Config{
 		Port:                getEnv("PORT", "8080"),
 		GinMode:             getEnv("GIN_MODE", "release"),
+		StorageDriver:       strings.ToLower(getEnv("STORAGE_DRIVER", StorageFirestore)),
+		SQLitePath:          getEnv("SQLITE_PATH", "data/verifier.db"),
 		FirebaseProjectID:   strings.TrimSpace(os.Getenv("FIREBASE_PROJECT_ID")),
 		FirebaseCredsBase64: strings.TrimSpace(os.Getenv("FIREBASE_CREDS_BASE64")),
 		FirebaseCredsFile:   strings.TrimSpace(os.Getenv("FIREBASE_CREDS_FILE")),
-		SmartyAuthIDs:       splitCSV(os.Getenv("SMARTY_AUTH_ID")),      // Parse comma-separated IDs
-		SmartyAuthTokens:    splitCSV(os.Getenv("SMARTY_AUTH_TOKEN")),   // Parse comma-separated tokens
+		Validator:           strings.ToLower(getEnv("VALIDATOR", ValidatorSmarty)),
+		ConsensusValidators: splitCSV(strings.ToLower(getEnv("CONSENSUS_VALIDATORS", ValidatorSmarty+","+ValidatorUSPS))),
+		SmartyAuthIDs:       splitCSV(os.Getenv("SMARTY_AUTH_ID")),    // Parse comma-separated IDs
+		SmartyAuthTokens:    splitCSV(os.Getenv("SMARTY_AUTH_TOKEN")), // Parse comma-separated tokens
+		USPSClientID:        strings.TrimSpace(os.Getenv("USPS_CLIENT_ID")),
+		USPSClientSecret:    strings.TrimSpace(os.Getenv("USPS_CLIENT_SECRET")),
+		USPSBaseURL:         strings.TrimSpace(os.Getenv("USPS_BASE_URL")),
 		AllowedOrigins:      strings.TrimSpace(os.Getenv("ALLOWED_ORIGINS")),
 		CrawlLinkSeeds:      splitCSV(os.Getenv("CRAWL_LINK_SEEDS")),
+		FeedConfigFile:      strings.TrimSpace(os.Getenv("FEED_CONFIG_FILE")),
+		CrawlCacheDir:       strings.TrimSpace(os.Getenv("CRAWL_CACHE_DIR")),
+	}
+
+	concurrency, err := parseIntEnv("CRAWLER_CONCURRENCY", 5)
+	if err != nil {
+		return Config{}, fmt.Errorf("parse CRAWLER_CONCURRENCY: %w", err)
+	}
+	cfg.CrawlerConcurrency = concurrency
+
+	offline, err := parseBoolEnv("CRAWL_OFFLINE", false)
+	if err != nil {
+		return Config{}, fmt.Errorf("parse CRAWL_OFFLINE: %w", err)
+	}
+	cfg.CrawlOffline = offline
+
+	coverage, err := parseFloatEnv("SWEEP_MIN_COVERAGE", 0.8)
+	if err != nil {
+		return Config{}, fmt.Errorf("parse SWEEP_MIN_COVERAGE: %w", err)
+	}
+	cfg.SweepMinCoverage = coverage
+
+	schedulerEnabled, err := parseBoolEnv("SCHEDULER_ENABLED", true)
+	if err != nil {
+		return Config{}, fmt.Errorf("parse SCHEDULER_ENABLED: %w", err)
 	}
+	cfg.SchedulerEnabled = schedulerEnabled
+
+	policies, err := loadCrawlPolicies(os.Environ())
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.CrawlPolicies = policies
 
 	mock, err := parseBoolEnv("SMARTY_MOCK", false)
 	if err != nil {
@@ -43,6 +123,24 @@ func Load() (Config, error) {
 	}
 	cfg.SmartyMock = mock
 
+	candidates, err := parseIntEnv("SMARTY_CANDIDATES", 5)
+	if err != nil {
+		return Config{}, fmt.Errorf("parse SMARTY_CANDIDATES: %w", err)
+	}
+	cfg.SmartyCandidates = candidates
+
+	demo, err := parseBoolEnv("DEMO_MODE", false)
+	if err != nil {
+		return Config{}, fmt.Errorf("parse DEMO_MODE: %w", err)
+	}
+	cfg.DemoMode = demo
+	if demo {
+		// Demo mode never touches external services.
+		cfg.StorageDriver = StorageMemory
+		cfg.Validator = ValidatorSmarty
+		cfg.SmartyMock = true
+	}
+
 	if err := cfg.Validate(); err != nil {
 		return Config{}, err
 	}
@@ -54,23 +152,101 @@ func (c Config) Validate() error {
 	if c.Port == "" {
 		return errors.New("PORT is required")
 	}
-	if c.FirebaseProjectID == "" {
-		return errors.New("FIREBASE_PROJECT_ID is required")
+	if c.CrawlerConcurrency <= 0 {
+		return errors.New("CRAWLER_CONCURRENCY must be positive")
+	}
+	if c.SmartyCandidates < 1 || c.SmartyCandidates > 10 {
+		return errors.New("SMARTY_CANDIDATES must be between 1 and 10")
+	}
+	if c.SweepMinCoverage < 0 || c.SweepMinCoverage > 1 {
+		return errors.New("SWEEP_MIN_COVERAGE must be between 0 and 1")
+	}
+	if c.CrawlOffline && c.CrawlCacheDir == "" {
+		return errors.New("CRAWL_CACHE_DIR is required when CRAWL_OFFLINE=true")
+	}
+	switch c.StorageDriver {
+	case StorageFirestore:
+		if c.FirebaseProjectID == "" {
+			return errors.New("FIREBASE_PROJECT_ID is required")
+		}
+		if c.FirebaseCredsBase64 == "" && c.FirebaseCredsFile == "" {
+			return errors.New("provide FIREBASE_CREDS_BASE64 or FIREBASE_CREDS_FILE for Firestore auth")
+		}
+	case StorageSQLite:
+		if c.SQLitePath == "" {
+			return errors.New("SQLITE_PATH is required when STORAGE_DRIVER=sqlite")
+		}
+	case StorageMemory:
+		// Nothing to configure; data lives only for the life of the process.
+	default:
+		return fmt.Errorf("unsupported STORAGE_DRIVER %q (use %q, %q or %q)", c.StorageDriver, StorageFirestore, StorageSQLite, StorageMemory)
+	}
+	if c.Validator != ValidatorConsensus {
+		return c.validateBackend(c.Validator)
 	}
-	if c.FirebaseCredsBase64 == "" && c.FirebaseCredsFile == "" {
-		return errors.New("provide FIREBASE_CREDS_BASE64 or FIREBASE_CREDS_FILE for Firestore auth")
+	if len(c.ConsensusValidators) < 2 {
+		return errors.New("CONSENSUS_VALIDATORS must list at least two validators when VALIDATOR=consensus")
 	}
-	// Validate Smarty credentials count matches (when not in mock mode)
-	if !c.SmartyMock && len(c.SmartyAuthIDs) != len(c.SmartyAuthTokens) {
-		return fmt.Errorf("SMARTY_AUTH_ID count (%d) must match SMARTY_AUTH_TOKEN count (%d)",
-			len(c.SmartyAuthIDs), len(c.SmartyAuthTokens))
+	seen := make(map[string]bool)
+	for _, name := range c.ConsensusValidators {
+		if seen[name] {
+			return fmt.Errorf("CONSENSUS_VALIDATORS lists %q twice", name)
+		}
+		seen[name] = true
+		if err := c.validateBackend(name); err != nil {
+			return err
+		}
 	}
-	if !c.SmartyMock && len(c.SmartyAuthIDs) == 0 {
-		return errors.New("SMARTY_AUTH_ID and SMARTY_AUTH_TOKEN are required when SMARTY_MOCK=false")
+	return nil
+}
+
+// validateBackend checks the settings of a single validator backend.
+func (c Config) validateBackend(name string) error {
+	switch name {
+	case ValidatorSmarty:
+		// Validate Smarty credentials count matches (when not in mock mode)
+		if !c.SmartyMock && len(c.SmartyAuthIDs) != len(c.SmartyAuthTokens) {
+			return fmt.Errorf("SMARTY_AUTH_ID count (%d) must match SMARTY_AUTH_TOKEN count (%d)",
+				len(c.SmartyAuthIDs), len(c.SmartyAuthTokens))
+		}
+		if !c.SmartyMock && len(c.SmartyAuthIDs) == 0 {
+			return errors.New("SMARTY_AUTH_ID and SMARTY_AUTH_TOKEN are required when SMARTY_MOCK=false")
+		}
+	case ValidatorUSPS:
+		if c.USPSClientID == "" || c.USPSClientSecret == "" {
+			return errors.New("USPS_CLIENT_ID and USPS_CLIENT_SECRET are required to validate with usps")
+		}
+	default:
+		return fmt.Errorf("unsupported validator %q (use %q, %q or %q)", name, ValidatorSmarty, ValidatorUSPS, ValidatorConsensus)
 	}
 	return nil
 }
 
+// CrawlPolicyFor returns the politeness overrides for a provider: its own settings on top of the CRAWL_* defaults.
+func (c Config) CrawlPolicyFor(provider string) CrawlPolicy {
+	merged := c.CrawlPolicies[""]
+	own, ok := c.CrawlPolicies[strings.ToLower(provider)]
+	if !ok {
+		return merged
+	}
+	if own.RequestsPerSecond != nil {
+		merged.RequestsPerSecond = own.RequestsPerSecond
+	}
+	if own.Burst != nil {
+		merged.Burst = own.Burst
+	}
+	if own.MaxRetries != nil {
+		merged.MaxRetries = own.MaxRetries
+	}
+	if own.RespectRobots != nil {
+		merged.RespectRobots = own.RespectRobots
+	}
+	if own.UserAgent != nil {
+		merged.UserAgent = own.UserAgent
+	}
+	return merged
+}
+
 // FirebaseCredentialsJSON returns the service account JSON bytes and the source used.
 func (c Config) FirebaseCredentialsJSON() ([]byte, string, error) {
 	if c.FirebaseCredsBase64 != "" {
@@ -109,6 +285,77 @@ func parseBoolEnv(key string, defaultVal bool) (bool, error) {
 	return parsed, nil
 }
 
+func parseIntEnv(key string, defaultVal int) (int, error) {
+	val := strings.TrimSpace(os.Getenv(key))
+	if val == "" {
+		return defaultVal, nil
+	}
+	return strconv.Atoi(val)
+}
+
+func parseFloatEnv(key string, defaultVal float64) (float64, error) {
+	val := strings.TrimSpace(os.Getenv(key))
+	if val == "" {
+		return defaultVal, nil
+	}
+	return strconv.ParseFloat(val, 64)
+}
+
+var crawlPolicyEnv = []string{"CRAWL_RPS", "CRAWL_BURST", "CRAWL_MAX_RETRIES", "CRAWL_RESPECT_ROBOTS", "CRAWL_USER_AGENT"}
+
+// loadCrawlPolicies collects CRAWL_* politeness settings from environ ("KEY=value" pairs).
+func loadCrawlPolicies(environ []string) (map[string]CrawlPolicy, error) {
+	policies := make(map[string]CrawlPolicy)
+	for _, kv := range environ {
+		key, val, _ := strings.Cut(kv, "=")
+		val = strings.TrimSpace(val)
+		if val == "" {
+			continue
+		}
+		for _, name := range crawlPolicyEnv {
+			provider, ok := "", key == name
+			if !ok {
+				if suffix, found := strings.CutPrefix(key, name+"_"); found {
+					provider, ok = strings.ToLower(suffix), true
+				}
+			}
+			if !ok {
+				continue
+			}
+
+			p := policies[provider]
+			switch name {
+			case "CRAWL_RPS":
+				rps, err := strconv.ParseFloat(val, 64)
+				if err != nil || rps < 0 {
+					return nil, fmt.Errorf("parse %s: invalid rate %q", key, val)
+				}
+				p.RequestsPerSecond = &rps
+			case "CRAWL_BURST", "CRAWL_MAX_RETRIES":
+				n, err := strconv.Atoi(val)
+				if err != nil || n < 0 {
+					return nil, fmt.Errorf("parse %s: invalid count %q", key, val)
+				}
+				if name == "CRAWL_BURST" {
+					p.Burst = &n
+				} else {
+					p.MaxRetries = &n
+				}
+			case "CRAWL_RESPECT_ROBOTS":
+				b, err := strconv.ParseBool(val)
+				if err != nil {
+					return nil, fmt.Errorf("parse %s: %w", key, err)
+				}
+				p.RespectRobots = &b
+			case "CRAWL_USER_AGENT":
+				p.UserAgent = &val
+			}
+			policies[provider] = p
+		}
+	}
+	return policies, nil
+}
+
 func splitCSV(val string) []string {
 	parts := strings.Split(val, ",")
 	out := make([]string, 0, len(parts))
@@ -0,0 +1,80 @@
+package config
+
+import "testing"
+
+func TestCrawlPolicyFor(t *testing.T) {
+	policies, err := loadCrawlPolicies([]string{
+		"CRAWL_RPS=2",
+		"CRAWL_MAX_RETRIES=3",
+		"CRAWL_RPS_ATMB=0.5",
+		"CRAWL_RESPECT_ROBOTS_POSTSCANMAIL=true",
+		"CRAWL_BURST_ATMB=",
+		"CRAWL_USER_AGENT_ATMB=VirtualBoxBot/1.0",
+		"UNRELATED=1",
+	})
+	if err != nil {
+		t.Fatalf("loadCrawlPolicies: %v", err)
+	}
+	cfg := Config{CrawlPolicies: policies}
+
+	atmb := cfg.CrawlPolicyFor("ATMB")
+	if atmb.RequestsPerSecond == nil || *atmb.RequestsPerSecond != 0.5 {
+		t.Errorf("ATMB rps = %v, want provider override 0.5", atmb.RequestsPerSecond)
+	}
+	if atmb.MaxRetries == nil || *atmb.MaxRetries != 3 {
+		t.Errorf("ATMB retries = %v, want global 3", atmb.MaxRetries)
+	}
+	if atmb.UserAgent == nil || *atmb.UserAgent != "VirtualBoxBot/1.0" {
+		t.Errorf("ATMB user agent = %v, want VirtualBoxBot/1.0", atmb.UserAgent)
+	}
+	if atmb.Burst != nil || atmb.RespectRobots != nil {
+		t.Errorf("unset fields should stay nil: %+v", atmb)
+	}
+
+	postscan := cfg.CrawlPolicyFor("PostScanMail")
+	if postscan.RespectRobots == nil || !*postscan.RespectRobots || *postscan.RequestsPerSecond != 2 || postscan.UserAgent != nil {
+		t.Errorf("unexpected PostScanMail policy: %+v", postscan)
+	}
+
+	if _, err := loadCrawlPolicies([]string{"CRAWL_BURST_IPOST1=lots"}); err == nil {
+		t.Errorf("expected an error for a non-numeric burst")
+	}
+}
+
+func TestValidateValidator(t *testing.T) {
+	base := Config{Port: "8080", CrawlerConcurrency: 1, SmartyCandidates: 5, StorageDriver: StorageMemory}
+
+	tests := []struct {
+		name    string
+		mutate  func(*Config)
+		wantErr bool
+	}{
+		{"smarty mock", func(c *Config) { c.Validator = ValidatorSmarty; c.SmartyMock = true }, false},
+		{"smarty without credentials", func(c *Config) { c.Validator = ValidatorSmarty }, true},
+		{"usps", func(c *Config) { c.Validator = ValidatorUSPS; c.USPSClientID, c.USPSClientSecret = "id", "secret" }, false},
+		{"usps without secret", func(c *Config) { c.Validator = ValidatorUSPS; c.USPSClientID = "id" }, true},
+		{"unknown", func(c *Config) { c.Validator = "melissa"; c.SmartyMock = true }, true},
+		{"consensus", func(c *Config) {
+			c.Validator, c.ConsensusValidators = ValidatorConsensus, []string{ValidatorSmarty, ValidatorUSPS}
+			c.SmartyMock, c.USPSClientID, c.USPSClientSecret = true, "id", "secret"
+		}, false},
+		{"consensus of one", func(c *Config) {
+			c.Validator, c.ConsensusValidators, c.SmartyMock = ValidatorConsensus, []string{ValidatorSmarty}, true
+		}, true},
+		{"consensus missing usps credentials", func(c *Config) {
+			c.Validator, c.ConsensusValidators, c.SmartyMock = ValidatorConsensus, []string{ValidatorSmarty, ValidatorUSPS}, true
+		}, true},
+		{"consensus nested", func(c *Config) {
+			c.Validator, c.ConsensusValidators, c.SmartyMock = ValidatorConsensus, []string{ValidatorSmarty, ValidatorConsensus}, true
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := base
+			tt.mutate(&cfg)
+			if err := cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -2,32 +2,40 @@ package http
 
 import (
 	"encoding/csv"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/internal/business/crawler"
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/internal/business/scheduler"
 	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/internal/repository"
 	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/model"
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/util"
 )
 
 // Router wires HTTP handlers.
 type Router struct {
-	mailboxes *repository.MailboxRepository
-	runs      *repository.RunRepository
-	stats     *repository.StatsRepository
+	mailboxes repository.MailboxStore
+	runs      repository.RunStore
+	stats     repository.StatsStore
+	history   repository.HistoryStore
 	crawler   *crawler.Service
+	scheduler *scheduler.Scheduler
 	origins   string
 }
 
-func NewRouter(mailboxes *repository.MailboxRepository, runs *repository.RunRepository, stats *repository.StatsRepository, crawlerSvc *crawler.Service, allowedOrigins string) *gin.Engine {
+func NewRouter(mailboxes repository.MailboxStore, runs repository.RunStore, stats repository.StatsStore, history repository.HistoryStore, crawlerSvc *crawler.Service, sched *scheduler.Scheduler, allowedOrigins string) *gin.Engine {
 	r := &Router{
 		mailboxes: mailboxes,
 		runs:      runs,
 		stats:     stats,
+		history:   history,
 		crawler:   crawlerSvc,
+		scheduler: sched,
 		origins:   allowedOrigins,
 	}
 
@@ -42,16 +50,30 @@ func NewRouter(mailboxes *repository.MailboxRepository, runs *repository.RunRepo
 	{
 		api.GET("/mailboxes", r.listMailboxes)
 		api.GET("/mailboxes/export", r.exportMailboxes)
+		api.GET("/mailboxes/review", r.listReviewQueue)
+		api.GET("/mailboxes/disagreements", r.listDisagreements)
+		api.GET("/mailboxes/disagreements/export", r.exportDisagreements)
+		api.POST("/mailboxes/:id/candidate", r.pickMailboxCandidate)
+		api.GET("/mailboxes/:id/history", r.getMailboxHistory)
+		api.GET("/mailboxes/:id/prices", r.getMailboxPrices)
+		api.GET("/prices/changes", r.listPriceChanges)
 		api.GET("/stats", r.getStats)
 		api.POST("/stats/refresh", r.refreshStats)
+		api.GET("/providers", r.listProviders)
 		api.POST("/crawl/run", r.startCrawl)
+		api.POST("/crawl/:provider/run", r.startProviderCrawl)
 		api.POST("/crawl/reprocess", r.reprocessMailboxes)
+		api.POST("/crawl/revalidate", r.revalidateMailboxes)
 		api.GET("/crawl/status", r.getCrawlStatus)
 		api.GET("/crawl/runs", r.listCrawlRuns)
+		api.GET("/crawl/runs/:runId/events", r.streamCrawlRunEvents)
+		api.GET("/crawl/runs/:runId/errors", r.listCrawlRunErrors)
 		api.POST("/crawl/runs/:runId/cancel", r.cancelCrawlRun)
-
-		// iPost1 specific endpoints
-		api.POST("/crawl/ipost1/run", r.startIPost1Crawl)
+		api.POST("/crawl/runs/:runId/pause", r.pauseCrawlRun)
+		api.POST("/crawl/runs/:runId/resume", r.resumeCrawlRun)
+		api.GET("/schedules", r.listSchedules)
+		api.POST("/schedules", r.createSchedule)
+		api.DELETE("/schedules/:id", r.deleteSchedule)
 	}
 
 	return router
@@ -76,7 +98,7 @@ func (r *Router) corsMiddleware() gin.HandlerFunc {
 		}
 		c.Header("Access-Control-Allow-Origin", allowed)
 		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization")
-		c.Header("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		c.Header("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
 		if c.Request.Method == http.MethodOptions {
 			c.Status(http.StatusNoContent)
 			c.Abort()
@@ -86,7 +108,24 @@ func (r *Router) corsMiddleware() gin.HandlerFunc {
 	}
 }
 
+// statusQuery reads the validation status filter, answering 400 and reporting false when it is not
+// a known status.
+func statusQuery(c *gin.Context) (string, bool) {
+	status := c.Query("status")
+	switch status {
+	case "", model.ValidationPending, model.ValidationVerified, model.ValidationNoMatch,
+		model.ValidationAmbiguous, model.ValidationError, model.ValidationQuotaExhausted:
+		return status, true
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": "status must be pending, verified, no_match, ambiguous, error or quota_exhausted"})
+	return "", false
+}
+
 func (r *Router) listMailboxes(c *gin.Context) {
+	status, ok := statusQuery(c)
+	if !ok {
+		return
+	}
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "50"))
 	activeParam := c.Query("active")
@@ -97,13 +136,16 @@ func (r *Router) listMailboxes(c *gin.Context) {
 	}
 
 	items, total, err := r.mailboxes.List(c.Request.Context(), repository.MailboxQuery{
-		State:    c.Query("state"),
-		CMRA:     c.Query("cmra"),
-		RDI:      c.Query("rdi"),
-		Source:   c.Query("source"),
-		Active:   activePtr,
-		Page:     page,
-		PageSize: pageSize,
+		State:        c.Query("state"),
+		CMRA:         c.Query("cmra"),
+		RDI:          c.Query("rdi"),
+		Source:       c.Query("source"),
+		DPVMatchCode: c.Query("dpvMatchCode"),
+		Vacant:       c.Query("vacant"),
+		Status:       status,
+		Active:       activePtr,
+		Page:         page,
+		PageSize:     pageSize,
 	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -117,6 +159,10 @@ func (r *Router) listMailboxes(c *gin.Context) {
 }
 
 func (r *Router) exportMailboxes(c *gin.Context) {
+	status, ok := statusQuery(c)
+	if !ok {
+		return
+	}
 	c.Header("Content-Type", "text/csv")
 	c.Header("Content-Disposition", "attachment; filename=mailboxes.csv")
 
@@ -128,17 +174,20 @@ func (r *Router) exportMailboxes(c *gin.Context) {
 	}
 
 	query := repository.MailboxQuery{
-		State:  c.Query("state"),
-		CMRA:   c.Query("cmra"),
-		RDI:    c.Query("rdi"),
-		Source: c.Query("source"),
-		Active: activePtr,
+		State:        c.Query("state"),
+		CMRA:         c.Query("cmra"),
+		RDI:          c.Query("rdi"),
+		Source:       c.Query("source"),
+		DPVMatchCode: c.Query("dpvMatchCode"),
+		Vacant:       c.Query("vacant"),
+		Status:       status,
+		Active:       activePtr,
 	}
 
 	writer := csv.NewWriter(c.Writer)
 	defer writer.Flush()
 
-	if err := writer.Write([]string{"name", "street", "city", "state", "zip", "price", "link", "cmra", "rdi", "source"}); err != nil {
+	if err := writer.Write([]string{"name", "street", "city", "state", "zip", "price", "plans", "link", "cmra", "rdi", "source", "dpv_match_code", "vacant", "validation_status"}); err != nil {
 		c.Status(http.StatusInternalServerError)
 		return
 	}
@@ -151,10 +200,14 @@ func (r *Router) exportMailboxes(c *gin.Context) {
 			mb.AddressRaw.State,
 			mb.AddressRaw.Zip,
 			fmt.Sprintf("%.2f", mb.Price),
+			util.FormatPlans(mb.Plans),
 			mb.Link,
 			mb.CMRA,
 			mb.RDI,
 			mb.Source,
+			mb.Analysis.DPVMatchCode,
+			mb.Analysis.DPVVacant,
+			mb.ValidationStatus,
 		}
 		return writer.Write(row)
 	})
@@ -164,40 +217,215 @@ func (r *Router) exportMailboxes(c *gin.Context) {
 	}
 }
 
-func (r *Router) getStats(c *gin.Context) {
-	stats, err := r.stats.GetSystemStats(c.Request.Context())
+// listReviewQueue pages through active mailboxes whose validation was ambiguous, each with the
+// candidates an operator can pick from.
+func (r *Router) listReviewQueue(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "50"))
+	active := true
+
+	items, total, err := r.mailboxes.List(c.Request.Context(), repository.MailboxQuery{
+		Status:   model.ValidationAmbiguous,
+		Active:   &active,
+		Page:     page,
+		PageSize: pageSize,
+	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, stats)
+	c.JSON(http.StatusOK, gin.H{
+		"items": items,
+		"total": total,
+		"page":  page,
+	})
 }
 
-func (r *Router) refreshStats(c *gin.Context) {
-	ctx := c.Request.Context()
+// listDisagreements pages through active mailboxes whose consensus validators disagreed on CMRA or
+// RDI, each with every backend's verdict.
+func (r *Router) listDisagreements(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "50"))
+	active := true
+
+	items, total, err := r.mailboxes.List(c.Request.Context(), repository.MailboxQuery{
+		Disagree: true,
+		Active:   &active,
+		Page:     page,
+		PageSize: pageSize,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"items": items,
+		"total": total,
+		"page":  page,
+	})
+}
+
+// exportDisagreements streams the active mailboxes whose validators disagreed as CSV, with the
+// consensus verdict, its confidence and what each backend answered.
+func (r *Router) exportDisagreements(c *gin.Context) {
+	c.Header("Content-Type", "text/csv")
+	c.Header("Content-Disposition", "attachment; filename=disagreements.csv")
+
+	active := true
+	query := repository.MailboxQuery{
+		Source:   c.Query("source"),
+		Disagree: true,
+		Active:   &active,
+	}
+
+	writer := csv.NewWriter(c.Writer)
+	defer writer.Flush()
+
+	if err := writer.Write([]string{"id", "name", "street", "city", "state", "zip", "link", "source", "cmra", "rdi", "confidence", "verdicts"}); err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	err := r.mailboxes.StreamWithQuery(c.Request.Context(), query, func(mb model.Mailbox) error {
+		row := []string{
+			mb.ID,
+			mb.Name,
+			mb.AddressRaw.Street,
+			mb.AddressRaw.City,
+			mb.AddressRaw.State,
+			mb.AddressRaw.Zip,
+			mb.Link,
+			mb.Source,
+			mb.CMRA,
+			mb.RDI,
+			fmt.Sprintf("%.2f", mb.Confidence),
+			formatVerdicts(mb.Verdicts),
+		}
+		return writer.Write(row)
+	})
+	if err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+}
+
+// formatVerdicts renders every backend's verdict as one CSV cell, e.g.
+// "smarty Y/Commercial verified; usps N/Residential verified".
+func formatVerdicts(verdicts []model.ValidatorVerdict) string {
+	parts := make([]string, len(verdicts))
+	for i, v := range verdicts {
+		parts[i] = fmt.Sprintf("%s %s/%s %s", v.Validator, v.CMRA, v.RDI, v.Status)
+	}
+	return strings.Join(parts, "; ")
+}
+
+type pickCandidateReq struct {
+	Index *int `json:"index"` // Position in the mailbox's candidates
+}
 
-	// Fetch all mailboxes
-	all, err := r.mailboxes.FetchAllMap(ctx)
+// pickMailboxCandidate settles a mailbox from the review queue on the candidate the operator chose.
+func (r *Router) pickMailboxCandidate(c *gin.Context) {
+	var req pickCandidateReq
+	if err := c.BindJSON(&req); err != nil || req.Index == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "index is required"})
+		return
+	}
+	mb, err := r.crawler.PickCandidate(c.Request.Context(), c.Param("id"), *req.Index)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch mailboxes: " + err.Error()})
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, crawler.ErrMailboxNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, crawler.ErrNoSuchCandidate):
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
 		return
 	}
+	c.JSON(http.StatusOK, mb)
+}
 
-	// Convert map to slice
-	var list []model.Mailbox
-	for _, m := range all {
-		list = append(list, m)
+func (r *Router) getMailboxHistory(c *gin.Context) {
+	id := c.Param("id")
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+
+	items, err := r.history.ListHistory(c.Request.Context(), id, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
 	}
+	c.JSON(http.StatusOK, gin.H{
+		"mailboxId": id,
+		"items":     items,
+	})
+}
 
-	// Aggregate stats
-	sysStats := crawler.AggregateSystemStats(list)
+func (r *Router) getMailboxPrices(c *gin.Context) {
+	id := c.Param("id")
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
 
-	// Save stats
-	if err := r.stats.SaveSystemStats(ctx, sysStats); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save stats: " + err.Error()})
+	items, err := r.history.ListPrices(c.Request.Context(), id, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"mailboxId": id,
+		"items":     items,
+	})
+}
+
+// listPriceChanges compares the prices observed in two runs and returns mailboxes that moved by more than minPct percent.
+func (r *Router) listPriceChanges(c *gin.Context) {
+	fromRun := c.Query("fromRun")
+	toRun := c.Query("toRun")
+	if fromRun == "" || toRun == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "fromRun and toRun are required"})
+		return
+	}
+	minPct, err := strconv.ParseFloat(c.DefaultQuery("minPct", "0"), 64)
+	if err != nil || minPct < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "minPct must be a non-negative number"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	from, err := r.history.ListRunPrices(ctx, fromRun)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	to, err := r.history.ListRunPrices(ctx, toRun)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	items := util.PriceChanges(from, to, minPct)
+	c.JSON(http.StatusOK, gin.H{
+		"fromRun": fromRun,
+		"toRun":   toRun,
+		"minPct":  minPct,
+		"items":   items,
+		"total":   len(items),
+	})
+}
+
+func (r *Router) getStats(c *gin.Context) {
+	stats, err := r.stats.GetSystemStats(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, stats)
+}
 
+func (r *Router) refreshStats(c *gin.Context) {
+	sysStats, err := r.crawler.RefreshStats(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to refresh stats: " + err.Error()})
+		return
+	}
 	c.JSON(http.StatusOK, sysStats)
 }
 
@@ -205,13 +433,17 @@ type startCrawlReq struct {
 	Links []string `json:"links"`
 }
 
+// startCrawl is the original ATMB endpoint, kept for existing clients.
 func (r *Router) startCrawl(c *gin.Context) {
 	var req startCrawlReq
 	if err := c.BindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
 		return
 	}
-	runID, err := r.crawler.Start(c.Request.Context(), req.Links)
+	runID, err := r.crawler.StartProvider(c.Request.Context(), crawler.SourceATMB, req.Links)
+	if runConflict(c, err) {
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -219,6 +451,48 @@ func (r *Router) startCrawl(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"runId": runID})
 }
 
+// startProviderCrawl starts a run for any registered provider. The body is optional.
+func (r *Router) startProviderCrawl(c *gin.Context) {
+	var req startCrawlReq
+	if c.Request.ContentLength > 0 {
+		if err := c.BindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+			return
+		}
+	}
+	runID, err := r.crawler.StartProvider(c.Request.Context(), c.Param("provider"), req.Links)
+	if runConflict(c, err) {
+		return
+	}
+	if errors.Is(err, crawler.ErrUnknownProvider) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"runId":   runID,
+		"message": "Crawl started. Check status with GET /api/crawl/status?runId=" + runID,
+	})
+}
+
+// runConflict answers 409 with the ID of the run holding the source's lock when err is a
+// *crawler.RunConflictError, reporting whether it did.
+func runConflict(c *gin.Context, err error) bool {
+	var conflict *crawler.RunConflictError
+	if !errors.As(err, &conflict) {
+		return false
+	}
+	c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "runId": conflict.RunID})
+	return true
+}
+
+func (r *Router) listProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"items": r.crawler.Providers()})
+}
+
 func (r *Router) getCrawlStatus(c *gin.Context) {
 	runID := c.Query("runId")
 	if runID == "" {
@@ -242,6 +516,115 @@ func (r *Router) listCrawlRuns(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"items": runs})
 }
 
+// runEventsPollInterval is how often a run's event stream re-reads the stored run, which carries
+// progress of runs executing on other instances and keeps idle connections alive.
+const runEventsPollInterval = 5 * time.Second
+
+// streamCrawlRunEvents streams a run's stats, per-link errors, log lines and final status as
+// server-sent events, ending once the run has finished. Live events come from runs executing on
+// this instance; for runs elsewhere the stream falls back to the stored stats.
+func (r *Router) streamCrawlRunEvents(c *gin.Context) {
+	runID := c.Param("runId")
+	// Subscribe before reading the run so its final status cannot slip in between.
+	events, unsubscribe := r.crawler.SubscribeRun(runID)
+	defer unsubscribe()
+
+	run, err := r.runs.GetRun(c.Request.Context(), runID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	send := func(ev model.RunEvent) {
+		c.SSEvent(ev.Type, ev)
+		c.Writer.Flush()
+	}
+	snapshot := func(typ string, run model.CrawlRun) model.RunEvent {
+		stats := run.Stats
+		return model.RunEvent{Type: typ, RunID: runID, Time: time.Now().UTC(), Status: run.Status, Stats: &stats}
+	}
+
+	if runFinished(run.Status) {
+		send(snapshot(model.RunEventStatus, run))
+		return
+	}
+	send(snapshot(model.RunEventStats, run))
+
+	ticker := time.NewTicker(runEventsPollInterval)
+	defer ticker.Stop()
+	live := false // Once the run publishes here, the stored stats only lag behind its events.
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case ev := <-events:
+			live = true
+			send(ev)
+			if ev.Type == model.RunEventStatus && runFinished(ev.Status) {
+				return
+			}
+		case <-ticker.C:
+			latest, err := r.runs.GetRun(c.Request.Context(), runID)
+			switch {
+			case err != nil:
+				_, _ = fmt.Fprint(c.Writer, ": keep-alive\n\n")
+				c.Writer.Flush()
+			case runFinished(latest.Status):
+				send(snapshot(model.RunEventStatus, latest))
+				return
+			case !live && (latest.Stats != run.Stats || latest.Status != run.Status):
+				run = latest
+				send(snapshot(model.RunEventStats, run))
+			default:
+				_, _ = fmt.Fprint(c.Writer, ": keep-alive\n\n")
+				c.Writer.Flush()
+			}
+		}
+	}
+}
+
+// runFinished reports whether a run status is final.
+func runFinished(status string) bool {
+	switch status {
+	case "queued", "running", "paused":
+		return false
+	}
+	return true
+}
+
+// listCrawlRunErrors pages through the links a run failed on, optionally only those of one stage.
+func (r *Router) listCrawlRunErrors(c *gin.Context) {
+	stage := c.Query("stage")
+	switch stage {
+	case "", model.StageFetch, model.StageParse, model.StageValidate, model.StageSave:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "stage must be fetch, parse, validate or save"})
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "50"))
+
+	items, total, err := r.runs.ListRunEvents(c.Request.Context(), repository.RunEventQuery{
+		RunID:    c.Param("runId"),
+		Stage:    stage,
+		Page:     page,
+		PageSize: pageSize,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"items": items,
+		"total": total,
+		"page":  page,
+	})
+}
+
 func (r *Router) cancelCrawlRun(c *gin.Context) {
 	runID := c.Param("runId")
 	if runID == "" {
@@ -249,11 +632,9 @@ func (r *Router) cancelCrawlRun(c *gin.Context) {
 		return
 	}
 
-	// Cancel the running goroutine (if still running)
-	wasRunning := r.crawler.CancelJob(runID)
-
-	// Update database status
-	if err := r.runs.CancelRun(c.Request.Context(), runID); err != nil {
+	// Cancel the run here if it is running here, and through the job queue wherever else it is queued or running
+	wasRunning, err := r.crawler.CancelRun(c.Request.Context(), runID)
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -265,6 +646,42 @@ func (r *Router) cancelCrawlRun(c *gin.Context) {
 	})
 }
 
+func (r *Router) pauseCrawlRun(c *gin.Context) {
+	runID := c.Param("runId")
+	if err := r.crawler.PauseRun(c.Request.Context(), runID); err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, crawler.ErrRunNotPausable) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"runId":   runID,
+		"message": "Run paused. Continue it with POST /api/crawl/runs/" + runID + "/resume",
+	})
+}
+
+// resumeCrawlRun continues a paused run, or else resumes an interrupted one from its checkpoint.
+func (r *Router) resumeCrawlRun(c *gin.Context) {
+	runID := c.Param("runId")
+	if err := r.crawler.ResumeRun(c.Request.Context(), runID); err != nil {
+		if runConflict(c, err) {
+			return
+		}
+		status := http.StatusBadRequest
+		if errors.Is(err, crawler.ErrRunNotResumable) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"runId":   runID,
+		"message": "Run resumed. Check status with GET /api/crawl/status?runId=" + runID,
+	})
+}
+
 type reprocessReq struct {
 	TargetVersion   string `json:"targetVersion"`   // Optional: parser version to update to (defaults to current)
 	OnlyOutdated    bool   `json:"onlyOutdated"`    // Optional: only reprocess records with different parser version
@@ -285,6 +702,9 @@ func (r *Router) reprocessMailboxes(c *gin.Context) {
 	}
 
 	runID, err := r.crawler.Reprocess(c.Request.Context(), opts)
+	if runConflict(c, err) {
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -296,14 +716,74 @@ func (r *Router) reprocessMailboxes(c *gin.Context) {
 	})
 }
 
-func (r *Router) startIPost1Crawl(c *gin.Context) {
-	runID, err := r.crawler.StartIPost1Crawl(c.Request.Context())
+// revalidateMailboxes queues a reprocess run that re-validates every address with Smarty.
+func (r *Router) revalidateMailboxes(c *gin.Context) {
+	runID, err := r.crawler.Revalidate(c.Request.Context())
+	if runConflict(c, err) {
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"runId":   runID,
-		"message": "iPost1 crawl started. Check status with GET /api/crawl/status?runId=" + runID,
+		"message": "Revalidation started. Check status with GET /api/crawl/status?runId=" + runID,
 	})
 }
+
+func (r *Router) listSchedules(c *gin.Context) {
+	schedules, err := r.scheduler.List(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if schedules == nil {
+		schedules = []model.Schedule{}
+	}
+	c.JSON(http.StatusOK, gin.H{"items": schedules})
+}
+
+type createScheduleReq struct {
+	Action       string `json:"action"`       // "crawl", "reprocess" or "refresh_stats"
+	Provider     string `json:"provider"`     // Required for "crawl"
+	Cron         string `json:"cron"`         // Five-field cron expression in UTC, or @hourly/@daily/@weekly/@monthly
+	OnlyOutdated bool   `json:"onlyOutdated"` // Optional for "reprocess"
+}
+
+func (r *Router) createSchedule(c *gin.Context) {
+	var req createScheduleReq
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+	sched, err := r.scheduler.Create(c.Request.Context(), model.Schedule{
+		Action:       req.Action,
+		Provider:     req.Provider,
+		Cron:         req.Cron,
+		OnlyOutdated: req.OnlyOutdated,
+	})
+	if errors.Is(err, scheduler.ErrInvalidSchedule) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, sched)
+}
+
+func (r *Router) deleteSchedule(c *gin.Context) {
+	id := c.Param("id")
+	if err := r.scheduler.Delete(c.Request.Context(), id); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, scheduler.ErrScheduleNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"id": id, "message": "Schedule deleted"})
+}
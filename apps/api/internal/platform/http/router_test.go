@@ -0,0 +1,889 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/internal/business/crawler"
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/internal/business/scheduler"
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/internal/platform/smarty"
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/internal/repository"
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/model"
+)
+
+type staticFetcher struct {
+	html []byte
+}
+
+// Fetch serves the same page for every link, except links under /s/broken/ which fail.
+func (f staticFetcher) Fetch(ctx context.Context, url string) (io.ReadCloser, error) {
+	if strings.Contains(url, "/s/broken/") {
+		return nil, fmt.Errorf("fetch %s: status 500", url)
+	}
+	return io.NopCloser(bytes.NewReader(f.html)), nil
+}
+
+type testEnv struct {
+	engine    *gin.Engine
+	mailboxes *repository.MemoryMailboxRepository
+	runs      *repository.MemoryRunRepository
+	stats     *repository.MemoryStatsRepository
+	history   *repository.MemoryHistoryRepository
+	schedules *repository.MemoryScheduleRepository
+	jobs      *repository.MemoryJobRepository
+	svc       *crawler.Service
+}
+
+// newTestEnv returns a router whose crawl worker is already running.
+func newTestEnv(t *testing.T) testEnv {
+	t.Helper()
+	env := newIdleTestEnv(t)
+	env.startWorker(t)
+	return env
+}
+
+// newIdleTestEnv returns a router whose queued jobs wait until startWorker is called.
+func newIdleTestEnv(t *testing.T) testEnv {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	sample, err := os.ReadFile("../../business/crawler/testdata/sample_page.html")
+	if err != nil {
+		t.Fatalf("read sample html: %v", err)
+	}
+
+	env := testEnv{
+		mailboxes: repository.NewMemoryMailboxRepository(),
+		runs:      repository.NewMemoryRunRepository(),
+		stats:     repository.NewMemoryStatsRepository(),
+		history:   repository.NewMemoryHistoryRepository(),
+		jobs:      repository.NewMemoryJobRepository(),
+	}
+	validator := smarty.New(nil, smarty.Config{Mock: true})
+	providers := crawler.NewRegistry(crawler.NewATMBProvider(staticFetcher{html: sample}, nil))
+	env.svc = crawler.NewService(providers, validator, env.mailboxes, env.runs, env.stats, env.history, env.jobs, 2, crawler.DefaultSweepPolicy(), crawler.NewJobManager())
+	env.schedules = repository.NewMemoryScheduleRepository()
+	env.engine = NewRouter(env.mailboxes, env.runs, env.stats, env.history, env.svc, scheduler.New(env.schedules, env.runs, env.svc), "*")
+	return env
+}
+
+func (e testEnv) startWorker(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go e.svc.RunWorker(ctx)
+}
+
+func (e testEnv) do(t *testing.T, method, path string, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	rec := httptest.NewRecorder()
+	e.engine.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRouterListAndExportMailboxes(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	err := env.mailboxes.BatchUpsert(ctx, []model.Mailbox{
+		{ID: "1", Link: "https://a", Name: "A", Source: "ATMB", AddressRaw: model.AddressRaw{State: "CA"}, CMRA: "Y", Active: true, ValidationStatus: model.ValidationVerified},
+		{ID: "2", Link: "https://b", Name: "B", Source: "ATMB", AddressRaw: model.AddressRaw{State: "CA"}, CMRA: "N", Active: true, ValidationStatus: model.ValidationAmbiguous},
+		{ID: "3", Link: "https://c", Name: "C", Source: "iPost1", AddressRaw: model.AddressRaw{State: "TX"}, CMRA: "N", Active: false},
+	})
+	if err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+
+	rec := env.do(t, http.MethodGet, "/api/mailboxes?state=CA&cmra=N&active=true", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("list status = %d: %s", rec.Code, rec.Body.String())
+	}
+	var list struct {
+		Items []model.Mailbox `json:"items"`
+		Total int             `json:"total"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil {
+		t.Fatalf("decode list: %v", err)
+	}
+	if list.Total != 1 || len(list.Items) != 1 || list.Items[0].ID != "2" {
+		t.Errorf("unexpected list response: %+v", list)
+	}
+
+	rec = env.do(t, http.MethodGet, "/api/mailboxes?status=ambiguous", "")
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil {
+		t.Fatalf("decode list: %v", err)
+	}
+	if list.Total != 1 || list.Items[0].ID != "2" {
+		t.Errorf("unexpected list by status: %+v", list)
+	}
+	if rec := env.do(t, http.MethodGet, "/api/mailboxes?status=failed", ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown status = %d, want 400", rec.Code)
+	}
+
+	rec = env.do(t, http.MethodGet, "/api/mailboxes/export", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("export status = %d", rec.Code)
+	}
+	lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+	if len(lines) != 3 { // header + 2 active rows
+		t.Errorf("export returned %d lines, want 3:\n%s", len(lines), rec.Body.String())
+	}
+	if rec := env.do(t, http.MethodGet, "/api/mailboxes/export?status=failed", ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("export with unknown status = %d, want 400 like the list", rec.Code)
+	}
+}
+
+func TestRouterReviewQueue(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	err := env.mailboxes.BatchUpsert(ctx, []model.Mailbox{
+		{ID: "1", Link: "https://a", Name: "A", Active: true, CMRA: "N", RDI: "Commercial", RawHTML: "<html>a</html>",
+			ValidationStatus: model.ValidationAmbiguous, ValidationReason: "2 candidates disagree on CMRA or RDI",
+			Candidates: []model.AddressCandidate{
+				{StandardizedAddress: model.StandardizedAddress{DeliveryLine1: "500 Tower Rd Ste 100"}, CMRA: "N", RDI: "Commercial"},
+				{StandardizedAddress: model.StandardizedAddress{DeliveryLine1: "500 Tower Rd Ste 200"}, CMRA: "Y", RDI: "Commercial"},
+			}},
+		{ID: "2", Link: "https://b", Name: "B", Active: true, CMRA: "Y", ValidationStatus: model.ValidationVerified},
+		{ID: "3", Link: "https://c", Name: "C", Active: false, ValidationStatus: model.ValidationAmbiguous},
+	})
+	if err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+
+	rec := env.do(t, http.MethodGet, "/api/mailboxes/review", "")
+	var queue struct {
+		Items []model.Mailbox `json:"items"`
+		Total int             `json:"total"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &queue); err != nil {
+		t.Fatalf("decode queue: %v", err)
+	}
+	if queue.Total != 1 || queue.Items[0].ID != "1" || len(queue.Items[0].Candidates) != 2 {
+		t.Fatalf("unexpected review queue: %+v", queue)
+	}
+
+	if rec := env.do(t, http.MethodPost, "/api/mailboxes/1/candidate", `{"index": 2}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("out-of-range candidate = %d, want 400", rec.Code)
+	}
+	if rec := env.do(t, http.MethodPost, "/api/mailboxes/missing/candidate", `{"index": 0}`); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown mailbox = %d, want 404", rec.Code)
+	}
+	rec = env.do(t, http.MethodPost, "/api/mailboxes/1/candidate", `{"index": 1}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("pick status = %d: %s", rec.Code, rec.Body.String())
+	}
+
+	picked, err := env.mailboxes.Get(ctx, "1")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if picked.CMRA != "Y" || picked.StandardizedAddress.DeliveryLine1 != "500 Tower Rd Ste 200" || picked.ValidationStatus != model.ValidationVerified {
+		t.Errorf("candidate not applied: %+v", picked)
+	}
+	if picked.RawHTML == "" || len(picked.Candidates) != 2 {
+		t.Errorf("pick dropped stored fields: %+v", picked)
+	}
+	history, _ := env.history.ListHistory(ctx, "1", 10)
+	if len(history) != 1 {
+		t.Errorf("recorded %d history entries, want 1", len(history))
+	}
+	rec = env.do(t, http.MethodGet, "/api/mailboxes/review", "")
+	if err := json.Unmarshal(rec.Body.Bytes(), &queue); err != nil || queue.Total != 0 {
+		t.Errorf("review queue after pick: %+v (%v)", queue, err)
+	}
+}
+
+func TestRouterDisagreements(t *testing.T) {
+	env := newTestEnv(t)
+	split := []model.ValidatorVerdict{
+		{Validator: "smarty", CMRA: "Y", RDI: "Commercial", Status: model.ValidationVerified},
+		{Validator: "usps", CMRA: "N", RDI: "Commercial", Status: model.ValidationVerified},
+	}
+	err := env.mailboxes.BatchUpsert(context.Background(), []model.Mailbox{
+		{ID: "1", Link: "https://a", Name: "A", Active: true, CMRA: "Y", RDI: "Commercial", Validator: crawler.ConsensusValidatorName,
+			Verdicts: split, Confidence: 0.5, ValidatorsDisagree: true, ValidationStatus: model.ValidationAmbiguous,
+			Candidates: []model.AddressCandidate{{CMRA: "Y", RDI: "Commercial"}, {CMRA: "N", RDI: "Commercial"}}},
+		{ID: "2", Link: "https://b", Name: "B", Active: true, CMRA: "Y", Validator: crawler.ConsensusValidatorName,
+			Verdicts: split[:1], Confidence: 1, ValidationStatus: model.ValidationVerified},
+		{ID: "3", Link: "https://c", Name: "C", Active: false, Verdicts: split, ValidatorsDisagree: true},
+	})
+	if err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+
+	rec := env.do(t, http.MethodGet, "/api/mailboxes/disagreements", "")
+	var list struct {
+		Items []model.Mailbox `json:"items"`
+		Total int             `json:"total"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil {
+		t.Fatalf("decode disagreements: %v", err)
+	}
+	if list.Total != 1 || list.Items[0].ID != "1" || len(list.Items[0].Verdicts) != 2 || list.Items[0].Confidence != 0.5 {
+		t.Fatalf("unexpected disagreements: %+v", list)
+	}
+
+	rec = env.do(t, http.MethodGet, "/api/mailboxes/disagreements/export", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("export status = %d", rec.Code)
+	}
+	lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[1], "0.50,smarty Y/Commercial verified; usps N/Commercial verified") {
+		t.Errorf("unexpected disagreements export:\n%s", rec.Body.String())
+	}
+
+	// An operator settles the disagreement by picking a candidate.
+	if rec := env.do(t, http.MethodPost, "/api/mailboxes/1/candidate", `{"index": 1}`); rec.Code != http.StatusOK {
+		t.Fatalf("pick status = %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = env.do(t, http.MethodGet, "/api/mailboxes/disagreements", "")
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil || list.Total != 0 {
+		t.Errorf("disagreements after pick: %+v (%v)", list, err)
+	}
+	picked, err := env.mailboxes.Get(context.Background(), "1")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if picked.CMRA != "N" || picked.ValidatorsDisagree || picked.Confidence != 1 || len(picked.Verdicts) != 2 {
+		t.Errorf("picked mailbox: cmra %q disagree %v confidence %.2f with %d verdicts, want N false 1.00 2",
+			picked.CMRA, picked.ValidatorsDisagree, picked.Confidence, len(picked.Verdicts))
+	}
+}
+
+func TestRouterStatsRefresh(t *testing.T) {
+	env := newTestEnv(t)
+	_ = env.mailboxes.BatchUpsert(context.Background(), []model.Mailbox{
+		{ID: "1", Link: "https://a", Source: "ATMB", RDI: "Commercial", Price: 10, Active: true},
+		{ID: "2", Link: "https://b", Source: "iPost1", RDI: "Residential", Price: 20, Active: true},
+	})
+
+	if rec := env.do(t, http.MethodGet, "/api/stats", ""); rec.Code != http.StatusInternalServerError {
+		t.Errorf("stats before refresh status = %d, want 500", rec.Code)
+	}
+	rec := env.do(t, http.MethodPost, "/api/stats/refresh", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("refresh status = %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = env.do(t, http.MethodGet, "/api/stats", "")
+	var stats model.SystemStats
+	if err := json.Unmarshal(rec.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("decode stats: %v", err)
+	}
+	if stats.TotalMailboxes != 2 || stats.BySource["iPost1"] != 1 || stats.AvgPrice != 15 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestRouterCrawlRunEndToEnd(t *testing.T) {
+	env := newTestEnv(t)
+
+	rec := env.do(t, http.MethodPost, "/api/crawl/run", `{"links":["https://www.anytimemailbox.com/s/chicago-monroe-st"]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("start status = %d: %s", rec.Code, rec.Body.String())
+	}
+	var started struct {
+		RunID string `json:"runId"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &started); err != nil || started.RunID == "" {
+		t.Fatalf("decode start response: %v (%s)", err, rec.Body.String())
+	}
+
+	run := waitForRun(t, env, started.RunID)
+	if run.Status != "success" {
+		t.Fatalf("run status = %q, want success (%+v)", run.Status, run)
+	}
+	if run.Stats.Found != 1 || run.Stats.Validated != 1 {
+		t.Errorf("unexpected run stats: %+v", run.Stats)
+	}
+
+	rec = env.do(t, http.MethodGet, "/api/mailboxes?source=ATMB", "")
+	if !strings.Contains(rec.Body.String(), "Chicago - Monroe St") {
+		t.Errorf("crawled mailbox missing from list: %s", rec.Body.String())
+	}
+
+	rec = env.do(t, http.MethodGet, "/api/crawl/runs", "")
+	if !strings.Contains(rec.Body.String(), started.RunID) {
+		t.Errorf("run missing from history: %s", rec.Body.String())
+	}
+
+	rec = env.do(t, http.MethodPost, "/api/crawl/runs/"+started.RunID+"/cancel", "")
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("cancel finished run status = %d, want 400", rec.Code)
+	}
+}
+
+func TestRouterRejectsConcurrentRun(t *testing.T) {
+	env := newIdleTestEnv(t)
+	ctx := context.Background()
+
+	// Another instance is crawling ATMB.
+	link := "https://www.anytimemailbox.com/s/chicago-monroe-st"
+	if _, ok, err := env.jobs.EnqueueJob(ctx, model.Job{ID: "RUN_ELSEWHERE", Kind: model.JobCrawl, LockKey: "crawl:atmb", Provider: "ATMB", Seeds: []string{link}}); err != nil || !ok {
+		t.Fatalf("EnqueueJob = %v, %v", ok, err)
+	}
+	if _, ok, err := env.jobs.ClaimJob(ctx, "elsewhere", time.Now().UTC().Add(time.Minute)); err != nil || !ok {
+		t.Fatalf("ClaimJob = %v, %v", ok, err)
+	}
+
+	body := `{"links":["` + link + `"]}`
+	rec := env.do(t, http.MethodPost, "/api/crawl/atmb/run", body)
+	if rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), `"runId":"RUN_ELSEWHERE"`) {
+		t.Fatalf("duplicate start = %d: %s, want 409 naming RUN_ELSEWHERE", rec.Code, rec.Body.String())
+	}
+	if runs, _ := env.runs.ListRuns(ctx, 10); len(runs) != 0 {
+		t.Errorf("rejected start created runs: %+v", runs)
+	}
+
+	// The other instance dies: once its lease lapses, this instance's worker takes the job over.
+	if _, ok, _ := env.jobs.HeartbeatJob(ctx, "RUN_ELSEWHERE", "elsewhere", time.Now().UTC().Add(-time.Second)); !ok {
+		t.Fatalf("could not expire the lease")
+	}
+	env.startWorker(t)
+	if run := waitForRun(t, env, "RUN_ELSEWHERE"); run.Status != "success" {
+		t.Errorf("taken-over run status = %q, want success", run.Status)
+	}
+	if job, _ := env.jobs.GetJob(ctx, "RUN_ELSEWHERE"); job.Attempts != 2 {
+		t.Errorf("taken-over job attempts = %d, want 2", job.Attempts)
+	}
+
+	rec = env.do(t, http.MethodPost, "/api/crawl/atmb/run", body)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("start after takeover = %d: %s", rec.Code, rec.Body.String())
+	}
+	var started struct {
+		RunID string `json:"runId"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &started); err != nil {
+		t.Fatalf("decode start response: %v", err)
+	}
+	waitForRun(t, env, started.RunID)
+
+	// The finished run released its lease.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		holder, ok, err := env.runs.AcquireRunLock(ctx, model.RunLock{Key: "crawl:atmb", RunID: "RUN_PROBE", ExpiresAt: time.Now().Add(time.Minute)})
+		if err == nil && ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("lock still held by %s after the run finished", holder.RunID)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRouterCancelQueuedRun(t *testing.T) {
+	env := newIdleTestEnv(t)
+	ctx := context.Background()
+
+	rec := env.do(t, http.MethodPost, "/api/crawl/revalidate", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("revalidate = %d: %s", rec.Code, rec.Body.String())
+	}
+	var started struct {
+		RunID string `json:"runId"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &started); err != nil {
+		t.Fatalf("decode start response: %v", err)
+	}
+	if run, err := env.runs.GetRun(ctx, started.RunID); err != nil || run.Status != "queued" || run.Kind != model.RunKindReprocess {
+		t.Fatalf("queued run = %+v, %v", run, err)
+	}
+	if job, err := env.jobs.GetJob(ctx, started.RunID); err != nil || job.Kind != model.JobRevalidate || job.Status != model.JobQueued {
+		t.Fatalf("job = %+v, %v; want a queued revalidate job", job, err)
+	}
+
+	rec = env.do(t, http.MethodPost, "/api/crawl/runs/"+started.RunID+"/cancel", "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"wasRunning":false`) {
+		t.Fatalf("cancel queued run = %d: %s", rec.Code, rec.Body.String())
+	}
+	if job, _ := env.jobs.GetJob(ctx, started.RunID); job.Status != model.JobCancelled {
+		t.Errorf("job status = %q, want cancelled", job.Status)
+	}
+
+	// A worker never picks the cancelled job up, and its lock key is free again.
+	env.startWorker(t)
+	rec = env.do(t, http.MethodPost, "/api/crawl/reprocess", `{}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("reprocess after cancel = %d: %s", rec.Code, rec.Body.String())
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &started); err != nil {
+		t.Fatalf("decode start response: %v", err)
+	}
+	if run := waitForRun(t, env, started.RunID); run.Status != "success" {
+		t.Errorf("reprocess status = %q, want success", run.Status)
+	}
+	if job := waitForJob(t, env, started.RunID); job.Status != model.JobDone {
+		t.Errorf("reprocess job status = %q, want done", job.Status)
+	}
+}
+
+func TestRouterPauseAndResumeRun(t *testing.T) {
+	env := newIdleTestEnv(t)
+	ctx := context.Background()
+
+	// A run leased by another instance's worker.
+	if err := env.runs.CreateRun(ctx, model.CrawlRun{RunID: "RUN_ELSEWHERE", Source: "ATMB", Status: "running"}); err != nil {
+		t.Fatalf("CreateRun: %v", err)
+	}
+	if _, ok, err := env.jobs.EnqueueJob(ctx, model.Job{ID: "RUN_ELSEWHERE", Kind: model.JobCrawl, LockKey: "crawl:atmb", Provider: "ATMB"}); err != nil || !ok {
+		t.Fatalf("EnqueueJob = %v, %v", ok, err)
+	}
+	if _, ok, err := env.jobs.ClaimJob(ctx, "elsewhere", time.Now().UTC().Add(time.Minute)); err != nil || !ok {
+		t.Fatalf("ClaimJob = %v, %v", ok, err)
+	}
+
+	rec := env.do(t, http.MethodPost, "/api/crawl/runs/RUN_ELSEWHERE/pause", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("pause = %d: %s", rec.Code, rec.Body.String())
+	}
+	if job, _ := env.jobs.GetJob(ctx, "RUN_ELSEWHERE"); !job.PauseRequested {
+		t.Errorf("pause was not recorded on the job for its worker")
+	}
+	if rec := env.do(t, http.MethodPost, "/api/crawl/runs/RUN_ELSEWHERE/pause", ""); rec.Code != http.StatusConflict {
+		t.Errorf("second pause = %d, want 409", rec.Code)
+	}
+
+	// Resuming a paused run clears the pause instead of restarting from a checkpoint.
+	rec = env.do(t, http.MethodPost, "/api/crawl/runs/RUN_ELSEWHERE/resume", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("resume = %d: %s", rec.Code, rec.Body.String())
+	}
+	if job, _ := env.jobs.GetJob(ctx, "RUN_ELSEWHERE"); job.PauseRequested || job.Status != model.JobRunning || job.Owner != "elsewhere" {
+		t.Errorf("job after resume = %+v, want still running on its worker with the pause cleared", job)
+	}
+
+	// Finished runs cannot be paused.
+	if err := env.jobs.CompleteJob(ctx, "RUN_ELSEWHERE", "elsewhere", model.JobDone, ""); err != nil {
+		t.Fatalf("CompleteJob: %v", err)
+	}
+	if rec := env.do(t, http.MethodPost, "/api/crawl/runs/RUN_ELSEWHERE/pause", ""); rec.Code != http.StatusConflict {
+		t.Errorf("pause of a finished run = %d, want 409", rec.Code)
+	}
+}
+
+func TestRouterStreamCrawlRunEvents(t *testing.T) {
+	env := newIdleTestEnv(t)
+	srv := httptest.NewServer(env.engine)
+	defer srv.Close()
+
+	rec := env.do(t, http.MethodPost, "/api/crawl/run", `{"links":["https://www.anytimemailbox.com/s/chicago-monroe-st"]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("start status = %d: %s", rec.Code, rec.Body.String())
+	}
+	var started struct {
+		RunID string `json:"runId"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &started); err != nil || started.RunID == "" {
+		t.Fatalf("decode start response: %v (%s)", err, rec.Body.String())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/api/crawl/runs/"+started.RunID+"/events", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("open stream: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/event-stream") {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+
+	// The queued run only executes once a worker starts, after the stream is open.
+	env.startWorker(t)
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read stream: %v", err)
+	}
+	events := map[string]int{}
+	var last model.RunEvent
+	for _, block := range strings.Split(string(body), "\n\n") {
+		var name string
+		for _, line := range strings.Split(block, "\n") {
+			switch {
+			case strings.HasPrefix(line, "event:"):
+				name = line[len("event:"):]
+				events[name]++
+			case strings.HasPrefix(line, "data:") && name == model.RunEventStatus:
+				if err := json.Unmarshal([]byte(line[len("data:"):]), &last); err != nil {
+					t.Fatalf("decode status event: %v", err)
+				}
+			}
+		}
+	}
+	if events[model.RunEventStats] < 2 || events[model.RunEventLog] == 0 {
+		t.Errorf("stream events = %v, want the initial and live stats plus log lines", events)
+	}
+	if last.Status != "success" || last.Stats == nil || last.Stats.Found != 1 {
+		t.Errorf("final status event = %+v, want success with the run's stats", last)
+	}
+
+	// A finished run's stream reports its status and closes.
+	rec = env.do(t, http.MethodGet, "/api/crawl/runs/"+started.RunID+"/events", "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "event:status") {
+		t.Errorf("finished run stream = %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := env.do(t, http.MethodGet, "/api/crawl/runs/RUN_MISSING/events", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("missing run stream = %d, want 404", rec.Code)
+	}
+}
+
+func TestRouterListCrawlRunErrors(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	var events []model.LinkEvent
+	for i := 0; i < 3; i++ {
+		events = append(events, model.LinkEvent{Link: fmt.Sprintf("https://fetch/%d", i), Stage: model.StageFetch, Error: "status 500"})
+	}
+	events = append(events, model.LinkEvent{Link: "https://parse/0", Stage: model.StageParse, Error: "no address"})
+	if err := env.runs.AppendRunEvents(ctx, "RUN_1", events); err != nil {
+		t.Fatalf("AppendRunEvents: %v", err)
+	}
+
+	rec := env.do(t, http.MethodGet, "/api/crawl/runs/RUN_1/errors?stage=fetch&page=2&pageSize=2", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("errors status = %d: %s", rec.Code, rec.Body.String())
+	}
+	var page struct {
+		Items []model.LinkEvent `json:"items"`
+		Total int               `json:"total"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+		t.Fatalf("decode errors: %v", err)
+	}
+	if page.Total != 3 || len(page.Items) != 1 || page.Items[0].Link != "https://fetch/2" {
+		t.Errorf("second page of fetch errors = %+v", page)
+	}
+	if rec := env.do(t, http.MethodGet, "/api/crawl/runs/RUN_1/errors?stage=bogus", ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown stage = %d, want 400", rec.Code)
+	}
+
+	// A crawl records the links it failed on and keeps the first ones on the run.
+	broken := "https://www.anytimemailbox.com/s/broken/dallas"
+	rec = env.do(t, http.MethodPost, "/api/crawl/run", `{"links":["https://www.anytimemailbox.com/s/chicago-monroe-st","`+broken+`"]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("start status = %d: %s", rec.Code, rec.Body.String())
+	}
+	var started struct {
+		RunID string `json:"runId"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &started); err != nil {
+		t.Fatalf("decode start response: %v", err)
+	}
+	run := waitForRun(t, env, started.RunID)
+	if len(run.ErrorSample) != 1 || run.ErrorSample[0].Link != broken || run.ErrorSample[0].Stage != model.StageFetch {
+		t.Errorf("error sample = %+v, want the broken link's fetch failure", run.ErrorSample)
+	}
+	rec = env.do(t, http.MethodGet, "/api/crawl/runs/"+started.RunID+"/errors", "")
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+		t.Fatalf("decode errors: %v", err)
+	}
+	if page.Total != 1 || page.Items[0].Link != broken || !strings.Contains(page.Items[0].Error, "status 500") {
+		t.Errorf("run errors = %+v, want the broken link", page)
+	}
+}
+
+func TestRouterResumeCrawlRun(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	// An ATMB run that timed out after finishing one of its two listings.
+	const runID = "RUN_INTERRUPTED"
+	done := "https://www.anytimemailbox.com/s/done"
+	pending := "https://www.anytimemailbox.com/s/chicago-monroe-st"
+	if err := env.runs.CreateRun(ctx, model.CrawlRun{RunID: runID, Source: "ATMB", Status: "timeout", Stats: model.CrawlRunStats{Found: 1, Validated: 1}}); err != nil {
+		t.Fatalf("CreateRun: %v", err)
+	}
+	if err := env.runs.SaveCheckpoint(ctx, model.CrawlCheckpoint{RunID: runID, Source: "ATMB", Listings: []model.CheckpointEntry{
+		{Listing: model.Mailbox{Link: done}, Done: true},
+		{Listing: model.Mailbox{Link: pending}},
+	}}); err != nil {
+		t.Fatalf("SaveCheckpoint: %v", err)
+	}
+	if err := env.mailboxes.BatchUpsert(ctx, []model.Mailbox{
+		{Link: done, Name: "Done", Source: "ATMB", CrawlRunID: runID, Active: true},
+		{Link: "https://www.anytimemailbox.com/s/gone", Name: "Gone", Source: "ATMB", CrawlRunID: "RUN_OLD", Active: true},
+	}); err != nil {
+		t.Fatalf("BatchUpsert: %v", err)
+	}
+
+	rec := env.do(t, http.MethodPost, "/api/crawl/runs/"+runID+"/resume", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("resume status = %d: %s", rec.Code, rec.Body.String())
+	}
+	run := waitForRun(t, env, runID)
+	if run.Status != "success" || run.ResumedAt.IsZero() {
+		t.Fatalf("resumed run = %+v, want success", run)
+	}
+	if run.Stats.Found != 2 || run.Stats.Validated != 2 {
+		t.Errorf("resumed stats should include the first attempt: %+v", run.Stats)
+	}
+
+	all, _ := env.mailboxes.FetchAllMap(ctx)
+	if m := all[pending]; m.CrawlRunID != runID || !m.Active {
+		t.Errorf("pending listing not crawled under the same run: %+v", m)
+	}
+	if !all[done].Active || all["https://www.anytimemailbox.com/s/gone"].Active {
+		t.Errorf("sweep should keep discovered listings and deactivate the rest")
+	}
+
+	if rec := env.do(t, http.MethodPost, "/api/crawl/runs/"+runID+"/resume", ""); rec.Code != http.StatusConflict {
+		t.Errorf("resume finished run status = %d, want 409", rec.Code)
+	}
+}
+
+func TestRouterSweepHeldOnLowCoverage(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	// The previous crawl found 10 locations; this one will discover a single link.
+	if err := env.runs.CreateRun(ctx, model.CrawlRun{RunID: "RUN_0", Source: "ATMB", Kind: model.RunKindCrawl, Status: "success", Stats: model.CrawlRunStats{Found: 10}}); err != nil {
+		t.Fatalf("CreateRun: %v", err)
+	}
+	gone := "https://www.anytimemailbox.com/s/gone"
+	if err := env.mailboxes.BatchUpsert(ctx, []model.Mailbox{{Link: gone, Name: "Gone", Source: "ATMB", CrawlRunID: "RUN_0", Active: true}}); err != nil {
+		t.Fatalf("BatchUpsert: %v", err)
+	}
+
+	rec := env.do(t, http.MethodPost, "/api/crawl/run", `{"links":["https://www.anytimemailbox.com/s/chicago-monroe-st"]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("start status = %d: %s", rec.Code, rec.Body.String())
+	}
+	var started struct {
+		RunID string `json:"runId"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &started); err != nil {
+		t.Fatalf("decode start response: %v", err)
+	}
+
+	run := waitForRun(t, env, started.RunID)
+	if run.Sweep == nil || !run.Sweep.Held || run.Sweep.BaselineRun != "RUN_0" || run.Sweep.Coverage != 0.1 {
+		t.Fatalf("sweep should be held at 10%% coverage of RUN_0: %+v", run.Sweep)
+	}
+	if len(run.Sweep.Quarantined) != 1 || run.Sweep.Quarantined[0] != gone || len(run.Sweep.Swept) != 0 {
+		t.Errorf("unexpected sweep report: %+v", run.Sweep)
+	}
+	all, _ := env.mailboxes.FetchAllMap(ctx)
+	if !all[gone].Active {
+		t.Errorf("a held sweep must not deactivate anything")
+	}
+
+	// The provider really did shrink: a second crawl finding the same single link is measured
+	// against the held run, not RUN_0, and sweeps.
+	rec = env.do(t, http.MethodPost, "/api/crawl/run", `{"links":["https://www.anytimemailbox.com/s/chicago-monroe-st"]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("second start status = %d: %s", rec.Code, rec.Body.String())
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &started); err != nil {
+		t.Fatalf("decode second start response: %v", err)
+	}
+	second := waitForRun(t, env, started.RunID)
+	if second.Sweep == nil || second.Sweep.Held || second.Sweep.BaselineRun != run.RunID || second.Sweep.Coverage != 1 {
+		t.Fatalf("second low-coverage run should sweep against %s: %+v", run.RunID, second.Sweep)
+	}
+	all, _ = env.mailboxes.FetchAllMap(ctx)
+	if all[gone].Active {
+		t.Errorf("the second run should deactivate the quarantined mailbox")
+	}
+}
+
+func TestRouterProviders(t *testing.T) {
+	env := newTestEnv(t)
+
+	rec := env.do(t, http.MethodGet, "/api/providers", "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"name":"ATMB"`) {
+		t.Errorf("providers = %d: %s", rec.Code, rec.Body.String())
+	}
+
+	if rec := env.do(t, http.MethodPost, "/api/crawl/nope/run", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown provider status = %d, want 404", rec.Code)
+	}
+	if rec := env.do(t, http.MethodPost, "/api/crawl/atmb/run", ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("ATMB without links status = %d, want 400", rec.Code)
+	}
+
+	rec = env.do(t, http.MethodPost, "/api/crawl/atmb/run", `{"links":["https://www.anytimemailbox.com/s/chicago-monroe-st"]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("start status = %d: %s", rec.Code, rec.Body.String())
+	}
+	var started struct {
+		RunID string `json:"runId"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &started); err != nil {
+		t.Fatalf("decode start response: %v", err)
+	}
+	run := waitForRun(t, env, started.RunID)
+	if run.Status != "success" || run.Source != "ATMB" {
+		t.Errorf("run = %+v, want successful ATMB run", run)
+	}
+}
+
+func TestRouterSchedules(t *testing.T) {
+	env := newTestEnv(t)
+
+	for _, body := range []string{
+		`{"action":"crawl","provider":"nope","cron":"@daily"}`,
+		`{"action":"crawl","provider":"atmb","cron":"every day"}`,
+		`{"action":"vacuum","cron":"@daily"}`,
+	} {
+		if rec := env.do(t, http.MethodPost, "/api/schedules", body); rec.Code != http.StatusBadRequest {
+			t.Errorf("create %s status = %d, want 400", body, rec.Code)
+		}
+	}
+
+	rec := env.do(t, http.MethodPost, "/api/schedules", `{"action":"crawl","provider":"atmb","cron":"0 3 * * *"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create status = %d: %s", rec.Code, rec.Body.String())
+	}
+	var created model.Schedule
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatalf("decode schedule: %v", err)
+	}
+	if created.ID == "" || created.Provider != "ATMB" || created.NextRunAt.IsZero() {
+		t.Errorf("created = %+v, want ID, canonical provider and NextRunAt", created)
+	}
+
+	rec = env.do(t, http.MethodGet, "/api/schedules", "")
+	var list struct {
+		Items []model.Schedule `json:"items"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil || len(list.Items) != 1 || list.Items[0].ID != created.ID {
+		t.Fatalf("list = %s, want the created schedule", rec.Body.String())
+	}
+
+	if rec := env.do(t, http.MethodDelete, "/api/schedules/"+created.ID, ""); rec.Code != http.StatusOK {
+		t.Errorf("delete status = %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := env.do(t, http.MethodDelete, "/api/schedules/"+created.ID, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("second delete status = %d, want 404", rec.Code)
+	}
+	if rec := env.do(t, http.MethodGet, "/api/schedules", ""); !strings.Contains(rec.Body.String(), `"items":[]`) {
+		t.Errorf("list after delete = %s, want empty items", rec.Body.String())
+	}
+}
+
+func TestRouterMailboxHistory(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	err := env.history.AppendHistory(ctx, []model.MailboxHistory{
+		{MailboxID: "1", CrawlRunID: "RUN_1", ChangedAt: time.Now().Add(-time.Hour), Changes: []model.FieldChange{{Field: "price", Old: "9.99", New: "12.99"}}},
+		{MailboxID: "1", CrawlRunID: "RUN_2", ChangedAt: time.Now(), Changes: []model.FieldChange{{Field: "active", Old: "true", New: "false"}}},
+		{MailboxID: "2", CrawlRunID: "RUN_2", ChangedAt: time.Now(), Changes: []model.FieldChange{{Field: "cmra", Old: "", New: "Y"}}},
+	})
+	if err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+
+	rec := env.do(t, http.MethodGet, "/api/mailboxes/1/history", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("history status = %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		MailboxID string                 `json:"mailboxId"`
+		Items     []model.MailboxHistory `json:"items"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode history: %v", err)
+	}
+	if resp.MailboxID != "1" || len(resp.Items) != 2 || resp.Items[0].CrawlRunID != "RUN_2" {
+		t.Errorf("unexpected history response: %+v", resp)
+	}
+
+	rec = env.do(t, http.MethodGet, "/api/mailboxes/1/history?limit=1", "")
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || len(resp.Items) != 1 {
+		t.Errorf("limit=1 returned %d items (%v)", len(resp.Items), err)
+	}
+}
+
+func TestRouterPriceSeriesAndChanges(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	day := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	err := env.history.AppendPrices(ctx, []model.PricePoint{
+		{MailboxID: "1", Price: 10, CrawlRunID: "RUN_1", ObservedAt: day},
+		{MailboxID: "2", Price: 20, CrawlRunID: "RUN_1", ObservedAt: day},
+		{MailboxID: "1", Price: 15, CrawlRunID: "RUN_2", ObservedAt: day.Add(24 * time.Hour)},
+		{MailboxID: "2", Price: 21, CrawlRunID: "RUN_2", ObservedAt: day.Add(24 * time.Hour)},
+	})
+	if err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+
+	rec := env.do(t, http.MethodGet, "/api/mailboxes/1/prices", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("prices status = %d: %s", rec.Code, rec.Body.String())
+	}
+	var series struct {
+		Items []model.PricePoint `json:"items"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &series); err != nil {
+		t.Fatalf("decode prices: %v", err)
+	}
+	if len(series.Items) != 2 || series.Items[0].Price != 10 || series.Items[1].Price != 15 {
+		t.Errorf("unexpected price series: %+v", series.Items)
+	}
+
+	rec = env.do(t, http.MethodGet, "/api/prices/changes?fromRun=RUN_1&toRun=RUN_2&minPct=10", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("changes status = %d: %s", rec.Code, rec.Body.String())
+	}
+	var changes struct {
+		Items []model.PriceChange `json:"items"`
+		Total int                 `json:"total"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &changes); err != nil {
+		t.Fatalf("decode changes: %v", err)
+	}
+	if changes.Total != 1 || changes.Items[0].MailboxID != "1" || changes.Items[0].ChangePct != 50 {
+		t.Errorf("unexpected price changes: %+v", changes)
+	}
+
+	if rec := env.do(t, http.MethodGet, "/api/prices/changes?fromRun=RUN_1", ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("missing toRun status = %d, want 400", rec.Code)
+	}
+}
+
+func waitForRun(t *testing.T, env testEnv, runID string) model.CrawlRun {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		run, err := env.runs.GetRun(context.Background(), runID)
+		if err == nil && run.Status != "queued" && run.Status != "running" && !run.FinishedAt.IsZero() {
+			return run
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("run %s did not finish in time", runID)
+	return model.CrawlRun{}
+}
+
+func waitForJob(t *testing.T, env testEnv, id string) model.Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		job, err := env.jobs.GetJob(context.Background(), id)
+		if err == nil && job.Status != model.JobQueued && job.Status != model.JobRunning {
+			return job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("job %s did not finish in time", id)
+	return model.Job{}
+}
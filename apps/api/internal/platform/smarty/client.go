@@ -10,12 +10,16 @@ import (
 	"log"
 	"net/http"
 	"net/url"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/model"
 )
 
+// Name identifies this validator on the mailboxes it validates.
+const Name = "smarty"
+
 var (
 	// ErrCircuitOpen signals the breaker is open after repeated 402/429 responses.
 	ErrCircuitOpen = errors.New("smarty circuit open due to repeated rate/limit errors")
@@ -46,6 +50,7 @@ type Client struct {
 	mock             bool
 	maxRetries       int
 	breakerThreshold int
+	candidates       int
 }
 
 // Config defines settings for the Smarty client.
@@ -56,8 +61,14 @@ type Config struct {
 	Mock       bool
 	MaxRetries int
 	BreakerMax int
+	Candidates int // Matches to request per address (1-10, default 5)
 }
 
+const (
+	defaultCandidates = 5
+	maxCandidates     = 10 // Smarty's limit
+)
+
 // New creates a Smarty client with support for multiple credentials.
 func New(httpClient HTTPClient, cfg Config) *Client {
 	if httpClient == nil {
@@ -75,6 +86,13 @@ func New(httpClient HTTPClient, cfg Config) *Client {
 	if breaker <= 0 {
 		breaker = 5
 	}
+	candidates := cfg.Candidates
+	if candidates <= 0 {
+		candidates = defaultCandidates
+	}
+	if candidates > maxCandidates {
+		candidates = maxCandidates
+	}
 
 	// Build credentials slice from AuthIDs and AuthTokens
 	credentials := make([]credential, len(cfg.AuthIDs))
@@ -93,6 +111,7 @@ func New(httpClient HTTPClient, cfg Config) *Client {
 		mock:             cfg.Mock,
 		maxRetries:       maxRetries,
 		breakerThreshold: breaker,
+		candidates:       candidates,
 	}
 }
 
@@ -106,10 +125,24 @@ func (c *Client) ValidateMailbox(ctx context.Context, mailbox model.Mailbox) (mo
 			DeliveryLine1: mailbox.AddressRaw.Street,
 			LastLine:      fmt.Sprintf("%s, %s %s", mailbox.AddressRaw.City, mailbox.AddressRaw.State, mailbox.AddressRaw.Zip),
 		}
+		mailbox.Analysis = mockAnalysis
+		mailbox.Candidates = nil
+		mailbox.ValidationStatus = model.ValidationVerified
+		mailbox.ValidationReason = ""
+		mailbox.Validator = Name
 		mailbox.LastValidatedAt = time.Now().UTC()
 		return mailbox, nil
 	}
 
+	result, err := c.validateMailbox(ctx, mailbox)
+	if err != nil {
+		markFailed(&result, err)
+	}
+	return result, err
+}
+
+// validateMailbox tries each credential in round-robin order until one answers.
+func (c *Client) validateMailbox(ctx context.Context, mailbox model.Mailbox) (model.Mailbox, error) {
 	if len(c.credentials) == 0 {
 		return mailbox, errors.New("no smarty credentials configured")
 	}
@@ -171,6 +204,7 @@ func (c *Client) validateWithCredential(ctx context.Context, mailbox model.Mailb
 	params.Set("city", mailbox.AddressRaw.City)
 	params.Set("state", mailbox.AddressRaw.State)
 	params.Set("zipcode", mailbox.AddressRaw.Zip)
+	params.Set("candidates", strconv.Itoa(c.candidates))
 
 	endpoint := fmt.Sprintf("%s?%s", c.baseURL, params.Encode())
 
@@ -243,33 +277,171 @@ func decodeSmartyResponse(mailbox model.Mailbox, body io.Reader) (model.Mailbox,
 		return mailbox, fmt.Errorf("decode response: %w", err)
 	}
 	if len(candidates) == 0 {
-		return mailbox, errors.New("smarty: no candidates returned")
-	}
-	first := candidates[0]
-	mailbox.StandardizedAddress = model.StandardizedAddress{
-		DeliveryLine1: first.DeliveryLine1,
-		LastLine:      first.LastLine,
+		markNoMatch(&mailbox, time.Now().UTC())
+		return mailbox, nil
 	}
-	// CMRA is in analysis.dpv_cmra, RDI is in metadata.rdi
-	mailbox.CMRA = first.Analysis.DPVCMRA
-	mailbox.RDI = first.Metadata.RDI
-	mailbox.LastValidatedAt = time.Now().UTC()
+	applyCandidates(&mailbox, candidates, time.Now().UTC())
 	return mailbox, nil
 }
 
+// markNoMatch records that Smarty found no address to match the mailbox against. Any verdict
+// from an earlier validation is cleared, since it no longer describes the address.
+func markNoMatch(mailbox *model.Mailbox, now time.Time) {
+	mailbox.CMRA = ""
+	mailbox.RDI = ""
+	mailbox.StandardizedAddress = model.StandardizedAddress{}
+	mailbox.Analysis = model.AddressAnalysis{}
+	mailbox.Candidates = nil
+	mailbox.ValidationStatus = model.ValidationNoMatch
+	mailbox.ValidationReason = "no candidates returned"
+	mailbox.Validator = Name
+	mailbox.LastValidatedAt = now
+}
+
+// markFailed records a validation call that failed; the mailbox keeps whatever verdict it had.
+func markFailed(mailbox *model.Mailbox, err error) {
+	mailbox.ValidationStatus = model.ValidationError
+	if errors.Is(err, ErrAllCredentialsExhausted) || errors.Is(err, ErrCircuitOpen) {
+		mailbox.ValidationStatus = model.ValidationQuotaExhausted
+	}
+	mailbox.ValidationReason = err.Error()
+	mailbox.Validator = Name
+}
+
+// candidateStatus grades a candidate by its DPV match code. S and D still confirm the building, so
+// its CMRA/RDI verdict holds; the reason records the unconfirmed secondary. Responses without a code
+// (older fixtures, some non-USPS records) count as verified when they carry a CMRA verdict.
+func candidateStatus(c smartyCandidate) (status, reason string) {
+	switch c.Analysis.DPVMatchCode {
+	case "Y":
+		return model.ValidationVerified, ""
+	case "S":
+		return model.ValidationVerified, "secondary number not confirmed (DPV S)"
+	case "D":
+		return model.ValidationVerified, "secondary number missing (DPV D)"
+	case "N":
+		return model.ValidationNoMatch, "delivery point not confirmed (DPV N)"
+	}
+	if c.Analysis.DPVCMRA == "" {
+		return model.ValidationNoMatch, "no DPV analysis returned"
+	}
+	return model.ValidationVerified, ""
+}
+
+// applyCandidates stores every candidate on the mailbox and takes its verdict from the first, the
+// best match. Candidates that disagree on CMRA or RDI leave the mailbox ambiguous for an operator
+// to settle.
+func applyCandidates(mailbox *model.Mailbox, candidates []smartyCandidate, now time.Time) {
+	applyCandidate(mailbox, candidates[0], now)
+	mailbox.Candidates = make([]model.AddressCandidate, len(candidates))
+	for i, c := range candidates {
+		mailbox.Candidates[i] = addressCandidate(c)
+	}
+	for _, c := range mailbox.Candidates[1:] {
+		if c.CMRA != mailbox.CMRA || c.RDI != mailbox.RDI {
+			mailbox.ValidationStatus = model.ValidationAmbiguous
+			mailbox.ValidationReason = fmt.Sprintf("%d candidates disagree on CMRA or RDI", len(candidates))
+			break
+		}
+	}
+}
+
+// applyCandidate copies a Smarty candidate's standardized address, CMRA/RDI verdict and the rest of
+// its analysis and metadata onto the mailbox.
+func applyCandidate(mailbox *model.Mailbox, c smartyCandidate, now time.Time) {
+	cand := addressCandidate(c)
+	mailbox.StandardizedAddress = cand.StandardizedAddress
+	mailbox.Analysis = cand.Analysis
+	mailbox.CMRA = cand.CMRA
+	mailbox.RDI = cand.RDI
+	mailbox.ValidationStatus, mailbox.ValidationReason = candidateStatus(c)
+	mailbox.Validator = Name
+	mailbox.LastValidatedAt = now
+}
+
+func addressCandidate(c smartyCandidate) model.AddressCandidate {
+	return model.AddressCandidate{
+		StandardizedAddress: model.StandardizedAddress{
+			DeliveryLine1: c.DeliveryLine1,
+			LastLine:      c.LastLine,
+			Components: model.AddressComponents{
+				PrimaryNumber:       c.Components.PrimaryNumber,
+				StreetPredirection:  c.Components.StreetPredirection,
+				StreetName:          c.Components.StreetName,
+				StreetSuffix:        c.Components.StreetSuffix,
+				StreetPostdirection: c.Components.StreetPostdirection,
+				SecondaryDesignator: c.Components.SecondaryDesignator,
+				SecondaryNumber:     c.Components.SecondaryNumber,
+				PMBDesignator:       c.Components.PMBDesignator,
+				PMBNumber:           c.Components.PMBNumber,
+				CityName:            c.Components.CityName,
+				StateAbbreviation:   c.Components.StateAbbreviation,
+				Zipcode:             c.Components.Zipcode,
+				Plus4Code:           c.Components.Plus4Code,
+				DeliveryPoint:       c.Components.DeliveryPoint,
+			},
+		},
+		// CMRA is in analysis.dpv_cmra, RDI is in metadata.rdi
+		CMRA: c.Analysis.DPVCMRA,
+		RDI:  c.Metadata.RDI,
+		Analysis: model.AddressAnalysis{
+			DPVMatchCode: c.Analysis.DPVMatchCode,
+			DPVFootnotes: c.Analysis.DPVFootnotes,
+			DPVVacant:    c.Analysis.DPVVacant,
+			DPVNoStat:    c.Analysis.DPVNoStat,
+			RecordType:   c.Metadata.RecordType,
+			ZipType:      c.Metadata.ZipType,
+			CountyName:   c.Metadata.CountyName,
+			Latitude:     c.Metadata.Latitude,
+			Longitude:    c.Metadata.Longitude,
+			Precision:    c.Metadata.Precision,
+			TimeZone:     c.Metadata.TimeZone,
+		},
+	}
+}
+
 type smartyCandidate struct {
-	DeliveryLine1 string          `json:"delivery_line_1"`
-	LastLine      string          `json:"last_line"`
-	Metadata      smartyMetadata  `json:"metadata"`
-	Analysis      smartyAnalysis  `json:"analysis"`
+	DeliveryLine1 string           `json:"delivery_line_1"`
+	LastLine      string           `json:"last_line"`
+	Components    smartyComponents `json:"components"`
+	Metadata      smartyMetadata   `json:"metadata"`
+	Analysis      smartyAnalysis   `json:"analysis"`
+}
+
+type smartyComponents struct {
+	PrimaryNumber       string `json:"primary_number"`
+	StreetPredirection  string `json:"street_predirection"`
+	StreetName          string `json:"street_name"`
+	StreetSuffix        string `json:"street_suffix"`
+	StreetPostdirection string `json:"street_postdirection"`
+	SecondaryDesignator string `json:"secondary_designator"`
+	SecondaryNumber     string `json:"secondary_number"`
+	PMBDesignator       string `json:"pmb_designator"`
+	PMBNumber           string `json:"pmb_number"`
+	CityName            string `json:"city_name"`
+	StateAbbreviation   string `json:"state_abbreviation"`
+	Zipcode             string `json:"zipcode"`
+	Plus4Code           string `json:"plus4_code"`
+	DeliveryPoint       string `json:"delivery_point"`
 }
 
 type smartyMetadata struct {
-	RDI string `json:"rdi"` // "Commercial" or "Residential"
+	RDI        string  `json:"rdi"` // "Commercial" or "Residential"
+	RecordType string  `json:"record_type"`
+	ZipType    string  `json:"zip_type"`
+	CountyName string  `json:"county_name"`
+	Latitude   float64 `json:"latitude"`
+	Longitude  float64 `json:"longitude"`
+	Precision  string  `json:"precision"`
+	TimeZone   string  `json:"time_zone"`
 }
 
 type smartyAnalysis struct {
-	DPVCMRA string `json:"dpv_cmra"` // "Y" or "N"
+	DPVMatchCode string `json:"dpv_match_code"`
+	DPVFootnotes string `json:"dpv_footnotes"`
+	DPVCMRA      string `json:"dpv_cmra"` // "Y" or "N"
+	DPVVacant    string `json:"dpv_vacant"`
+	DPVNoStat    string `json:"dpv_no_stat"`
 }
 
 // ============================================================================
@@ -280,19 +452,17 @@ const maxBatchSize = 100 // Smarty API limit: 100 addresses per POST request
 
 // batchRequest represents a single address in a batch POST request.
 type batchRequest struct {
-	Street  string `json:"street"`
-	City    string `json:"city"`
-	State   string `json:"state"`
-	Zipcode string `json:"zipcode"`
+	Street     string `json:"street"`
+	City       string `json:"city"`
+	State      string `json:"state"`
+	Zipcode    string `json:"zipcode"`
+	Candidates int    `json:"candidates,omitempty"`
 }
 
 // batchResponseItem represents a single result in a batch response.
 type batchResponseItem struct {
-	InputIndex    int            `json:"input_index"`
-	DeliveryLine1 string         `json:"delivery_line_1"`
-	LastLine      string         `json:"last_line"`
-	Metadata      smartyMetadata `json:"metadata"`
-	Analysis      smartyAnalysis `json:"analysis"`
+	InputIndex int `json:"input_index"`
+	smartyCandidate
 }
 
 // ValidateMailboxBatch validates multiple mailboxes in batch using POST requests.
@@ -324,8 +494,11 @@ func (c *Client) ValidateMailboxBatch(ctx context.Context, mailboxes []model.Mai
 		chunk := mailboxes[start:end]
 		chunkResults, err := c.validateChunk(ctx, chunk)
 		if err != nil {
-			// On error, return partial results with original data for failed chunk
+			// On error, return the chunks validated so far; the rest carry the failure
 			log.Printf("batch validation chunk [%d:%d] failed: %v", start, end, err)
+			for i := start; i < len(results); i++ {
+				markFailed(&results[i], err)
+			}
 			return results, fmt.Errorf("batch validation chunk [%d:%d]: %w", start, end, err)
 		}
 
@@ -344,10 +517,11 @@ func (c *Client) validateChunk(ctx context.Context, mailboxes []model.Mailbox) (
 	reqBody := make([]batchRequest, len(mailboxes))
 	for i, mb := range mailboxes {
 		reqBody[i] = batchRequest{
-			Street:  mb.AddressRaw.Street,
-			City:    mb.AddressRaw.City,
-			State:   mb.AddressRaw.State,
-			Zipcode: mb.AddressRaw.Zip,
+			Street:     mb.AddressRaw.Street,
+			City:       mb.AddressRaw.City,
+			State:      mb.AddressRaw.State,
+			Zipcode:    mb.AddressRaw.Zip,
+			Candidates: c.candidates,
 		}
 	}
 
@@ -465,26 +639,31 @@ func decodeBatchResponse(mailboxes []model.Mailbox, body io.Reader) ([]model.Mai
 	results := make([]model.Mailbox, len(mailboxes))
 	copy(results, mailboxes)
 
-	// Map responses back to mailboxes by input_index
-	now := time.Now().UTC()
+	// Group candidates by input_index; an address with several matches appears once per candidate
+	candidates := make([][]smartyCandidate, len(results))
 	for _, resp := range responses {
 		if resp.InputIndex < 0 || resp.InputIndex >= len(results) {
 			continue // Skip invalid indices
 		}
+		candidates[resp.InputIndex] = append(candidates[resp.InputIndex], resp.smartyCandidate)
+	}
 
-		mb := &results[resp.InputIndex]
-		mb.StandardizedAddress = model.StandardizedAddress{
-			DeliveryLine1: resp.DeliveryLine1,
-			LastLine:      resp.LastLine,
+	// Smarty leaves addresses it could not match out of the response
+	now := time.Now().UTC()
+	for i := range results {
+		if len(candidates[i]) == 0 {
+			markNoMatch(&results[i], now)
+			continue
 		}
-		mb.CMRA = resp.Analysis.DPVCMRA
-		mb.RDI = resp.Metadata.RDI
-		mb.LastValidatedAt = now
+		applyCandidates(&results[i], candidates[i], now)
 	}
 
 	return results, nil
 }
 
+// mockAnalysis is the analysis mock validation reports: a confirmed, occupied delivery point.
+var mockAnalysis = model.AddressAnalysis{DPVMatchCode: "Y", DPVVacant: "N", DPVNoStat: "N"}
+
 // mockBatchValidation returns mock data for batch validation.
 func (c *Client) mockBatchValidation(mailboxes []model.Mailbox) []model.Mailbox {
 	results := make([]model.Mailbox, len(mailboxes))
@@ -496,6 +675,11 @@ func (c *Client) mockBatchValidation(mailboxes []model.Mailbox) []model.Mailbox
 			DeliveryLine1: mb.AddressRaw.Street,
 			LastLine:      fmt.Sprintf("%s, %s %s", mb.AddressRaw.City, mb.AddressRaw.State, mb.AddressRaw.Zip),
 		}
+		mb.Analysis = mockAnalysis
+		mb.Candidates = nil
+		mb.ValidationStatus = model.ValidationVerified
+		mb.ValidationReason = ""
+		mb.Validator = Name
 		mb.LastValidatedAt = now
 		results[i] = mb
 	}
@@ -61,6 +61,51 @@ func TestClientCircuitBreaker(t *testing.T) {
 			t.Fatalf("expected all credentials exhausted, got %v", err)
 		}
 	}
+
+	got, _ := c.ValidateMailbox(context.Background(), model.Mailbox{
+		AddressRaw: model.AddressRaw{Street: "1", City: "2", State: "3", Zip: "4"},
+	})
+	if got.ValidationStatus != model.ValidationQuotaExhausted {
+		t.Errorf("status = %q, want quota_exhausted", got.ValidationStatus)
+	}
+}
+
+func TestClientDecodesAnalysis(t *testing.T) {
+	// Trimmed from a real Smarty response for a CMRA suite.
+	body := `[{"delivery_line_1":"8 The Grn Ste 100","last_line":"Dover DE 19901-3618",
+		"components":{"primary_number":"8","street_name":"The Grn","secondary_designator":"Ste","secondary_number":"100",
+			"pmb_designator":"PMB","pmb_number":"2001","city_name":"Dover","state_abbreviation":"DE","zipcode":"19901","plus4_code":"3618","delivery_point":"99"},
+		"metadata":{"record_type":"H","zip_type":"Standard","county_name":"Kent","latitude":39.15628,"longitude":-75.52451,"precision":"Zip9","time_zone":"Eastern","rdi":"Commercial"},
+		"analysis":{"dpv_match_code":"Y","dpv_footnotes":"AABB","dpv_cmra":"Y","dpv_vacant":"N","dpv_no_stat":"N"}}]`
+	rt := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewBufferString(body))}, nil
+	})
+	c := New(rt, Config{Mock: false, AuthIDs: []string{"id"}, AuthTokens: []string{"token"}})
+	got, err := c.ValidateMailbox(context.Background(), model.Mailbox{
+		AddressRaw: model.AddressRaw{Street: "8 The Green", City: "Dover", State: "DE", Zip: "19901"},
+	})
+	if err != nil {
+		t.Fatalf("ValidateMailbox: %v", err)
+	}
+
+	want := model.AddressAnalysis{
+		DPVMatchCode: "Y", DPVFootnotes: "AABB", DPVVacant: "N", DPVNoStat: "N",
+		RecordType: "H", ZipType: "Standard", CountyName: "Kent",
+		Latitude: 39.15628, Longitude: -75.52451, Precision: "Zip9", TimeZone: "Eastern",
+	}
+	if got.Analysis != want {
+		t.Errorf("analysis = %+v, want %+v", got.Analysis, want)
+	}
+	comp := got.StandardizedAddress.Components
+	if comp.PrimaryNumber != "8" || comp.SecondaryNumber != "100" || comp.PMBNumber != "2001" || comp.Plus4Code != "3618" || comp.DeliveryPoint != "99" {
+		t.Errorf("unexpected components: %+v", comp)
+	}
+	if got.CMRA != "Y" || got.RDI != "Commercial" {
+		t.Errorf("unexpected cmra/rdi: %s/%s", got.CMRA, got.RDI)
+	}
+	if got.Validator != Name {
+		t.Errorf("validator = %q, want %q", got.Validator, Name)
+	}
 }
 
 func TestClientSuccess(t *testing.T) {
@@ -121,7 +166,7 @@ func TestClientBatchSuccess(t *testing.T) {
 	// Mock batch response with input_index to map results back
 	body := `[
 		{"input_index": 0, "delivery_line_1": "123 Main St", "last_line": "Dover, DE 19901", "metadata": {"rdi": "Commercial"}, "analysis": {"dpv_cmra": "Y"}},
-		{"input_index": 1, "delivery_line_1": "456 Oak Ave", "last_line": "Newark, DE 19702", "metadata": {"rdi": "Residential"}, "analysis": {"dpv_cmra": "N"}}
+		{"input_index": 1, "delivery_line_1": "456 Oak Ave", "last_line": "Newark, DE 19702", "metadata": {"rdi": "Residential"}, "analysis": {"dpv_cmra": "N", "dpv_match_code": "D", "dpv_vacant": "Y"}}
 	]`
 
 	rt := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
@@ -170,6 +215,53 @@ func TestClientBatchSuccess(t *testing.T) {
 	if results[1].StandardizedAddress.DeliveryLine1 != "456 Oak Ave" {
 		t.Errorf("mailbox 1: unexpected address: %s", results[1].StandardizedAddress.DeliveryLine1)
 	}
+	if results[1].Analysis.DPVMatchCode != "D" || results[1].Analysis.DPVVacant != "Y" {
+		t.Errorf("mailbox 1: unexpected analysis: %+v", results[1].Analysis)
+	}
+	// DPV D confirms the building: the verdict stands, with the missing secondary noted.
+	if results[1].ValidationStatus != model.ValidationVerified || results[1].ValidationReason == "" {
+		t.Errorf("mailbox 1: status %q reason %q, want verified with a reason", results[1].ValidationStatus, results[1].ValidationReason)
+	}
+}
+
+func TestClientBatchCandidates(t *testing.T) {
+	// A multi-tenant building: the second address matches two suites, one of them a CMRA.
+	body := `[
+		{"input_index": 0, "delivery_line_1": "123 Main St", "metadata": {"rdi": "Commercial"}, "analysis": {"dpv_match_code": "Y", "dpv_cmra": "N"}},
+		{"input_index": 1, "candidate_index": 0, "delivery_line_1": "500 Tower Rd Ste 100", "metadata": {"rdi": "Commercial"}, "analysis": {"dpv_match_code": "Y", "dpv_cmra": "N"}},
+		{"input_index": 1, "candidate_index": 1, "delivery_line_1": "500 Tower Rd Ste 200", "metadata": {"rdi": "Commercial"}, "analysis": {"dpv_match_code": "Y", "dpv_cmra": "Y"}}
+	]`
+	rt := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		sent, _ := io.ReadAll(req.Body)
+		if !bytes.Contains(sent, []byte(`"candidates":3`)) {
+			t.Errorf("request does not ask for 3 candidates: %s", sent)
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewBufferString(body))}, nil
+	})
+	c := New(rt, Config{Mock: false, AuthIDs: []string{"id"}, AuthTokens: []string{"token"}, Candidates: 3})
+
+	results, err := c.ValidateMailboxBatch(context.Background(), []model.Mailbox{
+		{AddressRaw: model.AddressRaw{Street: "123 Main", City: "Dover", State: "DE", Zip: "19901"}},
+		{AddressRaw: model.AddressRaw{Street: "500 Tower Rd", City: "Dover", State: "DE", Zip: "19901"}},
+	})
+	if err != nil {
+		t.Fatalf("ValidateMailboxBatch: %v", err)
+	}
+
+	if results[0].ValidationStatus != model.ValidationVerified || len(results[0].Candidates) != 1 {
+		t.Errorf("mailbox 0: status %q with %d candidates, want verified with 1", results[0].ValidationStatus, len(results[0].Candidates))
+	}
+	got := results[1]
+	if got.ValidationStatus != model.ValidationAmbiguous || got.ValidationReason == "" {
+		t.Errorf("mailbox 1: status %q (%q), want ambiguous with a reason", got.ValidationStatus, got.ValidationReason)
+	}
+	if len(got.Candidates) != 2 || got.Candidates[1].CMRA != "Y" || got.Candidates[1].StandardizedAddress.DeliveryLine1 != "500 Tower Rd Ste 200" {
+		t.Errorf("mailbox 1: unexpected candidates: %+v", got.Candidates)
+	}
+	// The verdict still comes from the best (first) candidate.
+	if got.CMRA != "N" || got.StandardizedAddress.DeliveryLine1 != "500 Tower Rd Ste 100" {
+		t.Errorf("mailbox 1: verdict %s at %s, want the first candidate's", got.CMRA, got.StandardizedAddress.DeliveryLine1)
+	}
 }
 
 func TestClientBatchEmpty(t *testing.T) {
@@ -223,4 +315,12 @@ func TestClientBatchPartialResponse(t *testing.T) {
 	if results[1].CMRA != "" {
 		t.Errorf("mailbox 1: expected empty CMRA (not validated), got %s", results[1].CMRA)
 	}
+
+	// Addresses left out of the response are recorded as not matched
+	if results[0].ValidationStatus != model.ValidationVerified {
+		t.Errorf("mailbox 0: expected status verified, got %s", results[0].ValidationStatus)
+	}
+	if results[1].ValidationStatus != model.ValidationNoMatch || results[1].ValidationReason == "" {
+		t.Errorf("mailbox 1: expected status no_match with a reason, got %q (%q)", results[1].ValidationStatus, results[1].ValidationReason)
+	}
 }
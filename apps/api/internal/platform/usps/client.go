@@ -0,0 +1,376 @@
+package usps
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/model"
+)
+
+// Name identifies this validator on the mailboxes it validates.
+const Name = "usps"
+
+var (
+	// ErrRateLimited signals USPS kept answering 429/503 after every retry.
+	ErrRateLimited = errors.New("usps rate limit exceeded")
+	// ErrUnauthorized signals USPS rejected the client credentials.
+	ErrUnauthorized = errors.New("usps credentials rejected")
+)
+
+// HTTPClient matches net/http.Client Do signature for testability.
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Client validates addresses with the USPS Addresses API v3. It authenticates with OAuth client
+// credentials and reuses the access token until shortly before it expires.
+type Client struct {
+	httpClient   HTTPClient
+	baseURL      string
+	clientID     string
+	clientSecret string
+	maxRetries   int
+
+	mu          sync.Mutex
+	token       string
+	tokenExpiry time.Time
+}
+
+// Config defines settings for the USPS client.
+type Config struct {
+	ClientID     string
+	ClientSecret string
+	BaseURL      string // Defaults to https://apis.usps.com
+	MaxRetries   int
+}
+
+// New creates a USPS client.
+func New(httpClient HTTPClient, cfg Config) *Client {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	base := strings.TrimRight(cfg.BaseURL, "/")
+	if base == "" {
+		base = "https://apis.usps.com"
+	}
+	maxRetries := cfg.MaxRetries
+	if maxRetries <= 0 {
+		maxRetries = 3
+	}
+	return &Client{
+		httpClient:   httpClient,
+		baseURL:      base,
+		clientID:     cfg.ClientID,
+		clientSecret: cfg.ClientSecret,
+		maxRetries:   maxRetries,
+	}
+}
+
+// ValidateMailbox standardizes the mailbox's address with USPS and maps the delivery point
+// confirmation, CMRA and business flags onto it.
+func (c *Client) ValidateMailbox(ctx context.Context, mailbox model.Mailbox) (model.Mailbox, error) {
+	result, err := c.lookup(ctx, mailbox)
+	if err != nil {
+		markFailed(&result, err)
+	}
+	return result, err
+}
+
+// ValidateMailboxBatch validates mailboxes one by one, since the Addresses API has no batch
+// endpoint. A failed lookup is recorded on its mailbox and the batch goes on, unless USPS is
+// rate limiting or rejecting the credentials: then the remaining mailboxes carry that failure
+// and the error is returned.
+func (c *Client) ValidateMailboxBatch(ctx context.Context, mailboxes []model.Mailbox) ([]model.Mailbox, error) {
+	results := make([]model.Mailbox, len(mailboxes))
+	copy(results, mailboxes)
+	for i := range results {
+		validated, err := c.ValidateMailbox(ctx, results[i])
+		results[i] = validated
+		if err == nil {
+			continue
+		}
+		if errors.Is(err, ErrRateLimited) || errors.Is(err, ErrUnauthorized) || ctx.Err() != nil {
+			for k := i + 1; k < len(results); k++ {
+				markFailed(&results[k], err)
+			}
+			return results, fmt.Errorf("usps batch stopped at %d/%d: %w", i, len(results), err)
+		}
+	}
+	return results, nil
+}
+
+func (c *Client) lookup(ctx context.Context, mailbox model.Mailbox) (model.Mailbox, error) {
+	params := url.Values{}
+	params.Set("streetAddress", mailbox.AddressRaw.Street)
+	params.Set("city", mailbox.AddressRaw.City)
+	params.Set("state", mailbox.AddressRaw.State)
+	if zip := mailbox.AddressRaw.Zip; len(zip) >= 5 {
+		params.Set("ZIPCode", zip[:5]) // The API takes the 5-digit ZIP only
+	}
+	endpoint := c.baseURL + "/addresses/v3/address?" + params.Encode()
+
+	refreshed := false
+	for attempt := 0; attempt < c.maxRetries; attempt++ {
+		token, err := c.accessToken(ctx)
+		if err != nil {
+			return mailbox, err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+		if err != nil {
+			return mailbox, fmt.Errorf("build request: %w", err)
+		}
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			if attempt == c.maxRetries-1 {
+				return mailbox, fmt.Errorf("request: %w", err)
+			}
+			time.Sleep(100 * time.Millisecond) // Brief backoff
+			continue
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		switch {
+		case resp.StatusCode == http.StatusOK:
+			var decoded addressResponse
+			if err := json.Unmarshal(bytes.TrimSpace(body), &decoded); err != nil {
+				return mailbox, fmt.Errorf("decode response: %w", err)
+			}
+			applyAddress(&mailbox, decoded, time.Now().UTC())
+			return mailbox, nil
+		case resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusNotFound:
+			// USPS found no address to match, or rejected it as malformed.
+			markNoMatch(&mailbox, errorMessage(body, resp.StatusCode), time.Now().UTC())
+			return mailbox, nil
+		case resp.StatusCode == http.StatusUnauthorized && !refreshed:
+			// The token expired early or was revoked: fetch a new one once.
+			c.dropToken()
+			refreshed = true
+			attempt--
+			continue
+		case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+			return mailbox, fmt.Errorf("%w: %s", ErrUnauthorized, errorMessage(body, resp.StatusCode))
+		case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable:
+			if attempt == c.maxRetries-1 {
+				return mailbox, ErrRateLimited
+			}
+			time.Sleep(500 * time.Millisecond) // Backoff before retry
+		default:
+			if attempt == c.maxRetries-1 {
+				return mailbox, fmt.Errorf("usps status %d: %s", resp.StatusCode, errorMessage(body, resp.StatusCode))
+			}
+			time.Sleep(200 * time.Millisecond) // Backoff before retry
+		}
+	}
+	return mailbox, fmt.Errorf("usps validation failed after %d retries", c.maxRetries)
+}
+
+// accessToken returns the cached OAuth token, requesting a new one when it is missing or about
+// to expire.
+func (c *Client) accessToken(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token != "" && time.Now().Before(c.tokenExpiry) {
+		return c.token, nil
+	}
+	if c.clientID == "" || c.clientSecret == "" {
+		return "", fmt.Errorf("%w: no usps client credentials configured", ErrUnauthorized)
+	}
+
+	payload, err := json.Marshal(map[string]string{
+		"grant_type":    "client_credentials",
+		"client_id":     c.clientID,
+		"client_secret": c.clientSecret,
+	})
+	if err != nil {
+		return "", fmt.Errorf("marshal token request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/oauth2/v3/token", bytes.NewReader(payload))
+	if err != nil {
+		return "", fmt.Errorf("build token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("token request: %w", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusBadRequest:
+		return "", fmt.Errorf("%w: %s", ErrUnauthorized, errorMessage(body, resp.StatusCode))
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return "", ErrRateLimited
+	case resp.StatusCode != http.StatusOK:
+		return "", fmt.Errorf("usps token status %d: %s", resp.StatusCode, errorMessage(body, resp.StatusCode))
+	}
+
+	var tok tokenResponse
+	if err := json.Unmarshal(body, &tok); err != nil {
+		return "", fmt.Errorf("decode token response: %w", err)
+	}
+	if tok.AccessToken == "" {
+		return "", errors.New("usps token response has no access_token")
+	}
+	// Renew a minute early so a token never expires mid-request.
+	ttl := time.Duration(tok.ExpiresIn)*time.Second - time.Minute
+	if ttl <= 0 {
+		ttl = 0
+	}
+	c.token = tok.AccessToken
+	c.tokenExpiry = time.Now().Add(ttl)
+	return c.token, nil
+}
+
+func (c *Client) dropToken() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = ""
+}
+
+// applyAddress copies the USPS standardized address and its delivery point flags onto the mailbox.
+// USPS has no RDI; its business flag answers the same question.
+func applyAddress(mailbox *model.Mailbox, r addressResponse, now time.Time) {
+	info := r.AdditionalInfo
+	lastLine := fmt.Sprintf("%s %s %s", r.Address.City, r.Address.State, r.Address.ZIPCode)
+	if r.Address.ZIPPlus4 != "" {
+		lastLine += "-" + r.Address.ZIPPlus4
+	}
+	cand := model.AddressCandidate{
+		StandardizedAddress: model.StandardizedAddress{
+			DeliveryLine1: strings.TrimSpace(r.Address.StreetAddress + " " + r.Address.SecondaryAddress),
+			LastLine:      lastLine,
+			Components: model.AddressComponents{
+				CityName:          r.Address.City,
+				StateAbbreviation: r.Address.State,
+				Zipcode:           r.Address.ZIPCode,
+				Plus4Code:         r.Address.ZIPPlus4,
+				DeliveryPoint:     info.DeliveryPoint,
+			},
+		},
+		CMRA: info.DPVCMRA,
+		RDI:  businessRDI(info.Business),
+		Analysis: model.AddressAnalysis{
+			DPVMatchCode: info.DPVConfirmation,
+			DPVVacant:    info.Vacant,
+		},
+	}
+
+	mailbox.StandardizedAddress = cand.StandardizedAddress
+	mailbox.CMRA = cand.CMRA
+	mailbox.RDI = cand.RDI
+	mailbox.Analysis = cand.Analysis
+	mailbox.Candidates = []model.AddressCandidate{cand}
+	mailbox.ValidationStatus, mailbox.ValidationReason = confirmationStatus(info)
+	mailbox.Validator = Name
+	mailbox.LastValidatedAt = now
+}
+
+func businessRDI(business string) string {
+	switch business {
+	case "Y":
+		return "Commercial"
+	case "N":
+		return "Residential"
+	}
+	return ""
+}
+
+// confirmationStatus grades a result by its DPV confirmation, which uses the same codes as Smarty's
+// DPV match code and is graded the same way: S and D are verified with the reason noted.
+func confirmationStatus(info additionalInfo) (status, reason string) {
+	switch info.DPVConfirmation {
+	case "Y":
+		return model.ValidationVerified, ""
+	case "S":
+		return model.ValidationVerified, "secondary address not confirmed (DPV S)"
+	case "D":
+		return model.ValidationVerified, "secondary address missing (DPV D)"
+	case "N":
+		return model.ValidationNoMatch, "delivery point not confirmed (DPV N)"
+	}
+	if info.DPVCMRA == "" {
+		return model.ValidationNoMatch, "no DPV confirmation returned"
+	}
+	return model.ValidationVerified, ""
+}
+
+// markNoMatch records that USPS found no address to match; an earlier verdict is cleared.
+func markNoMatch(mailbox *model.Mailbox, reason string, now time.Time) {
+	mailbox.CMRA = ""
+	mailbox.RDI = ""
+	mailbox.StandardizedAddress = model.StandardizedAddress{}
+	mailbox.Analysis = model.AddressAnalysis{}
+	mailbox.Candidates = nil
+	mailbox.ValidationStatus = model.ValidationNoMatch
+	mailbox.ValidationReason = reason
+	mailbox.Validator = Name
+	mailbox.LastValidatedAt = now
+}
+
+// markFailed records a lookup that failed; the mailbox keeps whatever verdict it had.
+func markFailed(mailbox *model.Mailbox, err error) {
+	mailbox.ValidationStatus = model.ValidationError
+	if errors.Is(err, ErrRateLimited) {
+		mailbox.ValidationStatus = model.ValidationQuotaExhausted
+	}
+	mailbox.ValidationReason = err.Error()
+	mailbox.Validator = Name
+}
+
+// errorMessage pulls the message out of a USPS error body, falling back to the status text.
+func errorMessage(body []byte, status int) string {
+	var e errorResponse
+	if err := json.Unmarshal(body, &e); err == nil && e.Error.Message != "" {
+		return e.Error.Message
+	}
+	return http.StatusText(status)
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"` // Seconds
+}
+
+type addressResponse struct {
+	Firm           string         `json:"firm"`
+	Address        address        `json:"address"`
+	AdditionalInfo additionalInfo `json:"additionalInfo"`
+}
+
+type address struct {
+	StreetAddress    string `json:"streetAddress"`
+	SecondaryAddress string `json:"secondaryAddress"`
+	City             string `json:"city"`
+	State            string `json:"state"`
+	ZIPCode          string `json:"ZIPCode"`
+	ZIPPlus4         string `json:"ZIPPlus4"`
+}
+
+type additionalInfo struct {
+	DeliveryPoint   string `json:"deliveryPoint"`
+	DPVConfirmation string `json:"DPVConfirmation"` // Y, S, D or N
+	DPVCMRA         string `json:"DPVCMRA"`         // "Y" or "N"
+	Business        string `json:"business"`        // "Y" for a business address
+	Vacant          string `json:"vacant"`
+}
+
+type errorResponse struct {
+	Error struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
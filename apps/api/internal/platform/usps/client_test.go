@@ -0,0 +1,202 @@
+package usps
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/model"
+)
+
+// fakeUSPS stands in for the token and address endpoints. Addresses are answered by street:
+// "404 Nowhere" is not found, "429 Busy" is rate limited, anything else is a confirmed CMRA.
+type fakeUSPS struct {
+	tokens      atomic.Int32
+	lookups     atomic.Int32
+	rejectFirst atomic.Bool // Answer the next lookup 401, as if the token had been revoked
+}
+
+func (f *fakeUSPS) handler(t *testing.T) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /oauth2/v3/token", func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode token request: %v", err)
+		}
+		if req["grant_type"] != "client_credentials" || req["client_id"] != "id" || req["client_secret"] != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":{"message":"invalid client"}}`))
+			return
+		}
+		n := f.tokens.Add(1)
+		_ = json.NewEncoder(w).Encode(map[string]any{"access_token": fmt.Sprintf("token-%d", n), "expires_in": 28800})
+	})
+	mux.HandleFunc("GET /addresses/v3/address", func(w http.ResponseWriter, r *http.Request) {
+		f.lookups.Add(1)
+		if r.Header.Get("Authorization") == "" || f.rejectFirst.CompareAndSwap(true, false) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		q := r.URL.Query()
+		switch q.Get("streetAddress") {
+		case "404 Nowhere":
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":{"message":"Address Not Found."}}`))
+			return
+		case "429 Busy":
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		case "7 No Suite":
+			_, _ = w.Write([]byte(`{"address":{"streetAddress":"7 NO SUITE","city":"DOVER","state":"DE","ZIPCode":"19901"},
+				"additionalInfo":{"DPVConfirmation":"D","DPVCMRA":"N","business":"Y"}}`))
+			return
+		}
+		if q.Get("ZIPCode") != "19901" {
+			t.Errorf("ZIPCode = %q, want the 5-digit ZIP", q.Get("ZIPCode"))
+		}
+		_, _ = w.Write([]byte(`{"firm":"","address":{"streetAddress":"8 THE GRN","secondaryAddress":"STE 100",
+			"city":"DOVER","state":"DE","ZIPCode":"19901","ZIPPlus4":"3618"},
+			"additionalInfo":{"deliveryPoint":"99","DPVConfirmation":"Y","DPVCMRA":"Y","business":"Y","vacant":"N"}}`))
+	})
+	return mux
+}
+
+func newTestClient(t *testing.T) (*Client, *fakeUSPS) {
+	t.Helper()
+	fake := &fakeUSPS{}
+	srv := httptest.NewServer(fake.handler(t))
+	t.Cleanup(srv.Close)
+	return New(srv.Client(), Config{ClientID: "id", ClientSecret: "secret", BaseURL: srv.URL, MaxRetries: 1}), fake
+}
+
+func mailboxAt(street string) model.Mailbox {
+	return model.Mailbox{AddressRaw: model.AddressRaw{Street: street, City: "Dover", State: "DE", Zip: "19901-3618"}}
+}
+
+func TestClientMapsAddress(t *testing.T) {
+	c, fake := newTestClient(t)
+
+	got, err := c.ValidateMailbox(context.Background(), mailboxAt("8 The Green Ste 100"))
+	if err != nil {
+		t.Fatalf("ValidateMailbox: %v", err)
+	}
+	if got.CMRA != "Y" || got.RDI != "Commercial" {
+		t.Errorf("CMRA/RDI = %q/%q, want Y/Commercial", got.CMRA, got.RDI)
+	}
+	if got.StandardizedAddress.DeliveryLine1 != "8 THE GRN STE 100" || got.StandardizedAddress.LastLine != "DOVER DE 19901-3618" {
+		t.Errorf("standardized address = %+v", got.StandardizedAddress)
+	}
+	if got.StandardizedAddress.Components.Plus4Code != "3618" || got.StandardizedAddress.Components.DeliveryPoint != "99" {
+		t.Errorf("components = %+v", got.StandardizedAddress.Components)
+	}
+	if got.Analysis.DPVMatchCode != "Y" || got.Analysis.DPVVacant != "N" {
+		t.Errorf("analysis = %+v", got.Analysis)
+	}
+	if got.ValidationStatus != model.ValidationVerified || got.Validator != Name || got.LastValidatedAt.IsZero() {
+		t.Errorf("status = %q, validator = %q, validated at %v", got.ValidationStatus, got.Validator, got.LastValidatedAt)
+	}
+	if len(got.Candidates) != 1 || got.Candidates[0].CMRA != "Y" {
+		t.Errorf("candidates = %+v", got.Candidates)
+	}
+
+	if _, err := c.ValidateMailbox(context.Background(), mailboxAt("8 The Green Ste 100")); err != nil {
+		t.Fatalf("second ValidateMailbox: %v", err)
+	}
+	if n := fake.tokens.Load(); n != 1 {
+		t.Errorf("fetched %d tokens, want the first one reused", n)
+	}
+}
+
+func TestClientVerifiesMissingSecondary(t *testing.T) {
+	c, _ := newTestClient(t)
+
+	got, err := c.ValidateMailbox(context.Background(), mailboxAt("7 No Suite"))
+	if err != nil {
+		t.Fatalf("ValidateMailbox: %v", err)
+	}
+	// DPV D confirms the building, so the verdict stands and the missing suite is only noted.
+	if got.ValidationStatus != model.ValidationVerified || got.ValidationReason != "secondary address missing (DPV D)" {
+		t.Errorf("status = %q (%q), want verified with the DPV D reason", got.ValidationStatus, got.ValidationReason)
+	}
+	if got.CMRA != "N" || got.RDI != "Commercial" {
+		t.Errorf("CMRA/RDI = %q/%q, want N/Commercial", got.CMRA, got.RDI)
+	}
+}
+
+func TestClientNoMatchClearsVerdict(t *testing.T) {
+	c, _ := newTestClient(t)
+	m := mailboxAt("404 Nowhere")
+	m.CMRA, m.RDI = "Y", "Commercial"
+
+	got, err := c.ValidateMailbox(context.Background(), m)
+	if err != nil {
+		t.Fatalf("ValidateMailbox: %v", err)
+	}
+	if got.ValidationStatus != model.ValidationNoMatch || got.ValidationReason != "Address Not Found." {
+		t.Errorf("status = %q (%q), want no_match", got.ValidationStatus, got.ValidationReason)
+	}
+	if got.CMRA != "" || got.RDI != "" {
+		t.Errorf("expected the earlier verdict cleared, got %q/%q", got.CMRA, got.RDI)
+	}
+}
+
+func TestClientRefreshesRejectedToken(t *testing.T) {
+	c, fake := newTestClient(t)
+	if _, err := c.ValidateMailbox(context.Background(), mailboxAt("8 The Green")); err != nil {
+		t.Fatalf("ValidateMailbox: %v", err)
+	}
+	fake.rejectFirst.Store(true)
+
+	got, err := c.ValidateMailbox(context.Background(), mailboxAt("8 The Green"))
+	if err != nil {
+		t.Fatalf("ValidateMailbox after 401: %v", err)
+	}
+	if got.ValidationStatus != model.ValidationVerified {
+		t.Errorf("status = %q, want verified", got.ValidationStatus)
+	}
+	if n := fake.tokens.Load(); n != 2 {
+		t.Errorf("fetched %d tokens, want a fresh one after the 401", n)
+	}
+}
+
+func TestClientBadCredentials(t *testing.T) {
+	fake := &fakeUSPS{}
+	srv := httptest.NewServer(fake.handler(t))
+	defer srv.Close()
+	c := New(srv.Client(), Config{ClientID: "id", ClientSecret: "wrong", BaseURL: srv.URL, MaxRetries: 1})
+
+	got, err := c.ValidateMailbox(context.Background(), mailboxAt("8 The Green"))
+	if !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("err = %v, want ErrUnauthorized", err)
+	}
+	if got.ValidationStatus != model.ValidationError || got.Validator != Name {
+		t.Errorf("status = %q, validator = %q", got.ValidationStatus, got.Validator)
+	}
+	if fake.lookups.Load() != 0 {
+		t.Errorf("looked up an address without a token")
+	}
+}
+
+func TestClientBatchStopsWhenRateLimited(t *testing.T) {
+	c, fake := newTestClient(t)
+	batch := []model.Mailbox{mailboxAt("8 The Green"), mailboxAt("404 Nowhere"), mailboxAt("429 Busy"), mailboxAt("9 The Green")}
+
+	got, err := c.ValidateMailboxBatch(context.Background(), batch)
+	if !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("err = %v, want ErrRateLimited", err)
+	}
+	want := []string{model.ValidationVerified, model.ValidationNoMatch, model.ValidationQuotaExhausted, model.ValidationQuotaExhausted}
+	for i, status := range want {
+		if got[i].ValidationStatus != status {
+			t.Errorf("mailbox %d status = %q, want %q", i, got[i].ValidationStatus, status)
+		}
+	}
+	if n := fake.lookups.Load(); n != 3 {
+		t.Errorf("made %d lookups, want the batch to stop at the rate limit", n)
+	}
+}
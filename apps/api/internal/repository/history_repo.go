@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"cloud.google.com/go/firestore"
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/model"
+	"google.golang.org/api/iterator"
+)
+
+// defaultHistoryLimit caps history reads when the caller does not specify a limit.
+const defaultHistoryLimit = 100
+
+// HistoryRepository stores mailbox change records in the `mailbox_history` collection
+// and observed prices in the `price_history` collection.
+type HistoryRepository struct {
+	client *firestore.Client
+}
+
+func NewHistoryRepository(client *firestore.Client) *HistoryRepository {
+	return &HistoryRepository{client: client}
+}
+
+// AppendHistory writes change records with auto-generated IDs.
+func (r *HistoryRepository) AppendHistory(ctx context.Context, entries []model.MailboxHistory) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	const batchSize = 400
+
+	for start := 0; start < len(entries); start += batchSize {
+		end := start + batchSize
+		if end > len(entries) {
+			end = len(entries)
+		}
+		batch := r.client.Batch()
+		for _, h := range entries[start:end] {
+			batch.Create(r.client.Collection("mailbox_history").NewDoc(), h)
+		}
+		if _, err := batch.Commit(ctx); err != nil {
+			return fmt.Errorf("commit history batch [%d:%d]: %w", start, end, err)
+		}
+	}
+	return nil
+}
+
+// ListHistory returns the change records of one mailbox, newest first.
+func (r *HistoryRepository) ListHistory(ctx context.Context, mailboxID string, limit int) ([]model.MailboxHistory, error) {
+	if mailboxID == "" {
+		return nil, fmt.Errorf("mailboxId is required")
+	}
+	if limit <= 0 {
+		limit = defaultHistoryLimit
+	}
+	iter := r.client.Collection("mailbox_history").
+		Where("mailboxId", "==", mailboxID).
+		OrderBy("changedAt", firestore.Desc).
+		Limit(limit).
+		Documents(ctx)
+
+	var items []model.MailboxHistory
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("list history %s: %w", mailboxID, err)
+		}
+		var h model.MailboxHistory
+		if err := doc.DataTo(&h); err != nil {
+			return nil, fmt.Errorf("decode history %s: %w", doc.Ref.ID, err)
+		}
+		h.ID = doc.Ref.ID
+		items = append(items, h)
+	}
+	return items, nil
+}
+
+// AppendPrices writes price observations to the `price_history` collection with auto-generated IDs.
+func (r *HistoryRepository) AppendPrices(ctx context.Context, points []model.PricePoint) error {
+	if len(points) == 0 {
+		return nil
+	}
+	const batchSize = 400
+
+	for start := 0; start < len(points); start += batchSize {
+		end := start + batchSize
+		if end > len(points) {
+			end = len(points)
+		}
+		batch := r.client.Batch()
+		for _, p := range points[start:end] {
+			batch.Create(r.client.Collection("price_history").NewDoc(), p)
+		}
+		if _, err := batch.Commit(ctx); err != nil {
+			return fmt.Errorf("commit price batch [%d:%d]: %w", start, end, err)
+		}
+	}
+	return nil
+}
+
+// ListPrices returns the most recent price observations of one mailbox, oldest first.
+func (r *HistoryRepository) ListPrices(ctx context.Context, mailboxID string, limit int) ([]model.PricePoint, error) {
+	if mailboxID == "" {
+		return nil, fmt.Errorf("mailboxId is required")
+	}
+	if limit <= 0 {
+		limit = defaultHistoryLimit
+	}
+	iter := r.client.Collection("price_history").
+		Where("mailboxId", "==", mailboxID).
+		OrderBy("observedAt", firestore.Desc).
+		Limit(limit).
+		Documents(ctx)
+
+	points, err := collectPricePoints(iter)
+	if err != nil {
+		return nil, fmt.Errorf("list prices %s: %w", mailboxID, err)
+	}
+	reversePricePoints(points)
+	return points, nil
+}
+
+// ListRunPrices returns every price observed during one run.
+func (r *HistoryRepository) ListRunPrices(ctx context.Context, runID string) ([]model.PricePoint, error) {
+	if runID == "" {
+		return nil, fmt.Errorf("runId is required")
+	}
+	iter := r.client.Collection("price_history").
+		Where("crawlRunId", "==", runID).
+		Documents(ctx)
+
+	points, err := collectPricePoints(iter)
+	if err != nil {
+		return nil, fmt.Errorf("list run prices %s: %w", runID, err)
+	}
+	return points, nil
+}
+
+func collectPricePoints(iter *firestore.DocumentIterator) ([]model.PricePoint, error) {
+	defer iter.Stop()
+	var points []model.PricePoint
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		var p model.PricePoint
+		if err := doc.DataTo(&p); err != nil {
+			return nil, fmt.Errorf("decode price %s: %w", doc.Ref.ID, err)
+		}
+		p.ID = doc.Ref.ID
+		points = append(points, p)
+	}
+	return points, nil
+}
+
+// reversePricePoints flips a newest-first query result into chronological order.
+func reversePricePoints(points []model.PricePoint) {
+	for i, j := 0, len(points)-1; i < j; i, j = i+1, j-1 {
+		points[i], points[j] = points[j], points[i]
+	}
+}
@@ -0,0 +1,304 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/model"
+)
+
+// JobRepository keeps the crawl job queue in the jobs collection. Every state change runs in a
+// transaction, so workers on different instances never claim the same job.
+type JobRepository struct {
+	client *firestore.Client
+}
+
+func NewJobRepository(client *firestore.Client) *JobRepository {
+	return &JobRepository{client: client}
+}
+
+func (r *JobRepository) EnqueueJob(ctx context.Context, job model.Job) (model.Job, bool, error) {
+	if job.ID == "" {
+		return model.Job{}, false, fmt.Errorf("job id is required")
+	}
+	jobs := r.client.Collection("jobs")
+	var holder model.Job
+	enqueued := false
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		enqueued = false
+		snaps, err := tx.Documents(jobs.Where("lockKey", "==", job.LockKey)).GetAll()
+		if err != nil {
+			return err
+		}
+		for _, snap := range snaps {
+			var other model.Job
+			if err := snap.DataTo(&other); err != nil {
+				return err
+			}
+			if jobActive(other) {
+				holder = other
+				return nil
+			}
+		}
+		enqueued = true
+		return tx.Set(jobs.Doc(job.ID), queuedJob(job))
+	})
+	if err != nil {
+		return model.Job{}, false, fmt.Errorf("enqueue job %s: %w", job.ID, err)
+	}
+	if !enqueued {
+		return holder, false, nil
+	}
+	return queuedJob(job), true, nil
+}
+
+func (r *JobRepository) ClaimJob(ctx context.Context, owner string, leaseUntil time.Time) (model.Job, bool, error) {
+	queued, err := r.query(ctx, r.client.Collection("jobs").Where("status", "==", model.JobQueued))
+	if err != nil {
+		return model.Job{}, false, fmt.Errorf("claim job: %w", err)
+	}
+	sortJobs(queued)
+	for _, candidate := range queued {
+		job, ok, err := r.update(ctx, candidate.ID, func(j *model.Job) bool {
+			if j.Status != model.JobQueued {
+				return false // Claimed by another worker meanwhile.
+			}
+			claimJob(j, owner, leaseUntil)
+			return true
+		})
+		if err != nil {
+			return model.Job{}, false, fmt.Errorf("claim job: %w", err)
+		}
+		if ok {
+			return job, true, nil
+		}
+	}
+	return model.Job{}, false, nil
+}
+
+func (r *JobRepository) HeartbeatJob(ctx context.Context, id, owner string, leaseUntil time.Time) (model.Job, bool, error) {
+	job, ok, err := r.update(ctx, id, func(j *model.Job) bool {
+		if !leasedTo(*j, owner) {
+			return false
+		}
+		j.LeaseExpiresAt = leaseUntil
+		return true
+	})
+	if err != nil {
+		return model.Job{}, false, fmt.Errorf("heartbeat job %s: %w", id, err)
+	}
+	return job, ok, nil
+}
+
+func (r *JobRepository) CompleteJob(ctx context.Context, id, owner, status, errMsg string) error {
+	_, ok, err := r.update(ctx, id, func(j *model.Job) bool {
+		if !leasedTo(*j, owner) {
+			return false
+		}
+		completeJob(j, status, errMsg, time.Now().UTC())
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("complete job %s: %w", id, err)
+	}
+	if !ok {
+		return fmt.Errorf("complete job %s: lease no longer held by %s", id, owner)
+	}
+	return nil
+}
+
+func (r *JobRepository) RequeueExpiredJobs(ctx context.Context, now time.Time, maxAttempts int) ([]model.Job, error) {
+	running, err := r.query(ctx, r.client.Collection("jobs").Where("status", "==", model.JobRunning))
+	if err != nil {
+		return nil, fmt.Errorf("requeue jobs: %w", err)
+	}
+	var changed []model.Job
+	for _, candidate := range running {
+		if candidate.LeaseExpiresAt.After(now) {
+			continue
+		}
+		job, ok, err := r.update(ctx, candidate.ID, func(j *model.Job) bool {
+			return expireJob(j, now, maxAttempts)
+		})
+		if err != nil {
+			return changed, fmt.Errorf("requeue jobs: %w", err)
+		}
+		if ok {
+			changed = append(changed, job)
+		}
+	}
+	return changed, nil
+}
+
+func (r *JobRepository) RequestJobCancel(ctx context.Context, id string) (model.Job, error) {
+	job, _, err := r.update(ctx, id, func(j *model.Job) bool {
+		return cancelJob(j, time.Now().UTC())
+	})
+	if err != nil {
+		return model.Job{}, fmt.Errorf("cancel job %s: %w", id, err)
+	}
+	return job, nil
+}
+
+func (r *JobRepository) RequestJobPause(ctx context.Context, id string, paused bool) (model.Job, bool, error) {
+	job, changed, err := r.update(ctx, id, func(j *model.Job) bool {
+		return pauseJob(j, paused)
+	})
+	if err != nil {
+		return model.Job{}, false, fmt.Errorf("pause job %s: %w", id, err)
+	}
+	return job, changed, nil
+}
+
+func (r *JobRepository) GetJob(ctx context.Context, id string) (model.Job, error) {
+	if id == "" {
+		return model.Job{}, fmt.Errorf("job id is required")
+	}
+	snap, err := r.client.Collection("jobs").Doc(id).Get(ctx)
+	if err != nil {
+		return model.Job{}, fmt.Errorf("get job %s: %w", id, err)
+	}
+	var job model.Job
+	if err := snap.DataTo(&job); err != nil {
+		return model.Job{}, fmt.Errorf("decode job %s: %w", id, err)
+	}
+	return job, nil
+}
+
+func (r *JobRepository) query(ctx context.Context, q firestore.Query) ([]model.Job, error) {
+	snaps, err := q.Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	jobs := make([]model.Job, 0, len(snaps))
+	for _, snap := range snaps {
+		var job model.Job
+		if err := snap.DataTo(&job); err != nil {
+			return nil, fmt.Errorf("decode job %s: %w", snap.Ref.ID, err)
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// update applies fn to the stored job in a transaction, saving it only if fn reports a change.
+func (r *JobRepository) update(ctx context.Context, id string, fn func(*model.Job) bool) (model.Job, bool, error) {
+	ref := r.client.Collection("jobs").Doc(id)
+	var job model.Job
+	changed := false
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		changed = false
+		snap, err := tx.Get(ref)
+		if err != nil {
+			return err
+		}
+		job = model.Job{}
+		if err := snap.DataTo(&job); err != nil {
+			return err
+		}
+		if !fn(&job) {
+			return nil
+		}
+		changed = true
+		return tx.Set(ref, job)
+	})
+	return job, changed, err
+}
+
+// The queue rules below are shared by every backend.
+
+// jobActive reports whether a job still occupies its LockKey.
+func jobActive(j model.Job) bool {
+	return j.Status == model.JobQueued || j.Status == model.JobRunning
+}
+
+func queuedJob(j model.Job) model.Job {
+	j.Status = model.JobQueued
+	j.Owner = ""
+	j.LeaseExpiresAt = time.Time{}
+	j.CancelRequested = false
+	j.Error = ""
+	j.FinishedAt = time.Time{}
+	if j.EnqueuedAt.IsZero() {
+		j.EnqueuedAt = time.Now().UTC()
+	}
+	return j
+}
+
+// sortJobs orders jobs oldest first, the order workers claim them in.
+func sortJobs(jobs []model.Job) {
+	sort.Slice(jobs, func(i, k int) bool {
+		if !jobs[i].EnqueuedAt.Equal(jobs[k].EnqueuedAt) {
+			return jobs[i].EnqueuedAt.Before(jobs[k].EnqueuedAt)
+		}
+		return jobs[i].ID < jobs[k].ID
+	})
+}
+
+func claimJob(j *model.Job, owner string, leaseUntil time.Time) {
+	j.Status = model.JobRunning
+	j.Owner = owner
+	j.LeaseExpiresAt = leaseUntil
+	j.Attempts++
+}
+
+func leasedTo(j model.Job, owner string) bool {
+	return j.Status == model.JobRunning && j.Owner == owner
+}
+
+func completeJob(j *model.Job, status, errMsg string, now time.Time) {
+	j.Status = status
+	j.Error = errMsg
+	j.Owner = ""
+	j.LeaseExpiresAt = time.Time{}
+	j.FinishedAt = now
+}
+
+// expireJob requeues a running job whose lease lapsed, or fails it after maxAttempts claims.
+func expireJob(j *model.Job, now time.Time, maxAttempts int) bool {
+	if j.Status != model.JobRunning || j.LeaseExpiresAt.After(now) {
+		return false
+	}
+	if j.CancelRequested {
+		completeJob(j, model.JobCancelled, "worker lost while cancelling", now)
+		return true
+	}
+	if maxAttempts > 0 && j.Attempts >= maxAttempts {
+		completeJob(j, model.JobFailed, fmt.Sprintf("lease expired after %d attempts", j.Attempts), now)
+		return true
+	}
+	j.Status = model.JobQueued
+	j.Owner = ""
+	j.LeaseExpiresAt = time.Time{}
+	j.Error = fmt.Sprintf("lease expired on attempt %d", j.Attempts)
+	return true
+}
+
+// cancelJob cancels a queued job and flags a running one; finished jobs are left alone.
+func cancelJob(j *model.Job, now time.Time) bool {
+	switch j.Status {
+	case model.JobQueued:
+		completeJob(j, model.JobCancelled, "", now)
+		return true
+	case model.JobRunning:
+		if j.CancelRequested {
+			return false
+		}
+		j.CancelRequested = true
+		return true
+	default:
+		return false
+	}
+}
+
+// pauseJob flags a running job to pause (or to resume); other jobs are left alone.
+func pauseJob(j *model.Job, paused bool) bool {
+	if j.Status != model.JobRunning || j.PauseRequested == paused {
+		return false
+	}
+	j.PauseRequested = paused
+	return true
+}
@@ -51,9 +51,11 @@ func (r *MailboxRepository) FetchAllMap(ctx context.Context) (map[string]model.M
 // FetchAllMetadata loads only essential fields for deduplication (excludes RawHTML).
 // This is ~90% faster than FetchAllMap as it doesn't load the large RawHTML field.
 func (r *MailboxRepository) FetchAllMetadata(ctx context.Context) (map[string]model.Mailbox, error) {
-	// Select only the fields needed for scraper deduplication
+	// Select only the fields needed for scraper deduplication, change history diffs and price-only updates
 	iter := r.client.Collection("mailboxes").
-		Select("link", "dataHash", "cmra", "rdi", "id").
+		Select("link", "dataHash", "cmra", "rdi", "id", "name", "price", "plans", "addressRaw", "active", "source",
+			"standardizedAddress", "analysis", "candidates", "validationStatus", "validationReason", "validator",
+			"verdicts", "confidence", "validatorsDisagree", "lastValidatedAt").
 		Documents(ctx)
 
 	result := make(map[string]model.Mailbox)
@@ -109,15 +111,37 @@ func (r *MailboxRepository) BatchUpsert(ctx context.Context, mailboxes []model.M
 	return nil
 }
 
+func (r *MailboxRepository) Get(ctx context.Context, id string) (model.Mailbox, error) {
+	if id == "" {
+		return model.Mailbox{}, fmt.Errorf("mailbox id is required")
+	}
+	snap, err := r.client.Collection("mailboxes").Doc(id).Get(ctx)
+	if err != nil {
+		return model.Mailbox{}, fmt.Errorf("get mailbox %s: %w", id, err)
+	}
+	var m model.Mailbox
+	if err := snap.DataTo(&m); err != nil {
+		return model.Mailbox{}, fmt.Errorf("decode mailbox %s: %w", id, err)
+	}
+	if m.ID == "" {
+		m.ID = id
+	}
+	return m, nil
+}
+
 // MailboxQuery represents filters and pagination options.
 type MailboxQuery struct {
-	State    string
-	CMRA     string
-	RDI      string
-	Source   string
-	Active   *bool
-	Page     int
-	PageSize int
+	State        string
+	CMRA         string
+	RDI          string
+	Source       string
+	DPVMatchCode string // Smarty's dpv_match_code: Y, S, D or N
+	Vacant       string // Smarty's dpv_vacant: Y or N
+	Status       string // One of the model.Validation* statuses
+	Disagree     bool   // Only mailboxes whose consensus validators disagreed
+	Active       *bool
+	Page         int
+	PageSize     int
 }
 
 // List returns filtered mailboxes with pagination and total count.
@@ -129,22 +153,7 @@ func (r *MailboxRepository) List(ctx context.Context, q MailboxQuery) ([]model.M
 		q.PageSize = 50
 	}
 
-	query := r.client.Collection("mailboxes").Query
-	if q.State != "" {
-		query = query.Where("addressRaw.state", "==", q.State)
-	}
-	if q.CMRA != "" {
-		query = query.Where("cmra", "==", q.CMRA)
-	}
-	if q.RDI != "" {
-		query = query.Where("rdi", "==", q.RDI)
-	}
-	if q.Source != "" {
-		query = query.Where("source", "==", q.Source)
-	}
-	if q.Active != nil {
-		query = query.Where("active", "==", *q.Active)
-	}
+	query := applyMailboxFilters(r.client.Collection("mailboxes").Query, q)
 
 	// Use Firestore Aggregation Count API for efficient counting (SDK v1.11+)
 	countQuery := query.NewAggregationQuery().WithCount("total")
@@ -209,22 +218,7 @@ func (r *MailboxRepository) StreamAll(ctx context.Context, activeOnly bool, fn f
 
 // StreamWithQuery streams mailboxes with filters to a callback without loading all into memory.
 func (r *MailboxRepository) StreamWithQuery(ctx context.Context, q MailboxQuery, fn func(model.Mailbox) error) error {
-	query := r.client.Collection("mailboxes").Query
-	if q.State != "" {
-		query = query.Where("addressRaw.state", "==", q.State)
-	}
-	if q.CMRA != "" {
-		query = query.Where("cmra", "==", q.CMRA)
-	}
-	if q.RDI != "" {
-		query = query.Where("rdi", "==", q.RDI)
-	}
-	if q.Source != "" {
-		query = query.Where("source", "==", q.Source)
-	}
-	if q.Active != nil {
-		query = query.Where("active", "==", *q.Active)
-	}
+	query := applyMailboxFilters(r.client.Collection("mailboxes").Query, q)
 
 	iter := query.Documents(ctx)
 	for {
@@ -248,6 +242,38 @@ func (r *MailboxRepository) StreamWithQuery(ctx context.Context, q MailboxQuery,
 	}
 }
 
+// applyMailboxFilters adds the MailboxQuery equality filters to a Firestore query.
+func applyMailboxFilters(query firestore.Query, q MailboxQuery) firestore.Query {
+	if q.State != "" {
+		query = query.Where("addressRaw.state", "==", q.State)
+	}
+	if q.CMRA != "" {
+		query = query.Where("cmra", "==", q.CMRA)
+	}
+	if q.RDI != "" {
+		query = query.Where("rdi", "==", q.RDI)
+	}
+	if q.Source != "" {
+		query = query.Where("source", "==", q.Source)
+	}
+	if q.DPVMatchCode != "" {
+		query = query.Where("analysis.dpvMatchCode", "==", q.DPVMatchCode)
+	}
+	if q.Vacant != "" {
+		query = query.Where("analysis.dpvVacant", "==", q.Vacant)
+	}
+	if q.Status != "" {
+		query = query.Where("validationStatus", "==", q.Status)
+	}
+	if q.Disagree {
+		query = query.Where("validatorsDisagree", "==", true)
+	}
+	if q.Active != nil {
+		query = query.Where("active", "==", *q.Active)
+	}
+	return query
+}
+
 func documentID(m model.Mailbox) string {
 	if m.ID != "" {
 		return m.ID
@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/model"
+)
+
+// MemoryHistoryRepository keeps mailbox change records and price observations in process memory.
+type MemoryHistoryRepository struct {
+	mu      sync.RWMutex
+	nextID  int
+	entries []model.MailboxHistory
+	prices  []model.PricePoint
+}
+
+func NewMemoryHistoryRepository() *MemoryHistoryRepository {
+	return &MemoryHistoryRepository{}
+}
+
+// AppendHistory stores change records with sequential IDs.
+func (r *MemoryHistoryRepository) AppendHistory(ctx context.Context, entries []model.MailboxHistory) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, h := range entries {
+		r.nextID++
+		h.ID = strconv.Itoa(r.nextID)
+		r.entries = append(r.entries, h)
+	}
+	return nil
+}
+
+// ListHistory returns the change records of one mailbox, newest first.
+func (r *MemoryHistoryRepository) ListHistory(ctx context.Context, mailboxID string, limit int) ([]model.MailboxHistory, error) {
+	if mailboxID == "" {
+		return nil, fmt.Errorf("mailboxId is required")
+	}
+	if limit <= 0 {
+		limit = defaultHistoryLimit
+	}
+	r.mu.RLock()
+	var items []model.MailboxHistory
+	for i := len(r.entries) - 1; i >= 0; i-- {
+		if r.entries[i].MailboxID == mailboxID {
+			items = append(items, r.entries[i])
+		}
+	}
+	r.mu.RUnlock()
+
+	// Entries are appended in order, so a stable sort keeps insertion order for equal timestamps.
+	sort.SliceStable(items, func(i, j int) bool { return items[i].ChangedAt.After(items[j].ChangedAt) })
+	if len(items) > limit {
+		items = items[:limit]
+	}
+	return items, nil
+}
+
+// AppendPrices stores price observations with sequential IDs.
+func (r *MemoryHistoryRepository) AppendPrices(ctx context.Context, points []model.PricePoint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, p := range points {
+		r.nextID++
+		p.ID = strconv.Itoa(r.nextID)
+		r.prices = append(r.prices, p)
+	}
+	return nil
+}
+
+// ListPrices returns the most recent price observations of one mailbox, oldest first.
+func (r *MemoryHistoryRepository) ListPrices(ctx context.Context, mailboxID string, limit int) ([]model.PricePoint, error) {
+	if mailboxID == "" {
+		return nil, fmt.Errorf("mailboxId is required")
+	}
+	if limit <= 0 {
+		limit = defaultHistoryLimit
+	}
+	r.mu.RLock()
+	var points []model.PricePoint
+	for _, p := range r.prices {
+		if p.MailboxID == mailboxID {
+			points = append(points, p)
+		}
+	}
+	r.mu.RUnlock()
+
+	sort.SliceStable(points, func(i, j int) bool { return points[i].ObservedAt.Before(points[j].ObservedAt) })
+	if len(points) > limit {
+		points = points[len(points)-limit:]
+	}
+	return points, nil
+}
+
+// ListRunPrices returns every price observed during one run.
+func (r *MemoryHistoryRepository) ListRunPrices(ctx context.Context, runID string) ([]model.PricePoint, error) {
+	if runID == "" {
+		return nil, fmt.Errorf("runId is required")
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var points []model.PricePoint
+	for _, p := range r.prices {
+		if p.CrawlRunID == runID {
+			points = append(points, p)
+		}
+	}
+	return points, nil
+}
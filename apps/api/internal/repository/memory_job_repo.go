@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/model"
+)
+
+// MemoryJobRepository keeps the crawl job queue in process memory.
+type MemoryJobRepository struct {
+	mu   sync.Mutex
+	jobs map[string]model.Job
+}
+
+func NewMemoryJobRepository() *MemoryJobRepository {
+	return &MemoryJobRepository{jobs: make(map[string]model.Job)}
+}
+
+func (r *MemoryJobRepository) EnqueueJob(ctx context.Context, job model.Job) (model.Job, bool, error) {
+	if job.ID == "" {
+		return model.Job{}, false, fmt.Errorf("job id is required")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, other := range r.sorted() {
+		if other.LockKey == job.LockKey && jobActive(other) {
+			return other, false, nil
+		}
+	}
+	job = queuedJob(job)
+	job.Seeds = append([]string(nil), job.Seeds...)
+	r.jobs[job.ID] = job
+	return job, true, nil
+}
+
+func (r *MemoryJobRepository) ClaimJob(ctx context.Context, owner string, leaseUntil time.Time) (model.Job, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, job := range r.sorted() {
+		if job.Status == model.JobQueued {
+			claimJob(&job, owner, leaseUntil)
+			r.jobs[job.ID] = job
+			return job, true, nil
+		}
+	}
+	return model.Job{}, false, nil
+}
+
+func (r *MemoryJobRepository) HeartbeatJob(ctx context.Context, id, owner string, leaseUntil time.Time) (model.Job, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	job, ok := r.jobs[id]
+	if !ok {
+		return model.Job{}, false, fmt.Errorf("heartbeat job %s: not found", id)
+	}
+	if !leasedTo(job, owner) {
+		return job, false, nil
+	}
+	job.LeaseExpiresAt = leaseUntil
+	r.jobs[id] = job
+	return job, true, nil
+}
+
+func (r *MemoryJobRepository) CompleteJob(ctx context.Context, id, owner, status, errMsg string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	job, ok := r.jobs[id]
+	if !ok || !leasedTo(job, owner) {
+		return fmt.Errorf("complete job %s: lease no longer held by %s", id, owner)
+	}
+	completeJob(&job, status, errMsg, time.Now().UTC())
+	r.jobs[id] = job
+	return nil
+}
+
+func (r *MemoryJobRepository) RequeueExpiredJobs(ctx context.Context, now time.Time, maxAttempts int) ([]model.Job, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var changed []model.Job
+	for _, job := range r.sorted() {
+		if expireJob(&job, now, maxAttempts) {
+			r.jobs[job.ID] = job
+			changed = append(changed, job)
+		}
+	}
+	return changed, nil
+}
+
+func (r *MemoryJobRepository) RequestJobCancel(ctx context.Context, id string) (model.Job, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	job, ok := r.jobs[id]
+	if !ok {
+		return model.Job{}, fmt.Errorf("cancel job %s: not found", id)
+	}
+	if cancelJob(&job, time.Now().UTC()) {
+		r.jobs[id] = job
+	}
+	return job, nil
+}
+
+func (r *MemoryJobRepository) RequestJobPause(ctx context.Context, id string, paused bool) (model.Job, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	job, ok := r.jobs[id]
+	if !ok {
+		return model.Job{}, false, fmt.Errorf("pause job %s: not found", id)
+	}
+	if !pauseJob(&job, paused) {
+		return job, false, nil
+	}
+	r.jobs[id] = job
+	return job, true, nil
+}
+
+func (r *MemoryJobRepository) GetJob(ctx context.Context, id string) (model.Job, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	job, ok := r.jobs[id]
+	if !ok {
+		return model.Job{}, fmt.Errorf("get job %s: not found", id)
+	}
+	return job, nil
+}
+
+// sorted returns the jobs oldest first. The caller holds mu.
+func (r *MemoryJobRepository) sorted() []model.Job {
+	jobs := make([]model.Job, 0, len(r.jobs))
+	for _, job := range r.jobs {
+		jobs = append(jobs, job)
+	}
+	sortJobs(jobs)
+	return jobs
+}
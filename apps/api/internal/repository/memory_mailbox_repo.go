@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/model"
+)
+
+// MemoryMailboxRepository keeps mailboxes in process memory.
+// It is safe for concurrent use and intended for tests and DEMO_MODE.
+type MemoryMailboxRepository struct {
+	mu    sync.RWMutex
+	items map[string]model.Mailbox // keyed by document ID
+}
+
+func NewMemoryMailboxRepository() *MemoryMailboxRepository {
+	return &MemoryMailboxRepository{items: make(map[string]model.Mailbox)}
+}
+
+// FetchAllMap returns all mailboxes (including RawHTML) keyed by link.
+func (r *MemoryMailboxRepository) FetchAllMap(ctx context.Context) (map[string]model.Mailbox, error) {
+	return r.fetchAll(true), nil
+}
+
+// FetchAllMetadata returns all mailboxes without RawHTML, keyed by link.
+func (r *MemoryMailboxRepository) FetchAllMetadata(ctx context.Context) (map[string]model.Mailbox, error) {
+	return r.fetchAll(false), nil
+}
+
+func (r *MemoryMailboxRepository) fetchAll(withHTML bool) map[string]model.Mailbox {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	result := make(map[string]model.Mailbox, len(r.items))
+	for id, m := range r.items {
+		if !withHTML {
+			m.RawHTML = ""
+		}
+		key := m.Link
+		if key == "" {
+			key = id
+		}
+		result[key] = m
+	}
+	return result
+}
+
+// BatchUpsert stores mailboxes, replacing any existing record with the same document ID.
+func (r *MemoryMailboxRepository) BatchUpsert(ctx context.Context, mailboxes []model.Mailbox) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, m := range mailboxes {
+		docID := documentID(m)
+		if m.ID == "" {
+			m.ID = docID
+		}
+		r.items[docID] = m
+	}
+	return nil
+}
+
+func (r *MemoryMailboxRepository) Get(ctx context.Context, id string) (model.Mailbox, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	m, ok := r.items[id]
+	if !ok {
+		return model.Mailbox{}, fmt.Errorf("get mailbox %s: not found", id)
+	}
+	return m, nil
+}
+
+// List returns filtered mailboxes ordered by ID with pagination and total count.
+func (r *MemoryMailboxRepository) List(ctx context.Context, q MailboxQuery) ([]model.Mailbox, int, error) {
+	if q.Page <= 0 {
+		q.Page = 1
+	}
+	if q.PageSize <= 0 {
+		q.PageSize = 50
+	}
+
+	matched := r.filter(q)
+	total := len(matched)
+
+	offset := (q.Page - 1) * q.PageSize
+	if offset >= total {
+		return nil, total, nil
+	}
+	end := offset + q.PageSize
+	if end > total {
+		end = total
+	}
+	return matched[offset:end], total, nil
+}
+
+// StreamAll streams mailboxes (optionally filtered by active) to a callback.
+func (r *MemoryMailboxRepository) StreamAll(ctx context.Context, activeOnly bool, fn func(model.Mailbox) error) error {
+	var q MailboxQuery
+	if activeOnly {
+		active := true
+		q.Active = &active
+	}
+	return r.StreamWithQuery(ctx, q, fn)
+}
+
+// StreamWithQuery streams mailboxes matching the filters to a callback.
+func (r *MemoryMailboxRepository) StreamWithQuery(ctx context.Context, q MailboxQuery, fn func(model.Mailbox) error) error {
+	for _, m := range r.filter(q) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// filter returns a snapshot of matching mailboxes (without RawHTML) sorted by ID.
+func (r *MemoryMailboxRepository) filter(q MailboxQuery) []model.Mailbox {
+	r.mu.RLock()
+	var matched []model.Mailbox
+	for _, m := range r.items {
+		if matchesMailboxQuery(m, q) {
+			m.RawHTML = ""
+			matched = append(matched, m)
+		}
+	}
+	r.mu.RUnlock()
+	sort.Slice(matched, func(i, j int) bool { return matched[i].ID < matched[j].ID })
+	return matched
+}
+
+// matchesMailboxQuery applies the same equality filters as the Firestore List query.
+func matchesMailboxQuery(m model.Mailbox, q MailboxQuery) bool {
+	if q.State != "" && m.AddressRaw.State != q.State {
+		return false
+	}
+	if q.CMRA != "" && m.CMRA != q.CMRA {
+		return false
+	}
+	if q.RDI != "" && m.RDI != q.RDI {
+		return false
+	}
+	if q.Source != "" && m.Source != q.Source {
+		return false
+	}
+	if q.DPVMatchCode != "" && m.Analysis.DPVMatchCode != q.DPVMatchCode {
+		return false
+	}
+	if q.Vacant != "" && m.Analysis.DPVVacant != q.Vacant {
+		return false
+	}
+	if q.Status != "" && m.ValidationStatus != q.Status {
+		return false
+	}
+	if q.Disagree && !m.ValidatorsDisagree {
+		return false
+	}
+	if q.Active != nil && m.Active != *q.Active {
+		return false
+	}
+	return true
+}
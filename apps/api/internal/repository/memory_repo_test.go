@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/model"
+)
+
+func TestMemoryMailboxRepositoryList(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryMailboxRepository()
+
+	seed := []model.Mailbox{
+		{ID: "a", Link: "https://a", Source: "ATMB", AddressRaw: model.AddressRaw{State: "CA"}, CMRA: "Y", RDI: "Commercial", Active: true, RawHTML: "<html/>"},
+		{ID: "b", Link: "https://b", Source: "ATMB", AddressRaw: model.AddressRaw{State: "CA"}, CMRA: "N", RDI: "Commercial", Active: true},
+		{ID: "c", Link: "https://c", Source: "iPost1", AddressRaw: model.AddressRaw{State: "CA"}, CMRA: "N", RDI: "Residential", Active: false},
+		{ID: "d", Link: "https://d", Source: "iPost1", AddressRaw: model.AddressRaw{State: "TX"}, CMRA: "Y", RDI: "Commercial", Active: true,
+			Analysis: model.AddressAnalysis{DPVMatchCode: "D", DPVVacant: "Y"}},
+	}
+	seed[0].Analysis = model.AddressAnalysis{DPVMatchCode: "Y", DPVVacant: "N"}
+	seed[1].ValidationStatus = model.ValidationQuotaExhausted
+	if err := repo.BatchUpsert(ctx, seed); err != nil {
+		t.Fatalf("BatchUpsert: %v", err)
+	}
+
+	active := true
+	inactive := false
+	tests := []struct {
+		name      string
+		q         MailboxQuery
+		wantTotal int
+		wantIDs   []string
+	}{
+		{name: "no filters", q: MailboxQuery{}, wantTotal: 4, wantIDs: []string{"a", "b", "c", "d"}},
+		{name: "state", q: MailboxQuery{State: "CA"}, wantTotal: 3, wantIDs: []string{"a", "b", "c"}},
+		{name: "cmra", q: MailboxQuery{CMRA: "Y"}, wantTotal: 2, wantIDs: []string{"a", "d"}},
+		{name: "rdi", q: MailboxQuery{RDI: "Residential"}, wantTotal: 1, wantIDs: []string{"c"}},
+		{name: "source", q: MailboxQuery{Source: "iPost1"}, wantTotal: 2, wantIDs: []string{"c", "d"}},
+		{name: "dpv match code", q: MailboxQuery{DPVMatchCode: "D"}, wantTotal: 1, wantIDs: []string{"d"}},
+		{name: "not vacant", q: MailboxQuery{Vacant: "N"}, wantTotal: 1, wantIDs: []string{"a"}},
+		{name: "status", q: MailboxQuery{Status: model.ValidationQuotaExhausted}, wantTotal: 1, wantIDs: []string{"b"}},
+		{name: "active", q: MailboxQuery{Active: &active}, wantTotal: 3, wantIDs: []string{"a", "b", "d"}},
+		{name: "inactive", q: MailboxQuery{Active: &inactive}, wantTotal: 1, wantIDs: []string{"c"}},
+		{name: "page 2", q: MailboxQuery{PageSize: 3, Page: 2}, wantTotal: 4, wantIDs: []string{"d"}},
+		{name: "past last page", q: MailboxQuery{PageSize: 3, Page: 5}, wantTotal: 4, wantIDs: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, total, err := repo.List(ctx, tt.q)
+			if err != nil {
+				t.Fatalf("List: %v", err)
+			}
+			if total != tt.wantTotal {
+				t.Errorf("total = %d, want %d", total, tt.wantTotal)
+			}
+			if len(items) != len(tt.wantIDs) {
+				t.Fatalf("got %d items, want %d", len(items), len(tt.wantIDs))
+			}
+			for i, id := range tt.wantIDs {
+				if items[i].ID != id {
+					t.Errorf("items[%d].ID = %q, want %q", i, items[i].ID, id)
+				}
+				if items[i].RawHTML != "" {
+					t.Errorf("List should not return RawHTML")
+				}
+			}
+		})
+	}
+
+	all, _ := repo.FetchAllMap(ctx)
+	if all["https://a"].RawHTML == "" {
+		t.Errorf("FetchAllMap should include RawHTML")
+	}
+}
+
+func TestMemoryMailboxRepositoryConcurrentUpsert(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryMailboxRepository()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			link := "https://example.com/" + string(rune('a'+i))
+			_ = repo.BatchUpsert(ctx, []model.Mailbox{{Link: link, Active: true}})
+			_, _, _ = repo.List(ctx, MailboxQuery{})
+		}(i)
+	}
+	wg.Wait()
+
+	_, total, err := repo.List(ctx, MailboxQuery{})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if total != 20 {
+		t.Errorf("total = %d, want 20", total)
+	}
+}
@@ -0,0 +1,232 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/model"
+)
+
+// MemoryRunRepository keeps crawl run records in process memory.
+type MemoryRunRepository struct {
+	mu          sync.RWMutex
+	runs        map[string]model.CrawlRun
+	checkpoints map[string]model.CrawlCheckpoint
+	events      map[string][]model.LinkEvent
+	locks       map[string]model.RunLock
+}
+
+func NewMemoryRunRepository() *MemoryRunRepository {
+	return &MemoryRunRepository{
+		runs:        make(map[string]model.CrawlRun),
+		checkpoints: make(map[string]model.CrawlCheckpoint),
+		events:      make(map[string][]model.LinkEvent),
+		locks:       make(map[string]model.RunLock),
+	}
+}
+
+func (r *MemoryRunRepository) CreateRun(ctx context.Context, run model.CrawlRun) error {
+	if run.RunID == "" {
+		return fmt.Errorf("runId is required")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.runs[run.RunID] = run
+	return nil
+}
+
+func (r *MemoryRunRepository) UpdateRun(ctx context.Context, run model.CrawlRun) error {
+	if run.RunID == "" {
+		return fmt.Errorf("runId is required")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.runs[run.RunID] = run
+	return nil
+}
+
+// DeleteRun removes a crawl run record.
+func (r *MemoryRunRepository) DeleteRun(ctx context.Context, runID string) error {
+	if runID == "" {
+		return fmt.Errorf("runId is required")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.runs, runID)
+	return nil
+}
+
+// GetRun returns a crawl run by ID.
+func (r *MemoryRunRepository) GetRun(ctx context.Context, runID string) (model.CrawlRun, error) {
+	if runID == "" {
+		return model.CrawlRun{}, fmt.Errorf("runId is required")
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	run, ok := r.runs[runID]
+	if !ok {
+		return model.CrawlRun{}, fmt.Errorf("get run %s: not found", runID)
+	}
+	return run, nil
+}
+
+// ListRuns returns recent crawl runs ordered by runId descending.
+// It also auto-marks stale running jobs as "timeout".
+func (r *MemoryRunRepository) ListRuns(ctx context.Context, limit int) ([]model.CrawlRun, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ids := make([]string, 0, len(r.runs))
+	for id := range r.runs {
+		ids = append(ids, id)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(ids)))
+	if len(ids) > limit {
+		ids = ids[:limit]
+	}
+
+	now := time.Now().UTC()
+	runs := make([]model.CrawlRun, 0, len(ids))
+	for _, id := range ids {
+		run := r.runs[id]
+		if markStale(&run, now) {
+			r.runs[id] = run
+		}
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
+
+// CancelRun marks a running job as cancelled.
+func (r *MemoryRunRepository) CancelRun(ctx context.Context, runID string) error {
+	return cancelRun(ctx, r, runID)
+}
+
+// SaveCheckpoint records the listings discovered by a run.
+func (r *MemoryRunRepository) SaveCheckpoint(ctx context.Context, cp model.CrawlCheckpoint) error {
+	if cp.RunID == "" {
+		return fmt.Errorf("runId is required")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cp.Listings = append([]model.CheckpointEntry(nil), cp.Listings...)
+	r.checkpoints[cp.RunID] = cp
+	return nil
+}
+
+// MarkCheckpointDone flags the given links of a run's checkpoint as finished.
+func (r *MemoryRunRepository) MarkCheckpointDone(ctx context.Context, runID string, links []string) error {
+	if runID == "" {
+		return fmt.Errorf("runId is required")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cp, ok := r.checkpoints[runID]
+	if !ok {
+		return fmt.Errorf("mark checkpoint %s: not found", runID)
+	}
+	done := make(map[string]bool, len(links))
+	for _, link := range links {
+		done[link] = true
+	}
+	for i, e := range cp.Listings {
+		if done[e.Listing.Link] {
+			cp.Listings[i].Done = true
+		}
+	}
+	return nil
+}
+
+// GetCheckpoint returns a run's checkpoint with every discovered listing in discovery order.
+func (r *MemoryRunRepository) GetCheckpoint(ctx context.Context, runID string) (model.CrawlCheckpoint, error) {
+	if runID == "" {
+		return model.CrawlCheckpoint{}, fmt.Errorf("runId is required")
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	cp, ok := r.checkpoints[runID]
+	if !ok {
+		return model.CrawlCheckpoint{}, fmt.Errorf("get checkpoint %s: not found", runID)
+	}
+	cp.Listings = append([]model.CheckpointEntry(nil), cp.Listings...)
+	return cp, nil
+}
+
+// AppendRunEvents records links that failed during a run.
+func (r *MemoryRunRepository) AppendRunEvents(ctx context.Context, runID string, events []model.LinkEvent) error {
+	if runID == "" {
+		return fmt.Errorf("runId is required")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, e := range events {
+		e.RunID = runID
+		r.events[runID] = append(r.events[runID], e)
+	}
+	return nil
+}
+
+// ListRunEvents returns a page of a run's failed links in the order they were recorded.
+func (r *MemoryRunRepository) ListRunEvents(ctx context.Context, q RunEventQuery) ([]model.LinkEvent, int, error) {
+	if q.RunID == "" {
+		return nil, 0, fmt.Errorf("runId is required")
+	}
+	q = pageRunEvents(q)
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var matched []model.LinkEvent
+	for _, e := range r.events[q.RunID] {
+		if q.Stage == "" || e.Stage == q.Stage {
+			matched = append(matched, e)
+		}
+	}
+	total := len(matched)
+	offset := (q.Page - 1) * q.PageSize
+	if offset >= total {
+		return nil, total, nil
+	}
+	return matched[offset:min(offset+q.PageSize, total)], total, nil
+}
+
+// AcquireRunLock takes lock.Key for lock.RunID if it is free, expired or already held by that run.
+func (r *MemoryRunRepository) AcquireRunLock(ctx context.Context, lock model.RunLock) (model.RunLock, bool, error) {
+	if lock.Key == "" || lock.RunID == "" {
+		return model.RunLock{}, false, fmt.Errorf("lock key and runId are required")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if held, ok := r.locks[lock.Key]; ok && !lockAvailable(held, lock, time.Now().UTC()) {
+		return held, false, nil
+	}
+	r.locks[lock.Key] = lock
+	return lock, true, nil
+}
+
+// RenewRunLock extends runID's lease on key.
+func (r *MemoryRunRepository) RenewRunLock(ctx context.Context, key, runID string, expiresAt time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	held, ok := r.locks[key]
+	if !ok || held.RunID != runID {
+		return false, nil
+	}
+	held.ExpiresAt = expiresAt
+	r.locks[key] = held
+	return true, nil
+}
+
+// ReleaseRunLock drops runID's lease on key.
+func (r *MemoryRunRepository) ReleaseRunLock(ctx context.Context, key, runID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if held, ok := r.locks[key]; ok && held.RunID == runID {
+		delete(r.locks, key)
+	}
+	return nil
+}
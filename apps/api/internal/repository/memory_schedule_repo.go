@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/model"
+)
+
+// MemoryScheduleRepository keeps schedules in process memory.
+type MemoryScheduleRepository struct {
+	mu        sync.Mutex
+	schedules map[string]model.Schedule
+}
+
+func NewMemoryScheduleRepository() *MemoryScheduleRepository {
+	return &MemoryScheduleRepository{schedules: make(map[string]model.Schedule)}
+}
+
+// ListSchedules returns every schedule ordered by ID.
+func (r *MemoryScheduleRepository) ListSchedules(ctx context.Context) ([]model.Schedule, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	schedules := make([]model.Schedule, 0, len(r.schedules))
+	for _, s := range r.schedules {
+		schedules = append(schedules, s)
+	}
+	sort.Slice(schedules, func(i, j int) bool { return schedules[i].ID < schedules[j].ID })
+	return schedules, nil
+}
+
+// GetSchedule returns a schedule by ID.
+func (r *MemoryScheduleRepository) GetSchedule(ctx context.Context, id string) (model.Schedule, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, ok := r.schedules[id]
+	if !ok {
+		return model.Schedule{}, fmt.Errorf("get schedule %s: not found", id)
+	}
+	return s, nil
+}
+
+// SaveSchedule creates or replaces a schedule.
+func (r *MemoryScheduleRepository) SaveSchedule(ctx context.Context, s model.Schedule) error {
+	if s.ID == "" {
+		return fmt.Errorf("schedule id is required")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.schedules[s.ID] = s
+	return nil
+}
+
+// DeleteSchedule removes a schedule. Deleting a missing schedule is not an error.
+func (r *MemoryScheduleRepository) DeleteSchedule(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.schedules, id)
+	return nil
+}
+
+// ClaimSchedule replaces the schedule with s only if its stored NextRunAt still equals due.
+func (r *MemoryScheduleRepository) ClaimSchedule(ctx context.Context, s model.Schedule, due time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	current, ok := r.schedules[s.ID]
+	if !ok {
+		return false, fmt.Errorf("claim schedule %s: not found", s.ID)
+	}
+	if !current.NextRunAt.Equal(due) {
+		return false, nil
+	}
+	r.schedules[s.ID] = s
+	return true, nil
+}
@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/model"
+)
+
+// MemoryStatsRepository keeps the system stats singleton in process memory.
+type MemoryStatsRepository struct {
+	mu    sync.RWMutex
+	stats *model.SystemStats
+}
+
+func NewMemoryStatsRepository() *MemoryStatsRepository {
+	return &MemoryStatsRepository{}
+}
+
+func (r *MemoryStatsRepository) SaveSystemStats(ctx context.Context, stats model.SystemStats) error {
+	stats.LastUpdated = time.Now().UTC()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stats = &stats
+	return nil
+}
+
+func (r *MemoryStatsRepository) GetSystemStats(ctx context.Context) (model.SystemStats, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.stats == nil {
+		return model.SystemStats{}, fmt.Errorf("get system stats: not found")
+	}
+	return *r.stats, nil
+}
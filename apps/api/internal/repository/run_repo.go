@@ -6,7 +6,9 @@ import (
 	"time"
 
 	"cloud.google.com/go/firestore"
+	firestorepb "cloud.google.com/go/firestore/apiv1/firestorepb"
 	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/model"
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/util"
 	"google.golang.org/api/iterator"
 )
 
@@ -44,6 +46,17 @@ func (r *RunRepository) UpdateRun(ctx context.Context, run model.CrawlRun) error
 	return nil
 }
 
+// DeleteRun removes a crawl run record.
+func (r *RunRepository) DeleteRun(ctx context.Context, runID string) error {
+	if runID == "" {
+		return fmt.Errorf("runId is required")
+	}
+	if _, err := r.client.Collection("crawl_runs").Doc(runID).Delete(ctx); err != nil {
+		return fmt.Errorf("delete run %s: %w", runID, err)
+	}
+	return nil
+}
+
 // GetRun returns a crawl run by ID.
 func (r *RunRepository) GetRun(ctx context.Context, runID string) (model.CrawlRun, error) {
 	if runID == "" {
@@ -85,9 +98,7 @@ func (r *RunRepository) ListRuns(ctx context.Context, limit int) ([]model.CrawlR
 		}
 
 		// Auto-mark stale running jobs as timeout
-		if run.Status == "running" && !run.StartedAt.IsZero() && now.Sub(run.StartedAt) > StaleRunTimeout {
-			run.Status = "timeout"
-			run.FinishedAt = now
+		if markStale(&run, now) {
 			// Update in background, don't block the list response
 			go func(r *RunRepository, run model.CrawlRun) {
 				_ = r.UpdateRun(context.Background(), run)
@@ -101,18 +112,208 @@ func (r *RunRepository) ListRuns(ctx context.Context, limit int) ([]model.CrawlR
 
 // CancelRun marks a running job as cancelled.
 func (r *RunRepository) CancelRun(ctx context.Context, runID string) error {
+	return cancelRun(ctx, r, runID)
+}
+
+// SaveCheckpoint records the listings discovered by a run. Each listing is its own document under
+// crawl_checkpoints/{runId}/listings so completion can be marked in small batches.
+func (r *RunRepository) SaveCheckpoint(ctx context.Context, cp model.CrawlCheckpoint) error {
+	if cp.RunID == "" {
+		return fmt.Errorf("runId is required")
+	}
+	ref := r.client.Collection("crawl_checkpoints").Doc(cp.RunID)
+	if _, err := ref.Set(ctx, cp); err != nil {
+		return fmt.Errorf("save checkpoint %s: %w", cp.RunID, err)
+	}
+	const batchSize = 400
+
+	for start := 0; start < len(cp.Listings); start += batchSize {
+		end := start + batchSize
+		if end > len(cp.Listings) {
+			end = len(cp.Listings)
+		}
+		batch := r.client.Batch()
+		for _, e := range cp.Listings[start:end] {
+			batch.Set(ref.Collection("listings").Doc(util.HashString(e.Listing.Link)), e)
+		}
+		if _, err := batch.Commit(ctx); err != nil {
+			return fmt.Errorf("save checkpoint %s [%d:%d]: %w", cp.RunID, start, end, err)
+		}
+	}
+	return nil
+}
+
+// MarkCheckpointDone flags the given links of a run's checkpoint as finished.
+func (r *RunRepository) MarkCheckpointDone(ctx context.Context, runID string, links []string) error {
+	if runID == "" {
+		return fmt.Errorf("runId is required")
+	}
+	listings := r.client.Collection("crawl_checkpoints").Doc(runID).Collection("listings")
+	const batchSize = 400
+
+	for start := 0; start < len(links); start += batchSize {
+		end := start + batchSize
+		if end > len(links) {
+			end = len(links)
+		}
+		batch := r.client.Batch()
+		for _, link := range links[start:end] {
+			batch.Set(listings.Doc(util.HashString(link)), map[string]interface{}{"done": true}, firestore.MergeAll)
+		}
+		if _, err := batch.Commit(ctx); err != nil {
+			return fmt.Errorf("mark checkpoint %s [%d:%d]: %w", runID, start, end, err)
+		}
+	}
+	return nil
+}
+
+// GetCheckpoint returns a run's checkpoint with every discovered listing.
+func (r *RunRepository) GetCheckpoint(ctx context.Context, runID string) (model.CrawlCheckpoint, error) {
+	if runID == "" {
+		return model.CrawlCheckpoint{}, fmt.Errorf("runId is required")
+	}
+	ref := r.client.Collection("crawl_checkpoints").Doc(runID)
+	snap, err := ref.Get(ctx)
+	if err != nil {
+		return model.CrawlCheckpoint{}, fmt.Errorf("get checkpoint %s: %w", runID, err)
+	}
+	var cp model.CrawlCheckpoint
+	if err := snap.DataTo(&cp); err != nil {
+		return model.CrawlCheckpoint{}, fmt.Errorf("decode checkpoint %s: %w", runID, err)
+	}
+	iter := ref.Collection("listings").Documents(ctx)
+	defer iter.Stop()
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return model.CrawlCheckpoint{}, fmt.Errorf("get checkpoint %s: %w", runID, err)
+		}
+		var e model.CheckpointEntry
+		if err := doc.DataTo(&e); err != nil {
+			return model.CrawlCheckpoint{}, fmt.Errorf("decode checkpoint %s: %w", runID, err)
+		}
+		cp.Listings = append(cp.Listings, e)
+	}
+	return cp, nil
+}
+
+// RunEventQuery selects a page of a run's failed links, optionally only those of one stage.
+type RunEventQuery struct {
+	RunID    string
+	Stage    string
+	Page     int
+	PageSize int
+}
+
+// AppendRunEvents stores each failed link as its own document under crawl_runs/{runId}/events.
+func (r *RunRepository) AppendRunEvents(ctx context.Context, runID string, events []model.LinkEvent) error {
+	if runID == "" {
+		return fmt.Errorf("runId is required")
+	}
+	col := r.client.Collection("crawl_runs").Doc(runID).Collection("events")
+	const batchSize = 400
+
+	for start := 0; start < len(events); start += batchSize {
+		end := start + batchSize
+		if end > len(events) {
+			end = len(events)
+		}
+		batch := r.client.Batch()
+		for _, e := range events[start:end] {
+			e.RunID = runID
+			batch.Set(col.NewDoc(), e)
+		}
+		if _, err := batch.Commit(ctx); err != nil {
+			return fmt.Errorf("append run events %s [%d:%d]: %w", runID, start, end, err)
+		}
+	}
+	return nil
+}
+
+// ListRunEvents returns a page of a run's failed links ordered by when they were recorded.
+func (r *RunRepository) ListRunEvents(ctx context.Context, q RunEventQuery) ([]model.LinkEvent, int, error) {
+	if q.RunID == "" {
+		return nil, 0, fmt.Errorf("runId is required")
+	}
+	q = pageRunEvents(q)
+	query := r.client.Collection("crawl_runs").Doc(q.RunID).Collection("events").Query
+	if q.Stage != "" {
+		query = query.Where("stage", "==", q.Stage)
+	}
+
+	countResult, err := query.NewAggregationQuery().WithCount("total").Get(ctx)
+	if err != nil {
+		return nil, 0, fmt.Errorf("count run events %s: %w", q.RunID, err)
+	}
+	total := int(countResult["total"].(*firestorepb.Value).GetIntegerValue())
+
+	iter := query.OrderBy("at", firestore.Asc).Offset((q.Page - 1) * q.PageSize).Limit(q.PageSize).Documents(ctx)
+	defer iter.Stop()
+	var events []model.LinkEvent
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("list run events %s: %w", q.RunID, err)
+		}
+		var e model.LinkEvent
+		if err := doc.DataTo(&e); err != nil {
+			return nil, 0, fmt.Errorf("decode run event %s: %w", doc.Ref.ID, err)
+		}
+		events = append(events, e)
+	}
+	return events, total, nil
+}
+
+// pageRunEvents applies the default page of a RunEventQuery.
+func pageRunEvents(q RunEventQuery) RunEventQuery {
+	if q.Page <= 0 {
+		q.Page = 1
+	}
+	if q.PageSize <= 0 {
+		q.PageSize = 50
+	}
+	return q
+}
+
+// markStale flips a running job to "timeout" once StaleRunTimeout has passed since it started or was last resumed,
+// from a pause or its checkpoint.
+// Returns true if the run was modified and should be persisted.
+func markStale(run *model.CrawlRun, now time.Time) bool {
+	since := run.StartedAt
+	if run.ResumedAt.After(since) {
+		since = run.ResumedAt
+	}
+	if run.Status != "running" || since.IsZero() || now.Sub(since) <= StaleRunTimeout {
+		return false
+	}
+	run.Status = "timeout"
+	run.FinishedAt = now
+	return true
+}
+
+// cancelRun implements CancelRun on top of GetRun/UpdateRun so every backend shares the same rules.
+func cancelRun(ctx context.Context, repo interface {
+	GetRun(ctx context.Context, runID string) (model.CrawlRun, error)
+	UpdateRun(ctx context.Context, run model.CrawlRun) error
+}, runID string) error {
 	if runID == "" {
 		return fmt.Errorf("runId is required")
 	}
 
 	// Get the current run
-	run, err := r.GetRun(ctx, runID)
+	run, err := repo.GetRun(ctx, runID)
 	if err != nil {
 		return err
 	}
 
-	// Only allow cancelling running jobs
-	if run.Status != "running" {
+	// Only allow cancelling queued, running or paused jobs
+	if run.Status != "running" && run.Status != "queued" && run.Status != "paused" {
 		return fmt.Errorf("run %s is not running (status: %s)", runID, run.Status)
 	}
 
@@ -120,5 +321,98 @@ func (r *RunRepository) CancelRun(ctx context.Context, runID string) error {
 	run.Status = "cancelled"
 	run.FinishedAt = time.Now().UTC()
 
-	return r.UpdateRun(ctx, run)
+	return repo.UpdateRun(ctx, run)
+}
+
+// lockAvailable reports whether lock may replace held: the lease has expired or already belongs to the same run.
+func lockAvailable(held, lock model.RunLock, now time.Time) bool {
+	return held.RunID == lock.RunID || !held.ExpiresAt.After(now)
+}
+
+// AcquireRunLock takes lock.Key for lock.RunID inside a transaction, so concurrent starts on
+// different instances cannot both win. When the lock is taken it returns the current holder.
+func (r *RunRepository) AcquireRunLock(ctx context.Context, lock model.RunLock) (model.RunLock, bool, error) {
+	if lock.Key == "" || lock.RunID == "" {
+		return model.RunLock{}, false, fmt.Errorf("lock key and runId are required")
+	}
+	ref := r.client.Collection("run_locks").Doc(lock.Key)
+	var holder model.RunLock
+	acquired := false
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		acquired = false
+		snap, err := tx.Get(ref)
+		if err != nil && (snap == nil || snap.Exists()) {
+			return err
+		}
+		if snap.Exists() {
+			if err := snap.DataTo(&holder); err != nil {
+				return err
+			}
+			if !lockAvailable(holder, lock, time.Now().UTC()) {
+				return nil
+			}
+		}
+		holder = lock
+		acquired = true
+		return tx.Set(ref, lock)
+	})
+	if err != nil {
+		return model.RunLock{}, false, fmt.Errorf("acquire lock %s: %w", lock.Key, err)
+	}
+	return holder, acquired, nil
+}
+
+// RenewRunLock extends runID's lease on key.
+func (r *RunRepository) RenewRunLock(ctx context.Context, key, runID string, expiresAt time.Time) (bool, error) {
+	ref := r.client.Collection("run_locks").Doc(key)
+	renewed := false
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		renewed = false
+		snap, err := tx.Get(ref)
+		if err != nil {
+			if snap != nil && !snap.Exists() {
+				return nil
+			}
+			return err
+		}
+		var held model.RunLock
+		if err := snap.DataTo(&held); err != nil {
+			return err
+		}
+		if held.RunID != runID {
+			return nil
+		}
+		renewed = true
+		return tx.Update(ref, []firestore.Update{{Path: "expiresAt", Value: expiresAt}})
+	})
+	if err != nil {
+		return false, fmt.Errorf("renew lock %s: %w", key, err)
+	}
+	return renewed, nil
+}
+
+// ReleaseRunLock deletes runID's lease on key.
+func (r *RunRepository) ReleaseRunLock(ctx context.Context, key, runID string) error {
+	ref := r.client.Collection("run_locks").Doc(key)
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		snap, err := tx.Get(ref)
+		if err != nil {
+			if snap != nil && !snap.Exists() {
+				return nil
+			}
+			return err
+		}
+		var held model.RunLock
+		if err := snap.DataTo(&held); err != nil {
+			return err
+		}
+		if held.RunID != runID {
+			return nil
+		}
+		return tx.Delete(ref)
+	})
+	if err != nil {
+		return fmt.Errorf("release lock %s: %w", key, err)
+	}
+	return nil
 }
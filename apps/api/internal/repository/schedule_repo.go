@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/model"
+	"google.golang.org/api/iterator"
+)
+
+// ScheduleRepository manages the recurring jobs in the schedules collection.
+type ScheduleRepository struct {
+	client *firestore.Client
+}
+
+func NewScheduleRepository(client *firestore.Client) *ScheduleRepository {
+	return &ScheduleRepository{client: client}
+}
+
+// ListSchedules returns every schedule ordered by ID.
+func (r *ScheduleRepository) ListSchedules(ctx context.Context) ([]model.Schedule, error) {
+	iter := r.client.Collection("schedules").OrderBy("id", firestore.Asc).Documents(ctx)
+	defer iter.Stop()
+	var schedules []model.Schedule
+	for {
+		snap, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("list schedules: %w", err)
+		}
+		var s model.Schedule
+		if err := snap.DataTo(&s); err != nil {
+			return nil, fmt.Errorf("decode schedule %s: %w", snap.Ref.ID, err)
+		}
+		schedules = append(schedules, s)
+	}
+	return schedules, nil
+}
+
+// GetSchedule returns a schedule by ID.
+func (r *ScheduleRepository) GetSchedule(ctx context.Context, id string) (model.Schedule, error) {
+	if id == "" {
+		return model.Schedule{}, fmt.Errorf("schedule id is required")
+	}
+	snap, err := r.client.Collection("schedules").Doc(id).Get(ctx)
+	if err != nil {
+		return model.Schedule{}, fmt.Errorf("get schedule %s: %w", id, err)
+	}
+	var s model.Schedule
+	if err := snap.DataTo(&s); err != nil {
+		return model.Schedule{}, fmt.Errorf("decode schedule %s: %w", id, err)
+	}
+	return s, nil
+}
+
+// SaveSchedule creates or replaces a schedule.
+func (r *ScheduleRepository) SaveSchedule(ctx context.Context, s model.Schedule) error {
+	if s.ID == "" {
+		return fmt.Errorf("schedule id is required")
+	}
+	if _, err := r.client.Collection("schedules").Doc(s.ID).Set(ctx, s); err != nil {
+		return fmt.Errorf("save schedule %s: %w", s.ID, err)
+	}
+	return nil
+}
+
+// DeleteSchedule removes a schedule. Deleting a missing schedule is not an error.
+func (r *ScheduleRepository) DeleteSchedule(ctx context.Context, id string) error {
+	if id == "" {
+		return fmt.Errorf("schedule id is required")
+	}
+	if _, err := r.client.Collection("schedules").Doc(id).Delete(ctx); err != nil {
+		return fmt.Errorf("delete schedule %s: %w", id, err)
+	}
+	return nil
+}
+
+// ClaimSchedule replaces the schedule with s only if its stored NextRunAt still equals due.
+// The read and write share a transaction, so when several instances see the same due schedule
+// exactly one of them claims (and fires) it.
+func (r *ScheduleRepository) ClaimSchedule(ctx context.Context, s model.Schedule, due time.Time) (bool, error) {
+	if s.ID == "" {
+		return false, fmt.Errorf("schedule id is required")
+	}
+	ref := r.client.Collection("schedules").Doc(s.ID)
+	claimed := false
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		claimed = false
+		snap, err := tx.Get(ref)
+		if err != nil {
+			return err
+		}
+		var current model.Schedule
+		if err := snap.DataTo(&current); err != nil {
+			return err
+		}
+		if !current.NextRunAt.Equal(due) {
+			return nil
+		}
+		claimed = true
+		return tx.Set(ref, s)
+	})
+	if err != nil {
+		return false, fmt.Errorf("claim schedule %s: %w", s.ID, err)
+	}
+	return claimed, nil
+}
@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/model"
+)
+
+// SQLiteHistoryRepository stores mailbox change records and price observations in SQLite.
+type SQLiteHistoryRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteHistoryRepository(db *sql.DB) *SQLiteHistoryRepository {
+	return &SQLiteHistoryRepository{db: db}
+}
+
+// AppendHistory writes change records in a single transaction.
+func (r *SQLiteHistoryRepository) AppendHistory(ctx context.Context, entries []model.MailboxHistory) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("append history: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, h := range entries {
+		data, err := json.Marshal(h)
+		if err != nil {
+			return fmt.Errorf("encode history %s: %w", h.MailboxID, err)
+		}
+		if _, err := tx.ExecContext(ctx, "INSERT INTO mailbox_history (mailbox_id, changed_at, data) VALUES (?, ?, ?)",
+			h.MailboxID, h.ChangedAt.UnixNano(), string(data)); err != nil {
+			return fmt.Errorf("append history %s: %w", h.MailboxID, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("append history: %w", err)
+	}
+	return nil
+}
+
+// ListHistory returns the change records of one mailbox, newest first.
+func (r *SQLiteHistoryRepository) ListHistory(ctx context.Context, mailboxID string, limit int) ([]model.MailboxHistory, error) {
+	if mailboxID == "" {
+		return nil, fmt.Errorf("mailboxId is required")
+	}
+	if limit <= 0 {
+		limit = defaultHistoryLimit
+	}
+	rows, err := r.db.QueryContext(ctx, "SELECT id, data FROM mailbox_history WHERE mailbox_id = ? ORDER BY changed_at DESC, id DESC LIMIT ?", mailboxID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list history %s: %w", mailboxID, err)
+	}
+	defer rows.Close()
+
+	var items []model.MailboxHistory
+	for rows.Next() {
+		var id int64
+		var data string
+		if err := rows.Scan(&id, &data); err != nil {
+			return nil, fmt.Errorf("list history %s: %w", mailboxID, err)
+		}
+		var h model.MailboxHistory
+		if err := json.Unmarshal([]byte(data), &h); err != nil {
+			return nil, fmt.Errorf("decode history %d: %w", id, err)
+		}
+		h.ID = strconv.FormatInt(id, 10)
+		items = append(items, h)
+	}
+	return items, rows.Err()
+}
+
+// AppendPrices writes price observations in a single transaction.
+func (r *SQLiteHistoryRepository) AppendPrices(ctx context.Context, points []model.PricePoint) error {
+	if len(points) == 0 {
+		return nil
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("append prices: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, p := range points {
+		data, err := json.Marshal(p)
+		if err != nil {
+			return fmt.Errorf("encode price %s: %w", p.MailboxID, err)
+		}
+		if _, err := tx.ExecContext(ctx, "INSERT INTO price_history (mailbox_id, run_id, observed_at, data) VALUES (?, ?, ?, ?)",
+			p.MailboxID, p.CrawlRunID, p.ObservedAt.UnixNano(), string(data)); err != nil {
+			return fmt.Errorf("append price %s: %w", p.MailboxID, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("append prices: %w", err)
+	}
+	return nil
+}
+
+// ListPrices returns the most recent price observations of one mailbox, oldest first.
+func (r *SQLiteHistoryRepository) ListPrices(ctx context.Context, mailboxID string, limit int) ([]model.PricePoint, error) {
+	if mailboxID == "" {
+		return nil, fmt.Errorf("mailboxId is required")
+	}
+	if limit <= 0 {
+		limit = defaultHistoryLimit
+	}
+	points, err := r.queryPrices(ctx, "WHERE mailbox_id = ? ORDER BY observed_at DESC, id DESC LIMIT ?", mailboxID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list prices %s: %w", mailboxID, err)
+	}
+	reversePricePoints(points)
+	return points, nil
+}
+
+// ListRunPrices returns every price observed during one run.
+func (r *SQLiteHistoryRepository) ListRunPrices(ctx context.Context, runID string) ([]model.PricePoint, error) {
+	if runID == "" {
+		return nil, fmt.Errorf("runId is required")
+	}
+	points, err := r.queryPrices(ctx, "WHERE run_id = ? ORDER BY id", runID)
+	if err != nil {
+		return nil, fmt.Errorf("list run prices %s: %w", runID, err)
+	}
+	return points, nil
+}
+
+func (r *SQLiteHistoryRepository) queryPrices(ctx context.Context, suffix string, args ...any) ([]model.PricePoint, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT id, data FROM price_history "+suffix, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var points []model.PricePoint
+	for rows.Next() {
+		var id int64
+		var data string
+		if err := rows.Scan(&id, &data); err != nil {
+			return nil, err
+		}
+		var p model.PricePoint
+		if err := json.Unmarshal([]byte(data), &p); err != nil {
+			return nil, fmt.Errorf("decode price %d: %w", id, err)
+		}
+		p.ID = strconv.FormatInt(id, 10)
+		points = append(points, p)
+	}
+	return points, rows.Err()
+}
@@ -0,0 +1,233 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/model"
+)
+
+// SQLiteJobRepository keeps the crawl job queue in SQLite. Each state change is a transaction, so
+// processes sharing the database file never claim the same job.
+type SQLiteJobRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteJobRepository(db *sql.DB) *SQLiteJobRepository {
+	return &SQLiteJobRepository{db: db}
+}
+
+type sqlQuerier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+func (r *SQLiteJobRepository) EnqueueJob(ctx context.Context, job model.Job) (model.Job, bool, error) {
+	if job.ID == "" {
+		return model.Job{}, false, fmt.Errorf("job id is required")
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return model.Job{}, false, fmt.Errorf("enqueue job %s: %w", job.ID, err)
+	}
+	defer tx.Rollback()
+
+	active, err := r.list(ctx, tx, "WHERE lock_key = ? AND status IN (?, ?) ORDER BY enqueued_at, id LIMIT 1",
+		job.LockKey, model.JobQueued, model.JobRunning)
+	if err != nil {
+		return model.Job{}, false, fmt.Errorf("enqueue job %s: %w", job.ID, err)
+	}
+	if len(active) > 0 {
+		return active[0], false, nil
+	}
+	job = queuedJob(job)
+	if err := r.put(ctx, tx, job); err != nil {
+		return model.Job{}, false, fmt.Errorf("enqueue job %s: %w", job.ID, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return model.Job{}, false, fmt.Errorf("enqueue job %s: %w", job.ID, err)
+	}
+	return job, true, nil
+}
+
+func (r *SQLiteJobRepository) ClaimJob(ctx context.Context, owner string, leaseUntil time.Time) (model.Job, bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return model.Job{}, false, fmt.Errorf("claim job: %w", err)
+	}
+	defer tx.Rollback()
+
+	queued, err := r.list(ctx, tx, "WHERE status = ? ORDER BY enqueued_at, id LIMIT 1", model.JobQueued)
+	if err != nil {
+		return model.Job{}, false, fmt.Errorf("claim job: %w", err)
+	}
+	if len(queued) == 0 {
+		return model.Job{}, false, nil
+	}
+	job := queued[0]
+	claimJob(&job, owner, leaseUntil)
+	if err := r.put(ctx, tx, job); err != nil {
+		return model.Job{}, false, fmt.Errorf("claim job %s: %w", job.ID, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return model.Job{}, false, fmt.Errorf("claim job %s: %w", job.ID, err)
+	}
+	return job, true, nil
+}
+
+func (r *SQLiteJobRepository) HeartbeatJob(ctx context.Context, id, owner string, leaseUntil time.Time) (model.Job, bool, error) {
+	job, ok, err := r.update(ctx, id, func(j *model.Job) bool {
+		if !leasedTo(*j, owner) {
+			return false
+		}
+		j.LeaseExpiresAt = leaseUntil
+		return true
+	})
+	if err != nil {
+		return model.Job{}, false, fmt.Errorf("heartbeat job %s: %w", id, err)
+	}
+	return job, ok, nil
+}
+
+func (r *SQLiteJobRepository) CompleteJob(ctx context.Context, id, owner, status, errMsg string) error {
+	_, ok, err := r.update(ctx, id, func(j *model.Job) bool {
+		if !leasedTo(*j, owner) {
+			return false
+		}
+		completeJob(j, status, errMsg, time.Now().UTC())
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("complete job %s: %w", id, err)
+	}
+	if !ok {
+		return fmt.Errorf("complete job %s: lease no longer held by %s", id, owner)
+	}
+	return nil
+}
+
+func (r *SQLiteJobRepository) RequeueExpiredJobs(ctx context.Context, now time.Time, maxAttempts int) ([]model.Job, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("requeue jobs: %w", err)
+	}
+	defer tx.Rollback()
+
+	running, err := r.list(ctx, tx, "WHERE status = ? ORDER BY enqueued_at, id", model.JobRunning)
+	if err != nil {
+		return nil, fmt.Errorf("requeue jobs: %w", err)
+	}
+	var changed []model.Job
+	for _, job := range running {
+		if !expireJob(&job, now, maxAttempts) {
+			continue
+		}
+		if err := r.put(ctx, tx, job); err != nil {
+			return nil, fmt.Errorf("requeue job %s: %w", job.ID, err)
+		}
+		changed = append(changed, job)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("requeue jobs: %w", err)
+	}
+	return changed, nil
+}
+
+func (r *SQLiteJobRepository) RequestJobCancel(ctx context.Context, id string) (model.Job, error) {
+	job, _, err := r.update(ctx, id, func(j *model.Job) bool {
+		return cancelJob(j, time.Now().UTC())
+	})
+	if err != nil {
+		return model.Job{}, fmt.Errorf("cancel job %s: %w", id, err)
+	}
+	return job, nil
+}
+
+func (r *SQLiteJobRepository) RequestJobPause(ctx context.Context, id string, paused bool) (model.Job, bool, error) {
+	job, changed, err := r.update(ctx, id, func(j *model.Job) bool {
+		return pauseJob(j, paused)
+	})
+	if err != nil {
+		return model.Job{}, false, fmt.Errorf("pause job %s: %w", id, err)
+	}
+	return job, changed, nil
+}
+
+func (r *SQLiteJobRepository) GetJob(ctx context.Context, id string) (model.Job, error) {
+	if id == "" {
+		return model.Job{}, fmt.Errorf("job id is required")
+	}
+	jobs, err := r.list(ctx, r.db, "WHERE id = ?", id)
+	if err != nil {
+		return model.Job{}, fmt.Errorf("get job %s: %w", id, err)
+	}
+	if len(jobs) == 0 {
+		return model.Job{}, fmt.Errorf("get job %s: not found", id)
+	}
+	return jobs[0], nil
+}
+
+// update applies fn to the stored job in a transaction, saving it only if fn reports a change.
+func (r *SQLiteJobRepository) update(ctx context.Context, id string, fn func(*model.Job) bool) (model.Job, bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return model.Job{}, false, err
+	}
+	defer tx.Rollback()
+
+	jobs, err := r.list(ctx, tx, "WHERE id = ?", id)
+	if err != nil {
+		return model.Job{}, false, err
+	}
+	if len(jobs) == 0 {
+		return model.Job{}, false, errors.New("not found")
+	}
+	job := jobs[0]
+	if !fn(&job) {
+		return job, false, nil
+	}
+	if err := r.put(ctx, tx, job); err != nil {
+		return model.Job{}, false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return model.Job{}, false, err
+	}
+	return job, true, nil
+}
+
+func (r *SQLiteJobRepository) list(ctx context.Context, q sqlQuerier, where string, args ...any) ([]model.Job, error) {
+	rows, err := q.QueryContext(ctx, "SELECT data FROM jobs "+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var jobs []model.Job
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var job model.Job
+		if err := json.Unmarshal([]byte(data), &job); err != nil {
+			return nil, fmt.Errorf("decode job: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+func (r *SQLiteJobRepository) put(ctx context.Context, q sqlQuerier, job model.Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	_, err = q.ExecContext(ctx, `INSERT INTO jobs (id, lock_key, status, enqueued_at, data) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET lock_key = excluded.lock_key, status = excluded.status, enqueued_at = excluded.enqueued_at, data = excluded.data`,
+		job.ID, job.LockKey, job.Status, job.EnqueuedAt.UnixNano(), string(data))
+	return err
+}
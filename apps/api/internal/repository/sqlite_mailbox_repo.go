@@ -0,0 +1,242 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/model"
+)
+
+// SQLiteMailboxRepository stores mailboxes in a local SQLite database.
+type SQLiteMailboxRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteMailboxRepository(db *sql.DB) *SQLiteMailboxRepository {
+	return &SQLiteMailboxRepository{db: db}
+}
+
+// FetchAllMap loads all mailboxes (including RawHTML) keyed by link.
+func (r *SQLiteMailboxRepository) FetchAllMap(ctx context.Context) (map[string]model.Mailbox, error) {
+	return r.fetchAll(ctx, true)
+}
+
+// FetchAllMetadata loads all mailboxes without RawHTML, keyed by link.
+func (r *SQLiteMailboxRepository) FetchAllMetadata(ctx context.Context) (map[string]model.Mailbox, error) {
+	return r.fetchAll(ctx, false)
+}
+
+func (r *SQLiteMailboxRepository) fetchAll(ctx context.Context, withHTML bool) (map[string]model.Mailbox, error) {
+	result := make(map[string]model.Mailbox)
+	err := r.query(ctx, MailboxQuery{}, withHTML, "", nil, func(m model.Mailbox) error {
+		key := m.Link
+		if key == "" {
+			key = m.ID
+		}
+		result[key] = m
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// BatchUpsert writes mailboxes in a single transaction per batch.
+func (r *SQLiteMailboxRepository) BatchUpsert(ctx context.Context, mailboxes []model.Mailbox) error {
+	if len(mailboxes) == 0 {
+		return nil
+	}
+	const batchSize = 400
+
+	for start := 0; start < len(mailboxes); start += batchSize {
+		end := start + batchSize
+		if end > len(mailboxes) {
+			end = len(mailboxes)
+		}
+		if err := r.upsertBatch(ctx, mailboxes[start:end]); err != nil {
+			return fmt.Errorf("commit batch [%d:%d]: %w", start, end, err)
+		}
+	}
+	return nil
+}
+
+func (r *SQLiteMailboxRepository) upsertBatch(ctx context.Context, mailboxes []model.Mailbox) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO mailboxes (id, link, source, state, cmra, rdi, active, data, raw_html)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			link = excluded.link, source = excluded.source, state = excluded.state,
+			cmra = excluded.cmra, rdi = excluded.rdi, active = excluded.active,
+			data = excluded.data, raw_html = excluded.raw_html`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, m := range mailboxes {
+		docID := documentID(m)
+		if m.ID == "" {
+			m.ID = docID
+		}
+		data, err := json.Marshal(m)
+		if err != nil {
+			return fmt.Errorf("encode mailbox %s: %w", docID, err)
+		}
+		if _, err := stmt.ExecContext(ctx, docID, m.Link, m.Source, m.AddressRaw.State, m.CMRA, m.RDI, m.Active, string(data), m.RawHTML); err != nil {
+			return fmt.Errorf("upsert mailbox %s: %w", docID, err)
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *SQLiteMailboxRepository) Get(ctx context.Context, id string) (model.Mailbox, error) {
+	var data, rawHTML string
+	err := r.db.QueryRowContext(ctx, "SELECT data, raw_html FROM mailboxes WHERE id = ?", id).Scan(&data, &rawHTML)
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.Mailbox{}, fmt.Errorf("get mailbox %s: not found", id)
+	}
+	if err != nil {
+		return model.Mailbox{}, fmt.Errorf("get mailbox %s: %w", id, err)
+	}
+	var m model.Mailbox
+	if err := json.Unmarshal([]byte(data), &m); err != nil {
+		return model.Mailbox{}, fmt.Errorf("decode mailbox %s: %w", id, err)
+	}
+	if m.ID == "" {
+		m.ID = id
+	}
+	m.RawHTML = rawHTML
+	return m, nil
+}
+
+// List returns filtered mailboxes with pagination and total count.
+func (r *SQLiteMailboxRepository) List(ctx context.Context, q MailboxQuery) ([]model.Mailbox, int, error) {
+	if q.Page <= 0 {
+		q.Page = 1
+	}
+	if q.PageSize <= 0 {
+		q.PageSize = 50
+	}
+
+	where, args := sqliteMailboxWhere(q)
+	var total int
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM mailboxes"+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("count mailboxes: %w", err)
+	}
+
+	offset := (q.Page - 1) * q.PageSize
+	var items []model.Mailbox
+	err := r.query(ctx, q, false, " LIMIT ? OFFSET ?", []any{q.PageSize, offset}, func(m model.Mailbox) error {
+		items = append(items, m)
+		return nil
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("list mailboxes: %w", err)
+	}
+	return items, total, nil
+}
+
+// StreamAll streams mailboxes (optionally filtered by active) to a callback.
+func (r *SQLiteMailboxRepository) StreamAll(ctx context.Context, activeOnly bool, fn func(model.Mailbox) error) error {
+	var q MailboxQuery
+	if activeOnly {
+		active := true
+		q.Active = &active
+	}
+	return r.query(ctx, q, false, "", nil, fn)
+}
+
+// StreamWithQuery streams mailboxes with filters to a callback.
+func (r *SQLiteMailboxRepository) StreamWithQuery(ctx context.Context, q MailboxQuery, fn func(model.Mailbox) error) error {
+	return r.query(ctx, q, false, "", nil, fn)
+}
+
+// query runs a filtered SELECT ordered by id and decodes each row into fn.
+func (r *SQLiteMailboxRepository) query(ctx context.Context, q MailboxQuery, withHTML bool, suffix string, suffixArgs []any, fn func(model.Mailbox) error) error {
+	where, args := sqliteMailboxWhere(q)
+	cols := "id, data, ''"
+	if withHTML {
+		cols = "id, data, raw_html"
+	}
+	rows, err := r.db.QueryContext(ctx, "SELECT "+cols+" FROM mailboxes"+where+" ORDER BY id"+suffix, append(args, suffixArgs...)...)
+	if err != nil {
+		return fmt.Errorf("iterate mailboxes: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, data, rawHTML string
+		if err := rows.Scan(&id, &data, &rawHTML); err != nil {
+			return fmt.Errorf("iterate mailboxes: %w", err)
+		}
+		var m model.Mailbox
+		if err := json.Unmarshal([]byte(data), &m); err != nil {
+			return fmt.Errorf("decode mailbox %s: %w", id, err)
+		}
+		if m.ID == "" {
+			m.ID = id
+		}
+		m.RawHTML = rawHTML
+		if err := fn(m); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// sqliteMailboxWhere translates MailboxQuery filters into a WHERE clause.
+func sqliteMailboxWhere(q MailboxQuery) (string, []any) {
+	var conds []string
+	var args []any
+	if q.State != "" {
+		conds = append(conds, "state = ?")
+		args = append(args, q.State)
+	}
+	if q.CMRA != "" {
+		conds = append(conds, "cmra = ?")
+		args = append(args, q.CMRA)
+	}
+	if q.RDI != "" {
+		conds = append(conds, "rdi = ?")
+		args = append(args, q.RDI)
+	}
+	if q.Source != "" {
+		conds = append(conds, "source = ?")
+		args = append(args, q.Source)
+	}
+	// The analysis and status filters read the JSON document rather than promoted columns, so
+	// databases created before those fields were stored need no migration.
+	if q.DPVMatchCode != "" {
+		conds = append(conds, "json_extract(data, '$.analysis.dpvMatchCode') = ?")
+		args = append(args, q.DPVMatchCode)
+	}
+	if q.Vacant != "" {
+		conds = append(conds, "json_extract(data, '$.analysis.dpvVacant') = ?")
+		args = append(args, q.Vacant)
+	}
+	if q.Status != "" {
+		conds = append(conds, "json_extract(data, '$.validationStatus') = ?")
+		args = append(args, q.Status)
+	}
+	if q.Disagree {
+		conds = append(conds, "json_extract(data, '$.validatorsDisagree') = 1")
+	}
+	if q.Active != nil {
+		conds = append(conds, "active = ?")
+		args = append(args, *q.Active)
+	}
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
@@ -0,0 +1,452 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	sqliteclient "github.com/weiwei-tsao/virtualbox-verifier/apps/api/internal/platform/sqlite"
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/model"
+)
+
+func openTestSQLite(t *testing.T) *sql.DB {
+	t.Helper()
+	ctx := context.Background()
+	db, err := sqliteclient.New(ctx, filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := MigrateSQLite(ctx, db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
+
+func newTestSQLite(t *testing.T) (*SQLiteMailboxRepository, *SQLiteRunRepository, *SQLiteStatsRepository) {
+	t.Helper()
+	db := openTestSQLite(t)
+	return NewSQLiteMailboxRepository(db), NewSQLiteRunRepository(db), NewSQLiteStatsRepository(db)
+}
+
+func TestSQLiteMailboxRepository(t *testing.T) {
+	ctx := context.Background()
+	mailboxes, _, _ := newTestSQLite(t)
+
+	seed := []model.Mailbox{
+		{Link: "https://a", Name: "A", Source: "ATMB", AddressRaw: model.AddressRaw{State: "CA"}, CMRA: "Y", RDI: "Commercial", Active: true, RawHTML: "<html>a</html>"},
+		{Link: "https://b", Name: "B", Source: "ATMB", AddressRaw: model.AddressRaw{State: "CA"}, CMRA: "N", RDI: "Commercial", Active: true,
+			ValidatorsDisagree: true},
+		{Link: "https://c", Name: "C", Source: "iPost1", AddressRaw: model.AddressRaw{State: "TX"}, CMRA: "N", RDI: "Residential", Active: false,
+			Analysis: model.AddressAnalysis{DPVMatchCode: "Y", DPVVacant: "Y"}},
+	}
+	if err := mailboxes.BatchUpsert(ctx, seed); err != nil {
+		t.Fatalf("BatchUpsert: %v", err)
+	}
+
+	all, err := mailboxes.FetchAllMap(ctx)
+	if err != nil {
+		t.Fatalf("FetchAllMap: %v", err)
+	}
+	if len(all) != 3 {
+		t.Fatalf("FetchAllMap returned %d items, want 3", len(all))
+	}
+	if all["https://a"].RawHTML != "<html>a</html>" {
+		t.Errorf("FetchAllMap should include RawHTML")
+	}
+	if all["https://a"].ID == "" {
+		t.Errorf("ID should be assigned on upsert")
+	}
+	got, err := mailboxes.Get(ctx, all["https://a"].ID)
+	if err != nil || got.Link != "https://a" || got.RawHTML != "<html>a</html>" {
+		t.Errorf("Get = %+v, %v; want https://a with RawHTML", got, err)
+	}
+	if _, err := mailboxes.Get(ctx, "missing"); err == nil {
+		t.Errorf("Get of a missing mailbox should fail")
+	}
+
+	meta, err := mailboxes.FetchAllMetadata(ctx)
+	if err != nil {
+		t.Fatalf("FetchAllMetadata: %v", err)
+	}
+	if meta["https://a"].RawHTML != "" {
+		t.Errorf("FetchAllMetadata should not include RawHTML")
+	}
+
+	active := true
+	items, total, err := mailboxes.List(ctx, MailboxQuery{State: "CA", Active: &active, PageSize: 1, Page: 2})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if total != 2 || len(items) != 1 {
+		t.Fatalf("List total=%d len=%d, want total=2 len=1", total, len(items))
+	}
+
+	_, total, err = mailboxes.List(ctx, MailboxQuery{CMRA: "N", RDI: "Residential", Source: "iPost1"})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if total != 1 {
+		t.Errorf("List by cmra/rdi/source total=%d, want 1", total)
+	}
+	items, total, err = mailboxes.List(ctx, MailboxQuery{DPVMatchCode: "Y", Vacant: "Y"})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if total != 1 || items[0].Link != "https://c" || items[0].Analysis.DPVVacant != "Y" {
+		t.Errorf("List by dpv match code/vacancy = %+v (total %d), want https://c", items, total)
+	}
+	items, total, err = mailboxes.List(ctx, MailboxQuery{Disagree: true})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if total != 1 || items[0].Link != "https://b" {
+		t.Errorf("List by disagreement = %+v (total %d), want https://b", items, total)
+	}
+
+	// Upserting an existing ID replaces the record.
+	updated := all["https://b"]
+	updated.CMRA = "Y"
+	if err := mailboxes.BatchUpsert(ctx, []model.Mailbox{updated}); err != nil {
+		t.Fatalf("BatchUpsert update: %v", err)
+	}
+	var streamed int
+	err = mailboxes.StreamWithQuery(ctx, MailboxQuery{CMRA: "Y"}, func(model.Mailbox) error {
+		streamed++
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("StreamWithQuery: %v", err)
+	}
+	if streamed != 2 {
+		t.Errorf("StreamWithQuery streamed %d, want 2", streamed)
+	}
+}
+
+func TestSQLiteRunRepository(t *testing.T) {
+	ctx := context.Background()
+	_, runs, stats := newTestSQLite(t)
+
+	stale := model.CrawlRun{RunID: "RUN_1", Status: "running", StartedAt: time.Now().Add(-2 * StaleRunTimeout)}
+	fresh := model.CrawlRun{RunID: "RUN_2", Status: "running", StartedAt: time.Now()}
+	for _, run := range []model.CrawlRun{stale, fresh} {
+		if err := runs.CreateRun(ctx, run); err != nil {
+			t.Fatalf("CreateRun: %v", err)
+		}
+	}
+
+	list, err := runs.ListRuns(ctx, 10)
+	if err != nil {
+		t.Fatalf("ListRuns: %v", err)
+	}
+	if len(list) != 2 || list[0].RunID != "RUN_2" {
+		t.Fatalf("ListRuns order unexpected: %+v", list)
+	}
+	if got, _ := runs.GetRun(ctx, "RUN_1"); got.Status != "timeout" {
+		t.Errorf("stale run status = %q, want timeout", got.Status)
+	}
+
+	if err := runs.CancelRun(ctx, "RUN_2"); err != nil {
+		t.Fatalf("CancelRun: %v", err)
+	}
+	if err := runs.CancelRun(ctx, "RUN_2"); err == nil {
+		t.Errorf("CancelRun on a cancelled run should fail")
+	}
+
+	if err := stats.SaveSystemStats(ctx, model.SystemStats{TotalMailboxes: 7}); err != nil {
+		t.Fatalf("SaveSystemStats: %v", err)
+	}
+	got, err := stats.GetSystemStats(ctx)
+	if err != nil {
+		t.Fatalf("GetSystemStats: %v", err)
+	}
+	if got.TotalMailboxes != 7 || got.LastUpdated.IsZero() {
+		t.Errorf("unexpected stats: %+v", got)
+	}
+}
+
+func TestSQLiteRunRepositoryCheckpoint(t *testing.T) {
+	ctx := context.Background()
+	_, runs, _ := newTestSQLite(t)
+
+	if _, err := runs.GetCheckpoint(ctx, "RUN_1"); err == nil {
+		t.Errorf("GetCheckpoint without a checkpoint should fail")
+	}
+	cp := model.CrawlCheckpoint{RunID: "RUN_1", Source: "ATMB", Failures: []string{"Texas"}, Listings: []model.CheckpointEntry{
+		{Listing: model.Mailbox{Link: "https://b", Name: "B"}},
+		{Listing: model.Mailbox{Link: "https://a"}},
+	}}
+	if err := runs.SaveCheckpoint(ctx, cp); err != nil {
+		t.Fatalf("SaveCheckpoint: %v", err)
+	}
+	if err := runs.MarkCheckpointDone(ctx, "RUN_1", []string{"https://a"}); err != nil {
+		t.Fatalf("MarkCheckpointDone: %v", err)
+	}
+
+	got, err := runs.GetCheckpoint(ctx, "RUN_1")
+	if err != nil {
+		t.Fatalf("GetCheckpoint: %v", err)
+	}
+	if got.Source != "ATMB" || len(got.Listings) != 2 || len(got.Failures) != 1 {
+		t.Fatalf("unexpected checkpoint: %+v", got)
+	}
+	if got.Listings[0].Listing.Link != "https://b" || got.Listings[0].Listing.Name != "B" || got.Listings[0].Done {
+		t.Errorf("first listing = %+v, want pending https://b in discovery order", got.Listings[0])
+	}
+	if !got.Listings[1].Done {
+		t.Errorf("https://a should be done")
+	}
+}
+
+func TestSQLiteRunRepositoryEvents(t *testing.T) {
+	ctx := context.Background()
+	_, runs, _ := newTestSQLite(t)
+
+	err := runs.AppendRunEvents(ctx, "RUN_1", []model.LinkEvent{
+		{Link: "https://a", Stage: model.StageFetch, Error: "status 500", DurationMs: 12},
+		{Link: "https://b", Stage: model.StageParse, Error: "no address"},
+		{Link: "https://c", Stage: model.StageFetch, Error: "timeout"},
+	})
+	if err != nil {
+		t.Fatalf("AppendRunEvents: %v", err)
+	}
+	if err := runs.AppendRunEvents(ctx, "RUN_2", []model.LinkEvent{{Link: "https://d", Stage: model.StageSave}}); err != nil {
+		t.Fatalf("AppendRunEvents: %v", err)
+	}
+
+	got, total, err := runs.ListRunEvents(ctx, RunEventQuery{RunID: "RUN_1", Stage: model.StageFetch, PageSize: 1, Page: 2})
+	if err != nil {
+		t.Fatalf("ListRunEvents: %v", err)
+	}
+	if total != 2 || len(got) != 1 || got[0].Link != "https://c" || got[0].RunID != "RUN_1" {
+		t.Errorf("second fetch failure = %+v (total %d), want https://c of 2", got, total)
+	}
+	all, total, err := runs.ListRunEvents(ctx, RunEventQuery{RunID: "RUN_1"})
+	if err != nil {
+		t.Fatalf("ListRunEvents: %v", err)
+	}
+	if total != 3 || len(all) != 3 || all[0].Link != "https://a" || all[0].DurationMs != 12 {
+		t.Errorf("run events = %+v (total %d), want all three in recorded order", all, total)
+	}
+}
+
+func TestSQLiteRunRepositoryLock(t *testing.T) {
+	ctx := context.Background()
+	_, runs, _ := newTestSQLite(t)
+	now := time.Now().UTC()
+
+	lock := model.RunLock{Key: "crawl:atmb", RunID: "RUN_A", AcquiredAt: now, ExpiresAt: now.Add(time.Minute)}
+	if _, ok, err := runs.AcquireRunLock(ctx, lock); err != nil || !ok {
+		t.Fatalf("acquire = %v, %v; want acquired", ok, err)
+	}
+	holder, ok, err := runs.AcquireRunLock(ctx, model.RunLock{Key: "crawl:atmb", RunID: "RUN_B", ExpiresAt: now.Add(time.Minute)})
+	if err != nil || ok || holder.RunID != "RUN_A" {
+		t.Fatalf("second acquire = %+v, %v, %v; want refused with RUN_A as holder", holder, ok, err)
+	}
+	if _, ok, _ := runs.AcquireRunLock(ctx, model.RunLock{Key: "reprocess", RunID: "RUN_B", ExpiresAt: now.Add(time.Minute)}); !ok {
+		t.Errorf("a different key should be independent")
+	}
+
+	if ok, err := runs.RenewRunLock(ctx, "crawl:atmb", "RUN_B", now.Add(time.Hour)); err != nil || ok {
+		t.Errorf("renew by non-holder = %v, %v; want false", ok, err)
+	}
+	if ok, err := runs.RenewRunLock(ctx, "crawl:atmb", "RUN_A", now.Add(-time.Second)); err != nil || !ok {
+		t.Fatalf("renew = %v, %v; want renewed", ok, err)
+	}
+	// RUN_A's lease is now expired, so RUN_B takes over and RUN_A can no longer release it.
+	if _, ok, _ := runs.AcquireRunLock(ctx, model.RunLock{Key: "crawl:atmb", RunID: "RUN_B", ExpiresAt: now.Add(time.Minute)}); !ok {
+		t.Fatalf("acquire of an expired lock refused")
+	}
+	if err := runs.ReleaseRunLock(ctx, "crawl:atmb", "RUN_A"); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	if holder, ok, _ := runs.AcquireRunLock(ctx, model.RunLock{Key: "crawl:atmb", RunID: "RUN_C", ExpiresAt: now.Add(time.Minute)}); ok || holder.RunID != "RUN_B" {
+		t.Errorf("stale release dropped RUN_B's lock (holder %+v)", holder)
+	}
+	if err := runs.ReleaseRunLock(ctx, "crawl:atmb", "RUN_B"); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	if _, ok, _ := runs.AcquireRunLock(ctx, model.RunLock{Key: "crawl:atmb", RunID: "RUN_C", ExpiresAt: now.Add(time.Minute)}); !ok {
+		t.Errorf("acquire after release refused")
+	}
+}
+
+func TestSQLiteHistoryRepository(t *testing.T) {
+	ctx := context.Background()
+	history := NewSQLiteHistoryRepository(openTestSQLite(t))
+
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	entries := []model.MailboxHistory{
+		{MailboxID: "m1", CrawlRunID: "RUN_1", ChangedAt: base, Changes: []model.FieldChange{{Field: "price", Old: "9.99", New: "12.99"}}},
+		{MailboxID: "m1", CrawlRunID: "RUN_2", ChangedAt: base.Add(time.Hour), Changes: []model.FieldChange{{Field: "cmra", Old: "", New: "Y"}}},
+		{MailboxID: "m2", CrawlRunID: "RUN_2", ChangedAt: base, Changes: []model.FieldChange{{Field: "active", Old: "true", New: "false"}}},
+	}
+	if err := history.AppendHistory(ctx, entries); err != nil {
+		t.Fatalf("AppendHistory: %v", err)
+	}
+
+	got, err := history.ListHistory(ctx, "m1", 10)
+	if err != nil {
+		t.Fatalf("ListHistory: %v", err)
+	}
+	if len(got) != 2 || got[0].CrawlRunID != "RUN_2" || got[1].CrawlRunID != "RUN_1" {
+		t.Fatalf("ListHistory should return newest first: %+v", got)
+	}
+	if got[0].ID == "" || got[0].Changes[0].New != "Y" {
+		t.Errorf("unexpected entry: %+v", got[0])
+	}
+
+	if got, _ := history.ListHistory(ctx, "m1", 1); len(got) != 1 {
+		t.Errorf("ListHistory limit ignored: got %d", len(got))
+	}
+}
+
+func TestSQLiteHistoryRepositoryPrices(t *testing.T) {
+	ctx := context.Background()
+	history := NewSQLiteHistoryRepository(openTestSQLite(t))
+
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	points := []model.PricePoint{
+		{MailboxID: "m1", Price: 9.99, CrawlRunID: "RUN_1", ObservedAt: base},
+		{MailboxID: "m2", Price: 20, CrawlRunID: "RUN_1", ObservedAt: base},
+		{MailboxID: "m1", Price: 12.99, CrawlRunID: "RUN_2", ObservedAt: base.Add(24 * time.Hour)},
+		{MailboxID: "m1", Price: 14.99, CrawlRunID: "RUN_3", ObservedAt: base.Add(48 * time.Hour)},
+	}
+	if err := history.AppendPrices(ctx, points); err != nil {
+		t.Fatalf("AppendPrices: %v", err)
+	}
+
+	series, err := history.ListPrices(ctx, "m1", 2)
+	if err != nil {
+		t.Fatalf("ListPrices: %v", err)
+	}
+	if len(series) != 2 || series[0].Price != 12.99 || series[1].Price != 14.99 {
+		t.Fatalf("ListPrices should return the latest points oldest first: %+v", series)
+	}
+
+	run, err := history.ListRunPrices(ctx, "RUN_1")
+	if err != nil {
+		t.Fatalf("ListRunPrices: %v", err)
+	}
+	if len(run) != 2 {
+		t.Errorf("ListRunPrices returned %d points, want 2", len(run))
+	}
+}
+
+func TestSQLiteScheduleRepository(t *testing.T) {
+	ctx := context.Background()
+	repo := NewSQLiteScheduleRepository(openTestSQLite(t))
+
+	due := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+	for _, s := range []model.Schedule{
+		{ID: "SCHED_2", Action: model.ScheduleRefreshStats, Cron: "@hourly", NextRunAt: due},
+		{ID: "SCHED_1", Action: model.ScheduleCrawl, Provider: "ATMB", Cron: "0 3 * * *", NextRunAt: due},
+	} {
+		if err := repo.SaveSchedule(ctx, s); err != nil {
+			t.Fatalf("save schedule: %v", err)
+		}
+	}
+	list, err := repo.ListSchedules(ctx)
+	if err != nil || len(list) != 2 || list[0].ID != "SCHED_1" || list[0].Provider != "ATMB" {
+		t.Fatalf("list = %+v, %v; want both schedules ordered by ID", list, err)
+	}
+
+	claimed := list[0]
+	claimed.NextRunAt = due.Add(24 * time.Hour)
+	if ok, err := repo.ClaimSchedule(ctx, claimed, due); err != nil || !ok {
+		t.Fatalf("claim = %v, %v; want claimed", ok, err)
+	}
+	if ok, err := repo.ClaimSchedule(ctx, claimed, due); err != nil || ok {
+		t.Errorf("second claim = %v, %v; want lost", ok, err)
+	}
+	got, err := repo.GetSchedule(ctx, "SCHED_1")
+	if err != nil || !got.NextRunAt.Equal(claimed.NextRunAt) {
+		t.Errorf("get = %+v, %v; want NextRunAt %v", got, err, claimed.NextRunAt)
+	}
+
+	if err := repo.DeleteSchedule(ctx, "SCHED_1"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := repo.GetSchedule(ctx, "SCHED_1"); err == nil {
+		t.Errorf("get after delete succeeded")
+	}
+	if _, err := repo.ClaimSchedule(ctx, claimed, claimed.NextRunAt); err == nil {
+		t.Errorf("claim of a deleted schedule succeeded, want error")
+	}
+}
+
+func TestSQLiteJobRepository(t *testing.T) {
+	ctx := context.Background()
+	repo := NewSQLiteJobRepository(openTestSQLite(t))
+	now := time.Now().UTC()
+
+	for _, job := range []model.Job{
+		{ID: "RUN_2", Kind: model.JobReprocess, LockKey: "reprocess", EnqueuedAt: now.Add(time.Second)},
+		{ID: "RUN_1", Kind: model.JobCrawl, LockKey: "crawl:atmb", Provider: "ATMB", Seeds: []string{"https://a"}, EnqueuedAt: now},
+	} {
+		if _, ok, err := repo.EnqueueJob(ctx, job); err != nil || !ok {
+			t.Fatalf("EnqueueJob(%s) = %v, %v", job.ID, ok, err)
+		}
+	}
+	holder, ok, err := repo.EnqueueJob(ctx, model.Job{ID: "RUN_3", Kind: model.JobCrawl, LockKey: "crawl:atmb", Provider: "ATMB"})
+	if err != nil || ok || holder.ID != "RUN_1" {
+		t.Fatalf("duplicate enqueue = %+v, %v, %v; want refused with RUN_1 as holder", holder, ok, err)
+	}
+
+	// Jobs are claimed oldest first, each by one worker.
+	job, ok, err := repo.ClaimJob(ctx, "w1", now.Add(time.Minute))
+	if err != nil || !ok || job.ID != "RUN_1" || job.Attempts != 1 || job.Owner != "w1" || len(job.Seeds) != 1 {
+		t.Fatalf("first claim = %+v, %v, %v; want RUN_1 leased to w1", job, ok, err)
+	}
+	if job, ok, _ := repo.ClaimJob(ctx, "w2", now.Add(time.Minute)); !ok || job.ID != "RUN_2" {
+		t.Fatalf("second claim = %+v, %v; want RUN_2", job, ok)
+	}
+	if _, ok, _ := repo.ClaimJob(ctx, "w2", now.Add(time.Minute)); ok {
+		t.Fatalf("claimed a job from an empty queue")
+	}
+
+	if _, ok, err := repo.HeartbeatJob(ctx, "RUN_1", "w2", now.Add(time.Hour)); err != nil || ok {
+		t.Errorf("heartbeat by non-owner = %v, %v; want false", ok, err)
+	}
+	// w1 stops heartbeating: its lease lapses and the job is queued again.
+	if _, ok, err := repo.HeartbeatJob(ctx, "RUN_1", "w1", now.Add(-time.Second)); err != nil || !ok {
+		t.Fatalf("heartbeat = %v, %v; want renewed", ok, err)
+	}
+	requeued, err := repo.RequeueExpiredJobs(ctx, now, 2)
+	if err != nil || len(requeued) != 1 || requeued[0].Status != model.JobQueued {
+		t.Fatalf("RequeueExpiredJobs = %+v, %v; want RUN_1 queued", requeued, err)
+	}
+	if err := repo.CompleteJob(ctx, "RUN_1", "w1", model.JobDone, ""); err == nil {
+		t.Errorf("complete by the expired owner succeeded, want error")
+	}
+	job, ok, _ = repo.ClaimJob(ctx, "w2", now.Add(-time.Second))
+	if !ok || job.ID != "RUN_1" || job.Attempts != 2 {
+		t.Fatalf("reclaim = %+v, %v; want RUN_1 on attempt 2", job, ok)
+	}
+	// A second lapse exhausts its attempts.
+	if failed, _ := repo.RequeueExpiredJobs(ctx, now, 2); len(failed) != 1 || failed[0].Status != model.JobFailed {
+		t.Fatalf("RequeueExpiredJobs after max attempts = %+v; want RUN_1 failed", failed)
+	}
+	if _, ok, _ := repo.EnqueueJob(ctx, model.Job{ID: "RUN_3", Kind: model.JobCrawl, LockKey: "crawl:atmb", Provider: "ATMB"}); !ok {
+		t.Errorf("enqueue after the holder failed was refused")
+	}
+
+	// Cancelling drops a queued job and flags a running one for its worker.
+	if job, err := repo.RequestJobCancel(ctx, "RUN_3"); err != nil || job.Status != model.JobCancelled {
+		t.Errorf("cancel queued = %+v, %v; want cancelled", job, err)
+	}
+	if job, err := repo.RequestJobCancel(ctx, "RUN_2"); err != nil || job.Status != model.JobRunning || !job.CancelRequested {
+		t.Errorf("cancel running = %+v, %v; want running with CancelRequested", job, err)
+	}
+	if job, ok, _ := repo.HeartbeatJob(ctx, "RUN_2", "w2", now.Add(time.Minute)); !ok || !job.CancelRequested {
+		t.Errorf("heartbeat = %+v, %v; want the cancel request to reach the owner", job, ok)
+	}
+	if err := repo.CompleteJob(ctx, "RUN_2", "w2", model.JobCancelled, ""); err != nil {
+		t.Fatalf("CompleteJob: %v", err)
+	}
+	if job, err := repo.GetJob(ctx, "RUN_2"); err != nil || job.Status != model.JobCancelled || job.FinishedAt.IsZero() {
+		t.Errorf("GetJob = %+v, %v; want cancelled and finished", job, err)
+	}
+}
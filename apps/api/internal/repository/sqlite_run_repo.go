@@ -0,0 +1,389 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/model"
+)
+
+// SQLiteRunRepository manages crawl run records in SQLite.
+type SQLiteRunRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteRunRepository(db *sql.DB) *SQLiteRunRepository {
+	return &SQLiteRunRepository{db: db}
+}
+
+func (r *SQLiteRunRepository) CreateRun(ctx context.Context, run model.CrawlRun) error {
+	if run.RunID == "" {
+		return fmt.Errorf("runId is required")
+	}
+	if err := r.put(ctx, run); err != nil {
+		return fmt.Errorf("create run %s: %w", run.RunID, err)
+	}
+	return nil
+}
+
+func (r *SQLiteRunRepository) UpdateRun(ctx context.Context, run model.CrawlRun) error {
+	if run.RunID == "" {
+		return fmt.Errorf("runId is required")
+	}
+	if err := r.put(ctx, run); err != nil {
+		return fmt.Errorf("update run %s: %w", run.RunID, err)
+	}
+	return nil
+}
+
+func (r *SQLiteRunRepository) put(ctx context.Context, run model.CrawlRun) error {
+	data, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(ctx, `INSERT INTO crawl_runs (run_id, data) VALUES (?, ?)
+		ON CONFLICT(run_id) DO UPDATE SET data = excluded.data`, run.RunID, string(data))
+	return err
+}
+
+// DeleteRun removes a crawl run record.
+func (r *SQLiteRunRepository) DeleteRun(ctx context.Context, runID string) error {
+	if runID == "" {
+		return fmt.Errorf("runId is required")
+	}
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM crawl_runs WHERE run_id = ?`, runID); err != nil {
+		return fmt.Errorf("delete run %s: %w", runID, err)
+	}
+	return nil
+}
+
+// GetRun returns a crawl run by ID.
+func (r *SQLiteRunRepository) GetRun(ctx context.Context, runID string) (model.CrawlRun, error) {
+	if runID == "" {
+		return model.CrawlRun{}, fmt.Errorf("runId is required")
+	}
+	var data string
+	err := r.db.QueryRowContext(ctx, "SELECT data FROM crawl_runs WHERE run_id = ?", runID).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.CrawlRun{}, fmt.Errorf("get run %s: not found", runID)
+	}
+	if err != nil {
+		return model.CrawlRun{}, fmt.Errorf("get run %s: %w", runID, err)
+	}
+	var run model.CrawlRun
+	if err := json.Unmarshal([]byte(data), &run); err != nil {
+		return model.CrawlRun{}, fmt.Errorf("decode run %s: %w", runID, err)
+	}
+	return run, nil
+}
+
+// ListRuns returns recent crawl runs ordered by runId descending.
+// It also auto-marks stale running jobs as "timeout".
+func (r *SQLiteRunRepository) ListRuns(ctx context.Context, limit int) ([]model.CrawlRun, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	rows, err := r.db.QueryContext(ctx, "SELECT run_id, data FROM crawl_runs ORDER BY run_id DESC LIMIT ?", limit)
+	if err != nil {
+		return nil, fmt.Errorf("list runs: %w", err)
+	}
+	var runs []model.CrawlRun
+	var stale []model.CrawlRun
+	now := time.Now().UTC()
+	for rows.Next() {
+		var id, data string
+		if err := rows.Scan(&id, &data); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("list runs: %w", err)
+		}
+		var run model.CrawlRun
+		if err := json.Unmarshal([]byte(data), &run); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("decode run %s: %w", id, err)
+		}
+		// Auto-mark stale running jobs as timeout
+		if markStale(&run, now) {
+			stale = append(stale, run)
+		}
+		runs = append(runs, run)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list runs: %w", err)
+	}
+
+	// Persist after the cursor is closed; SQLite runs on a single connection.
+	for _, run := range stale {
+		_ = r.UpdateRun(ctx, run)
+	}
+	return runs, nil
+}
+
+// CancelRun marks a running job as cancelled.
+func (r *SQLiteRunRepository) CancelRun(ctx context.Context, runID string) error {
+	return cancelRun(ctx, r, runID)
+}
+
+// SaveCheckpoint records the listings discovered by a run, one row per listing, plus a header row
+// with the discovery failures.
+func (r *SQLiteRunRepository) SaveCheckpoint(ctx context.Context, cp model.CrawlCheckpoint) error {
+	if cp.RunID == "" {
+		return fmt.Errorf("runId is required")
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("save checkpoint %s: %w", cp.RunID, err)
+	}
+	defer tx.Rollback()
+
+	header := cp
+	header.Listings = nil
+	data, err := json.Marshal(header)
+	if err != nil {
+		return fmt.Errorf("encode checkpoint %s: %w", cp.RunID, err)
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO crawl_checkpoint_runs (run_id, data) VALUES (?, ?)
+		ON CONFLICT(run_id) DO UPDATE SET data = excluded.data`, cp.RunID, string(data)); err != nil {
+		return fmt.Errorf("save checkpoint %s: %w", cp.RunID, err)
+	}
+	for _, e := range cp.Listings {
+		data, err := json.Marshal(e.Listing)
+		if err != nil {
+			return fmt.Errorf("encode checkpoint %s: %w", e.Listing.Link, err)
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO crawl_checkpoints (run_id, link, source, done, data) VALUES (?, ?, ?, ?, ?)
+			ON CONFLICT(run_id, link) DO UPDATE SET source = excluded.source, done = excluded.done, data = excluded.data`,
+			cp.RunID, e.Listing.Link, cp.Source, e.Done, string(data)); err != nil {
+			return fmt.Errorf("save checkpoint %s: %w", cp.RunID, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("save checkpoint %s: %w", cp.RunID, err)
+	}
+	return nil
+}
+
+// MarkCheckpointDone flags the given links of a run's checkpoint as finished.
+func (r *SQLiteRunRepository) MarkCheckpointDone(ctx context.Context, runID string, links []string) error {
+	if runID == "" {
+		return fmt.Errorf("runId is required")
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("mark checkpoint %s: %w", runID, err)
+	}
+	defer tx.Rollback()
+
+	for _, link := range links {
+		if _, err := tx.ExecContext(ctx, "UPDATE crawl_checkpoints SET done = 1 WHERE run_id = ? AND link = ?", runID, link); err != nil {
+			return fmt.Errorf("mark checkpoint %s: %w", runID, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("mark checkpoint %s: %w", runID, err)
+	}
+	return nil
+}
+
+// GetCheckpoint returns a run's checkpoint with every discovered listing in discovery order.
+func (r *SQLiteRunRepository) GetCheckpoint(ctx context.Context, runID string) (model.CrawlCheckpoint, error) {
+	if runID == "" {
+		return model.CrawlCheckpoint{}, fmt.Errorf("runId is required")
+	}
+	rows, err := r.db.QueryContext(ctx, "SELECT source, done, data FROM crawl_checkpoints WHERE run_id = ? ORDER BY rowid", runID)
+	if err != nil {
+		return model.CrawlCheckpoint{}, fmt.Errorf("get checkpoint %s: %w", runID, err)
+	}
+	defer rows.Close()
+
+	cp := model.CrawlCheckpoint{RunID: runID}
+	for rows.Next() {
+		var data string
+		var e model.CheckpointEntry
+		if err := rows.Scan(&cp.Source, &e.Done, &data); err != nil {
+			return model.CrawlCheckpoint{}, fmt.Errorf("get checkpoint %s: %w", runID, err)
+		}
+		if err := json.Unmarshal([]byte(data), &e.Listing); err != nil {
+			return model.CrawlCheckpoint{}, fmt.Errorf("decode checkpoint %s: %w", runID, err)
+		}
+		cp.Listings = append(cp.Listings, e)
+	}
+	if err := rows.Err(); err != nil {
+		return model.CrawlCheckpoint{}, fmt.Errorf("get checkpoint %s: %w", runID, err)
+	}
+	if len(cp.Listings) == 0 {
+		return model.CrawlCheckpoint{}, fmt.Errorf("get checkpoint %s: not found", runID)
+	}
+	rows.Close()
+
+	var data string
+	err = r.db.QueryRowContext(ctx, "SELECT data FROM crawl_checkpoint_runs WHERE run_id = ?", runID).Scan(&data)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return model.CrawlCheckpoint{}, fmt.Errorf("get checkpoint %s: %w", runID, err)
+	}
+	if err == nil {
+		var header model.CrawlCheckpoint
+		if err := json.Unmarshal([]byte(data), &header); err != nil {
+			return model.CrawlCheckpoint{}, fmt.Errorf("decode checkpoint %s: %w", runID, err)
+		}
+		cp.Failures = header.Failures
+	}
+	return cp, nil
+}
+
+// AppendRunEvents records links that failed during a run.
+func (r *SQLiteRunRepository) AppendRunEvents(ctx context.Context, runID string, events []model.LinkEvent) error {
+	if runID == "" {
+		return fmt.Errorf("runId is required")
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("append run events %s: %w", runID, err)
+	}
+	defer tx.Rollback()
+
+	for _, e := range events {
+		e.RunID = runID
+		data, err := json.Marshal(e)
+		if err != nil {
+			return fmt.Errorf("encode run event %s: %w", runID, err)
+		}
+		if _, err := tx.ExecContext(ctx, "INSERT INTO run_events (run_id, stage, data) VALUES (?, ?, ?)", runID, e.Stage, string(data)); err != nil {
+			return fmt.Errorf("append run events %s: %w", runID, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("append run events %s: %w", runID, err)
+	}
+	return nil
+}
+
+// ListRunEvents returns a page of a run's failed links in the order they were recorded.
+func (r *SQLiteRunRepository) ListRunEvents(ctx context.Context, q RunEventQuery) ([]model.LinkEvent, int, error) {
+	if q.RunID == "" {
+		return nil, 0, fmt.Errorf("runId is required")
+	}
+	q = pageRunEvents(q)
+	where := "WHERE run_id = ?"
+	args := []any{q.RunID}
+	if q.Stage != "" {
+		where += " AND stage = ?"
+		args = append(args, q.Stage)
+	}
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM run_events "+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("count run events %s: %w", q.RunID, err)
+	}
+	rows, err := r.db.QueryContext(ctx, "SELECT data FROM run_events "+where+" ORDER BY id LIMIT ? OFFSET ?",
+		append(args, q.PageSize, (q.Page-1)*q.PageSize)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("list run events %s: %w", q.RunID, err)
+	}
+	defer rows.Close()
+
+	var events []model.LinkEvent
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, 0, fmt.Errorf("list run events %s: %w", q.RunID, err)
+		}
+		var e model.LinkEvent
+		if err := json.Unmarshal([]byte(data), &e); err != nil {
+			return nil, 0, fmt.Errorf("decode run event %s: %w", q.RunID, err)
+		}
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("list run events %s: %w", q.RunID, err)
+	}
+	return events, total, nil
+}
+
+// AcquireRunLock takes lock.Key for lock.RunID. The read and write share a transaction, so two
+// processes sharing the database cannot both win.
+func (r *SQLiteRunRepository) AcquireRunLock(ctx context.Context, lock model.RunLock) (model.RunLock, bool, error) {
+	if lock.Key == "" || lock.RunID == "" {
+		return model.RunLock{}, false, fmt.Errorf("lock key and runId are required")
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return model.RunLock{}, false, fmt.Errorf("acquire lock %s: %w", lock.Key, err)
+	}
+	defer tx.Rollback()
+
+	var data string
+	err = tx.QueryRowContext(ctx, "SELECT data FROM run_locks WHERE key = ?", lock.Key).Scan(&data)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return model.RunLock{}, false, fmt.Errorf("acquire lock %s: %w", lock.Key, err)
+	}
+	if err == nil {
+		var held model.RunLock
+		if err := json.Unmarshal([]byte(data), &held); err != nil {
+			return model.RunLock{}, false, fmt.Errorf("decode lock %s: %w", lock.Key, err)
+		}
+		if !lockAvailable(held, lock, time.Now().UTC()) {
+			return held, false, nil
+		}
+	}
+
+	encoded, err := json.Marshal(lock)
+	if err != nil {
+		return model.RunLock{}, false, fmt.Errorf("encode lock %s: %w", lock.Key, err)
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO run_locks (key, run_id, expires_at, data) VALUES (?, ?, ?, ?)
+		ON CONFLICT(key) DO UPDATE SET run_id = excluded.run_id, expires_at = excluded.expires_at, data = excluded.data`,
+		lock.Key, lock.RunID, lock.ExpiresAt.Unix(), string(encoded)); err != nil {
+		return model.RunLock{}, false, fmt.Errorf("acquire lock %s: %w", lock.Key, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return model.RunLock{}, false, fmt.Errorf("acquire lock %s: %w", lock.Key, err)
+	}
+	return lock, true, nil
+}
+
+// RenewRunLock extends runID's lease on key.
+func (r *SQLiteRunRepository) RenewRunLock(ctx context.Context, key, runID string, expiresAt time.Time) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("renew lock %s: %w", key, err)
+	}
+	defer tx.Rollback()
+
+	var data string
+	err = tx.QueryRowContext(ctx, "SELECT data FROM run_locks WHERE key = ? AND run_id = ?", key, runID).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("renew lock %s: %w", key, err)
+	}
+	var lock model.RunLock
+	if err := json.Unmarshal([]byte(data), &lock); err != nil {
+		return false, fmt.Errorf("decode lock %s: %w", key, err)
+	}
+	lock.ExpiresAt = expiresAt
+	encoded, err := json.Marshal(lock)
+	if err != nil {
+		return false, fmt.Errorf("encode lock %s: %w", key, err)
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE run_locks SET expires_at = ?, data = ? WHERE key = ?", expiresAt.Unix(), string(encoded), key); err != nil {
+		return false, fmt.Errorf("renew lock %s: %w", key, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("renew lock %s: %w", key, err)
+	}
+	return true, nil
+}
+
+// ReleaseRunLock deletes runID's lease on key.
+func (r *SQLiteRunRepository) ReleaseRunLock(ctx context.Context, key, runID string) error {
+	if _, err := r.db.ExecContext(ctx, "DELETE FROM run_locks WHERE key = ? AND run_id = ?", key, runID); err != nil {
+		return fmt.Errorf("release lock %s: %w", key, err)
+	}
+	return nil
+}
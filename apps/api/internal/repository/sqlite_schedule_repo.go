@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/model"
+)
+
+// SQLiteScheduleRepository manages the recurring jobs in SQLite.
+type SQLiteScheduleRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteScheduleRepository(db *sql.DB) *SQLiteScheduleRepository {
+	return &SQLiteScheduleRepository{db: db}
+}
+
+// ListSchedules returns every schedule ordered by ID.
+func (r *SQLiteScheduleRepository) ListSchedules(ctx context.Context) ([]model.Schedule, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT data FROM schedules ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("list schedules: %w", err)
+	}
+	defer rows.Close()
+	var schedules []model.Schedule
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("list schedules: %w", err)
+		}
+		var s model.Schedule
+		if err := json.Unmarshal([]byte(data), &s); err != nil {
+			return nil, fmt.Errorf("decode schedule: %w", err)
+		}
+		schedules = append(schedules, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list schedules: %w", err)
+	}
+	return schedules, nil
+}
+
+// GetSchedule returns a schedule by ID.
+func (r *SQLiteScheduleRepository) GetSchedule(ctx context.Context, id string) (model.Schedule, error) {
+	if id == "" {
+		return model.Schedule{}, fmt.Errorf("schedule id is required")
+	}
+	return r.get(ctx, r.db, id)
+}
+
+func (r *SQLiteScheduleRepository) get(ctx context.Context, q interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}, id string) (model.Schedule, error) {
+	var data string
+	err := q.QueryRowContext(ctx, "SELECT data FROM schedules WHERE id = ?", id).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.Schedule{}, fmt.Errorf("get schedule %s: not found", id)
+	}
+	if err != nil {
+		return model.Schedule{}, fmt.Errorf("get schedule %s: %w", id, err)
+	}
+	var s model.Schedule
+	if err := json.Unmarshal([]byte(data), &s); err != nil {
+		return model.Schedule{}, fmt.Errorf("decode schedule %s: %w", id, err)
+	}
+	return s, nil
+}
+
+// SaveSchedule creates or replaces a schedule.
+func (r *SQLiteScheduleRepository) SaveSchedule(ctx context.Context, s model.Schedule) error {
+	if s.ID == "" {
+		return fmt.Errorf("schedule id is required")
+	}
+	if err := r.put(ctx, r.db, s); err != nil {
+		return fmt.Errorf("save schedule %s: %w", s.ID, err)
+	}
+	return nil
+}
+
+func (r *SQLiteScheduleRepository) put(ctx context.Context, q interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}, s model.Schedule) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	_, err = q.ExecContext(ctx, `INSERT INTO schedules (id, next_run_at, data) VALUES (?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET next_run_at = excluded.next_run_at, data = excluded.data`,
+		s.ID, s.NextRunAt.Unix(), string(data))
+	return err
+}
+
+// DeleteSchedule removes a schedule. Deleting a missing schedule is not an error.
+func (r *SQLiteScheduleRepository) DeleteSchedule(ctx context.Context, id string) error {
+	if id == "" {
+		return fmt.Errorf("schedule id is required")
+	}
+	if _, err := r.db.ExecContext(ctx, "DELETE FROM schedules WHERE id = ?", id); err != nil {
+		return fmt.Errorf("delete schedule %s: %w", id, err)
+	}
+	return nil
+}
+
+// ClaimSchedule replaces the schedule with s only if its stored NextRunAt still equals due,
+// so when several processes share the database exactly one of them fires a due schedule.
+func (r *SQLiteScheduleRepository) ClaimSchedule(ctx context.Context, s model.Schedule, due time.Time) (bool, error) {
+	if s.ID == "" {
+		return false, fmt.Errorf("schedule id is required")
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("claim schedule %s: %w", s.ID, err)
+	}
+	defer tx.Rollback()
+
+	current, err := r.get(ctx, tx, s.ID)
+	if err != nil {
+		return false, fmt.Errorf("claim schedule %s: %w", s.ID, err)
+	}
+	if !current.NextRunAt.Equal(due) {
+		return false, nil
+	}
+	if err := r.put(ctx, tx, s); err != nil {
+		return false, fmt.Errorf("claim schedule %s: %w", s.ID, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("claim schedule %s: %w", s.ID, err)
+	}
+	return true, nil
+}
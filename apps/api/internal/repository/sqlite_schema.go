@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// sqliteSchema mirrors the Firestore collections. Each table keeps the full document as JSON in `data`
+// and promotes the fields we filter or sort on into indexed columns.
+var sqliteSchema = []string{
+	`CREATE TABLE IF NOT EXISTS mailboxes (
+		id        TEXT PRIMARY KEY,
+		link      TEXT NOT NULL DEFAULT '',
+		source    TEXT NOT NULL DEFAULT '',
+		state     TEXT NOT NULL DEFAULT '',
+		cmra      TEXT NOT NULL DEFAULT '',
+		rdi       TEXT NOT NULL DEFAULT '',
+		active    INTEGER NOT NULL DEFAULT 0,
+		data      TEXT NOT NULL,
+		raw_html  TEXT NOT NULL DEFAULT ''
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_mailboxes_link ON mailboxes(link)`,
+	`CREATE INDEX IF NOT EXISTS idx_mailboxes_active_state ON mailboxes(active, state)`,
+	`CREATE INDEX IF NOT EXISTS idx_mailboxes_source ON mailboxes(source)`,
+	`CREATE TABLE IF NOT EXISTS crawl_runs (
+		run_id  TEXT PRIMARY KEY,
+		data    TEXT NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS crawl_checkpoints (
+		run_id  TEXT NOT NULL,
+		link    TEXT NOT NULL,
+		source  TEXT NOT NULL DEFAULT '',
+		done    INTEGER NOT NULL DEFAULT 0,
+		data    TEXT NOT NULL,
+		PRIMARY KEY (run_id, link)
+	)`,
+	`CREATE TABLE IF NOT EXISTS crawl_checkpoint_runs (
+		run_id  TEXT PRIMARY KEY,
+		data    TEXT NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS run_events (
+		id      INTEGER PRIMARY KEY AUTOINCREMENT,
+		run_id  TEXT NOT NULL,
+		stage   TEXT NOT NULL DEFAULT '',
+		data    TEXT NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_run_events_run ON run_events(run_id, stage)`,
+	`CREATE TABLE IF NOT EXISTS run_locks (
+		key         TEXT PRIMARY KEY,
+		run_id      TEXT NOT NULL,
+		expires_at  INTEGER NOT NULL,
+		data        TEXT NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS mailbox_history (
+		id          INTEGER PRIMARY KEY AUTOINCREMENT,
+		mailbox_id  TEXT NOT NULL,
+		changed_at  INTEGER NOT NULL,
+		data        TEXT NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_mailbox_history_mailbox ON mailbox_history(mailbox_id, changed_at)`,
+	`CREATE TABLE IF NOT EXISTS price_history (
+		id           INTEGER PRIMARY KEY AUTOINCREMENT,
+		mailbox_id   TEXT NOT NULL,
+		run_id       TEXT NOT NULL DEFAULT '',
+		observed_at  INTEGER NOT NULL,
+		data         TEXT NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_price_history_mailbox ON price_history(mailbox_id, observed_at)`,
+	`CREATE INDEX IF NOT EXISTS idx_price_history_run ON price_history(run_id)`,
+	`CREATE TABLE IF NOT EXISTS jobs (
+		id           TEXT PRIMARY KEY,
+		lock_key     TEXT NOT NULL DEFAULT '',
+		status       TEXT NOT NULL,
+		enqueued_at  INTEGER NOT NULL,
+		data         TEXT NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_jobs_status ON jobs(status, enqueued_at)`,
+	`CREATE INDEX IF NOT EXISTS idx_jobs_lock_key ON jobs(lock_key, status)`,
+	`CREATE TABLE IF NOT EXISTS schedules (
+		id           TEXT PRIMARY KEY,
+		next_run_at  INTEGER NOT NULL DEFAULT 0,
+		data         TEXT NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS system (
+		id    TEXT PRIMARY KEY,
+		data  TEXT NOT NULL
+	)`,
+}
+
+// MigrateSQLite creates the tables used by the SQLite repositories if they do not exist.
+func MigrateSQLite(ctx context.Context, db *sql.DB) error {
+	for _, stmt := range sqliteSchema {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("migrate sqlite: %w", err)
+		}
+	}
+	return nil
+}
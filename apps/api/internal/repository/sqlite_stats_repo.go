@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/model"
+)
+
+// SQLiteStatsRepository manages the system stats singleton row.
+type SQLiteStatsRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteStatsRepository(db *sql.DB) *SQLiteStatsRepository {
+	return &SQLiteStatsRepository{db: db}
+}
+
+func (r *SQLiteStatsRepository) SaveSystemStats(ctx context.Context, stats model.SystemStats) error {
+	stats.LastUpdated = time.Now().UTC()
+	data, err := json.Marshal(stats)
+	if err != nil {
+		return fmt.Errorf("save system stats: %w", err)
+	}
+	_, err = r.db.ExecContext(ctx, `INSERT INTO system (id, data) VALUES ('stats', ?)
+		ON CONFLICT(id) DO UPDATE SET data = excluded.data`, string(data))
+	if err != nil {
+		return fmt.Errorf("save system stats: %w", err)
+	}
+	return nil
+}
+
+func (r *SQLiteStatsRepository) GetSystemStats(ctx context.Context) (model.SystemStats, error) {
+	var data string
+	err := r.db.QueryRowContext(ctx, "SELECT data FROM system WHERE id = 'stats'").Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.SystemStats{}, fmt.Errorf("get system stats: not found")
+	}
+	if err != nil {
+		return model.SystemStats{}, fmt.Errorf("get system stats: %w", err)
+	}
+	var stats model.SystemStats
+	if err := json.Unmarshal([]byte(data), &stats); err != nil {
+		return model.SystemStats{}, fmt.Errorf("decode system stats: %w", err)
+	}
+	return stats, nil
+}
@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/model"
+)
+
+// MailboxStore is the persistence contract for mailboxes, implemented by every storage backend.
+type MailboxStore interface {
+	FetchAllMap(ctx context.Context) (map[string]model.Mailbox, error)
+	FetchAllMetadata(ctx context.Context) (map[string]model.Mailbox, error)
+	BatchUpsert(ctx context.Context, mailboxes []model.Mailbox) error
+	// Get loads one mailbox, RawHTML included, by its document ID.
+	Get(ctx context.Context, id string) (model.Mailbox, error)
+	List(ctx context.Context, q MailboxQuery) ([]model.Mailbox, int, error)
+	StreamAll(ctx context.Context, activeOnly bool, fn func(model.Mailbox) error) error
+	StreamWithQuery(ctx context.Context, q MailboxQuery, fn func(model.Mailbox) error) error
+}
+
+// RunStore is the persistence contract for crawl run records, their failed links, their resume
+// checkpoints and the per-source leases that keep two runs of the same source from overlapping.
+type RunStore interface {
+	CreateRun(ctx context.Context, run model.CrawlRun) error
+	UpdateRun(ctx context.Context, run model.CrawlRun) error
+	// DeleteRun drops a run record, such as one a refused enqueue had already written. Its checkpoint
+	// and events are left alone.
+	DeleteRun(ctx context.Context, runID string) error
+	GetRun(ctx context.Context, runID string) (model.CrawlRun, error)
+	ListRuns(ctx context.Context, limit int) ([]model.CrawlRun, error)
+	CancelRun(ctx context.Context, runID string) error
+	SaveCheckpoint(ctx context.Context, cp model.CrawlCheckpoint) error
+	MarkCheckpointDone(ctx context.Context, runID string, links []string) error
+	GetCheckpoint(ctx context.Context, runID string) (model.CrawlCheckpoint, error)
+	// AppendRunEvents records links that failed during a run.
+	AppendRunEvents(ctx context.Context, runID string, events []model.LinkEvent) error
+	// ListRunEvents returns one page of a run's failed links in the order they were recorded, with their total.
+	ListRunEvents(ctx context.Context, q RunEventQuery) ([]model.LinkEvent, int, error)
+	// AcquireRunLock takes lock.Key for lock.RunID if it is free, expired or already held by that run.
+	// Otherwise it reports false along with the current holder.
+	AcquireRunLock(ctx context.Context, lock model.RunLock) (model.RunLock, bool, error)
+	// RenewRunLock extends the lease of runID on key, reporting false if the run no longer holds it.
+	RenewRunLock(ctx context.Context, key, runID string, expiresAt time.Time) (bool, error)
+	// ReleaseRunLock drops the lease of runID on key; a lock held by another run is left alone.
+	ReleaseRunLock(ctx context.Context, key, runID string) error
+}
+
+// StatsStore is the persistence contract for the system stats singleton.
+type StatsStore interface {
+	SaveSystemStats(ctx context.Context, stats model.SystemStats) error
+	GetSystemStats(ctx context.Context) (model.SystemStats, error)
+}
+
+// HistoryStore persists field-level change records and observed prices for mailboxes.
+type HistoryStore interface {
+	AppendHistory(ctx context.Context, entries []model.MailboxHistory) error
+	ListHistory(ctx context.Context, mailboxID string, limit int) ([]model.MailboxHistory, error)
+	AppendPrices(ctx context.Context, points []model.PricePoint) error
+	ListPrices(ctx context.Context, mailboxID string, limit int) ([]model.PricePoint, error)
+	ListRunPrices(ctx context.Context, runID string) ([]model.PricePoint, error)
+}
+
+// JobStore is the durable queue behind the crawl worker loop. Jobs are leased to one worker at a
+// time; a worker that stops heartbeating loses its jobs to RequeueExpiredJobs.
+type JobStore interface {
+	// EnqueueJob adds a queued job unless a queued or running job (this one included) has the same LockKey,
+	// in which case it reports false along with that job.
+	EnqueueJob(ctx context.Context, job model.Job) (model.Job, bool, error)
+	// ClaimJob leases the oldest queued job to owner until leaseUntil, reporting false when none is queued.
+	ClaimJob(ctx context.Context, owner string, leaseUntil time.Time) (model.Job, bool, error)
+	// HeartbeatJob extends owner's lease and returns the job, so the worker sees cancellation requests.
+	// It reports false if owner no longer holds the lease.
+	HeartbeatJob(ctx context.Context, id, owner string, leaseUntil time.Time) (model.Job, bool, error)
+	// CompleteJob records the final status of a job owner holds.
+	CompleteJob(ctx context.Context, id, owner, status, errMsg string) error
+	// RequeueExpiredJobs queues running jobs whose lease expired before now again, or fails those
+	// already claimed maxAttempts times, and returns the jobs it changed.
+	RequeueExpiredJobs(ctx context.Context, now time.Time, maxAttempts int) ([]model.Job, error)
+	// RequestJobCancel cancels a queued job outright and flags a running one for the worker holding it.
+	RequestJobCancel(ctx context.Context, id string) (model.Job, error)
+	// RequestJobPause sets or clears a running job's PauseRequested flag for the worker holding it,
+	// reporting whether the flag changed.
+	RequestJobPause(ctx context.Context, id string, paused bool) (model.Job, bool, error)
+	GetJob(ctx context.Context, id string) (model.Job, error)
+}
+
+// ScheduleStore persists the server scheduler's recurring jobs.
+type ScheduleStore interface {
+	ListSchedules(ctx context.Context) ([]model.Schedule, error)
+	GetSchedule(ctx context.Context, id string) (model.Schedule, error)
+	SaveSchedule(ctx context.Context, s model.Schedule) error
+	DeleteSchedule(ctx context.Context, id string) error
+	// ClaimSchedule stores s only if the stored schedule's NextRunAt still equals due, reporting whether it did.
+	// It is how one of several server instances wins the right to fire a due schedule.
+	ClaimSchedule(ctx context.Context, s model.Schedule, due time.Time) (bool, error)
+}
+
+var (
+	_ MailboxStore  = (*MailboxRepository)(nil)
+	_ RunStore      = (*RunRepository)(nil)
+	_ StatsStore    = (*StatsRepository)(nil)
+	_ HistoryStore  = (*HistoryRepository)(nil)
+	_ ScheduleStore = (*ScheduleRepository)(nil)
+	_ JobStore      = (*JobRepository)(nil)
+
+	_ MailboxStore  = (*SQLiteMailboxRepository)(nil)
+	_ RunStore      = (*SQLiteRunRepository)(nil)
+	_ StatsStore    = (*SQLiteStatsRepository)(nil)
+	_ HistoryStore  = (*SQLiteHistoryRepository)(nil)
+	_ ScheduleStore = (*SQLiteScheduleRepository)(nil)
+	_ JobStore      = (*SQLiteJobRepository)(nil)
+
+	_ MailboxStore  = (*MemoryMailboxRepository)(nil)
+	_ RunStore      = (*MemoryRunRepository)(nil)
+	_ StatsStore    = (*MemoryStatsRepository)(nil)
+	_ HistoryStore  = (*MemoryHistoryRepository)(nil)
+	_ ScheduleStore = (*MemoryScheduleRepository)(nil)
+	_ JobStore      = (*MemoryJobRepository)(nil)
+)
@@ -12,8 +12,75 @@ type AddressRaw struct {
 
 // StandardizedAddress represents the normalized address returned by Smarty.
 type StandardizedAddress struct {
-	DeliveryLine1 string `json:"deliveryLine1,omitempty" firestore:"deliveryLine1,omitempty"`
-	LastLine      string `json:"lastLine,omitempty" firestore:"lastLine,omitempty"`
+	DeliveryLine1 string            `json:"deliveryLine1,omitempty" firestore:"deliveryLine1,omitempty"`
+	LastLine      string            `json:"lastLine,omitempty" firestore:"lastLine,omitempty"`
+	Components    AddressComponents `json:"components,omitempty" firestore:"components,omitempty"`
+}
+
+// AddressComponents is the standardized address split into its USPS parts.
+type AddressComponents struct {
+	PrimaryNumber       string `json:"primaryNumber,omitempty" firestore:"primaryNumber,omitempty"`
+	StreetPredirection  string `json:"streetPredirection,omitempty" firestore:"streetPredirection,omitempty"`
+	StreetName          string `json:"streetName,omitempty" firestore:"streetName,omitempty"`
+	StreetSuffix        string `json:"streetSuffix,omitempty" firestore:"streetSuffix,omitempty"`
+	StreetPostdirection string `json:"streetPostdirection,omitempty" firestore:"streetPostdirection,omitempty"`
+	SecondaryDesignator string `json:"secondaryDesignator,omitempty" firestore:"secondaryDesignator,omitempty"`
+	SecondaryNumber     string `json:"secondaryNumber,omitempty" firestore:"secondaryNumber,omitempty"`
+	PMBDesignator       string `json:"pmbDesignator,omitempty" firestore:"pmbDesignator,omitempty"`
+	PMBNumber           string `json:"pmbNumber,omitempty" firestore:"pmbNumber,omitempty"`
+	CityName            string `json:"cityName,omitempty" firestore:"cityName,omitempty"`
+	StateAbbreviation   string `json:"stateAbbreviation,omitempty" firestore:"stateAbbreviation,omitempty"`
+	Zipcode             string `json:"zipcode,omitempty" firestore:"zipcode,omitempty"`
+	Plus4Code           string `json:"plus4Code,omitempty" firestore:"plus4Code,omitempty"`
+	DeliveryPoint       string `json:"deliveryPoint,omitempty" firestore:"deliveryPoint,omitempty"`
+}
+
+// AddressAnalysis is the validator's delivery point analysis and metadata for an address. A CMRA
+// or RDI answer only means something for a confirmed (DPVMatchCode Y), occupied delivery point.
+type AddressAnalysis struct {
+	DPVMatchCode string  `json:"dpvMatchCode,omitempty" firestore:"dpvMatchCode,omitempty"` // Y confirmed, S confirmed without the secondary, D secondary missing, N not confirmed
+	DPVFootnotes string  `json:"dpvFootnotes,omitempty" firestore:"dpvFootnotes,omitempty"` // Two-character USPS codes, e.g. "AABB"
+	DPVVacant    string  `json:"dpvVacant,omitempty" firestore:"dpvVacant,omitempty"`       // Y if USPS reports the delivery point vacant
+	DPVNoStat    string  `json:"dpvNoStat,omitempty" firestore:"dpvNoStat,omitempty"`       // Y if USPS does not deliver to it
+	RecordType   string  `json:"recordType,omitempty" firestore:"recordType,omitempty"`     // S street, H high-rise, F firm, P PO box, R rural route, G general delivery
+	ZipType      string  `json:"zipType,omitempty" firestore:"zipType,omitempty"`           // Standard, PO Box, Unique or Military
+	CountyName   string  `json:"countyName,omitempty" firestore:"countyName,omitempty"`
+	Latitude     float64 `json:"latitude,omitempty" firestore:"latitude,omitempty"`
+	Longitude    float64 `json:"longitude,omitempty" firestore:"longitude,omitempty"`
+	Precision    string  `json:"precision,omitempty" firestore:"precision,omitempty"` // How exact the coordinates are, e.g. Zip9 or Rooftop
+	TimeZone     string  `json:"timeZone,omitempty" firestore:"timeZone,omitempty"`
+}
+
+// AddressCandidate is one match the validator offered for a mailbox's address. Addresses in
+// multi-tenant buildings can match several delivery points that disagree on CMRA or RDI.
+type AddressCandidate struct {
+	StandardizedAddress StandardizedAddress `json:"standardizedAddress,omitempty" firestore:"standardizedAddress,omitempty"`
+	CMRA                string              `json:"cmra,omitempty" firestore:"cmra,omitempty"`
+	RDI                 string              `json:"rdi,omitempty" firestore:"rdi,omitempty"`
+	Analysis            AddressAnalysis     `json:"analysis,omitempty" firestore:"analysis,omitempty"`
+}
+
+// ValidatorVerdict is what one validator backend answered for a mailbox validated in consensus mode.
+type ValidatorVerdict struct {
+	Validator string `json:"validator,omitempty" firestore:"validator,omitempty"`
+	CMRA      string `json:"cmra,omitempty" firestore:"cmra,omitempty"`
+	RDI       string `json:"rdi,omitempty" firestore:"rdi,omitempty"`
+	Status    string `json:"status,omitempty" firestore:"status,omitempty"` // One of the Validation* statuses
+	Reason    string `json:"reason,omitempty" firestore:"reason,omitempty"`
+}
+
+// Billing periods for Plan.BillingPeriod.
+const (
+	BillingMonthly = "monthly"
+	BillingAnnual  = "annual"
+)
+
+// Plan is one pricing tier offered at a mailbox location.
+type Plan struct {
+	Name          string   `json:"name,omitempty" firestore:"name,omitempty"`
+	MonthlyPrice  float64  `json:"monthlyPrice,omitempty" firestore:"monthlyPrice,omitempty"`   // Normalized to a per-month amount
+	BillingPeriod string   `json:"billingPeriod,omitempty" firestore:"billingPeriod,omitempty"` // BillingMonthly or BillingAnnual
+	Features      []string `json:"features,omitempty" firestore:"features,omitempty"`           // e.g. "30 mail items/mo", "5 scans"
 }
 
 // Mailbox is the core document stored in the `mailboxes` collection.
@@ -22,46 +89,218 @@ type Mailbox struct {
 	Source              string              `json:"source,omitempty" firestore:"source,omitempty"` // Data source: "ATMB" or "iPost1"
 	Name                string              `json:"name,omitempty" firestore:"name,omitempty"`
 	AddressRaw          AddressRaw          `json:"addressRaw,omitempty" firestore:"addressRaw,omitempty"`
-	Price               float64             `json:"price,omitempty" firestore:"price,omitempty"`
+	Price               float64             `json:"price,omitempty" firestore:"price,omitempty"` // Cheapest monthly price across Plans
+	Plans               []Plan              `json:"plans,omitempty" firestore:"plans,omitempty"`
 	Link                string              `json:"link,omitempty" firestore:"link,omitempty"`
 	CMRA                string              `json:"cmra,omitempty" firestore:"cmra,omitempty"`
 	RDI                 string              `json:"rdi,omitempty" firestore:"rdi,omitempty"`
 	StandardizedAddress StandardizedAddress `json:"standardizedAddress,omitempty" firestore:"standardizedAddress,omitempty"`
+	Analysis            AddressAnalysis     `json:"analysis,omitempty" firestore:"analysis,omitempty"`
+	Candidates          []AddressCandidate  `json:"candidates,omitempty" firestore:"candidates,omitempty"`                 // Every match the validator offered, best first
+	ValidationStatus    string              `json:"validationStatus,omitempty" firestore:"validationStatus,omitempty"`     // One of the Validation* statuses; empty on records validated before it was tracked
+	ValidationReason    string              `json:"validationReason,omitempty" firestore:"validationReason,omitempty"`     // Why the status is not verified
+	Validator           string              `json:"validator,omitempty" firestore:"validator,omitempty"`                   // Backend that produced the verdict, e.g. "smarty" or "usps"
+	Verdicts            []ValidatorVerdict  `json:"verdicts,omitempty" firestore:"verdicts,omitempty"`                     // Each backend's own verdict when Validator is "consensus"
+	Confidence          float64             `json:"confidence,omitempty" firestore:"confidence,omitempty"`                 // Share of backends backing the consensus CMRA/RDI, 0-1
+	ValidatorsDisagree  bool                `json:"validatorsDisagree,omitempty" firestore:"validatorsDisagree,omitempty"` // Backends answered with different CMRA/RDI
 	DataHash            string              `json:"dataHash,omitempty" firestore:"dataHash,omitempty"`
 	LastValidatedAt     time.Time           `json:"lastValidatedAt,omitempty" firestore:"lastValidatedAt,omitempty"`
 	CrawlRunID          string              `json:"crawlRunId,omitempty" firestore:"crawlRunId,omitempty"`
 	Active              bool                `json:"active,omitempty" firestore:"active,omitempty"`
 	// Fields for reprocessing support
-	RawHTML       string    `json:"-" firestore:"rawHTML,omitempty"`             // Original HTML (not exposed to API)
+	RawHTML       string    `json:"-" firestore:"rawHTML,omitempty"`                             // Original HTML (not exposed to API)
 	ParserVersion string    `json:"parserVersion,omitempty" firestore:"parserVersion,omitempty"` // Parser version (e.g., "v1.0")
 	LastParsedAt  time.Time `json:"lastParsedAt,omitempty" firestore:"lastParsedAt,omitempty"`   // Last parsing timestamp
 }
 
+// Validation statuses for Mailbox.ValidationStatus.
+const (
+	ValidationPending        = "pending"         // Parsed but not validated yet
+	ValidationVerified       = "verified"        // Matched a delivery point; CMRA/RDI can be trusted
+	ValidationNoMatch        = "no_match"        // The validator found no deliverable address
+	ValidationAmbiguous      = "ambiguous"       // Candidates or validators disagree on CMRA/RDI
+	ValidationError          = "error"           // The validation call failed
+	ValidationQuotaExhausted = "quota_exhausted" // Every validator credential was rate limited or out of quota
+)
+
+// ValidationOutcomes counts a run's validation results by status.
+type ValidationOutcomes struct {
+	Verified       int `json:"verified,omitempty" firestore:"verified,omitempty"`
+	NoMatch        int `json:"noMatch,omitempty" firestore:"noMatch,omitempty"`
+	Ambiguous      int `json:"ambiguous,omitempty" firestore:"ambiguous,omitempty"`
+	Error          int `json:"error,omitempty" firestore:"error,omitempty"`
+	QuotaExhausted int `json:"quotaExhausted,omitempty" firestore:"quotaExhausted,omitempty"`
+}
+
 // CrawlRunStats stores aggregated counters for a crawl job.
 type CrawlRunStats struct {
-	Found     int `json:"found,omitempty" firestore:"found,omitempty"`
-	Validated int `json:"validated,omitempty" firestore:"validated,omitempty"`
-	Skipped   int `json:"skipped,omitempty" firestore:"skipped,omitempty"`
-	Failed    int `json:"failed,omitempty" firestore:"failed,omitempty"`
+	Found      int                `json:"found,omitempty" firestore:"found,omitempty"`
+	Validated  int                `json:"validated,omitempty" firestore:"validated,omitempty"`
+	Skipped    int                `json:"skipped,omitempty" firestore:"skipped,omitempty"`
+	Failed     int                `json:"failed,omitempty" firestore:"failed,omitempty"`
+	Validation ValidationOutcomes `json:"validation,omitempty" firestore:"validation,omitempty"`
 }
 
 // CrawlRun tracks the lifecycle of a crawler execution.
 type CrawlRun struct {
 	RunID       string        `json:"runId,omitempty" firestore:"runId,omitempty"`
 	Source      string        `json:"source,omitempty" firestore:"source,omitempty"` // Data source: "ATMB" or "iPost1"
-	Status      string        `json:"status,omitempty" firestore:"status,omitempty"`
+	Kind        string        `json:"kind,omitempty" firestore:"kind,omitempty"`     // RunKindCrawl or RunKindReprocess; empty on older runs
+	Status      string        `json:"status,omitempty" firestore:"status,omitempty"` // queued, running, paused, success, failed, partial_halt, timeout or cancelled
 	Stats       CrawlRunStats `json:"stats,omitempty" firestore:"stats,omitempty"`
 	StartedAt   time.Time     `json:"startedAt,omitempty" firestore:"startedAt,omitempty"`
 	FinishedAt  time.Time     `json:"finishedAt,omitempty" firestore:"finishedAt,omitempty"`
+	ResumedAt   time.Time     `json:"resumedAt,omitempty" firestore:"resumedAt,omitempty"` // Last time the run was continued from a pause or its checkpoint
 	ErrorSample []ErrorSample `json:"errorsSample,omitempty" firestore:"errorsSample,omitempty"`
+	Sweep       *SweepReport  `json:"sweep,omitempty" firestore:"sweep,omitempty"`
+}
+
+// Kinds of work recorded in CrawlRun.Kind.
+const (
+	RunKindCrawl     = "crawl"
+	RunKindReprocess = "reprocess"
+)
+
+// SweepReport records what MarkAndSweep did, or was kept from doing, at the end of a crawl run.
+type SweepReport struct {
+	Held        bool     `json:"held,omitempty" firestore:"held,omitempty"`                   // Deactivation was skipped by the sweep policy
+	Reason      string   `json:"reason,omitempty" firestore:"reason,omitempty"`               // Why it was held
+	Coverage    float64  `json:"coverage,omitempty" firestore:"coverage,omitempty"`           // Discovered listings relative to the previous successful crawl
+	BaselineRun string   `json:"baselineRunId,omitempty" firestore:"baselineRunId,omitempty"` // Run the coverage was measured against
+	Swept       []string `json:"swept,omitempty" firestore:"swept,omitempty"`                 // Links deactivated by this run
+	Quarantined []string `json:"quarantined,omitempty" firestore:"quarantined,omitempty"`     // Links that would have been deactivated had the sweep not been held
+}
+
+// Schedule actions.
+const (
+	ScheduleCrawl        = "crawl"         // Crawl Provider
+	ScheduleReprocess    = "reprocess"     // Re-parse stored HTML
+	ScheduleRefreshStats = "refresh_stats" // Recompute the system stats document
+)
+
+// Schedule is a document in the `schedules` collection: a recurring job run by the server's scheduler.
+type Schedule struct {
+	ID           string    `json:"id" firestore:"id"`
+	Action       string    `json:"action" firestore:"action"`                                 // ScheduleCrawl, ScheduleReprocess or ScheduleRefreshStats
+	Provider     string    `json:"provider,omitempty" firestore:"provider,omitempty"`         // Provider to crawl, for ScheduleCrawl
+	Cron         string    `json:"cron" firestore:"cron"`                                     // Five-field cron expression in UTC, e.g. "0 3 * * *"
+	OnlyOutdated bool      `json:"onlyOutdated,omitempty" firestore:"onlyOutdated,omitempty"` // Reprocess only records with an older parser version
+	NextRunAt    time.Time `json:"nextRunAt,omitempty" firestore:"nextRunAt,omitempty"`
+	LastRunAt    time.Time `json:"lastRunAt,omitempty" firestore:"lastRunAt,omitempty"`
+	LastRunID    string    `json:"lastRunId,omitempty" firestore:"lastRunId,omitempty"`
+	LastError    string    `json:"lastError,omitempty" firestore:"lastError,omitempty"` // Why the last firing did not start, if it did not
+	CreatedAt    time.Time `json:"createdAt,omitempty" firestore:"createdAt,omitempty"`
+}
+
+// Kinds of queued Job.
+const (
+	JobCrawl      = "crawl"      // Crawl Provider, or resume its checkpointed run
+	JobReprocess  = "reprocess"  // Re-parse stored HTML
+	JobRevalidate = "revalidate" // Re-parse stored HTML and re-validate every address with Smarty
+)
+
+// Job statuses.
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobDone      = "done"
+	JobCancelled = "cancelled"
+	JobFailed    = "failed" // Could not start, or its lease expired too many times
+)
+
+// Job is a document in the `jobs` collection: durable work for the crawl worker loop. A job is
+// claimed under a lease the worker renews with heartbeats; when a worker dies its lease expires
+// and the job is queued again. Its ID is the ID of the CrawlRun it executes.
+type Job struct {
+	ID              string    `json:"id" firestore:"id"`
+	Kind            string    `json:"kind" firestore:"kind"`                             // JobCrawl, JobReprocess or JobRevalidate
+	LockKey         string    `json:"lockKey" firestore:"lockKey"`                       // Only one queued or running job per key, e.g. "crawl:atmb"
+	Provider        string    `json:"provider,omitempty" firestore:"provider,omitempty"` // For JobCrawl
+	Seeds           []string  `json:"seeds,omitempty" firestore:"seeds,omitempty"`       // For JobCrawl
+	Resume          bool      `json:"resume,omitempty" firestore:"resume,omitempty"`     // Continue the run's checkpoint instead of crawling from scratch
+	TargetVersion   string    `json:"targetVersion,omitempty" firestore:"targetVersion,omitempty"`
+	OnlyOutdated    bool      `json:"onlyOutdated,omitempty" firestore:"onlyOutdated,omitempty"`
+	Status          string    `json:"status" firestore:"status"`
+	Attempts        int       `json:"attempts" firestore:"attempts"`               // Times a worker claimed it
+	Owner           string    `json:"owner,omitempty" firestore:"owner,omitempty"` // Worker holding the lease
+	LeaseExpiresAt  time.Time `json:"leaseExpiresAt,omitempty" firestore:"leaseExpiresAt,omitempty"`
+	CancelRequested bool      `json:"cancelRequested,omitempty" firestore:"cancelRequested,omitempty"` // Honored by whichever worker holds the lease
+	PauseRequested  bool      `json:"pauseRequested,omitempty" firestore:"pauseRequested,omitempty"`   // Hold the run until cleared; honored like CancelRequested
+	Error           string    `json:"error,omitempty" firestore:"error,omitempty"`
+	EnqueuedAt      time.Time `json:"enqueuedAt" firestore:"enqueuedAt"`
+	FinishedAt      time.Time `json:"finishedAt,omitempty" firestore:"finishedAt,omitempty"`
+}
+
+// RunLock is a document in the `run_locks` collection: a lease on a source held by the run crawling it.
+// The holder renews ExpiresAt while it runs, so a lock left by a crashed instance lapses on its own.
+type RunLock struct {
+	Key        string    `json:"key" firestore:"key"`     // e.g. "crawl:atmb" or "reprocess"
+	RunID      string    `json:"runId" firestore:"runId"` // Run holding the lease
+	AcquiredAt time.Time `json:"acquiredAt" firestore:"acquiredAt"`
+	ExpiresAt  time.Time `json:"expiresAt" firestore:"expiresAt"`
+}
+
+// CrawlCheckpoint is the resumable state of a crawl run: every listing its discovery produced
+// and whether the pipeline has finished with it.
+type CrawlCheckpoint struct {
+	RunID    string            `json:"runId,omitempty" firestore:"runId,omitempty"`
+	Source   string            `json:"source,omitempty" firestore:"source,omitempty"`
+	Listings []CheckpointEntry `json:"listings,omitempty" firestore:"-"`                                    // Stored per listing so completion can be marked without rewriting the run
+	Failures []string          `json:"discoveryFailures,omitempty" firestore:"discoveryFailures,omitempty"` // Segments (e.g., states) discovery could not list
+}
+
+// CheckpointEntry is one discovered listing of a checkpointed run.
+type CheckpointEntry struct {
+	Listing Mailbox `json:"listing" firestore:"listing"`
+	Done    bool    `json:"done" firestore:"done"`
 }
 
 // ErrorSample captures a subset of errors for observability without heavy logging.
 type ErrorSample struct {
 	Link   string `json:"link,omitempty" firestore:"link,omitempty"`
+	Stage  string `json:"stage,omitempty" firestore:"stage,omitempty"`
 	Reason string `json:"reason,omitempty" firestore:"reason,omitempty"`
 }
 
+// Pipeline stages a link can fail at, recorded in LinkEvent.Stage.
+const (
+	StageFetch    = "fetch"
+	StageParse    = "parse"
+	StageValidate = "validate"
+	StageSave     = "save"
+)
+
+// LinkEvent records a link that failed during a run, stored under crawl_runs/{runId}/events.
+type LinkEvent struct {
+	RunID      string    `json:"runId" firestore:"runId"`
+	Link       string    `json:"link" firestore:"link"`
+	Stage      string    `json:"stage" firestore:"stage"`
+	Error      string    `json:"error" firestore:"error"`
+	DurationMs int64     `json:"durationMs" firestore:"durationMs"` // Time spent in the failed stage
+	At         time.Time `json:"at" firestore:"at"`
+}
+
+// Types of RunEvent.
+const (
+	RunEventStats     = "stats"      // Updated counters
+	RunEventLinkError = "link_error" // A listing failed
+	RunEventLog       = "log"        // A pipeline log line
+	RunEventStatus    = "status"     // The run's status changed; a finished status ends the stream
+)
+
+// RunEvent is one live update from a run in progress, streamed by GET /api/crawl/runs/:runId/events.
+// It is not persisted.
+type RunEvent struct {
+	Type    string         `json:"type"`
+	RunID   string         `json:"runId"`
+	Time    time.Time      `json:"time"`
+	Status  string         `json:"status,omitempty"`
+	Stats   *CrawlRunStats `json:"stats,omitempty"`
+	Link    string         `json:"link,omitempty"`    // For RunEventLinkError
+	Stage   string         `json:"stage,omitempty"`   // For RunEventLinkError
+	Message string         `json:"message,omitempty"` // Log line or error reason
+}
+
 // SystemStats is a singleton document that pre-aggregates dashboard metrics.
 type SystemStats struct {
 	LastUpdated      time.Time      `json:"lastUpdated,omitempty" firestore:"lastUpdated,omitempty"`
@@ -72,3 +311,44 @@ type SystemStats struct {
 	ByState          map[string]int `json:"byState,omitempty" firestore:"byState,omitempty"`
 	BySource         map[string]int `json:"bySource,omitempty" firestore:"bySource,omitempty"`
 }
+
+// FieldChange records the before/after value of a single mailbox field.
+type FieldChange struct {
+	Field string `json:"field" firestore:"field"`
+	Old   string `json:"old" firestore:"old"`
+	New   string `json:"new" firestore:"new"`
+}
+
+// MailboxHistory is a document in the `mailbox_history` collection describing one update to a mailbox.
+type MailboxHistory struct {
+	ID         string        `json:"id,omitempty" firestore:"-"`
+	MailboxID  string        `json:"mailboxId,omitempty" firestore:"mailboxId,omitempty"`
+	Link       string        `json:"link,omitempty" firestore:"link,omitempty"`
+	Source     string        `json:"source,omitempty" firestore:"source,omitempty"`
+	CrawlRunID string        `json:"crawlRunId,omitempty" firestore:"crawlRunId,omitempty"` // Run that caused the change
+	ChangedAt  time.Time     `json:"changedAt,omitempty" firestore:"changedAt,omitempty"`
+	Changes    []FieldChange `json:"changes,omitempty" firestore:"changes,omitempty"`
+}
+
+// PricePoint is a document in the `price_history` collection: one observed price of a mailbox during a run.
+type PricePoint struct {
+	ID         string    `json:"id,omitempty" firestore:"-"`
+	MailboxID  string    `json:"mailboxId,omitempty" firestore:"mailboxId,omitempty"`
+	Link       string    `json:"link,omitempty" firestore:"link,omitempty"`
+	Name       string    `json:"name,omitempty" firestore:"name,omitempty"`
+	Source     string    `json:"source,omitempty" firestore:"source,omitempty"`
+	Price      float64   `json:"price" firestore:"price"`
+	CrawlRunID string    `json:"crawlRunId,omitempty" firestore:"crawlRunId,omitempty"`
+	ObservedAt time.Time `json:"observedAt,omitempty" firestore:"observedAt,omitempty"`
+}
+
+// PriceChange describes a mailbox whose price moved between two runs.
+type PriceChange struct {
+	MailboxID string  `json:"mailboxId"`
+	Link      string  `json:"link,omitempty"`
+	Name      string  `json:"name,omitempty"`
+	Source    string  `json:"source,omitempty"`
+	OldPrice  float64 `json:"oldPrice"`
+	NewPrice  float64 `json:"newPrice"`
+	ChangePct float64 `json:"changePct"`
+}
@@ -80,10 +80,9 @@ func removeRedundantCityStateZip(street, city, state, zip string) string {
 
 // CleanStandardizedAddress removes HTML remnants from StandardizedAddress.
 func CleanStandardizedAddress(addr model.StandardizedAddress) model.StandardizedAddress {
-	return model.StandardizedAddress{
-		DeliveryLine1: cleanField(addr.DeliveryLine1),
-		LastLine:      cleanField(addr.LastLine),
-	}
+	addr.DeliveryLine1 = cleanField(addr.DeliveryLine1)
+	addr.LastLine = cleanField(addr.LastLine)
+	return addr
 }
 
 // CleanLink fixes escaped URLs (e.g., https:\/\/ -> https://)
@@ -0,0 +1,62 @@
+package util
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/model"
+)
+
+// DiffMailbox lists the tracked fields (name, price, address, CMRA, RDI, active) that differ between prev and next.
+func DiffMailbox(prev, next model.Mailbox) []model.FieldChange {
+	var changes []model.FieldChange
+	add := func(field, oldVal, newVal string) {
+		if oldVal != newVal {
+			changes = append(changes, model.FieldChange{Field: field, Old: oldVal, New: newVal})
+		}
+	}
+	add("name", prev.Name, next.Name)
+	add("price", formatPrice(prev.Price), formatPrice(next.Price))
+	add("address", FormatAddress(prev.AddressRaw), FormatAddress(next.AddressRaw))
+	add("cmra", prev.CMRA, next.CMRA)
+	add("rdi", prev.RDI, next.RDI)
+	add("active", strconv.FormatBool(prev.Active), strconv.FormatBool(next.Active))
+	return changes
+}
+
+// HistoryEntry builds a history record for the transition prev -> next.
+// Returns false when none of the tracked fields changed.
+func HistoryEntry(prev, next model.Mailbox, runID string, at time.Time) (model.MailboxHistory, bool) {
+	changes := DiffMailbox(prev, next)
+	if len(changes) == 0 {
+		return model.MailboxHistory{}, false
+	}
+	id := next.ID
+	if id == "" {
+		id = prev.ID
+	}
+	return model.MailboxHistory{
+		MailboxID:  id,
+		Link:       next.Link,
+		Source:     next.Source,
+		CrawlRunID: runID,
+		ChangedAt:  at,
+		Changes:    changes,
+	}, true
+}
+
+// FormatAddress renders a raw address as a single "street, city, ST zip" line.
+func FormatAddress(addr model.AddressRaw) string {
+	if addr == (model.AddressRaw{}) {
+		return ""
+	}
+	return fmt.Sprintf("%s, %s, %s %s", addr.Street, addr.City, addr.State, addr.Zip)
+}
+
+func formatPrice(price float64) string {
+	if price == 0 {
+		return ""
+	}
+	return strconv.FormatFloat(price, 'f', 2, 64)
+}
@@ -0,0 +1,66 @@
+package util
+
+import (
+	"testing"
+	"time"
+
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/model"
+)
+
+func TestDiffMailbox(t *testing.T) {
+	prev := model.Mailbox{
+		ID:         "m1",
+		Name:       "Chicago - Monroe St",
+		Price:      9.99,
+		AddressRaw: model.AddressRaw{Street: "73 W Monroe St", City: "Chicago", State: "IL", Zip: "60603"},
+		CMRA:       "",
+		RDI:        "Commercial",
+		Active:     true,
+	}
+
+	if changes := DiffMailbox(prev, prev); len(changes) != 0 {
+		t.Fatalf("identical mailboxes produced changes: %+v", changes)
+	}
+
+	next := prev
+	next.Price = 12.5
+	next.CMRA = "Y"
+	next.Active = false
+
+	changes := DiffMailbox(prev, next)
+	want := []model.FieldChange{
+		{Field: "price", Old: "9.99", New: "12.50"},
+		{Field: "cmra", Old: "", New: "Y"},
+		{Field: "active", Old: "true", New: "false"},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("got %d changes, want %d: %+v", len(changes), len(want), changes)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Errorf("changes[%d] = %+v, want %+v", i, changes[i], want[i])
+		}
+	}
+}
+
+func TestHistoryEntry(t *testing.T) {
+	at := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	prev := model.Mailbox{ID: "m1", Link: "https://a", Source: "ATMB", Name: "A"}
+
+	if _, ok := HistoryEntry(prev, prev, "RUN_1", at); ok {
+		t.Errorf("unchanged mailbox should not produce an entry")
+	}
+
+	next := prev
+	next.Name = "B"
+	entry, ok := HistoryEntry(prev, next, "RUN_1", at)
+	if !ok {
+		t.Fatalf("expected an entry")
+	}
+	if entry.MailboxID != "m1" || entry.CrawlRunID != "RUN_1" || !entry.ChangedAt.Equal(at) || entry.Source != "ATMB" {
+		t.Errorf("unexpected entry: %+v", entry)
+	}
+	if len(entry.Changes) != 1 || entry.Changes[0].Field != "name" {
+		t.Errorf("unexpected changes: %+v", entry.Changes)
+	}
+}
@@ -0,0 +1,79 @@
+package util
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/model"
+)
+
+// BillingPeriod classifies a price label such as "US$ 199 / year" or "$15.95/month".
+// Anything that is not clearly annual is treated as monthly.
+func BillingPeriod(label string) string {
+	lower := strings.ToLower(label)
+	for _, marker := range []string{"year", "/yr", "annual"} {
+		if strings.Contains(lower, marker) {
+			return model.BillingAnnual
+		}
+	}
+	return model.BillingMonthly
+}
+
+// MonthlyPrice normalizes a listed price to a per-month amount, rounded to cents.
+func MonthlyPrice(listed float64, period string) float64 {
+	if period == model.BillingAnnual {
+		return math.Round(listed/12*100) / 100
+	}
+	return listed
+}
+
+// CheapestMonthlyPrice returns the lowest price among monthly-billed plans.
+// When no plan is billed monthly it falls back to the lowest normalized price of any plan. Returns 0 without plans.
+func CheapestMonthlyPrice(plans []model.Plan) float64 {
+	var cheapest, fallback float64
+	for _, p := range plans {
+		if p.MonthlyPrice <= 0 {
+			continue
+		}
+		if fallback == 0 || p.MonthlyPrice < fallback {
+			fallback = p.MonthlyPrice
+		}
+		if p.BillingPeriod == model.BillingMonthly && (cheapest == 0 || p.MonthlyPrice < cheapest) {
+			cheapest = p.MonthlyPrice
+		}
+	}
+	if cheapest == 0 {
+		return fallback
+	}
+	return cheapest
+}
+
+// SamePlans reports whether two plan lists are identical, including order.
+func SamePlans(a, b []model.Plan) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Name != b[i].Name || a[i].MonthlyPrice != b[i].MonthlyPrice || a[i].BillingPeriod != b[i].BillingPeriod {
+			return false
+		}
+		if strings.Join(a[i].Features, "\n") != strings.Join(b[i].Features, "\n") {
+			return false
+		}
+	}
+	return true
+}
+
+// FormatPlans renders plans as a single CSV-friendly cell, e.g. "Bronze 19.99/mo; Gold 16.58/mo (annual)".
+func FormatPlans(plans []model.Plan) string {
+	parts := make([]string, 0, len(plans))
+	for _, p := range plans {
+		part := strings.TrimSpace(p.Name + " " + strconv.FormatFloat(p.MonthlyPrice, 'f', 2, 64) + "/mo")
+		if p.BillingPeriod == model.BillingAnnual {
+			part += " (annual)"
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, "; ")
+}
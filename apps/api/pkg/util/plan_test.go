@@ -0,0 +1,54 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/model"
+)
+
+func TestBillingPeriod(t *testing.T) {
+	tests := []struct {
+		label string
+		want  string
+	}{
+		{"US$ 19.99 / month", model.BillingMonthly},
+		{"$15.95/mo", model.BillingMonthly},
+		{"US$ 199.00 / year", model.BillingAnnual},
+		{"$99/yr", model.BillingAnnual},
+		{"Billed annually", model.BillingAnnual},
+		{"", model.BillingMonthly},
+	}
+	for _, tt := range tests {
+		if got := BillingPeriod(tt.label); got != tt.want {
+			t.Errorf("BillingPeriod(%q) = %q, want %q", tt.label, got, tt.want)
+		}
+	}
+}
+
+func TestCheapestMonthlyPrice(t *testing.T) {
+	plans := []model.Plan{
+		{Name: "Gold", MonthlyPrice: 49.99, BillingPeriod: model.BillingMonthly},
+		{Name: "Bronze", MonthlyPrice: 19.99, BillingPeriod: model.BillingMonthly},
+		{Name: "Bronze", MonthlyPrice: 16.66, BillingPeriod: model.BillingAnnual},
+	}
+	if got := CheapestMonthlyPrice(plans); got != 19.99 {
+		t.Errorf("CheapestMonthlyPrice = %v, want 19.99", got)
+	}
+	if got := CheapestMonthlyPrice(plans[2:]); got != 16.66 {
+		t.Errorf("annual-only CheapestMonthlyPrice = %v, want 16.66", got)
+	}
+	if got := CheapestMonthlyPrice(nil); got != 0 {
+		t.Errorf("CheapestMonthlyPrice(nil) = %v, want 0", got)
+	}
+}
+
+func TestFormatPlans(t *testing.T) {
+	plans := []model.Plan{
+		{Name: "Bronze", MonthlyPrice: 19.99, BillingPeriod: model.BillingMonthly},
+		{Name: "Gold", MonthlyPrice: 16.58, BillingPeriod: model.BillingAnnual},
+	}
+	want := "Bronze 19.99/mo; Gold 16.58/mo (annual)"
+	if got := FormatPlans(plans); got != want {
+		t.Errorf("FormatPlans = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,65 @@
+package util
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/model"
+)
+
+// ObservePrice builds a price observation for a mailbox seen during a run.
+// Mailboxes without an ID yet (first insert) are keyed by the same link hash the repositories use as document ID.
+// Returns false when no price was parsed.
+func ObservePrice(m model.Mailbox, runID string, at time.Time) (model.PricePoint, bool) {
+	if m.Price <= 0 {
+		return model.PricePoint{}, false
+	}
+	id := m.ID
+	if id == "" {
+		id = HashString(m.Link)
+	}
+	return model.PricePoint{
+		MailboxID:  id,
+		Link:       m.Link,
+		Name:       m.Name,
+		Source:     m.Source,
+		Price:      m.Price,
+		CrawlRunID: runID,
+		ObservedAt: at,
+	}, true
+}
+
+// PriceChanges pairs the prices observed in two runs by mailbox and returns those that moved by more than minPct percent,
+// largest move first. Mailboxes missing from either run are ignored.
+func PriceChanges(from, to []model.PricePoint, minPct float64) []model.PriceChange {
+	before := make(map[string]model.PricePoint, len(from))
+	for _, p := range from {
+		before[p.MailboxID] = p
+	}
+
+	var changes []model.PriceChange
+	for _, p := range to {
+		prev, ok := before[p.MailboxID]
+		if !ok || prev.Price <= 0 || prev.Price == p.Price {
+			continue
+		}
+		pct := (p.Price - prev.Price) / prev.Price * 100
+		if math.Abs(pct) <= minPct {
+			continue
+		}
+		changes = append(changes, model.PriceChange{
+			MailboxID: p.MailboxID,
+			Link:      p.Link,
+			Name:      p.Name,
+			Source:    p.Source,
+			OldPrice:  prev.Price,
+			NewPrice:  p.Price,
+			ChangePct: math.Round(pct*100) / 100,
+		})
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		return math.Abs(changes[i].ChangePct) > math.Abs(changes[j].ChangePct)
+	})
+	return changes
+}
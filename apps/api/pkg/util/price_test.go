@@ -0,0 +1,62 @@
+package util
+
+import (
+	"testing"
+	"time"
+
+	"github.com/weiwei-tsao/virtualbox-verifier/apps/api/pkg/model"
+)
+
+func TestObservePrice(t *testing.T) {
+	at := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	if _, ok := ObservePrice(model.Mailbox{Link: "https://a"}, "RUN_1", at); ok {
+		t.Errorf("mailbox without a price should not be observed")
+	}
+
+	p, ok := ObservePrice(model.Mailbox{Link: "https://a", Price: 9.99}, "RUN_1", at)
+	if !ok {
+		t.Fatalf("expected a price point")
+	}
+	if p.MailboxID != HashString("https://a") || p.CrawlRunID != "RUN_1" || !p.ObservedAt.Equal(at) {
+		t.Errorf("unexpected price point: %+v", p)
+	}
+
+	p, _ = ObservePrice(model.Mailbox{ID: "m1", Link: "https://a", Price: 9.99}, "RUN_1", at)
+	if p.MailboxID != "m1" {
+		t.Errorf("MailboxID = %q, want existing ID", p.MailboxID)
+	}
+}
+
+func TestPriceChanges(t *testing.T) {
+	from := []model.PricePoint{
+		{MailboxID: "a", Price: 10},
+		{MailboxID: "b", Price: 20},
+		{MailboxID: "c", Price: 10},
+		{MailboxID: "gone", Price: 10},
+	}
+	to := []model.PricePoint{
+		{MailboxID: "a", Price: 10.5}, // +5%
+		{MailboxID: "b", Price: 10},   // -50%
+		{MailboxID: "c", Price: 12},   // +20%
+		{MailboxID: "new", Price: 30},
+	}
+
+	changes := PriceChanges(from, to, 10)
+	if len(changes) != 2 {
+		t.Fatalf("got %d changes, want 2: %+v", len(changes), changes)
+	}
+	if changes[0].MailboxID != "b" || changes[0].ChangePct != -50 {
+		t.Errorf("changes[0] = %+v, want b at -50%%", changes[0])
+	}
+	if changes[1].MailboxID != "c" || changes[1].OldPrice != 10 || changes[1].NewPrice != 12 || changes[1].ChangePct != 20 {
+		t.Errorf("changes[1] = %+v, want c at +20%%", changes[1])
+	}
+
+	if all := PriceChanges(from, to, 0); len(all) != 3 {
+		t.Errorf("minPct=0 returned %d changes, want 3", len(all))
+	}
+	if edge := PriceChanges(from, to, 20); len(edge) != 1 || edge[0].MailboxID != "b" {
+		t.Errorf("minPct=20 = %+v, want only b: a move of exactly minPct is not more than it", edge)
+	}
+}
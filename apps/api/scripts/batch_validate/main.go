@@ -68,8 +68,9 @@ func main() {
 		fmt.Println()
 	}
 
-	// Create repository
+	// Create repositories
 	repo := repository.NewMailboxRepository(fsClient)
+	history := repository.NewHistoryRepository(fsClient)
 
 	// Create Smarty client
 	validator := smarty.New(&http.Client{Timeout: 30 * time.Second}, smarty.Config{
@@ -116,9 +117,10 @@ func main() {
 	opts := crawler.ReprocessOptions{
 		ForceRevalidate: *force,
 		BatchSize:       100,
+		RunID:           fmt.Sprintf("BATCH_VALIDATE_%d", startTime.Unix()),
 	}
 
-	stats, err := crawler.ReprocessFromDB(ctx, repo, validator, opts,
+	stats, err := crawler.ReprocessFromDB(ctx, repo, history, validator, opts,
 		func(msg string) {
 			fmt.Printf("[%s] %s\n", time.Now().Format("15:04:05"), msg)
 		},
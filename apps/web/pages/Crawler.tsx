@@ -1,7 +1,8 @@
-import React, { useState } from 'react';
+import React, { useEffect, useState } from 'react';
 import { useQuery, useQueryClient, useMutation } from '@tanstack/react-query';
 import { api } from '../services/api';
-import { Play, RotateCw, AlertTriangle, CheckCircle, Clock, XCircle } from 'lucide-react';
+import { CrawlRun } from '../types';
+import { Play, Pause, RotateCw, AlertTriangle, CheckCircle, Clock, XCircle } from 'lucide-react';
 
 const DEFAULT_LINKS = (import.meta.env.VITE_CRAWL_LINKS || '')
   .split(',')
@@ -16,12 +17,31 @@ export const Crawler: React.FC = () => {
     queryKey: ['crawlRuns'],
     queryFn: api.getCrawlRuns,
     refetchInterval: (query) => {
-      // Only poll every 5s when there's a running job
-      const hasRunning = query.state.data?.some((r) => r.status === 'running');
+      // Only poll every 5s when there's an active job
+      const hasRunning = query.state.data?.some((r) => ['queued', 'running', 'paused'].includes(r.status));
       return hasRunning ? 5000 : false;
     },
   });
 
+  // Stream the active run's progress instead of waiting for the next poll.
+  const activeRunId = runs.find((r) => ['queued', 'running', 'paused'].includes(r.status))?.id;
+  useEffect(() => {
+    if (!activeRunId) return;
+    return api.subscribeRunEvents(activeRunId, (event) => {
+      if (event.type !== 'stats' && event.type !== 'status') return;
+      queryClient.setQueryData<CrawlRun[]>(['crawlRuns'], (prev) =>
+        prev?.map((r) =>
+          r.id === event.runId
+            ? { ...r, status: event.status || r.status, stats: event.stats || r.stats }
+            : r
+        )
+      );
+      if (event.type === 'status' && !['queued', 'running', 'paused'].includes(event.status || '')) {
+        queryClient.invalidateQueries({ queryKey: ['crawlRuns'] });
+      }
+    });
+  }, [activeRunId, queryClient]);
+
   const cancelMutation = useMutation({
     mutationFn: api.cancelCrawlRun,
     onSuccess: () => {
@@ -29,6 +49,14 @@ export const Crawler: React.FC = () => {
     },
   });
 
+  const pauseMutation = useMutation({
+    mutationFn: ({ runId, pause }: { runId: string; pause: boolean }) =>
+      pause ? api.pauseCrawlRun(runId) : api.resumeCrawlRun(runId),
+    onSuccess: () => {
+      queryClient.invalidateQueries({ queryKey: ['crawlRuns'] });
+    },
+  });
+
   const refresh = () => {
     queryClient.invalidateQueries({ queryKey: ['crawlRuns'] });
   };
@@ -62,7 +90,9 @@ export const Crawler: React.FC = () => {
       case 'success': return 'bg-green-100 text-green-800';
       case 'failed': return 'bg-red-100 text-red-800';
       case 'partial_halt': return 'bg-amber-100 text-amber-800';
+      case 'queued': return 'bg-blue-50 text-blue-700';
       case 'running': return 'bg-blue-100 text-blue-800 animate-pulse';
+      case 'paused': return 'bg-yellow-100 text-yellow-800';
       case 'timeout': return 'bg-orange-100 text-orange-800';
       case 'cancelled': return 'bg-gray-100 text-gray-800';
       default: return 'bg-gray-100 text-gray-800';
@@ -134,7 +164,17 @@ export const Crawler: React.FC = () => {
                       <p className={`px-2 inline-flex text-xs leading-5 font-semibold rounded-full ${getStatusColor(run.status)}`}>
                         {run.status.toUpperCase()}
                       </p>
-                      {run.status === 'running' && (
+                      {(run.status === 'running' || run.status === 'paused') && (
+                        <button
+                          onClick={() => pauseMutation.mutate({ runId: run.id, pause: run.status === 'running' })}
+                          disabled={pauseMutation.isPending}
+                          className="text-yellow-600 hover:text-yellow-800 disabled:opacity-50"
+                          title={run.status === 'running' ? 'Pause this job' : 'Resume this job'}
+                        >
+                          {run.status === 'running' ? <Pause size={16} /> : <Play size={16} />}
+                        </button>
+                      )}
+                      {['queued', 'running', 'paused'].includes(run.status) && (
                         <button
                           onClick={() => handleCancel(run.id)}
                           disabled={cancelMutation.isPending}
@@ -171,7 +211,7 @@ export const Crawler: React.FC = () => {
                     <div className="mt-3 bg-red-50 p-2 rounded text-xs text-red-800 font-mono">
                       <p className="font-bold mb-1">Errors:</p>
                       {run.errorsSample.map((e, idx) => (
-                        <div key={idx} className="truncate">[{e.reason}] {e.link}</div>
+                        <div key={idx} className="truncate">[{e.stage ? `${e.stage}: ` : ''}{e.reason}] {e.link}</div>
                       ))}
                     </div>
                   )}
@@ -1,4 +1,4 @@
-import { Mailbox, CrawlRun, MailboxFilter, Stats } from '../types';
+import { Mailbox, CrawlRun, MailboxFilter, RunEvent, Schedule, Stats } from '../types';
 
 const API_BASE = import.meta.env.VITE_API_BASE_URL || 'http://localhost:8080';
 
@@ -28,6 +28,9 @@ export const api = {
       cmra: filter.cmra,
       rdi: filter.rdi,
       source: filter.source,
+      dpvMatchCode: filter.dpvMatchCode,
+      vacant: filter.vacant,
+      status: filter.status,
       active: 'true',
       page: filter.page,
       pageSize: filter.pageSize,
@@ -46,6 +49,10 @@ export const api = {
       cmra: m.cmra || 'Unknown',
       rdi: m.rdi || 'Unknown',
       standardizedAddress: m.standardizedAddress,
+      analysis: m.analysis,
+      validationStatus: m.validationStatus,
+      validationReason: m.validationReason,
+      candidates: m.candidates,
       lastValidatedAt: m.lastValidatedAt,
       crawlRunId: m.crawlRunId,
       source: m.source || 'Unknown',
@@ -127,24 +134,102 @@ export const api = {
       id: run.runId,
       startedAt: run.startedAt,
       finishedAt: run.finishedAt,
+      resumedAt: run.resumedAt,
       status: run.status,
       stats: run.stats || { found: 0, validated: 0, skipped: 0, failed: 0 },
       errorsSample: run.errorsSample || [],
+      sweep: run.sweep,
     }));
   },
 
+  // Streams a run's live events until it finishes; call the returned function to stop listening.
+  subscribeRunEvents: (runId: string, onEvent: (event: RunEvent) => void): (() => void) => {
+    const source = new EventSource(`${API_BASE}/api/crawl/runs/${encodeURIComponent(runId)}/events`);
+    const handle = (e: MessageEvent) => onEvent(JSON.parse(e.data));
+    ['stats', 'link_error', 'log'].forEach((type) => source.addEventListener(type, handle as EventListener));
+    source.addEventListener('status', ((e: MessageEvent) => {
+      handle(e);
+      source.close();
+    }) as EventListener);
+    return () => source.close();
+  },
+
+  getReviewQueue: async (page = 1, pageSize = 50): Promise<{ items: Mailbox[]; total: number }> => {
+    const res = await request(`/api/mailboxes/review?${toQueryString({ page, pageSize })}`);
+    const data = await res.json();
+    return { items: data.items || [], total: data.total || 0 };
+  },
+
+  getDisagreements: async (page = 1, pageSize = 50): Promise<{ items: Mailbox[]; total: number }> => {
+    const res = await request(`/api/mailboxes/disagreements?${toQueryString({ page, pageSize })}`);
+    const data = await res.json();
+    return { items: data.items || [], total: data.total || 0 };
+  },
+
+  exportDisagreementsCSV: async (source?: string) => {
+    const qs = toQueryString({ source });
+    const url = `${API_BASE}/api/mailboxes/disagreements/export${qs ? `?${qs}` : ''}`;
+    window.open(url, '_blank');
+    return true;
+  },
+
+  pickCandidate: async (mailboxId: string, index: number): Promise<Mailbox> => {
+    const res = await request(`/api/mailboxes/${encodeURIComponent(mailboxId)}/candidate`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ index }),
+    });
+    return res.json();
+  },
+
   cancelCrawlRun: async (runId: string): Promise<void> => {
     await request(`/api/crawl/runs/${encodeURIComponent(runId)}/cancel`, {
       method: 'POST',
     });
   },
 
+  pauseCrawlRun: async (runId: string): Promise<void> => {
+    await request(`/api/crawl/runs/${encodeURIComponent(runId)}/pause`, {
+      method: 'POST',
+    });
+  },
+
+  resumeCrawlRun: async (runId: string): Promise<void> => {
+    await request(`/api/crawl/runs/${encodeURIComponent(runId)}/resume`, {
+      method: 'POST',
+    });
+  },
+
+  getSchedules: async (): Promise<Schedule[]> => {
+    const res = await request(`/api/schedules?ts=${Date.now()}`);
+    const data = await res.json();
+    return data.items || [];
+  },
+
+  createSchedule: async (schedule: Pick<Schedule, 'action' | 'provider' | 'cron' | 'onlyOutdated'>): Promise<Schedule> => {
+    const res = await request('/api/schedules', {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify(schedule),
+    });
+    return res.json();
+  },
+
+  deleteSchedule: async (id: string): Promise<void> => {
+    await request(`/api/schedules/${encodeURIComponent(id)}`, {
+      method: 'DELETE',
+    });
+  },
+
   exportCSV: async (filter?: MailboxFilter) => {
     const qs = filter ? toQueryString({
       state: filter.state,
       cmra: filter.cmra,
       rdi: filter.rdi,
       source: filter.source,
+      dpvMatchCode: filter.dpvMatchCode,
+      vacant: filter.vacant,
+      status: filter.status,
       active: 'true',
     }) : 'active=true';
     const url = `${API_BASE}/api/mailboxes/export${qs ? `?${qs}` : ''}`;
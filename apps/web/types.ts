@@ -1,6 +1,45 @@
 export interface StandardizedAddress {
   deliveryLine1: string;
   lastLine: string;
+  components?: Record<string, string>;
+}
+
+export interface AddressCandidate {
+  standardizedAddress?: StandardizedAddress;
+  cmra?: string;
+  rdi?: string;
+  analysis?: AddressAnalysis;
+}
+
+export interface ValidatorVerdict {
+  validator?: string;
+  cmra?: string;
+  rdi?: string;
+  status?: ValidationStatus;
+  reason?: string;
+}
+
+export type ValidationStatus = 'pending' | 'verified' | 'no_match' | 'ambiguous' | 'error' | 'quota_exhausted';
+
+export interface AddressAnalysis {
+  dpvMatchCode?: 'Y' | 'S' | 'D' | 'N' | string;
+  dpvFootnotes?: string;
+  dpvVacant?: 'Y' | 'N' | string;
+  dpvNoStat?: 'Y' | 'N' | string;
+  recordType?: string;
+  zipType?: string;
+  countyName?: string;
+  latitude?: number;
+  longitude?: number;
+  precision?: string;
+  timeZone?: string;
+}
+
+export interface Plan {
+  name?: string;
+  monthlyPrice?: number;
+  billingPeriod?: 'monthly' | 'annual' | string;
+  features?: string[];
 }
 
 export interface Mailbox {
@@ -11,34 +50,86 @@ export interface Mailbox {
   state?: string;
   zip?: string;
   price: number;
+  plans?: Plan[];
   link: string;
   cmra?: 'Y' | 'N' | 'Unknown' | string;
   rdi?: 'Residential' | 'Commercial' | 'Unknown' | string;
   standardizedAddress?: StandardizedAddress;
+  analysis?: AddressAnalysis;
+  candidates?: AddressCandidate[];
+  validationStatus?: ValidationStatus;
+  validationReason?: string;
+  validator?: string; // "smarty", "usps" or "consensus"
+  verdicts?: ValidatorVerdict[];
+  confidence?: number;
+  validatorsDisagree?: boolean;
   lastValidatedAt?: string;
   crawlRunId?: string;
-  source?: 'ATMB' | 'iPost1' | string;
+  source?: 'ATMB' | 'iPost1' | 'PostScanMail' | string;
 }
 
 export interface CrawlRun {
   id: string;
   startedAt: string;
   finishedAt?: string;
-  status: 'running' | 'success' | 'failed' | 'partial_halt' | 'timeout' | 'cancelled';
+  resumedAt?: string;
+  status: 'queued' | 'running' | 'paused' | 'success' | 'failed' | 'partial_halt' | 'timeout' | 'cancelled';
   stats: {
     found: number;
     validated: number;
     skipped: number;
     failed: number;
+    validation?: {
+      verified?: number;
+      noMatch?: number;
+      ambiguous?: number;
+      error?: number;
+      quotaExhausted?: number;
+    };
   };
-  errorsSample?: Array<{ link: string; reason: string }>;
+  errorsSample?: Array<{ link: string; stage?: string; reason: string }>;
+  sweep?: {
+    held?: boolean;
+    reason?: string;
+    coverage?: number;
+    baselineRunId?: string;
+    swept?: string[];
+    quarantined?: string[];
+  };
+}
+
+export interface RunEvent {
+  type: 'stats' | 'link_error' | 'log' | 'status';
+  runId: string;
+  time: string;
+  status?: CrawlRun['status'];
+  stats?: CrawlRun['stats'];
+  link?: string;
+  stage?: string;
+  message?: string;
+}
+
+export interface Schedule {
+  id: string;
+  action: 'crawl' | 'reprocess' | 'refresh_stats';
+  provider?: string;
+  cron: string;
+  onlyOutdated?: boolean;
+  nextRunAt?: string;
+  lastRunAt?: string;
+  lastRunId?: string;
+  lastError?: string;
+  createdAt?: string;
 }
 
 export interface MailboxFilter {
   state?: string;
   cmra?: 'Y' | 'N';
   rdi?: 'Residential' | 'Commercial';
-  source?: 'ATMB' | 'iPost1';
+  source?: 'ATMB' | 'iPost1' | 'PostScanMail';
+  dpvMatchCode?: 'Y' | 'S' | 'D' | 'N';
+  vacant?: 'Y' | 'N';
+  status?: ValidationStatus;
   search?: string;
   page: number;
   pageSize: number;